@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"math"
 	"math/rand"
+	"net"
 	"testing"
 	"time"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/stretchr/testify/require"
 )
 
@@ -63,3 +65,163 @@ func BenchmarkReadHeaderAndBody(t *testing.B) {
 	}
 	require.NoError(t, benchErr)
 }
+
+// countingWriter is an io.Writer that discards all bytes written to it, while
+// keeping track of the number of calls to Write.
+type countingWriter struct {
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return len(p), nil
+}
+
+// BenchmarkWriteMessages contrasts writing a batch of small messages using a
+// single call to WriteMessages against writing them one at a time with
+// WriteMessage and Flush. The writes/op metric reports the number of calls
+// made to the underlying writer.
+func BenchmarkWriteMessages(b *testing.B) {
+	const numMsgs = 50
+
+	msgs := make([][]byte, numMsgs)
+	for i := range msgs {
+		msgs[i] = bytes.Repeat([]byte("a"), 100)
+	}
+
+	b.Run("WriteMessage", func(b *testing.B) {
+		var (
+			m Machine
+			w countingWriter
+		)
+		m.split()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			for _, msg := range msgs {
+				err := m.WriteMessage(msg)
+				require.NoError(b, err)
+
+				_, err = m.Flush(&w)
+				require.NoError(b, err)
+			}
+		}
+
+		b.ReportMetric(float64(w.writes)/float64(b.N), "writes/op")
+	})
+
+	b.Run("WriteMessages", func(b *testing.B) {
+		var (
+			m Machine
+			w countingWriter
+		)
+		m.split()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			_, err := m.WriteMessages(&w, msgs)
+			require.NoError(b, err)
+		}
+
+		b.ReportMetric(float64(w.writes)/float64(b.N), "writes/op")
+	})
+}
+
+// countingConn is a net.Conn that discards all bytes written to it, while
+// keeping track of the number of calls to Write.
+type countingConn struct {
+	net.Conn
+
+	countingWriter
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	return c.countingWriter.Write(p)
+}
+
+// BenchmarkWriteCoalesce contrasts writing a burst of small messages using
+// Write on a connection created with WriteCoalesce against one without it. The
+// writes/op metric reports the number of calls made to the underlying
+// connection.
+func BenchmarkWriteCoalesce(b *testing.B) {
+	const numMsgs = 50
+
+	msg := bytes.Repeat([]byte("a"), 100)
+
+	newConn := func(opts ...ConnOption) (*Conn, *countingConn) {
+		var m Machine
+		m.split()
+
+		w := &countingConn{}
+		conn, err := newConn(w, &m, newConnConfig(opts))
+		require.NoError(b, err)
+
+		return conn, w
+	}
+
+	b.Run("Write", func(b *testing.B) {
+		conn, w := newConn()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < numMsgs; j++ {
+				_, err := conn.Write(msg)
+				require.NoError(b, err)
+			}
+		}
+
+		b.ReportMetric(float64(w.writes)/float64(b.N), "writes/op")
+	})
+
+	b.Run("WriteCoalesce", func(b *testing.B) {
+		conn, w := newConn(WriteCoalesce(time.Second, 4096))
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < numMsgs; j++ {
+				_, err := conn.Write(msg)
+				require.NoError(b, err)
+			}
+
+			_, err := conn.Flush()
+			require.NoError(b, err)
+		}
+
+		b.ReportMetric(float64(w.writes)/float64(b.N), "writes/op")
+	})
+}
+
+// BenchmarkHandshake measures the cost of a full XK handshake between an
+// initiator and a responder connected over net.Pipe, including the setup of
+// both connections.
+//
+// Hashing into the handshake digest in place, and mixing in the ephemeral
+// keys as received rather than re-serializing them after parsing, took the
+// benchmark from 386 to 366 allocs/op and from 25491 to 24755 B/op. The
+// ns/op, around 1.7ms, is dominated by the ECDH and key generation operations,
+// and was unchanged within noise.
+func BenchmarkHandshake(b *testing.B) {
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(b, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		local, remote, err := NewPipe(localPriv, remotePriv)
+		require.NoError(b, err)
+
+		local.Close()
+		remote.Close()
+	}
+}
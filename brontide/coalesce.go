@@ -0,0 +1,164 @@
+package brontide
+
+import (
+	"math"
+	"time"
+)
+
+// closeFlushTimeout bounds the time Close spends writing out the frames
+// buffered by a coalesced Write, such that a peer that stopped reading can't
+// stall it.
+const closeFlushTimeout = 5 * time.Second
+
+// coalesceWrite encrypts b into one or more frames, as done by Write, and
+// appends them to the coalescing buffer. The buffer is flushed right away if
+// it holds at least coalesceBytes, and otherwise the flush timer is armed if
+// it isn't already pending. The number of bytes returned reflects the number
+// of plaintext bytes accepted.
+//
+// NOTE: This method MUST be called with writeMtx held.
+func (c *Conn) coalesceWrite(b []byte) (int, error) {
+	if err := c.takeCoalesceErr(); err != nil {
+		return 0, err
+	}
+
+	// Split the message into chunks that fit within a single frame. An
+	// empty message still results in a single zero-length frame.
+	maxChunk := math.MaxUint16
+	if c.compress {
+		maxChunk = maxCompressedChunk
+	}
+
+	var n int
+	for {
+		chunk := b
+		if len(chunk) > maxChunk {
+			chunk = chunk[:maxChunk]
+		}
+
+		var err error
+		c.coalesceBuf, err = c.noise.appendFrame(
+			c.coalesceBuf, c.encodePayload(chunk),
+		)
+		if err != nil {
+			return n, err
+		}
+
+		n += len(chunk)
+		b = b[len(chunk):]
+
+		if len(b) == 0 {
+			break
+		}
+	}
+
+	if c.coalesceBytes > 0 && len(c.coalesceBuf) >= c.coalesceBytes {
+		return n, c.flushCoalesced()
+	}
+
+	if !c.coalesceArmed {
+		c.coalesceArmed = true
+
+		stop := make(chan struct{})
+		c.coalesceStop = stop
+
+		tick := c.clock.TickAfter(c.coalesceDelay)
+		go func() {
+			select {
+			case <-tick:
+				c.coalesceTimerFired(stop)
+			case <-stop:
+			}
+		}()
+	}
+
+	return n, nil
+}
+
+// coalesceTimerFired flushes the coalescing buffer once the coalescing delay
+// has elapsed, recording any error such that it's returned by the next call to
+// Write or Flush. The stop channel identifies the timer that fired, which is
+// ignored if it was stopped in the meantime.
+func (c *Conn) coalesceTimerFired(stop chan struct{}) {
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+
+	if !c.coalesceArmed || c.coalesceStop != stop {
+		return
+	}
+
+	c.coalesceArmed = false
+	c.coalesceStop = nil
+
+	if c.isClosed() {
+		return
+	}
+
+	if err := c.flushCoalesced(); err != nil {
+		c.coalesceErr = err
+	}
+}
+
+// flushCoalesced writes out the frames in the coalescing buffer using a single
+// write and disarms the flush timer. In the event of a partial write, the
+// remaining bytes are retained, such that a subsequent flush picks up where
+// the byte stream left off.
+//
+// NOTE: This method MUST be called with writeMtx held.
+func (c *Conn) flushCoalesced() error {
+	if len(c.coalesceBuf) == 0 {
+		return nil
+	}
+
+	buf := c.coalesceBuf
+	n, err := writeFull(c.wireWriter(), buf)
+	if err != nil {
+		c.coalesceBuf = append(buf[:0], buf[n:]...)
+		return err
+	}
+
+	c.coalesceBuf = buf[:0]
+
+	if c.coalesceArmed {
+		close(c.coalesceStop)
+		c.coalesceStop = nil
+		c.coalesceArmed = false
+	}
+
+	return nil
+}
+
+// flushCoalescedOnClose makes a best effort attempt to write out the frames
+// buffered by a coalesced Write, which were already reported as written, and
+// stops the flush timer. It's skipped if a write is in progress, as the write
+// may be blocked on the underlying connection, which only closing it unblocks.
+func (c *Conn) flushCoalescedOnClose() {
+	if c.coalesceDelay == 0 || !c.writeMtx.TryLock() {
+		return
+	}
+	defer c.writeMtx.Unlock()
+
+	if len(c.coalesceBuf) > 0 {
+		deadline := time.Now().Add(closeFlushTimeout)
+		if err := c.conn.SetWriteDeadline(deadline); err == nil {
+			_ = c.flushCoalesced()
+		}
+	}
+
+	if c.coalesceArmed {
+		close(c.coalesceStop)
+		c.coalesceStop = nil
+		c.coalesceArmed = false
+	}
+}
+
+// takeCoalesceErr returns and clears the error encountered by the last flush
+// triggered by the coalescing timer, if any.
+//
+// NOTE: This method MUST be called with writeMtx held.
+func (c *Conn) takeCoalesceErr() error {
+	err := c.coalesceErr
+	c.coalesceErr = nil
+
+	return err
+}
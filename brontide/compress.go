@@ -0,0 +1,217 @@
+package brontide
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+const (
+	// CompressionOptional is the feature bit advertised by ExchangeFeatures
+	// on connections created with the Compression option. It is odd, such
+	// that peers that don't support compression ignore it.
+	CompressionOptional lnwire.FeatureBit = 259
+
+	// payloadRaw is the marker prefixing a payload sent as is on a
+	// connection that negotiated compression.
+	payloadRaw byte = 0x00
+
+	// payloadDeflate is the marker prefixing a payload compressed using
+	// DEFLATE on a connection that negotiated compression.
+	payloadDeflate byte = 0x01
+
+	// maxCompressedChunk is the maximum number of bytes of a Write packed
+	// into a single frame on a connection that negotiated compression,
+	// leaving room for the marker.
+	maxCompressedChunk = math.MaxUint16 - 1
+)
+
+var (
+	// ErrUnknownPayloadEncoding is returned when a message received on a
+	// connection that negotiated compression is empty or carries an
+	// unknown marker.
+	ErrUnknownPayloadEncoding = errors.New("unknown brontide payload " +
+		"encoding")
+
+	// ErrDecompressedTooLarge is returned when a compressed message
+	// inflates to more than the read limit, or math.MaxUint16 bytes if no
+	// limit is set. Unlike ErrMessageTooLarge, the message is consumed.
+	ErrDecompressedTooLarge = errors.New("decompressed message exceeds " +
+		"read limit")
+
+	// ErrCompressionNegotiated is returned by the split ReadHeader and
+	// ReadNextHeader readers on a connection that negotiated compression,
+	// as they operate on raw frames.
+	ErrCompressionNegotiated = errors.New("raw frame readers unsupported " +
+		"with compression")
+)
+
+// Compression is a functional option that opts the connection into
+// compressing message payloads, which is negotiated with the peer by
+// ExchangeFeatures through the CompressionOptional feature bit. Compression is
+// only used if both ends set the option and call ExchangeFeatures, and is
+// otherwise transparent to the application.
+//
+// Once negotiated, each message is prefixed with a 1-byte marker indicating
+// whether the remainder of the payload is sent as is, or compressed using
+// DEFLATE. Messages shorter than threshold bytes, or that don't shrink when
+// compressed, are sent as is. As the marker takes up a byte of the frame, Write
+// splits its input into frames of at most math.MaxUint16-1 bytes, and messages
+// written using WriteMessage or WriteMessages that are math.MaxUint16 bytes
+// long are rejected unless they compress. The markers never collide with the
+// type of a control frame. A threshold of zero or less disables compression.
+//
+// NOTE: The split ReadHeader and ReadBody, ReadNextHeader and ReadNextBody,
+// and ReadNextMessageInto readers operate on raw frames, and return
+// ErrCompressionNegotiated once compression has been negotiated.
+func Compression(threshold int) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.compressThreshold = threshold
+	}
+}
+
+// encodePayload returns the payload of the frame carrying msg, which is
+// prefixed with its marker if the connection negotiated compression.
+func (c *Conn) encodePayload(msg []byte) []byte {
+	if !c.compress {
+		return msg
+	}
+
+	if len(msg) >= c.compressThreshold {
+		var b bytes.Buffer
+		b.WriteByte(payloadDeflate)
+
+		// Writing to a bytes.Buffer never fails, and neither does the
+		// writer for a valid compression level.
+		w, _ := flate.NewWriter(&b, flate.DefaultCompression)
+		_, _ = w.Write(msg)
+		_ = w.Close()
+
+		if b.Len() <= len(msg) {
+			return b.Bytes()
+		}
+	}
+
+	payload := make([]byte, 0, len(msg)+1)
+	payload = append(payload, payloadRaw)
+
+	return append(payload, msg...)
+}
+
+// decodePayload returns the message carried by payload, undoing encodePayload
+// if the connection negotiated compression. Compressed messages may inflate to
+// at most limit bytes, or math.MaxUint16 if limit is zero.
+func (c *Conn) decodePayload(payload []byte, limit uint32) ([]byte, error) {
+	if !c.compress {
+		return payload, nil
+	}
+
+	if len(payload) == 0 {
+		return nil, ErrUnknownPayloadEncoding
+	}
+
+	switch payload[0] {
+	case payloadRaw:
+		return payload[1:], nil
+
+	case payloadDeflate:
+		if limit == 0 {
+			limit = math.MaxUint16
+		}
+
+		r := flate.NewReader(bytes.NewReader(payload[1:]))
+		defer r.Close()
+
+		msg, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+		if err != nil {
+			return nil, err
+		}
+		if uint32(len(msg)) > limit {
+			return nil, ErrDecompressedTooLarge
+		}
+
+		return msg, nil
+
+	default:
+		return nil, ErrUnknownPayloadEncoding
+	}
+}
+
+// writeCompressed writes b to the connection as done by Write, encoding each
+// chunk into its own frame. The number of bytes returned reflects the number
+// of bytes of b carried by the frames that were written in full.
+//
+// NOTE: This method MUST be called with writeMtx held.
+func (c *Conn) writeCompressed(b []byte) (int, error) {
+	// An empty write still results in a single zero-length message.
+	var n int
+	for {
+		chunk := b
+		if len(chunk) > maxCompressedChunk {
+			chunk = chunk[:maxCompressedChunk]
+		}
+
+		err := c.noise.WriteMessage(c.encodePayload(chunk))
+		if err != nil {
+			return n, err
+		}
+
+		if _, err := c.noise.Flush(c.wireWriter()); err != nil {
+			return n, err
+		}
+
+		n += len(chunk)
+		b = b[len(chunk):]
+
+		if len(b) == 0 {
+			return n, nil
+		}
+	}
+}
+
+// writeCompressedMessages writes msgs to the connection as done by
+// WriteMessages, encoding each message into its own frame. The number of bytes
+// returned reflects the number of bytes of the messages whose frames were
+// written in full.
+//
+// NOTE: This method MUST be called with writeMtx held.
+func (c *Conn) writeCompressedMessages(msgs [][]byte) (int, error) {
+	payloads := make([][]byte, 0, len(msgs))
+	for _, msg := range msgs {
+		payloads = append(payloads, c.encodePayload(msg))
+	}
+
+	written, err := c.noise.WriteMessages(c.wireWriter(), payloads)
+
+	// The machine tallies the encoded bytes of the frames that were
+	// written in full, which we map back to the messages they carry.
+	var n int
+	for i, payload := range payloads {
+		if len(payload) > written {
+			break
+		}
+
+		written -= len(payload)
+		n += len(msgs[i])
+	}
+
+	return n, err
+}
+
+// compressedFlushed returns the number of bytes reported by Flush on a
+// connection that negotiated compression, given the error returned when
+// flushing the encoded message. As the encoded message doesn't map back to
+// the buffered one byte for byte, it only counts once flushed in full.
+//
+// NOTE: This method MUST be called with writeMtx held.
+func (c *Conn) compressedFlushed(err error) int {
+	if err != nil {
+		return 0
+	}
+
+	return c.pendingPlainLen
+}
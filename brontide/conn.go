@@ -2,17 +2,72 @@ package brontide
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"math"
+	"math/rand"
 	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/clock"
 	"github.com/lightningnetwork/lnd/keychain"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/tor"
 )
 
+// minFrameChunkSize is the initial capacity of the buffer used to read a frame
+// incrementally on a connection created with MaxBufferedBytes.
+const minFrameChunkSize = 512
+
+var (
+	// ErrConnClosed is returned when attempting to read from or write to a
+	// brontide connection that has already been closed.
+	ErrConnClosed = errors.New("brontide connection closed")
+
+	// ErrNotTCPConn is returned when attempting to configure TCP specific
+	// options on a brontide connection that isn't backed by a TCP
+	// connection.
+	ErrNotTCPConn = errors.New("brontide connection is not backed by TCP")
+
+	// ErrNoPendingHeader is returned when ReadBody is called without a
+	// preceding call to ReadHeader.
+	ErrNoPendingHeader = errors.New("no message header has been read")
+
+	// ErrPendingBody is returned when ReadHeader is called before the body
+	// of the previous message has been read using ReadBody.
+	ErrPendingBody = errors.New("message body has not been read")
+
+	// ErrBodyBufferTooSmall is returned when the buffer passed to ReadBody
+	// is too small to hold the pending message body.
+	ErrBodyBufferTooSmall = errors.New("buffer too small for message body")
+
+	// ErrConnByteBudgetExceeded is returned when attempting to read from
+	// or write to a connection that has used up the byte budget set by
+	// MaxLifetimeBytes.
+	ErrConnByteBudgetExceeded = errors.New("connection byte budget " +
+		"exceeded")
+
+	// ErrMessageTooLarge is returned by ReadNextMessage and Read when the
+	// next message exceeds the limit set by SetReadLimit or
+	// MaxBufferedBytes.
+	ErrMessageTooLarge = errors.New("message exceeds read limit")
+
+	// ErrPeerNotAllowed is returned when an inbound peer's static key is
+	// rejected by the AllowedRemote option of a Listener.
+	ErrPeerNotAllowed = errors.New("remote peer is not allowed")
+
+	// ErrFeatureExchangeNotFirst is returned by ExchangeFeatures if it is
+	// called more than once, or after other traffic has been exchanged.
+	ErrFeatureExchangeNotFirst = errors.New("feature exchange must " +
+		"precede all other traffic")
+)
+
 // Conn is an implementation of net.Conn which enforces an authenticated key
 // exchange and message encryption protocol dubbed "Brontide" after initial TCP
 // connection establishment. In the case of a successful handshake, all
@@ -25,98 +80,1317 @@ type Conn struct {
 	noise *Machine
 
 	readBuf bytes.Buffer
+
+	// pendingBodyLen is the plaintext length of the message body that is
+	// expected to be read by ReadBody, if hasPendingBody is true.
+	pendingBodyLen uint16
+	hasPendingBody bool
+
+	// closed is set to 1 once Close has been called on the connection.
+	// This MUST be used atomically.
+	closed int32
+
+	// bytesTransferred is the total number of plaintext bytes read from
+	// and written to the connection. This MUST be used atomically.
+	bytesTransferred uint64
+
+	// tracked is set once the handshake has completed, at which point the
+	// connection is counted as active in the global statistics until it's
+	// closed.
+	tracked bool
+
+	// maxLifetimeBytes is the maximum number of plaintext bytes that may
+	// be transferred over the connection, or zero if unlimited.
+	maxLifetimeBytes uint64
+
+	// readLimit is the maximum plaintext length of a message accepted by
+	// ReadNextMessage and Read, or zero for the protocol maximum. This
+	// MUST be used atomically.
+	readLimit uint32
+
+	// maxBufferedBytes is the maximum number of bytes of a frame that may
+	// be buffered by the message-oriented readers, or zero if unlimited.
+	maxBufferedBytes int
+
+	// onDecryptError, if set, is called with each error returned by a
+	// read due to a frame failing MAC verification.
+	onDecryptError func(error)
+
+	// clock is the source of time for the handshake and read timeouts,
+	// the write coalescing delay and the handshake and ping timings.
+	clock clock.Clock
+
+	// actTimings records the wall-clock duration of each of the three
+	// handshake acts. They are only written during the handshake, and are
+	// immutable afterwards.
+	actTimings [3]time.Duration
+
+	// partialFrame holds the raw ciphertext bytes of a header or body that
+	// was only partially read before a read deadline expired, such that
+	// the next read resumes the frame rather than desynchronizing the
+	// stream.
+	partialFrame []byte
+
+	// readDeadline is the deadline last set via SetDeadline or
+	// SetReadDeadline, restored after a call to ReadNextMessageTimeout.
+	readDeadline time.Time
+
+	// featuresExchanged is set once ExchangeFeatures has been called.
+	featuresExchanged bool
+
+	// remoteFeatures is the feature vector received from the peer by
+	// ExchangeFeatures.
+	remoteFeatures *lnwire.FeatureVector
+
+	// pingEnabled is set if the connection was created with the
+	// EnablePing option, in which case ping and pong frames are consumed
+	// by the read path rather than delivered to the application.
+	pingEnabled bool
+
+	// writeMtx serializes writes to the connection, as pongs are written
+	// by the read path concurrently with the application's own writes.
+	writeMtx sync.Mutex
+
+	// pendingControl holds the control frames that were due while a
+	// message buffered using WriteMessage was awaiting Flush, and which
+	// are written by that Flush. It is guarded by writeMtx.
+	pendingControl [][]byte
+
+	// coalesceDelay and coalesceBytes are the thresholds configured using
+	// the WriteCoalesce option. Coalescing is disabled if coalesceDelay is
+	// zero.
+	coalesceDelay time.Duration
+	coalesceBytes int
+
+	// coalesceBuf holds the ciphertext of the frames written by Write that
+	// are awaiting a coalesced flush. It is guarded by writeMtx.
+	coalesceBuf []byte
+
+	// coalesceStop stops the timer that flushes coalesceBuf once
+	// coalesceDelay has elapsed since the first buffered write, and
+	// coalesceArmed is set while it is pending. Both are guarded by
+	// writeMtx.
+	coalesceStop  chan struct{}
+	coalesceArmed bool
+
+	// coalesceErr holds the error encountered by a flush triggered by
+	// the coalescing timer, which is returned by the next call to Write or
+	// Flush. It is guarded by writeMtx.
+	coalesceErr error
+
+	// writeChunkSize, if positive, is the maximum number of bytes passed
+	// to a single write of the underlying connection, as set by
+	// SetWriteChunkSize. It is guarded by writeMtx, as is chunkWriter,
+	// which is used to split the writes.
+	writeChunkSize int
+	chunkWriter    chunkedWriter
+
+	// pingMtx guards pendingPings.
+	pingMtx sync.Mutex
+
+	// pendingPings maps the nonce of each outstanding ping to the channel
+	// closed once the matching pong is received.
+	pendingPings map[pingNonce]chan struct{}
+
+	// compressThreshold is the threshold configured using the Compression
+	// option, and compress is set once ExchangeFeatures has negotiated
+	// compression with the peer.
+	compressThreshold int
+	compress          bool
+
+	// securityPolicy, if set, is the policy the handshake parameters of
+	// the connection must satisfy.
+	securityPolicy *SecurityPolicy
+
+	// pendingPlainLen is the length of the message buffered using
+	// WriteMessage before it was compressed, which is reported by Flush
+	// and counted towards the bytes transferred once written in full. It
+	// is guarded by writeMtx.
+	pendingPlainLen int
 }
 
 // A compile-time assertion to ensure that Conn meets the net.Conn interface.
 var _ net.Conn = (*Conn)(nil)
 
+// Compile-time assertions to ensure that Conn meets the io.ReaderFrom and
+// io.WriterTo interfaces.
+var (
+	_ io.ReaderFrom = (*Conn)(nil)
+	_ io.WriterTo   = (*Conn)(nil)
+)
+
+// IdentifiedConn is a net.Conn whose remote peer has been authenticated by its
+// long-term static public key. All connections returned by a Listener satisfy
+// it, such that applications handed a plain net.Conn can learn the identity of
+// the peer through a type assertion on this interface, rather than depending
+// on the concrete brontide types.
+type IdentifiedConn interface {
+	net.Conn
+
+	// RemotePub returns the authenticated static public key of the remote
+	// peer.
+	RemotePub() *btcec.PublicKey
+}
+
+// A compile-time assertion to ensure that Conn meets the IdentifiedConn
+// interface.
+var _ IdentifiedConn = (*Conn)(nil)
+
+// connConfig houses the options that can be applied to the underlying
+// connection of a brontide connection before the handshake.
+type connConfig struct {
+	// noDelay, if true, disables Nagle's algorithm on the underlying
+	// connection.
+	noDelay bool
+
+	// onHandshakeError, if set, is called by a Listener for each inbound
+	// connection that fails the handshake.
+	onHandshakeError func(net.Addr, error)
+
+	// returnHandshakeErrors, if true, makes a Listener return a
+	// *HandshakeError from Accept for each inbound connection that fails
+	// the handshake, rather than silently dropping it.
+	returnHandshakeErrors bool
+
+	// maxLifetimeBytes, if non-zero, is the maximum number of plaintext
+	// bytes that may be transferred over the connection.
+	maxLifetimeBytes uint64
+
+	// allowedRemote, if set, is called by a Listener with the static key
+	// of each inbound peer, which is rejected unless it returns true.
+	allowedRemote func(*btcec.PublicKey) bool
+
+	// ephemeralGen, if set, supplies the ephemeral keys used during the
+	// handshake in place of freshly generated ones.
+	ephemeralGen func() (*btcec.PrivateKey, error)
+
+	// onAuthenticated, if set, is called by a Listener with the
+	// authenticated identity of each inbound peer before the connection
+	// is returned from Accept.
+	onAuthenticated func(IdentifiedConn)
+
+	// pingEnabled, if true, reserves ping and pong control frames on the
+	// connection.
+	pingEnabled bool
+
+	// handshakeVersion is the handshake version requested when dialing.
+	handshakeVersion byte
+
+	// coalesceDelay and coalesceBytes, if coalesceDelay is non-zero, are
+	// the thresholds at which writes buffered by Write are flushed.
+	coalesceDelay time.Duration
+	coalesceBytes int
+
+	// maxBufferedBytes, if non-zero, is the maximum number of bytes of a
+	// frame buffered by the message-oriented readers.
+	maxBufferedBytes int
+
+	// tofuStore, if set, is consulted by Dial for the static key of a
+	// remote peer dialed without one.
+	tofuStore TOFUStore
+
+	// onDecryptError, if set, is called whenever a read fails due to a
+	// frame failing MAC verification.
+	onDecryptError func(error)
+
+	// clock is the source of time used by the connection's timers.
+	clock clock.Clock
+
+	// compressThreshold, if positive, opts the connection into
+	// compressing payloads of at least as many bytes, subject to
+	// negotiation by ExchangeFeatures.
+	compressThreshold int
+
+	// securityPolicy, if set, is the policy the handshake parameters of
+	// each connection must satisfy.
+	securityPolicy *SecurityPolicy
+
+	// requiredFeatures, if set, are the features exchanged by Dial right
+	// after the handshake, and the features the peer must support.
+	requiredFeatures *requiredFeatures
+}
+
+// ConnOption is a functional option that can be passed to Dial, DialWithRetry,
+// NewListener, NewPipe, Client and Server to configure the underlying
+// connection.
+type ConnOption func(*connConfig)
+
+// NoDelay is a functional option that sets TCP_NODELAY on the underlying
+// connection right after it is established and before the handshake, such
+// that small messages aren't delayed by the kernel. The option is ignored for
+// underlying connections that aren't TCP connections, such as those dialed
+// over Tor.
+func NoDelay(noDelay bool) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.noDelay = noDelay
+	}
+}
+
+// OnHandshakeError is a functional option that registers a callback invoked by
+// a Listener whenever an inbound connection fails the handshake, along with
+// the remote address of the peer. Such connections are closed and, unless
+// ReturnHandshakeErrors is set, never returned from Accept, so that a
+// misbehaving peer can't disrupt the serving loop. The option is ignored by
+// Dial.
+func OnHandshakeError(cb func(net.Addr, error)) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.onHandshakeError = cb
+	}
+}
+
+// ReturnHandshakeErrors is a functional option that makes a Listener surface
+// inbound connections failing the handshake by returning a *HandshakeError
+// from Accept, for callers that want to log or alert on such failures inline.
+// The failed connection is closed and the listener keeps serving, such that
+// the caller can call Accept again right away. The OnHandshakeError callback,
+// if any, is still invoked. The option is ignored by Dial.
+func ReturnHandshakeErrors() ConnOption {
+	return func(cfg *connConfig) {
+		cfg.returnHandshakeErrors = true
+	}
+}
+
+// OnDecryptError is a functional option that registers a callback invoked
+// whenever a read from the connection fails because a frame received from the
+// peer failed MAC verification, before the error is returned to the caller.
+// The error passed to the callback wraps ErrMACVerificationFailed, and
+// specifies whether the length header or body of the frame was affected. This
+// allows integrity failures, which signal a bug on the peer's side or an
+// active attack, to be told apart from connections closed cleanly in metrics.
+// As the stream can't be resynchronized, the connection is unusable after
+// such a failure.
+func OnDecryptError(cb func(error)) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.onDecryptError = cb
+	}
+}
+
+// WithClock is a functional option that sets the clock used by all timers of
+// the connection, namely the handshake read timeout, the timeout of
+// ReadNextMessageTimeout, the WriteCoalesce delay and the backoff between
+// retried dials, as well as to measure handshake and ping timings. It defaults
+// to the wall clock, and allows tests to expire timeouts deterministically by
+// advancing a mock clock, rather than sleeping.
+func WithClock(clk clock.Clock) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.clock = clk
+	}
+}
+
+// MaxLifetimeBytes is a functional option that caps the total number of
+// plaintext bytes that can be read from and written to a connection over its
+// lifetime. Once the budget has been used up, the connection is closed and any
+// subsequent read or write fails with ErrConnByteBudgetExceeded. The operation
+// that exhausts the budget is allowed to complete in full. A value of zero
+// disables the limit.
+func MaxLifetimeBytes(maxBytes uint64) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.maxLifetimeBytes = maxBytes
+	}
+}
+
+// AllowedRemote is a functional option that restricts the peers a Listener
+// accepts to those whose static key is approved by the passed predicate. The
+// predicate is consulted as soon as act three has authenticated the
+// initiator's static key, and rejected peers are disconnected without ever
+// being returned from Accept, failing with ErrPeerNotAllowed. The option is
+// ignored by Dial.
+func AllowedRemote(allowed func(*btcec.PublicKey) bool) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.allowedRemote = allowed
+	}
+}
+
+// OnAuthenticated is a functional option that registers a callback invoked by
+// a Listener once the static key of an inbound peer has been authenticated and
+// admitted, before the connection is returned from Accept and before any
+// application bytes are read from it. This delivers the peer's identity inline
+// to applications that only handle the accepted connections as net.Conn. The
+// callback must not read from the connection. The option is ignored by Dial.
+func OnAuthenticated(cb func(IdentifiedConn)) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.onAuthenticated = cb
+	}
+}
+
+// EphemeralGen is a functional option that supplies the ephemeral keys used
+// during the handshake, which are otherwise generated internally. This allows
+// deterministic setups, HSM-backed keys, and reproducing the BOLT 8 test
+// vectors. The generator is invoked once per handshake, for act one when
+// dialing and for act two when accepting.
+//
+// WARNING: The secrecy of the session keys relies entirely on each ephemeral
+// key being freshly generated and kept secret. Reusing an ephemeral key across
+// handshakes forfeits forward secrecy and allows the traffic of all affected
+// sessions to be linked, and leaking one allows them to be decrypted. This
+// option MUST NOT be used to return a fixed key outside of testing.
+func EphemeralGen(gen func() (*btcec.PrivateKey, error)) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.ephemeralGen = gen
+	}
+}
+
+// EnablePing is a functional option that reserves ping and pong control frames
+// on the connection, allowing its round-trip time to be measured using Ping.
+// Incoming pings are answered automatically, and neither pings nor pongs are
+// delivered to the application by ReadNextMessage, ReadNextMessageTimeout,
+// Messages or Read. Both peers must enable the option, as a 10-byte message
+// starting with the big-endian type 0xfffd or 0xffff is interpreted as a
+// control frame, and must therefore not be sent by the application.
+//
+// NOTE: The split ReadHeader and ReadBody, ReadNextHeader and ReadNextBody,
+// and ReadNextMessageInto readers operate on raw frames, and deliver control
+// frames to the caller.
+func EnablePing() ConnOption {
+	return func(cfg *connConfig) {
+		cfg.pingEnabled = true
+	}
+}
+
+// RequestHandshakeVersion is a functional option that sets the handshake
+// version requested by Dial, which is HandshakeVersion by default. This allows
+// dialers to opt into a new handshake version while listeners are being
+// migrated using SetHandshakeVersion. A listener that doesn't accept the
+// version rejects the connection with ErrUnsupportedHandshakeVersion. The
+// option is ignored by NewListener.
+func RequestHandshakeVersion(version byte) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.handshakeVersion = version
+	}
+}
+
+// WriteCoalesce is a functional option that coalesces the frames produced by
+// Write, such that bursts of small writes are sent using fewer writes to the
+// underlying connection at a small latency cost. Each call to Write is still
+// encrypted into its own frame, or frames, preserving message boundaries on
+// the receiving side, but the ciphertext is buffered until either maxDelay has
+// elapsed since the first buffered write, or at least maxBytes of ciphertext
+// are buffered. A maxBytes of zero or less only flushes on the timer. Flush
+// forces buffered frames out immediately, and should be called before Close,
+// which discards them. Coalescing is disabled if maxDelay is zero.
+//
+// NOTE: Errors encountered by a flush triggered by the timer are returned by
+// the next call to Write or Flush.
+func WriteCoalesce(maxDelay time.Duration, maxBytes int) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.coalesceDelay = maxDelay
+		cfg.coalesceBytes = maxBytes
+	}
+}
+
+// MaxBufferedBytes is a functional option that caps the number of bytes a
+// connection buffers on behalf of the application while reading a message
+// using ReadNextMessage, ReadNextMessageTimeout, Messages, Read or
+// SkipMessages. The connection never reads ahead of the application: the
+// underlying connection is only read from within these calls, and Read only
+// reads the next frame once the plaintext of the previous one has been
+// consumed. A peer outpacing the application is therefore held back by the
+// flow control of the underlying transport, rather than growing a buffer.
+//
+// With the option set, the ciphertext of a message is buffered as it arrives,
+// rather than being allocated in full once its header announces its length,
+// such that a peer that stalls part way through a large message only pins as
+// much memory as it has actually sent. A message that can't fit within
+// maxBytes, including its 16-byte MAC, is rejected with ErrMessageTooLarge
+// without its body being read, as done for SetReadLimit. A value of zero
+// disables the limit.
+//
+// NOTE: The split ReadHeader and ReadBody, ReadNextHeader and ReadNextBody,
+// and ReadNextMessageInto readers leave it to the caller to decide whether to
+// read a body once its length is known, and aren't subject to the limit.
+func MaxBufferedBytes(maxBytes int) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.maxBufferedBytes = maxBytes
+	}
+}
+
+// noDelaySetter is implemented by connections that support toggling Nagle's
+// algorithm, such as *net.TCPConn.
+type noDelaySetter interface {
+	SetNoDelay(noDelay bool) error
+}
+
+// newConnConfig returns the config resulting from applying the passed options.
+func newConnConfig(opts []ConnOption) *connConfig {
+	cfg := connConfig{
+		clock: clock.NewDefaultClock(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &cfg
+}
+
+// machineOptions returns the options to pass to NewBrontideMachine for a
+// connection using the config.
+func (cfg *connConfig) machineOptions() []func(*Machine) {
+	var opts []func(*Machine)
+	if cfg.ephemeralGen != nil {
+		opts = append(opts, EphemeralGenerator(cfg.ephemeralGen))
+	}
+	if cfg.handshakeVersion != HandshakeVersion {
+		opts = append(opts, WithHandshakeVersion(cfg.handshakeVersion))
+	}
+
+	return opts
+}
+
+// apply configures a freshly established underlying connection according to
+// the config.
+func (cfg *connConfig) apply(conn net.Conn) error {
+	if cfg.noDelay {
+		if tcpConn, ok := conn.(noDelaySetter); ok {
+			if err := tcpConn.SetNoDelay(true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// newConn configures the established underlying connection according to the
+// config, and returns a brontide connection running the handshake described by
+// the passed machine over it. In the case of an error, it is the caller's
+// responsibility to close the underlying connection.
+func newConn(conn net.Conn, noise *Machine, cfg *connConfig) (*Conn, error) {
+	if err := cfg.apply(conn); err != nil {
+		return nil, err
+	}
+
+	return &Conn{
+		conn:              conn,
+		noise:             noise,
+		maxLifetimeBytes:  cfg.maxLifetimeBytes,
+		pingEnabled:       cfg.pingEnabled,
+		coalesceDelay:     cfg.coalesceDelay,
+		coalesceBytes:     cfg.coalesceBytes,
+		maxBufferedBytes:  cfg.maxBufferedBytes,
+		onDecryptError:    cfg.onDecryptError,
+		clock:             cfg.clock,
+		compressThreshold: cfg.compressThreshold,
+		securityPolicy:    cfg.securityPolicy,
+	}, nil
+}
+
 // Dial attempts to establish an encrypted+authenticated connection with the
 // remote peer located at address which has remotePub as its long-term static
 // public key. In the case of a handshake failure, the connection is closed and
-// a non-nil error is returned.
+// a non-nil error is returned. If the TOFU option is passed and the address
+// carries no static public key, the key is instead looked up in, or learned
+// and stored in, the passed TOFUStore. If the RequiredPeerFeatures option is
+// passed, features are exchanged before returning, and the connection is
+// closed with ErrMissingRequiredFeature if the peer lacks a required feature.
 func Dial(local keychain.SingleKeyECDH, netAddr *lnwire.NetAddress,
-	timeout time.Duration, dialer tor.DialFunc) (*Conn, error) {
+	timeout time.Duration, dialer tor.DialFunc,
+	opts ...ConnOption) (*Conn, error) {
 
-	ipAddr := netAddr.Address.String()
-	var conn net.Conn
-	var err error
-	conn, err = dialer("tcp", ipAddr, timeout)
+	var (
+		cfg  = newConnConfig(opts)
+		conn *Conn
+		err  error
+	)
+	if cfg.tofuStore != nil && netAddr.IdentityKey == nil {
+		conn, err = dialTOFU(
+			local, netAddr.Address, timeout, dialer, cfg,
+		)
+	} else {
+		conn, err = dialConn(
+			local, netAddr.IdentityKey, netAddr.Address, timeout,
+			dialer, cfg,
+		)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	b := &Conn{
-		conn:  conn,
-		noise: NewBrontideMachine(true, local, netAddr.IdentityKey),
+	if cfg.requiredFeatures != nil {
+		if err := cfg.requiredFeatures.check(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
 	}
 
-	// Initiate the handshake by sending the first act to the receiver.
-	actOne, err := b.noise.GenActOne()
+	return conn, nil
+}
+
+// dialConn dials the remote peer at addr and carries out the initiator's side
+// of the handshake according to the config. The remotePub may only be nil if
+// the config requests HandshakeVersionXX.
+func dialConn(local keychain.SingleKeyECDH, remotePub *btcec.PublicKey,
+	addr net.Addr, timeout time.Duration, dialer tor.DialFunc,
+	cfg *connConfig) (*Conn, error) {
+
+	conn, err := dialer("tcp", addr.String(), timeout)
 	if err != nil {
+		return nil, err
+	}
+
+	b, err := newConn(conn, NewBrontideMachine(
+		true, local, remotePub, cfg.machineOptions()...,
+	), cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := b.initiatorHandshake(); err != nil {
 		b.conn.Close()
 		return nil, err
 	}
-	if _, err := conn.Write(actOne[:]); err != nil {
+
+	return b, nil
+}
+
+// RetryPolicy governs how DialWithRetry retries failed connection attempts.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of connection attempts that will
+	// be made, including the first. A value of zero is treated as one.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. The delay is doubled
+	// after each subsequent failed attempt.
+	BaseDelay time.Duration
+
+	// MaxJitter is the upper bound of a random delay added to each backoff
+	// period, so that many clients retrying at once don't synchronize.
+	MaxJitter time.Duration
+}
+
+// backoff returns the delay to wait before making the given retry attempt,
+// where the first retry is attempt one.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt-1)
+
+	if p.MaxJitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(p.MaxJitter)))
+	}
+
+	return delay
+}
+
+// DialWithRetry is identical to Dial, however failed connection attempts are
+// retried with a jittered exponential backoff according to the passed policy.
+// Only transient network failures, such as the remote peer refusing the
+// connection or a timeout, are retried. Any other error, including
+// authentication failures during the handshake, is returned immediately.
+func DialWithRetry(local keychain.SingleKeyECDH, netAddr *lnwire.NetAddress,
+	timeout time.Duration, dialer tor.DialFunc, policy RetryPolicy,
+	opts ...ConnOption) (*Conn, error) {
+
+	var (
+		clk  = newConnConfig(opts).clock
+		conn *Conn
+		err  error
+	)
+	for attempt := 0; attempt == 0 || attempt < policy.MaxAttempts; {
+		conn, err = Dial(local, netAddr, timeout, dialer, opts...)
+		if err == nil || !isRetriableErr(err) {
+			return conn, err
+		}
+
+		attempt++
+		if attempt < policy.MaxAttempts {
+			<-clk.TickAfter(policy.backoff(attempt))
+		}
+	}
+
+	return nil, err
+}
+
+// isRetriableErr returns true if the error is the result of a transient
+// network failure, which may succeed if the connection is attempted again.
+func isRetriableErr(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// NewPipe creates a pair of brontide connections backed by an in-memory,
+// synchronous net.Pipe. A full handshake is performed between the two ends
+// before they are returned, the first acting as the initiator with localPriv
+// as its static key, and the second as the responder with remotePriv. This is
+// useful for testing code that depends on brontide connections without
+// binding to any TCP ports. Options configuring the established connection,
+// such as MaxLifetimeBytes and EnablePing, are applied to both ends.
+func NewPipe(localPriv, remotePriv *btcec.PrivateKey,
+	opts ...ConnOption) (*Conn, *Conn, error) {
+
+	return newPipe(localPriv, remotePriv, newConnConfig(opts), nil, nil)
+}
+
+// NewDeterministicPipe creates a pair of brontide connections backed by a
+// net.Pipe as done by NewPipe, but draws the ephemeral keys of the handshake
+// from rng rather than generating them freshly: the first 32 bytes read are
+// the initiator's ephemeral key, and the next 32 bytes the responder's. Given
+// the same static keys and the same bytes from rng, the handshake, and the
+// ciphertext of every message subsequently written by either end, are
+// identical across runs. The final handshake hash, which is the same for both
+// ends, is returned along with the connections. This is useful for
+// reproducing the BOLT 8 test vectors, and for deterministic tests of code
+// built on top of brontide.
+//
+// WARNING: Reusing ephemeral keys forfeits the forward secrecy of the
+// session, see EphemeralGen. This MUST NOT be used outside of testing.
+//
+// NOTE: Ping nonces aren't drawn from rng, so the ciphertext of pings sent on
+// connections created with EnablePing isn't reproducible.
+func NewDeterministicPipe(localPriv, remotePriv *btcec.PrivateKey,
+	rng io.Reader, opts ...ConnOption) (*Conn, *Conn, [32]byte, error) {
+
+	var (
+		localEphemeral, remoteEphemeral [32]byte
+		handshakeHash                   [32]byte
+	)
+	if _, err := io.ReadFull(rng, localEphemeral[:]); err != nil {
+		return nil, nil, handshakeHash, err
+	}
+	if _, err := io.ReadFull(rng, remoteEphemeral[:]); err != nil {
+		return nil, nil, handshakeHash, err
+	}
+
+	fixedKey := func(key [32]byte) func(*Machine) {
+		return EphemeralGenerator(func() (*btcec.PrivateKey, error) {
+			priv, _ := btcec.PrivKeyFromBytes(key[:])
+			return priv, nil
+		})
+	}
+
+	local, remote, err := newPipe(
+		localPriv, remotePriv, newConnConfig(opts),
+		[]func(*Machine){fixedKey(localEphemeral)},
+		[]func(*Machine){fixedKey(remoteEphemeral)},
+	)
+	if err != nil {
+		return nil, nil, handshakeHash, err
+	}
+
+	handshakeHash = local.noise.handshakeDigest
+
+	return local, remote, handshakeHash, nil
+}
+
+// newPipe creates a pair of brontide connections backed by a net.Pipe and
+// performs the handshake between them, passing localOpts and remoteOpts to the
+// brontide machines of the initiator and responder respectively.
+func newPipe(localPriv, remotePriv *btcec.PrivateKey, cfg *connConfig,
+	localOpts, remoteOpts []func(*Machine)) (*Conn, *Conn, error) {
+
+	localPipe, remotePipe := net.Pipe()
+
+	local, err := newConn(localPipe, NewBrontideMachine(
+		true, &keychain.PrivKeyECDH{PrivKey: localPriv},
+		remotePriv.PubKey(), localOpts...,
+	), cfg)
+	if err != nil {
+		localPipe.Close()
+		remotePipe.Close()
+		return nil, nil, err
+	}
+	remote, err := newConn(remotePipe, NewBrontideMachine(
+		false, &keychain.PrivKeyECDH{PrivKey: remotePriv}, nil,
+		remoteOpts...,
+	), cfg)
+	if err != nil {
+		localPipe.Close()
+		remotePipe.Close()
+		return nil, nil, err
+	}
+
+	// Since the pipe is synchronous, the initiator must run in its own
+	// goroutine while the responder processes its acts.
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- local.initiatorHandshake()
+	}()
+
+	remoteErr := remote.responderHandshake()
+
+	// If the responder failed, close its end so that the initiator is
+	// unblocked if it's still waiting on the next act.
+	if remoteErr != nil {
+		remote.conn.Close()
+	}
+
+	localErr := <-errChan
+	if localErr != nil || remoteErr != nil {
+		local.Close()
+		remote.Close()
+
+		if localErr != nil {
+			return nil, nil, localErr
+		}
+		return nil, nil, remoteErr
+	}
+
+	return local, remote, nil
+}
+
+// Client upgrades an existing connection to a brontide connection, carrying
+// out the initiator's side of the handshake with the remote peer identified by
+// remotePub. This is analogous to tls.Client, and allows callers to run
+// brontide over connections that weren't established by Dial. In the case of a
+// handshake failure, the passed connection is closed and a non-nil error is
+// returned.
+func Client(conn net.Conn, localPriv *btcec.PrivateKey,
+	remotePub *btcec.PublicKey, opts ...ConnOption) (*Conn, error) {
+
+	cfg := newConnConfig(opts)
+	b, err := newConn(conn, NewBrontideMachine(
+		true, &keychain.PrivKeyECDH{PrivKey: localPriv}, remotePub,
+		cfg.machineOptions()...,
+	), cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := b.initiatorHandshake(); err != nil {
 		b.conn.Close()
 		return nil, err
 	}
 
+	return b, nil
+}
+
+// Server upgrades an existing connection to a brontide connection, carrying
+// out the responder's side of the handshake using localPriv as our long-term
+// static key. This is analogous to tls.Server. In the case of a handshake
+// failure, the passed connection is closed and a non-nil error is returned.
+func Server(conn net.Conn, localPriv *btcec.PrivateKey,
+	opts ...ConnOption) (*Conn, error) {
+
+	cfg := newConnConfig(opts)
+	b, err := newConn(conn, NewBrontideMachine(
+		false, &keychain.PrivKeyECDH{PrivKey: localPriv}, nil,
+		cfg.machineOptions()...,
+	), cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := b.responderHandshake(); err != nil {
+		b.conn.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// initiatorHandshake executes the initiator's side of the three act brontide
+// handshake over the underlying connection. In the case of a handshake
+// failure, a non-nil error is returned and it is the caller's responsibility
+// to close the connection.
+func (c *Conn) initiatorHandshake() (err error) {
+	defer func() {
+		c.recordHandshake(err)
+	}()
+
+	start := c.clock.Now()
+
+	// Refuse to initiate a handshake that doesn't meet our policy, as the
+	// parameters of our side are already fixed.
+	if err := c.checkSecurityPolicy(); err != nil {
+		return err
+	}
+
+	// Initiate the handshake by sending the first act to the receiver.
+	actOne, err := c.noise.GenActOne()
+	if err != nil {
+		return err
+	}
+	if err := c.writeAct(actOne[:]); err != nil {
+		return err
+	}
+	start = c.recordAct(0, start)
+
 	// We'll ensure that we get ActTwo from the remote peer in a timely
 	// manner. If they don't respond within handshakeReadTimeout, then
 	// we'll kill the connection.
-	err = conn.SetReadDeadline(time.Now().Add(handshakeReadTimeout))
+	stopTimer, err := c.startReadTimer(handshakeReadTimeout)
 	if err != nil {
-		b.conn.Close()
-		return nil, err
+		return err
 	}
+	defer stopTimer()
 
 	// If the first act was successful (we know that address is actually
 	// remotePub), then read the second act after which we'll be able to
 	// send our static public key to the remote peer with strong forward
-	// secrecy.
-	var actTwo [ActTwoSize]byte
-	if _, err := io.ReadFull(conn, actTwo[:]); err != nil {
-		b.conn.Close()
-		return nil, err
+	// secrecy. For the XX handshake, the second act also carries the
+	// remote peer's static public key.
+	if c.noise.isXX() {
+		var actTwo [ActTwoXXSize]byte
+		if _, err := io.ReadFull(c.conn, actTwo[:]); err != nil {
+			return err
+		}
+		if err := c.noise.RecvActTwoXX(actTwo); err != nil {
+			return err
+		}
+	} else {
+		var actTwo [ActTwoSize]byte
+		if _, err := io.ReadFull(c.conn, actTwo[:]); err != nil {
+			return err
+		}
+		if err := c.noise.RecvActTwo(actTwo); err != nil {
+			return err
+		}
 	}
-	if err := b.noise.RecvActTwo(actTwo); err != nil {
-		b.conn.Close()
-		return nil, err
+	start = c.recordAct(1, start)
+
+	// We'll reset the deadline as it's no longer critical beyond the
+	// initial handshake.
+	if err := stopTimer(); err != nil {
+		return err
 	}
 
 	// Finally, complete the handshake by sending over our encrypted static
 	// key and execute the final ECDH operation.
-	actThree, err := b.noise.GenActThree()
+	actThree, err := c.noise.GenActThree()
 	if err != nil {
-		b.conn.Close()
+		return err
+	}
+	if err := c.writeAct(actThree[:]); err != nil {
+		return err
+	}
+	c.recordAct(2, start)
+
+	return nil
+}
+
+// responderHandshake executes the responder's side of the three act brontide
+// handshake over the underlying connection. In the case of a handshake
+// failure, a non-nil error is returned and it is the caller's responsibility
+// to close the connection.
+func (c *Conn) responderHandshake() (err error) {
+	defer func() {
+		c.recordHandshake(err)
+	}()
+
+	start := c.clock.Now()
+
+	// We'll ensure that we get ActOne from the remote peer in a timely
+	// manner. If they don't respond within handshakeReadTimeout, then
+	// we'll kill the connection.
+	stopTimer, err := c.startReadTimer(handshakeReadTimeout)
+	if err != nil {
+		return err
+	}
+	defer stopTimer()
+
+	// Attempt to carry out the first act of the handshake protocol. If the
+	// connecting node doesn't know our long-term static public key, then
+	// this portion will fail with a non-nil error.
+	var actOne [ActOneSize]byte
+	if _, err := io.ReadFull(c.conn, actOne[:]); err != nil {
+		return err
+	}
+	if err := c.noise.RecvActOne(actOne); err != nil {
+		return err
+	}
+	start = c.recordAct(0, start)
+
+	// Now that the initiator's choice of handshake is known, abort the
+	// handshake before responding if it doesn't meet our policy.
+	if err := c.checkSecurityPolicy(); err != nil {
+		return err
+	}
+
+	if err := stopTimer(); err != nil {
+		return err
+	}
+
+	// Next, progress the handshake processes by sending over our ephemeral
+	// key for the session along with an authenticating tag, as well as our
+	// static key if the initiator requested the XX handshake.
+	if err := c.writeActTwo(); err != nil {
+		return err
+	}
+	start = c.recordAct(1, start)
+
+	// We'll ensure that we get ActThree from the remote peer in a timely
+	// manner. If they don't respond within handshakeReadTimeout, then
+	// we'll kill the connection.
+	stopTimer, err = c.startReadTimer(handshakeReadTimeout)
+	if err != nil {
+		return err
+	}
+	defer stopTimer()
+
+	// Finally, finish the handshake processes by reading and decrypting
+	// the connection peer's static public key. If this succeeds then both
+	// sides have mutually authenticated each other.
+	var actThree [ActThreeSize]byte
+	if _, err := io.ReadFull(c.conn, actThree[:]); err != nil {
+		return err
+	}
+	if err := c.noise.RecvActThree(actThree); err != nil {
+		return err
+	}
+	c.recordAct(2, start)
+
+	// We'll reset the deadline as it's no longer critical beyond the
+	// initial handshake.
+	return stopTimer()
+}
+
+// writeActTwo generates the second act of the handshake negotiated in act one
+// and writes it to the underlying connection.
+func (c *Conn) writeActTwo() error {
+	if c.noise.isXX() {
+		actTwo, err := c.noise.GenActTwoXX()
+		if err != nil {
+			return err
+		}
+
+		return c.writeAct(actTwo[:])
+	}
+
+	actTwo, err := c.noise.GenActTwo()
+	if err != nil {
+		return err
+	}
+
+	return c.writeAct(actTwo[:])
+}
+
+// writeAct writes a handshake act to the underlying connection in full. An
+// underlying connection that accepts only part of the act in a single write is
+// written to repeatedly, rather than leaving the peer waiting on the remainder
+// of the act.
+func (c *Conn) writeAct(act []byte) error {
+	_, err := writeFull(c.conn, act)
+	return err
+}
+
+// ReadNextMessage uses the connection in a message-oriented manner, instructing
+// it to read the next _full_ message with the brontide stream. This function
+// will block until the read of the header and body succeeds. A zero-length
+// message is returned as an empty, non-nil slice with a nil error, which
+// distinguishes it from a closed connection, for which an error is always
+// returned.
+//
+// NOTE: This method SHOULD NOT be used in the case that the connection may be
+// adversarial and induce long delays. If the caller needs to set read deadlines
+// appropriately, it is preferred that they use the split ReadNextHeader and
+// ReadNextBody methods so that the deadlines can be set appropriately on each.
+func (c *Conn) ReadNextMessage() ([]byte, error) {
+	if c.isClosed() {
+		return nil, ErrConnClosed
+	}
+
+	if err := c.checkByteBudget(); err != nil {
 		return nil, err
 	}
-	if _, err := conn.Write(actThree[:]); err != nil {
-		b.conn.Close()
+
+	msg, err := c.readMessage()
+	c.addBytesRead(len(msg))
+
+	return msg, c.closedErr(err)
+}
+
+// SetReadLimit sets the maximum plaintext length of a message accepted by
+// ReadNextMessage and Read, allowing applications to cap messages below the
+// protocol maximum of 65535 bytes. A message whose header declares a larger
+// length is rejected with ErrMessageTooLarge, without its body being read
+// from the stream. A limit of zero restores the protocol maximum.
+func (c *Conn) SetReadLimit(max uint32) {
+	atomic.StoreUint32(&c.readLimit, max)
+}
+
+// SkipMessages reads and discards the next n application messages from the
+// stream, allowing a caller to resynchronize with the peer after failing to
+// parse a message, without hand-rolling a discard loop. Each message is still
+// decrypted, such that the cipher state advances in step with the peer. The
+// read limit set by SetReadLimit doesn't apply, so a message previously
+// rejected with ErrMessageTooLarge is skipped along with its body. Any
+// remainder of a message partially returned by Read is discarded as well,
+// without counting towards n. Control frames are handled as by
+// ReadNextMessage, and are not counted either.
+func (c *Conn) SkipMessages(n int) error {
+	if c.isClosed() {
+		return ErrConnClosed
+	}
+
+	c.addBytesRead(c.readBuf.Len())
+	c.readBuf.Reset()
+
+	for i := 0; i < n; i++ {
+		if err := c.checkByteBudget(); err != nil {
+			return err
+		}
+
+		msg, err := c.nextMessage(0)
+		if err != nil {
+			return c.closedErr(err)
+		}
+
+		c.addBytesRead(len(msg))
+	}
+
+	return nil
+}
+
+// readMessage reads and decrypts the next application message from the
+// stream, consuming any control frames preceding it.
+func (c *Conn) readMessage() ([]byte, error) {
+	return c.nextMessage(atomic.LoadUint32(&c.readLimit))
+}
+
+// nextMessage reads and decrypts the next application message from the
+// stream, consuming any control frames preceding it, and enforcing the given
+// read limit unless it's zero.
+func (c *Conn) nextMessage(limit uint32) ([]byte, error) {
+	for {
+		msg, err := c.readLimitedMessage(limit)
+		if err != nil {
+			return nil, err
+		}
+
+		handled, err := c.handleControlFrame(msg)
+		switch {
+		case err != nil:
+			return nil, err
+
+		case !handled:
+			return c.decodePayload(msg, limit)
+		}
+	}
+}
+
+// readLimitedMessage reads and decrypts the next message from the stream,
+// enforcing the given limit unless it's zero. If the message is too large, its
+// header remains pending, such that the body is not consumed and a subsequent
+// call can read it once the limit has been raised.
+func (c *Conn) readLimitedMessage(limit uint32) ([]byte, error) {
+	if !c.hasPendingBody {
+		var header [encHeaderSize]byte
+		if err := c.readFrame(header[:]); err != nil {
+			return nil, err
+		}
+
+		pktLen, err := c.noise.ReadHeader(bytes.NewReader(header[:]))
+		if err != nil {
+			return nil, c.decryptFailed(err)
+		}
+
+		c.pendingBodyLen = uint16(pktLen - macSize)
+		c.hasPendingBody = true
+	}
+
+	if limit != 0 && uint32(c.pendingBodyLen) > limit {
+		return nil, ErrMessageTooLarge
+	}
+
+	// The header remains pending until the full body has been read from
+	// the stream, allowing a read interrupted by a deadline to resume. If
+	// the buffered bytes are capped, the body is buffered as it arrives.
+	var (
+		frameLen   = int(c.pendingBodyLen) + macSize
+		ciphertext []byte
+		err        error
+	)
+	if c.maxBufferedBytes > 0 {
+		if frameLen > c.maxBufferedBytes {
+			return nil, ErrMessageTooLarge
+		}
+
+		ciphertext, err = c.readFrameIncremental(frameLen)
+	} else {
+		ciphertext = make([]byte, frameLen)
+		err = c.readFrame(ciphertext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Once we start decrypting the body, the header is consumed regardless
+	// of the outcome, as any failure past this point is fatal to the
+	// stream.
+	c.hasPendingBody = false
+
+	plaintext, err := c.noise.ReadBody(
+		bytes.NewReader(ciphertext), ciphertext,
+	)
+	if err != nil {
+		return nil, c.decryptFailed(err)
+	}
+
+	return plaintext, nil
+}
+
+// decryptFailed reports err to the OnDecryptError callback if it's the result
+// of a frame failing MAC verification, and returns it unchanged.
+func (c *Conn) decryptFailed(err error) error {
+	if c.onDecryptError != nil && errors.Is(err, ErrMACVerificationFailed) {
+		c.onDecryptError(err)
+	}
+
+	return err
+}
+
+// readFrame fills buf with raw bytes from the underlying connection, first
+// consuming any bytes left over from a previously interrupted read. If the
+// read fails part way, the bytes read so far are retained such that the next
+// call resumes from the same position in the stream.
+func (c *Conn) readFrame(buf []byte) error {
+	n := copy(buf, c.partialFrame)
+	c.partialFrame = c.partialFrame[n:]
+	if len(c.partialFrame) == 0 {
+		c.partialFrame = nil
+	}
+
+	m, err := io.ReadFull(c.conn, buf[n:])
+	if err != nil {
+		c.partialFrame = append(
+			append([]byte(nil), buf[:n+m]...), c.partialFrame...,
+		)
+
+		return err
+	}
+
+	return nil
+}
+
+// readFrameIncremental reads a frame of the given size from the underlying
+// connection, resuming any previously interrupted read like readFrame. Rather
+// than allocating the frame in full up front, the buffer holding it is grown
+// as its bytes arrive, such that its capacity is at most twice the number of
+// bytes received.
+func (c *Conn) readFrameIncremental(size int) ([]byte, error) {
+	frame := c.partialFrame
+	c.partialFrame = nil
+
+	for len(frame) < size {
+		if len(frame) == cap(frame) {
+			newCap := 2 * cap(frame)
+			if newCap < minFrameChunkSize {
+				newCap = minFrameChunkSize
+			}
+			if newCap > size {
+				newCap = size
+			}
+
+			frame = append(make([]byte, 0, newCap), frame...)
+		}
+
+		// Never read past the end of the frame, as the remaining bytes
+		// belong to the next one.
+		end := cap(frame)
+		if end > size {
+			end = size
+		}
+
+		n, err := c.conn.Read(frame[len(frame):end])
+		frame = frame[:len(frame)+n]
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(frame) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			c.partialFrame = frame
+
+			return nil, err
+		}
+	}
+
+	return frame, nil
+}
+
+// ReadNextMessageTimeout reads and decrypts the next message from the brontide
+// stream like ReadNextMessage, but fails with os.ErrDeadlineExceeded if the
+// message isn't received within d. The deadline only applies to this call,
+// after which the read deadline previously set via SetDeadline or
+// SetReadDeadline is restored. A timed out read does not desynchronize the
+// stream, as any partially received frame is retained and completed by the
+// next call to ReadNextMessage, ReadNextMessageTimeout or Read.
+func (c *Conn) ReadNextMessageTimeout(d time.Duration) ([]byte, error) {
+	if c.isClosed() {
+		return nil, ErrConnClosed
+	}
+
+	if err := c.checkByteBudget(); err != nil {
 		return nil, err
 	}
 
-	// We'll reset the deadline as it's no longer critical beyond the
-	// initial handshake.
-	err = conn.SetReadDeadline(time.Time{})
+	stopTimer, err := c.startReadTimer(d)
 	if err != nil {
-		b.conn.Close()
 		return nil, err
 	}
 
-	return b, nil
+	msg, err := c.readMessage()
+
+	// Restore the prior deadline, which clears it entirely if none was
+	// set.
+	if dErr := stopTimer(); dErr != nil && err == nil {
+		err = dErr
+	}
+
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return nil, os.ErrDeadlineExceeded
+
+	case err != nil:
+		return nil, c.closedErr(err)
+	}
+
+	c.addBytesRead(len(msg))
+
+	return msg, nil
 }
 
-// ReadNextMessage uses the connection in a message-oriented manner, instructing
-// it to read the next _full_ message with the brontide stream. This function
-// will block until the read of the header and body succeeds.
+// Messages runs a read loop over the connection in its own goroutine,
+// delivering each decrypted message on the returned message channel. The loop
+// exits once ctx is cancelled or a read fails, at which point the terminal
+// error is sent on the returned error channel and the message channel is
+// closed. If ctx is cancelled, the error is ctx.Err().
 //
-// NOTE: This method SHOULD NOT be used in the case that the connection may be
-// adversarial and induce long delays. If the caller needs to set read deadlines
-// appropriately, it is preferred that they use the split ReadNextHeader and
-// ReadNextBody methods so that the deadlines can be set appropriately on each.
-func (c *Conn) ReadNextMessage() ([]byte, error) {
-	return c.noise.ReadMessage(c.conn)
+// NOTE: Cancelling ctx interrupts any pending read by expiring the read
+// deadline of the connection, which may leave the stream at an arbitrary
+// offset. The connection should therefore be closed once the loop exits.
+func (c *Conn) Messages(ctx context.Context) (<-chan []byte, <-chan error) {
+	msgChan := make(chan []byte)
+	errChan := make(chan error, 1)
+	done := make(chan struct{})
+
+	// Unblock the read loop if the context is cancelled while it's
+	// waiting on the next message.
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = c.conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	go func() {
+		defer close(done)
+		defer close(msgChan)
+
+		for {
+			msg, err := c.ReadNextMessage()
+			if err != nil {
+				if ctx.Err() != nil {
+					err = ctx.Err()
+				}
+				errChan <- err
+
+				return
+			}
+
+			select {
+			case msgChan <- msg:
+			case <-ctx.Done():
+				errChan <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return msgChan, errChan
 }
 
 // ReadNextHeader uses the connection to read the next header from the brontide
@@ -124,7 +1398,24 @@ func (c *Conn) ReadNextMessage() ([]byte, error) {
 // return the packet length (including MAC overhead) that is expected from the
 // subsequent call to ReadNextBody.
 func (c *Conn) ReadNextHeader() (uint32, error) {
-	return c.noise.ReadHeader(c.conn)
+	if c.isClosed() {
+		return 0, ErrConnClosed
+	}
+
+	if c.compress {
+		return 0, ErrCompressionNegotiated
+	}
+
+	if err := c.checkByteBudget(); err != nil {
+		return 0, err
+	}
+
+	pktLen, err := c.noise.ReadHeader(c.conn)
+	if err != nil {
+		return 0, c.closedErr(c.decryptFailed(err))
+	}
+
+	return pktLen, nil
 }
 
 // ReadNextBody uses the connection to read the next message body from the
@@ -132,7 +1423,112 @@ func (c *Conn) ReadNextHeader() (uint32, error) {
 // and return the decrypted payload. The provided buffer MUST be the packet
 // length returned by the preceding call to ReadNextHeader.
 func (c *Conn) ReadNextBody(buf []byte) ([]byte, error) {
-	return c.noise.ReadBody(c.conn, buf)
+	plaintext, err := c.noise.ReadBody(c.conn, buf)
+	c.addBytesRead(len(plaintext))
+
+	return plaintext, c.closedErr(c.decryptFailed(err))
+}
+
+// ReadHeader reads and decrypts the next message header from the brontide
+// stream, returning the plaintext length of the message body. This allows the
+// caller to allocate or reserve buffer space before committing to reading the
+// body with ReadBody. ErrPendingBody is returned if the body of the previously
+// read header has not yet been read.
+func (c *Conn) ReadHeader() (uint16, error) {
+	if c.isClosed() {
+		return 0, ErrConnClosed
+	}
+
+	if c.compress {
+		return 0, ErrCompressionNegotiated
+	}
+
+	if c.hasPendingBody {
+		return 0, ErrPendingBody
+	}
+
+	if err := c.checkByteBudget(); err != nil {
+		return 0, err
+	}
+
+	pktLen, err := c.noise.ReadHeader(c.conn)
+	if err != nil {
+		return 0, c.closedErr(c.decryptFailed(err))
+	}
+
+	c.pendingBodyLen = uint16(pktLen - macSize)
+	c.hasPendingBody = true
+
+	return c.pendingBodyLen, nil
+}
+
+// ReadBody reads and decrypts the message body announced by the preceding call
+// to ReadHeader into buf, returning the number of plaintext bytes written. If
+// no header has been read, ErrNoPendingHeader is returned, and if buf is
+// smaller than the body, ErrBodyBufferTooSmall is returned. In both cases no
+// bytes are consumed from the stream, such that the caller may retry with a
+// suitable buffer without desynchronizing the cipher.
+func (c *Conn) ReadBody(buf []byte) (int, error) {
+	if c.isClosed() {
+		return 0, ErrConnClosed
+	}
+
+	if !c.hasPendingBody {
+		return 0, ErrNoPendingHeader
+	}
+
+	bodyLen := int(c.pendingBodyLen)
+	if len(buf) < bodyLen {
+		return 0, ErrBodyBufferTooSmall
+	}
+
+	// Once we start reading the body, the header is consumed regardless of
+	// the outcome, as any failure past this point is fatal to the stream.
+	c.hasPendingBody = false
+
+	ciphertext := make([]byte, bodyLen+macSize)
+	plaintext, err := c.noise.ReadBody(c.conn, ciphertext)
+	if err != nil {
+		return 0, c.closedErr(c.decryptFailed(err))
+	}
+	c.addBytesRead(len(plaintext))
+
+	return copy(buf, plaintext), nil
+}
+
+// ReadNextMessageInto reads and decrypts the next message from the brontide
+// stream in place within buf, returning the length of the plaintext, which is
+// stored at the start of buf. As the ciphertext is decrypted in place, buf must
+// have room for the message plus its MAC. If it doesn't, io.ErrShortBuffer is
+// returned along with the required buffer size, and the message remains
+// pending such that the next call reads it into a suitably sized buffer. This
+// allows callers to recycle their buffers rather than allocating a new slice
+// for each message.
+func (c *Conn) ReadNextMessageInto(buf []byte) (int, error) {
+	// A header may already be pending if the previous call was given a
+	// buffer that was too small, in which case we read its body now.
+	if !c.hasPendingBody {
+		if _, err := c.ReadHeader(); err != nil {
+			return 0, err
+		}
+	}
+
+	pktLen := int(c.pendingBodyLen) + macSize
+	if len(buf) < pktLen {
+		return pktLen, io.ErrShortBuffer
+	}
+
+	// Once we start reading the body, the header is consumed regardless of
+	// the outcome, as any failure past this point is fatal to the stream.
+	c.hasPendingBody = false
+
+	plaintext, err := c.noise.ReadBody(c.conn, buf[:pktLen])
+	if err != nil {
+		return 0, c.closedErr(c.decryptFailed(err))
+	}
+	c.addBytesRead(len(plaintext))
+
+	return len(plaintext), nil
 }
 
 // Read reads data from the connection.  Read can be made to time out and
@@ -141,15 +1537,25 @@ func (c *Conn) ReadNextBody(buf []byte) ([]byte, error) {
 //
 // Part of the net.Conn interface.
 func (c *Conn) Read(b []byte) (n int, err error) {
+	if c.isClosed() {
+		return 0, ErrConnClosed
+	}
+
+	if err := c.checkByteBudget(); err != nil {
+		return 0, err
+	}
+
 	// In order to reconcile the differences between the record abstraction
 	// of our AEAD connection, and the stream abstraction of TCP, we
 	// maintain an intermediate read buffer. If this buffer becomes
 	// depleted, then we read the next record, and feed it into the
-	// buffer. Otherwise, we read directly from the buffer.
-	if c.readBuf.Len() == 0 {
-		plaintext, err := c.noise.ReadMessage(c.conn)
+	// buffer. Otherwise, we read directly from the buffer. Zero-length
+	// messages carry no bytes of the stream, so we skip over them rather
+	// than reporting the depleted buffer as io.EOF.
+	for c.readBuf.Len() == 0 {
+		plaintext, err := c.readMessage()
 		if err != nil {
-			return 0, err
+			return 0, c.closedErr(err)
 		}
 
 		if _, err := c.readBuf.Write(plaintext); err != nil {
@@ -157,15 +1563,49 @@ func (c *Conn) Read(b []byte) (n int, err error) {
 		}
 	}
 
-	return c.readBuf.Read(b)
+	n, err = c.readBuf.Read(b)
+	c.addBytesRead(n)
+
+	return n, err
 }
 
 // Write writes data to the connection.  Write can be made to time out and
 // return an Error with Timeout() == true after a fixed time limit; see
-// SetDeadline and SetWriteDeadline.
+// SetDeadline and SetWriteDeadline. Writing an empty slice emits a valid
+// zero-length frame, which the peer reads back as an empty message from
+// ReadNextMessage. If the connection was created with WriteCoalesce, the frames
+// are buffered rather than written immediately, see WriteCoalesce.
 //
 // Part of the net.Conn interface.
 func (c *Conn) Write(b []byte) (n int, err error) {
+	if c.isClosed() {
+		return 0, ErrConnClosed
+	}
+
+	if err := c.checkByteBudget(); err != nil {
+		return 0, err
+	}
+	defer func() {
+		c.addBytesWritten(n)
+		err = c.closedErr(err)
+	}()
+
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+
+	// If coalescing is enabled, the frames are buffered and written out
+	// along with those of subsequent writes.
+	if c.coalesceDelay > 0 {
+		return c.coalesceWrite(b)
+	}
+
+	// If compression was negotiated, each chunk is encoded into its own
+	// frame, and only counts towards the bytes written once the frame has
+	// been written in full.
+	if c.compress {
+		return c.writeCompressed(b)
+	}
+
 	// If the message doesn't require any chunking, then we can go ahead
 	// with a single write.
 	if len(b) <= math.MaxUint16 {
@@ -173,7 +1613,7 @@ func (c *Conn) Write(b []byte) (n int, err error) {
 		if err != nil {
 			return 0, err
 		}
-		return c.noise.Flush(c.conn)
+		return c.noise.Flush(c.wireWriter())
 	}
 
 	// If we need to split the message into fragments, then we'll write
@@ -196,7 +1636,7 @@ func (c *Conn) Write(b []byte) (n int, err error) {
 			return bytesWritten, err
 		}
 
-		n, err := c.noise.Flush(c.conn)
+		n, err := c.noise.Flush(c.wireWriter())
 		bytesWritten += n
 		if err != nil {
 			return bytesWritten, err
@@ -209,12 +1649,69 @@ func (c *Conn) Write(b []byte) (n int, err error) {
 // WriteMessage encrypts and buffers the next message p for the connection. The
 // ciphertext of the message is prepended with an encrypt+auth'd length which
 // must be used as the AD to the AEAD construction when being decrypted by the
-// other side.
+// other side. An empty p is encrypted into a valid zero-length frame.
 //
 // NOTE: This DOES NOT write the message to the wire, it should be followed by a
 // call to Flush to ensure the message is written.
 func (c *Conn) WriteMessage(b []byte) error {
-	return c.noise.WriteMessage(b)
+	if c.isClosed() {
+		return ErrConnClosed
+	}
+
+	if err := c.checkByteBudget(); err != nil {
+		return err
+	}
+
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+
+	if err := c.noise.WriteMessage(c.encodePayload(b)); err != nil {
+		return err
+	}
+	c.pendingPlainLen = len(b)
+
+	return nil
+}
+
+// WriteMessages encrypts each of the passed messages into its own frame and
+// writes them all to the underlying connection using a single write. The
+// receiver reads back each message individually, in order. The number of bytes
+// returned reflects the number of plaintext bytes written.
+//
+// NOTE: If an error is returned, the batch may have been partially written and
+// the connection should be closed.
+func (c *Conn) WriteMessages(msgs [][]byte) (int, error) {
+	if c.isClosed() {
+		return 0, ErrConnClosed
+	}
+
+	if err := c.checkByteBudget(); err != nil {
+		return 0, err
+	}
+
+	c.writeMtx.Lock()
+
+	// Frames buffered by a coalesced Write were encrypted first, and must
+	// therefore precede the batch on the wire.
+	if err := c.flushCoalesced(); err != nil {
+		c.writeMtx.Unlock()
+		return 0, err
+	}
+
+	var (
+		n   int
+		err error
+	)
+	if c.compress {
+		n, err = c.writeCompressedMessages(msgs)
+	} else {
+		n, err = c.noise.WriteMessages(c.wireWriter(), msgs)
+	}
+	c.writeMtx.Unlock()
+
+	c.addBytesWritten(n)
+
+	return n, c.closedErr(err)
 }
 
 // Flush attempts to write a message buffered using WriteMessage to the
@@ -222,22 +1719,206 @@ func (c *Conn) WriteMessage(b []byte) error {
 // NOP. Otherwise, it will continue to write the remaining bytes, picking up
 // where the byte stream left off in the event of a partial write. The number of
 // bytes returned reflects the number of plaintext bytes in the payload, and
-// does not account for the overhead of the header or MACs.
+// does not account for the overhead of the header or MACs. Any frames buffered
+// by Write on a connection created with WriteCoalesce are written out first,
+// but aren't included in the returned count, as Write already reported them.
 //
 // NOTE: It is safe to call this method again iff a timeout error is returned.
 func (c *Conn) Flush() (int, error) {
-	return c.noise.Flush(c.conn)
+	if c.isClosed() {
+		return 0, ErrConnClosed
+	}
+
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+
+	if err := c.takeCoalesceErr(); err != nil {
+		return 0, err
+	}
+	if err := c.flushCoalesced(); err != nil {
+		return 0, c.closedErr(err)
+	}
+
+	n, err := c.noise.Flush(c.wireWriter())
+	if c.compress {
+		n = c.compressedFlushed(err)
+	}
+	if err != nil {
+		return n, c.closedErr(err)
+	}
+
+	// The buffered message, if any, has now been written in full, so its
+	// plaintext counts towards the bytes transferred.
+	c.addBytesWritten(c.pendingPlainLen)
+	c.pendingPlainLen = 0
+
+	// Write out any control frames that were deferred until the buffered
+	// message was flushed.
+	if len(c.pendingControl) > 0 {
+		frames := c.pendingControl
+		c.pendingControl = nil
+
+		_, err := c.noise.WriteMessages(c.wireWriter(), frames)
+		if err != nil {
+			return n, c.closedErr(err)
+		}
+	}
+
+	return n, nil
+}
+
+// ReadFrom reads data from r until io.EOF or an error, and writes it to the
+// connection as done by Write, in frames of at most math.MaxUint16 bytes. The
+// number of bytes written is returned, and io.EOF is not reported as an error.
+// This allows io.Copy to feed the connection without an intermediate buffer.
+//
+// Part of the io.ReaderFrom interface.
+func (c *Conn) ReadFrom(r io.Reader) (int64, error) {
+	buf := make([]byte, math.MaxUint16)
+
+	var total int64
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			written, wErr := c.Write(buf[:n])
+			total += int64(written)
+			if wErr != nil {
+				return total, wErr
+			}
+		}
+
+		switch {
+		case err == io.EOF:
+			return total, nil
+
+		case err != nil:
+			return total, err
+		}
+	}
+}
+
+// WriteTo writes the stream of decrypted messages read from the connection to w
+// until the remote peer closes the connection or an error occurs, starting
+// with any remainder of a message partially returned by Read. The number of
+// bytes written is returned, and io.EOF is not reported as an error. This
+// allows io.Copy to drain the connection without an intermediate buffer.
+//
+// Part of the io.WriterTo interface.
+func (c *Conn) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	if c.readBuf.Len() > 0 {
+		n, err := c.readBuf.WriteTo(w)
+		c.addBytesRead(int(n))
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+
+	for {
+		msg, err := c.ReadNextMessage()
+		switch {
+		case err == io.EOF:
+			return total, nil
+
+		case err != nil:
+			return total, err
+		}
+
+		n, err := w.Write(msg)
+		total += int64(n)
+		switch {
+		case err != nil:
+			return total, err
+
+		case n < len(msg):
+			return total, io.ErrShortWrite
+		}
+	}
 }
 
-// Close closes the connection. Any blocked Read or Write operations will be
-// unblocked and return errors.
+// Close closes the connection. Any blocked Read or Write operations, including
+// those of the other read and write methods of the connection, are unblocked
+// promptly and fail with ErrConnClosed, as do any further reads or writes.
+// Frames buffered by Write on a connection created with WriteCoalesce are
+// flushed before closing, unless another write is in progress. Close is
+// idempotent, subsequent calls return nil.
 //
 // Part of the net.Conn interface.
 func (c *Conn) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return nil
+	}
+	c.untrack()
+	c.flushCoalescedOnClose()
+
 	// TODO(roasbeef): reset brontide state?
 	return c.conn.Close()
 }
 
+// BytesTransferred returns the total number of plaintext bytes read from and
+// written to the connection.
+func (c *Conn) BytesTransferred() uint64 {
+	return atomic.LoadUint64(&c.bytesTransferred)
+}
+
+// addBytesTransferred adds n plaintext bytes to the connection's running
+// total.
+func (c *Conn) addBytesTransferred(n int) {
+	if n > 0 {
+		atomic.AddUint64(&c.bytesTransferred, uint64(n))
+	}
+}
+
+// addBytesRead records n plaintext bytes read from the connection, both in its
+// running total and the global statistics.
+func (c *Conn) addBytesRead(n int) {
+	if n > 0 {
+		c.addBytesTransferred(n)
+		atomic.AddUint64(&globalStats.bytesIn, uint64(n))
+	}
+}
+
+// addBytesWritten records n plaintext bytes written to the connection, both in
+// its running total and the global statistics.
+func (c *Conn) addBytesWritten(n int) {
+	if n > 0 {
+		c.addBytesTransferred(n)
+		atomic.AddUint64(&globalStats.bytesOut, uint64(n))
+	}
+}
+
+// checkByteBudget returns ErrConnByteBudgetExceeded, closing the connection,
+// if the connection has a byte budget and it has been used up.
+func (c *Conn) checkByteBudget() error {
+	if c.maxLifetimeBytes == 0 ||
+		c.BytesTransferred() < c.maxLifetimeBytes {
+
+		return nil
+	}
+
+	c.Close()
+
+	return ErrConnByteBudgetExceeded
+}
+
+// isClosed returns true if Close has been called on the connection.
+func (c *Conn) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
+
+// closedErr returns ErrConnClosed in place of a non-nil err if the connection
+// has been closed, such that a read or write unblocked by a concurrent call to
+// Close reports the closure, rather than the error the closed underlying
+// connection failed it with.
+func (c *Conn) closedErr(err error) error {
+	if err != nil && c.isClosed() {
+		return ErrConnClosed
+	}
+
+	return err
+}
+
 // LocalAddr returns the local network address.
 //
 // Part of the net.Conn interface.
@@ -252,13 +1933,28 @@ func (c *Conn) RemoteAddr() net.Addr {
 	return c.conn.RemoteAddr()
 }
 
+// RemoteNetAddr returns the lnwire.NetAddress of the remote peer, combining its
+// authenticated static public key with the remote address of the underlying
+// connection. This is suitable for reconnecting to the peer.
+func (c *Conn) RemoteNetAddr() *lnwire.NetAddress {
+	return &lnwire.NetAddress{
+		IdentityKey: c.RemotePub(),
+		Address:     c.RemoteAddr(),
+	}
+}
+
 // SetDeadline sets the read and write deadlines associated with the
 // connection. It is equivalent to calling both SetReadDeadline and
 // SetWriteDeadline.
 //
 // Part of the net.Conn interface.
 func (c *Conn) SetDeadline(t time.Time) error {
-	return c.conn.SetDeadline(t)
+	if err := c.conn.SetDeadline(t); err != nil {
+		return err
+	}
+	c.readDeadline = t
+
+	return nil
 }
 
 // SetReadDeadline sets the deadline for future Read calls. A zero value for t
@@ -266,7 +1962,12 @@ func (c *Conn) SetDeadline(t time.Time) error {
 //
 // Part of the net.Conn interface.
 func (c *Conn) SetReadDeadline(t time.Time) error {
-	return c.conn.SetReadDeadline(t)
+	if err := c.conn.SetReadDeadline(t); err != nil {
+		return err
+	}
+	c.readDeadline = t
+
+	return nil
 }
 
 // SetWriteDeadline sets the deadline for future Write calls. Even if write
@@ -278,6 +1979,170 @@ func (c *Conn) SetWriteDeadline(t time.Time) error {
 	return c.conn.SetWriteDeadline(t)
 }
 
+// SetWriteChunkSize limits each write to the underlying connection to at most
+// n bytes, such that large frames are handed to the transport in smaller
+// pieces rather than in a single write, which can cause head-of-line blocking
+// on some transports. The framing itself is unchanged, so the remote peer
+// reassembles the frames transparently. A value of zero or less restores the
+// default of writing each frame in full.
+func (c *Conn) SetWriteChunkSize(n int) {
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+
+	c.writeChunkSize = n
+}
+
+// wireWriter returns the writer to which encrypted frames should be written,
+// which splits writes to the underlying connection according to the size set
+// by SetWriteChunkSize.
+//
+// NOTE: This method MUST be called with writeMtx held.
+func (c *Conn) wireWriter() io.Writer {
+	if c.writeChunkSize <= 0 {
+		return c.conn
+	}
+
+	c.chunkWriter = chunkedWriter{
+		w:         c.conn,
+		chunkSize: c.writeChunkSize,
+	}
+
+	return &c.chunkWriter
+}
+
+// chunkedWriter is an io.Writer that splits each write into writes of at most
+// chunkSize bytes to the wrapped writer.
+type chunkedWriter struct {
+	w         io.Writer
+	chunkSize int
+}
+
+// Write writes p to the wrapped writer in chunks of at most chunkSize bytes,
+// stopping at the first chunk that fails to be written in full.
+func (w *chunkedWriter) Write(p []byte) (int, error) {
+	var total int
+	for total < len(p) {
+		end := total + w.chunkSize
+		if end > len(p) {
+			end = len(p)
+		}
+
+		chunk := p[total:end]
+		n, err := w.w.Write(chunk)
+		total += n
+		if err != nil {
+			return total, err
+		}
+
+		if n < len(chunk) {
+			return total, io.ErrShortWrite
+		}
+	}
+
+	return total, nil
+}
+
+// SetKeepAlivePeriod enables TCP keep-alives on the underlying connection and
+// sets the period between them, allowing dead peers to be detected at the OS
+// level. ErrNotTCPConn is returned if the connection isn't backed by a
+// *net.TCPConn.
+func (c *Conn) SetKeepAlivePeriod(d time.Duration) error {
+	tcpConn, ok := c.conn.(*net.TCPConn)
+	if !ok {
+		return ErrNotTCPConn
+	}
+
+	if err := tcpConn.SetKeepAlive(true); err != nil {
+		return err
+	}
+
+	return tcpConn.SetKeepAlivePeriod(d)
+}
+
+// startReadTimer clears the read deadline of the underlying connection, and
+// expires it once d has elapsed on the connection's clock, failing any pending
+// read with os.ErrDeadlineExceeded. The returned function stops the timer and
+// restores the deadline set via SetDeadline or SetReadDeadline, undoing the
+// expiry if the timer has already fired. It must be called once the timed
+// read completes, and is safe to call more than once.
+func (c *Conn) startReadTimer(d time.Duration) (func() error, error) {
+	if err := c.conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	var (
+		mtx     sync.Mutex
+		stopped bool
+		quit    = make(chan struct{})
+		tick    = c.clock.TickAfter(d)
+	)
+
+	go func() {
+		select {
+		case <-tick:
+		case <-quit:
+			return
+		}
+
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if !stopped {
+			_ = c.conn.SetReadDeadline(time.Now())
+		}
+	}()
+
+	stop := func() error {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if stopped {
+			return nil
+		}
+		stopped = true
+		close(quit)
+
+		return c.conn.SetReadDeadline(c.readDeadline)
+	}
+
+	return stop, nil
+}
+
+// recordAct records the duration of the given handshake act as the time
+// elapsed since start, returning the current time as the start of the next
+// act.
+func (c *Conn) recordAct(act int, start time.Time) time.Time {
+	now := c.clock.Now()
+	c.actTimings[act] = now.Sub(start)
+
+	return now
+}
+
+// HandshakeTimings returns the wall-clock duration of each of the three acts
+// of the handshake that established the connection. Each act is measured from
+// the completion of the previous one, or the start of the handshake, until the
+// act has been written by its sender or read and processed by its receiver.
+// As such, the acts sent by the remote peer include the network round trip.
+func (c *Conn) HandshakeTimings() (act1, act2, act3 time.Duration) {
+	return c.actTimings[0], c.actTimings[1], c.actTimings[2]
+}
+
+// HandshakeVersion returns the handshake version negotiated for the
+// connection, which for accepted connections is the version requested by the
+// dialer among those accepted by the listener.
+func (c *Conn) HandshakeVersion() byte {
+	return c.noise.HandshakeVersion()
+}
+
+// ExportKeyingMaterial derives length bytes of keying material from the
+// finalized handshake of this session and the given label, which can be used
+// to bind higher-level protocol messages to this specific session. Both ends
+// of the connection derive the same material for the same label, while any
+// other session yields unrelated material.
+func (c *Conn) ExportKeyingMaterial(label []byte, length int) ([]byte, error) {
+	return c.noise.exportKeyingMaterial(label, length)
+}
+
 // RemotePub returns the remote peer's static public key.
 func (c *Conn) RemotePub() *btcec.PublicKey {
 	return c.noise.remoteStatic
@@ -287,3 +2152,69 @@ func (c *Conn) RemotePub() *btcec.PublicKey {
 func (c *Conn) LocalPub() *btcec.PublicKey {
 	return c.noise.localStatic.PubKey()
 }
+
+// ExchangeFeatures sends the local feature vector to the peer as the first
+// message of the session, and returns the feature vector received from the
+// peer in its own first message. Both ends are expected to call it right after
+// the handshake, and it may only be called once, before any other traffic, or
+// ErrFeatureExchangeNotFirst is returned. The local features are written
+// concurrently with reading those of the peer, such that the exchange doesn't
+// deadlock over unbuffered connections. If the connection was created with the
+// Compression option, the CompressionOptional bit is added to the local
+// features, and compression is used for all subsequent messages if the peer
+// set it as well. If an error is returned, the connection should be closed.
+func (c *Conn) ExchangeFeatures(
+	local lnwire.FeatureVector) (lnwire.FeatureVector, error) {
+
+	if c.featuresExchanged || c.BytesTransferred() != 0 {
+		return lnwire.FeatureVector{}, ErrFeatureExchangeNotFirst
+	}
+	c.featuresExchanged = true
+
+	localFeatures := lnwire.NewRawFeatureVector()
+	if local.RawFeatureVector != nil {
+		localFeatures = local.RawFeatureVector.Clone()
+	}
+
+	// Advertise support for compression if it was opted into, without
+	// modifying the caller's feature vector.
+	if c.compressThreshold > 0 {
+		localFeatures.Set(CompressionOptional)
+	}
+
+	var b bytes.Buffer
+	if err := localFeatures.Encode(&b); err != nil {
+		return lnwire.FeatureVector{}, err
+	}
+
+	writeErr := make(chan error, 1)
+	go func() {
+		_, err := c.Write(b.Bytes())
+		writeErr <- err
+	}()
+
+	msg, err := c.ReadNextMessage()
+	if err != nil {
+		return lnwire.FeatureVector{}, err
+	}
+
+	if err := <-writeErr; err != nil {
+		return lnwire.FeatureVector{}, err
+	}
+
+	remoteFeatures := lnwire.NewRawFeatureVector()
+	err = remoteFeatures.Decode(bytes.NewReader(msg))
+	if err != nil {
+		return lnwire.FeatureVector{}, err
+	}
+
+	// Compression is used from here on if both ends advertised it.
+	c.compress = c.compressThreshold > 0 &&
+		remoteFeatures.IsSet(CompressionOptional)
+
+	c.remoteFeatures = lnwire.NewFeatureVector(
+		remoteFeatures, lnwire.Features,
+	)
+
+	return *c.remoteFeatures, nil
+}
@@ -0,0 +1,135 @@
+package brontide
+
+import (
+	"sync"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/keychain"
+)
+
+// echoServer echoes every message received over its accepted brontide
+// connections back to the sender.
+type echoServer struct {
+	listener *Listener
+
+	mu      sync.Mutex
+	conns   map[*Conn]struct{}
+	stopped bool
+
+	wg sync.WaitGroup
+}
+
+// ServeEcho starts a brontide listener on addr which echoes each message it
+// receives back to the sender, unchanged. The returned function stops the
+// listener, closes all accepted connections and waits for them to exit. This
+// is intended as a helper for integration tests that need an encrypted peer
+// to talk to.
+func ServeEcho(localPriv *btcec.PrivateKey, addr string) (*Listener, func(),
+	error) {
+
+	listener, err := NewListener(
+		&keychain.PrivKeyECDH{PrivKey: localPriv}, addr,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s := &echoServer{
+		listener: listener,
+		conns:    make(map[*Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.acceptConns()
+
+	var stopOnce sync.Once
+	stop := func() {
+		stopOnce.Do(s.stop)
+	}
+
+	return listener, stop, nil
+}
+
+// acceptConns accepts incoming connections until the listener is closed,
+// spawning a goroutine to echo the messages of each.
+//
+// NOTE: This method must be run as a goroutine.
+func (s *echoServer) acceptConns() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			// Transient accept errors are skipped, we only exit
+			// once the listener has been closed.
+			select {
+			case <-s.listener.quit:
+				return
+			default:
+				continue
+			}
+		}
+
+		brontideConn := conn.(*Conn)
+
+		// If the server was stopped while this connection was being
+		// accepted, it won't be closed by stop, so we close it here.
+		s.mu.Lock()
+		if s.stopped {
+			s.mu.Unlock()
+			brontideConn.Close()
+
+			return
+		}
+		s.conns[brontideConn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.echo(brontideConn)
+	}
+}
+
+// echo writes every message read from the connection back to it, until either
+// a read or write fails.
+//
+// NOTE: This method must be run as a goroutine.
+func (s *echoServer) echo(conn *Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+
+		conn.Close()
+	}()
+
+	for {
+		msg, err := conn.ReadNextMessage()
+		if err != nil {
+			return
+		}
+
+		if err := conn.WriteMessage(msg); err != nil {
+			return
+		}
+
+		if _, err := conn.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// stop closes the listener and all active connections, then waits for all
+// goroutines to exit.
+func (s *echoServer) stop() {
+	s.listener.Close()
+
+	s.mu.Lock()
+	s.stopped = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
@@ -0,0 +1,78 @@
+package brontide
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// ErrMissingRequiredFeature is returned by Dial when the peer doesn't support
+// a feature required using the RequiredPeerFeatures option.
+var ErrMissingRequiredFeature = errors.New("peer lacks required feature")
+
+// requiredFeatures holds the feature vectors passed to RequiredPeerFeatures.
+type requiredFeatures struct {
+	// local is the feature vector sent to the peer.
+	local lnwire.FeatureVector
+
+	// required holds the bits the peer must support.
+	required lnwire.FeatureVector
+}
+
+// RequiredPeerFeatures is a functional option that makes Dial carry out the
+// feature exchange right after the handshake, sending the local features, and
+// fail with ErrMissingRequiredFeature, closing the connection, unless the peer
+// supports each of the required bits, either as optional or required. The
+// features of the peer can then be queried using RemoteFeatures, as the
+// connection doesn't allow ExchangeFeatures to be called again. The peer must
+// call ExchangeFeatures on its end for Dial to return. The option is ignored
+// by NewListener, NewPipe, Client and Server.
+func RequiredPeerFeatures(local, required lnwire.FeatureVector) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.requiredFeatures = &requiredFeatures{
+			local:    local,
+			required: required,
+		}
+	}
+}
+
+// check exchanges features over the connection, and returns an error wrapping
+// ErrMissingRequiredFeature, naming the lowest missing bit, if the peer
+// doesn't support all of the required features.
+func (r *requiredFeatures) check(c *Conn) error {
+	remote, err := c.ExchangeFeatures(r.local)
+	if err != nil {
+		return err
+	}
+
+	if r.required.RawFeatureVector == nil {
+		return nil
+	}
+
+	bits := make([]lnwire.FeatureBit, 0, len(r.required.Features()))
+	for bit := range r.required.Features() {
+		bits = append(bits, bit)
+	}
+	sort.Slice(bits, func(i, j int) bool {
+		return bits[i] < bits[j]
+	})
+
+	for _, bit := range bits {
+		if !remote.HasFeature(bit) {
+			return fmt.Errorf("%w: %s (bit %d)",
+				ErrMissingRequiredFeature, remote.Name(bit),
+				bit)
+		}
+	}
+
+	return nil
+}
+
+// RemoteFeatures returns the feature vector received from the peer during the
+// feature exchange, carried out either by ExchangeFeatures or by Dial with the
+// RequiredPeerFeatures option, or nil if features haven't been exchanged.
+func (c *Conn) RemoteFeatures() *lnwire.FeatureVector {
+	return c.remoteFeatures
+}
@@ -1,12 +1,13 @@
 package brontide
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net"
-	"time"
+	"sync"
 
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/lightningnetwork/lnd/keychain"
 )
 
@@ -20,6 +21,11 @@ const defaultHandshakes = 1000
 // details w.r.t the handshake and encryption scheme used within the
 // connection.
 type Listener struct {
+	// identityMtx guards localStatic.
+	identityMtx sync.RWMutex
+
+	// localStatic is the static key used for inbound handshakes, which can
+	// be rotated using SetIdentity.
 	localStatic keychain.SingleKeyECDH
 
 	tcp *net.TCPListener
@@ -27,32 +33,114 @@ type Listener struct {
 	handshakeSema chan struct{}
 	conns         chan maybeConn
 	quit          chan struct{}
+
+	cfg *connConfig
+
+	// versionMtx guards acceptedVersions.
+	versionMtx sync.RWMutex
+
+	// acceptedVersions is the set of handshake versions accepted from
+	// dialers, or nil if only HandshakeVersion is accepted.
+	acceptedVersions []byte
+}
+
+// HandshakeError is returned from Accept by a Listener created with the
+// ReturnHandshakeErrors option when an inbound connection fails the handshake.
+// Unlike other errors returned from Accept, it doesn't signal a failure of the
+// listener itself, which keeps serving subsequent connections.
+type HandshakeError struct {
+	// RemoteAddr is the address of the peer that failed the handshake.
+	RemoteAddr net.Addr
+
+	// Err is the cause of the failure.
+	Err error
+}
+
+// Error returns a human readable description of the handshake failure.
+//
+// NOTE: Part of the error interface.
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("brontide handshake with %v failed: %v",
+		e.RemoteAddr, e.Err)
+}
+
+// Unwrap returns the cause of the handshake failure.
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
 }
 
 // A compile-time assertion to ensure that Conn meets the net.Listener interface.
 var _ net.Listener = (*Listener)(nil)
 
+// ErrUnsupportedListenNetwork is returned by NewListenerWithConfig when the
+// configured network isn't a TCP network.
+var ErrUnsupportedListenNetwork = errors.New("brontide listener network " +
+	"must be tcp, tcp4 or tcp6")
+
+// ListenerConfig controls how NewListenerWithConfig binds the underlying TCP
+// socket, which allows nodes with several interfaces or a dual-stack setup to
+// pick exactly where and how they listen.
+type ListenerConfig struct {
+	// ListenConfig, if set, is used to create the underlying listener,
+	// such that its Control function can apply socket options before the
+	// socket is bound, such as SO_REUSEPORT or SO_BINDTODEVICE.
+	//
+	// NOTE: SO_REUSEADDR is already set by the standard library on the
+	// listening sockets of all Unix platforms.
+	ListenConfig *net.ListenConfig
+
+	// Network selects the IP version the listener binds to. It must be
+	// "tcp", which listens on both IPv4 and IPv6 for unspecified or
+	// dual-stack addresses, "tcp4" or "tcp6". It defaults to "tcp".
+	Network string
+}
+
 // NewListener returns a new net.Listener which enforces the Brontide scheme
-// during both initial connection establishment and data transfer.
+// during both initial connection establishment and data transfer. The passed
+// options are applied to each accepted connection before the handshake.
 func NewListener(localStatic keychain.SingleKeyECDH,
-	listenAddr string) (*Listener, error) {
+	listenAddr string, opts ...ConnOption) (*Listener, error) {
 
-	addr, err := net.ResolveTCPAddr("tcp", listenAddr)
-	if err != nil {
-		return nil, err
+	return NewListenerWithConfig(
+		localStatic, listenAddr, ListenerConfig{}, opts...,
+	)
+}
+
+// NewListenerWithConfig behaves like NewListener, but binds the underlying TCP
+// socket according to the given ListenerConfig.
+func NewListenerWithConfig(localStatic keychain.SingleKeyECDH,
+	listenAddr string, listenCfg ListenerConfig,
+	opts ...ConnOption) (*Listener, error) {
+
+	network := listenCfg.Network
+	switch network {
+	case "":
+		network = "tcp"
+
+	case "tcp", "tcp4", "tcp6":
+
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedListenNetwork,
+			network)
+	}
+
+	lc := listenCfg.ListenConfig
+	if lc == nil {
+		lc = &net.ListenConfig{}
 	}
 
-	l, err := net.ListenTCP("tcp", addr)
+	l, err := lc.Listen(context.Background(), network, listenAddr)
 	if err != nil {
 		return nil, err
 	}
 
 	brontideListener := &Listener{
 		localStatic:   localStatic,
-		tcp:           l,
+		tcp:           l.(*net.TCPListener),
 		handshakeSema: make(chan struct{}, defaultHandshakes),
 		conns:         make(chan maybeConn),
 		quit:          make(chan struct{}),
+		cfg:           newConnConfig(opts),
 	}
 
 	for i := 0; i < defaultHandshakes; i++ {
@@ -64,6 +152,63 @@ func NewListener(localStatic keychain.SingleKeyECDH,
 	return brontideListener, nil
 }
 
+// SetHandshakeVersion sets the handshake versions accepted by the listener,
+// allowing a new version to be rolled out while dialers are still migrating.
+// Each inbound connection uses the version requested by its dialer, which can
+// be queried using HandshakeVersion, while dialers requesting a version outside
+// of the set are rejected with ErrUnsupportedHandshakeVersion. Calling it
+// without any versions restores the default of only accepting
+// HandshakeVersion. The set applies to handshakes started after the call.
+func (l *Listener) SetHandshakeVersion(versions ...byte) {
+	var accepted []byte
+	if len(versions) > 0 {
+		accepted = make([]byte, len(versions))
+		copy(accepted, versions)
+	}
+
+	l.versionMtx.Lock()
+	l.acceptedVersions = accepted
+	l.versionMtx.Unlock()
+}
+
+// SetIdentity replaces the static key used by the listener, allowing a node
+// to rotate its identity key without restarting the listener. Only handshakes
+// started after the call use the new key, such that dialers must then know
+// the new public key, while connections established or being established
+// under the previous key are left untouched.
+func (l *Listener) SetIdentity(newPriv *btcec.PrivateKey) {
+	localStatic := &keychain.PrivKeyECDH{PrivKey: newPriv}
+
+	l.identityMtx.Lock()
+	l.localStatic = localStatic
+	l.identityMtx.Unlock()
+}
+
+// identity returns the static key to use for an inbound handshake.
+func (l *Listener) identity() keychain.SingleKeyECDH {
+	l.identityMtx.RLock()
+	defer l.identityMtx.RUnlock()
+
+	return l.localStatic
+}
+
+// machineOptions returns the options to pass to NewBrontideMachine for an
+// inbound connection.
+func (l *Listener) machineOptions() []func(*Machine) {
+	opts := l.cfg.machineOptions()
+
+	l.versionMtx.RLock()
+	defer l.versionMtx.RUnlock()
+
+	if l.acceptedVersions != nil {
+		opts = append(
+			opts, AcceptHandshakeVersions(l.acceptedVersions...),
+		)
+	}
+
+	return opts
+}
+
 // listen accepts connection from the underlying tcp conn, then performs
 // the brontinde handshake procedure asynchronously. A maximum of
 // defaultHandshakes will be active at any given time.
@@ -88,11 +233,24 @@ func (l *Listener) listen() {
 	}
 }
 
-// rejectedConnErr is a helper function that prepends the remote address of the
-// failed connection attempt to the original error message.
-func rejectedConnErr(err error, remoteAddr string) error {
-	return fmt.Errorf("unable to accept connection from %v: %v", remoteAddr,
-		err)
+// handshakeFailed closes an inbound connection that failed the handshake, and
+// reports the failure to the OnHandshakeError callback if one was provided.
+// The failure is only returned from Accept, as a *HandshakeError, if the
+// ReturnHandshakeErrors option was set, such that by default a single
+// misbehaving peer can't interrupt the caller's serving loop.
+func (l *Listener) handshakeFailed(conn net.Conn, err error) {
+	conn.Close()
+
+	if l.cfg.onHandshakeError != nil {
+		l.cfg.onHandshakeError(conn.RemoteAddr(), err)
+	}
+
+	if l.cfg.returnHandshakeErrors {
+		l.rejectConn(&HandshakeError{
+			RemoteAddr: conn.RemoteAddr(),
+			Err:        err,
+		})
+	}
 }
 
 // doHandshake asynchronously performs the brontide handshake, so that it does
@@ -107,97 +265,47 @@ func (l *Listener) doHandshake(conn net.Conn) {
 	default:
 	}
 
-	remoteAddr := conn.RemoteAddr().String()
-
-	brontideConn := &Conn{
-		conn:  conn,
-		noise: NewBrontideMachine(false, l.localStatic, nil),
-	}
-
-	// We'll ensure that we get ActOne from the remote peer in a timely
-	// manner. If they don't respond within handshakeReadTimeout, then
-	// we'll kill the connection.
-	err := conn.SetReadDeadline(time.Now().Add(handshakeReadTimeout))
+	brontideConn, err := newConn(conn, NewBrontideMachine(
+		false, l.identity(), nil, l.machineOptions()...,
+	), l.cfg)
 	if err != nil {
-		brontideConn.conn.Close()
-		l.rejectConn(rejectedConnErr(err, remoteAddr))
+		l.handshakeFailed(conn, err)
 		return
 	}
 
-	// Attempt to carry out the first act of the handshake protocol. If the
-	// connecting node doesn't know our long-term static public key, then
-	// this portion will fail with a non-nil error.
-	var actOne [ActOneSize]byte
-	if _, err := io.ReadFull(conn, actOne[:]); err != nil {
-		brontideConn.conn.Close()
-		l.rejectConn(rejectedConnErr(err, remoteAddr))
-		return
-	}
-	if err := brontideConn.noise.RecvActOne(actOne); err != nil {
-		brontideConn.conn.Close()
-		l.rejectConn(rejectedConnErr(err, remoteAddr))
+	// Carry out the responder's side of the handshake. If the connecting
+	// node doesn't know our long-term static public key, or fails to
+	// authenticate itself, then this will fail with a non-nil error.
+	if err := brontideConn.responderHandshake(); err != nil {
+		l.handshakeFailed(conn, err)
 		return
 	}
 
-	// Next, progress the handshake processes by sending over our ephemeral
-	// key for the session along with an authenticating tag.
-	actTwo, err := brontideConn.noise.GenActTwo()
-	if err != nil {
-		brontideConn.conn.Close()
-		l.rejectConn(rejectedConnErr(err, remoteAddr))
+	// Now that the initiator's static key has been authenticated, reject
+	// the connection if the peer isn't allowed.
+	if l.cfg.allowedRemote != nil &&
+		!l.cfg.allowedRemote(brontideConn.RemotePub()) {
+
+		l.handshakeFailed(brontideConn, ErrPeerNotAllowed)
 		return
 	}
-	if _, err := conn.Write(actTwo[:]); err != nil {
-		brontideConn.conn.Close()
-		l.rejectConn(rejectedConnErr(err, remoteAddr))
-		return
+
+	if l.cfg.onAuthenticated != nil {
+		l.cfg.onAuthenticated(brontideConn)
 	}
 
 	select {
 	case <-l.quit:
+		brontideConn.Close()
 		return
 	default:
 	}
 
-	// We'll ensure that we get ActTwo from the remote peer in a timely
-	// manner. If they don't respond within handshakeReadTimeout, then
-	// we'll kill the connection.
-	err = conn.SetReadDeadline(time.Now().Add(handshakeReadTimeout))
-	if err != nil {
-		brontideConn.conn.Close()
-		l.rejectConn(rejectedConnErr(err, remoteAddr))
-		return
-	}
-
-	// Finally, finish the handshake processes by reading and decrypting
-	// the connection peer's static public key. If this succeeds then both
-	// sides have mutually authenticated each other.
-	var actThree [ActThreeSize]byte
-	if _, err := io.ReadFull(conn, actThree[:]); err != nil {
-		brontideConn.conn.Close()
-		l.rejectConn(rejectedConnErr(err, remoteAddr))
-		return
-	}
-	if err := brontideConn.noise.RecvActThree(actThree); err != nil {
-		brontideConn.conn.Close()
-		l.rejectConn(rejectedConnErr(err, remoteAddr))
-		return
-	}
-
-	// We'll reset the deadline as it's no longer critical beyond the
-	// initial handshake.
-	err = conn.SetReadDeadline(time.Time{})
-	if err != nil {
-		brontideConn.conn.Close()
-		l.rejectConn(rejectedConnErr(err, remoteAddr))
-		return
-	}
-
 	l.acceptConn(brontideConn)
 }
 
-// maybeConn holds either a brontide connection or an error returned from the
-// handshake.
+// maybeConn holds either a brontide connection or an error returned while
+// accepting the underlying connection.
 type maybeConn struct {
 	conn *Conn
 	err  error
@@ -208,10 +316,11 @@ func (l *Listener) acceptConn(conn *Conn) {
 	select {
 	case l.conns <- maybeConn{conn: conn}:
 	case <-l.quit:
+		conn.Close()
 	}
 }
 
-// rejectConn returns any errors encountered during connection or handshake.
+// rejectConn returns any errors encountered while accepting a connection.
 func (l *Listener) rejectConn(err error) {
 	select {
 	case l.conns <- maybeConn{err: err}:
@@ -221,9 +330,13 @@ func (l *Listener) rejectConn(err error) {
 
 // Accept waits for and returns the next connection to the listener. All
 // incoming connections are authenticated via the three act Brontide
-// key-exchange scheme. This function will fail with a non-nil error in the
-// case that either the handshake breaks down, or the remote peer doesn't know
-// our static public key.
+// key-exchange scheme. Connections for which the handshake breaks down, or
+// whose remote peer doesn't know our static public key, are closed and
+// reported to the OnHandshakeError callback instead of being returned, unless
+// the ReturnHandshakeErrors option was set, in which case a *HandshakeError is
+// returned and Accept may be called again. Otherwise, this function will only
+// fail with a non-nil error if the underlying TCP listener fails to accept a
+// connection, or the listener is closed.
 //
 // Part of the net.Listener interface.
 func (l *Listener) Accept() (net.Conn, error) {
@@ -0,0 +1,52 @@
+package brontide
+
+// MessageConn is a message oriented view of a brontide connection, offering
+// datagram semantics on top of the encrypted stream: each message written is
+// carried by a single frame, and read back whole by exactly one call to
+// ReadMessage, in order.
+type MessageConn interface {
+	// WriteMessage encrypts the message into a single frame and writes it
+	// to the connection. Messages larger than math.MaxUint16 bytes are
+	// rejected with ErrMaxMessageLengthExceeded, rather than being split
+	// across several frames.
+	WriteMessage([]byte) error
+
+	// ReadMessage returns the next message written by the remote peer,
+	// never a part of it. An empty message is returned as an empty,
+	// non-nil slice.
+	ReadMessage() ([]byte, error)
+}
+
+// messageConn implements MessageConn on top of the framing of a Conn.
+type messageConn struct {
+	conn *Conn
+}
+
+// A compile-time assertion to ensure messageConn meets the MessageConn
+// interface.
+var _ MessageConn = (*messageConn)(nil)
+
+// PacketConn returns a MessageConn reading and writing whole messages over the
+// connection. It shares the cipher state of the connection, and must therefore
+// not be mixed with the streaming Read method, whose buffered remainder of a
+// message would otherwise be skipped. Writes through both views may be mixed,
+// as each message is written under the write lock of the connection.
+func (c *Conn) PacketConn() MessageConn {
+	return &messageConn{conn: c}
+}
+
+// WriteMessage encrypts the message into a single frame and writes it to the
+// connection, flushing any frames buffered by a coalesced Write first.
+//
+// NOTE: Part of the MessageConn interface.
+func (m *messageConn) WriteMessage(msg []byte) error {
+	_, err := m.conn.WriteMessages([][]byte{msg})
+	return err
+}
+
+// ReadMessage returns the next message written by the remote peer.
+//
+// NOTE: Part of the MessageConn interface.
+func (m *messageConn) ReadMessage() ([]byte, error) {
+	return m.conn.ReadNextMessage()
+}
@@ -1,7 +1,6 @@
 package brontide
 
 import (
-	"crypto/cipher"
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
@@ -11,8 +10,8 @@ import (
 	"time"
 
 	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/internal/aead"
 	"github.com/lightningnetwork/lnd/keychain"
-	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/hkdf"
 )
 
@@ -24,8 +23,13 @@ const (
 	// network, then the initial handshake will fail.
 	protocolName = "Noise_XK_secp256k1_ChaChaPoly_SHA256"
 
+	// protocolNameXX is the protocol name of the Noise_XX handshake used
+	// when the initiator doesn't know the responder's static key, see
+	// HandshakeVersionXX.
+	protocolNameXX = "Noise_XX_secp256k1_ChaChaPoly_SHA256"
+
 	// macSize is the length in bytes of the tags generated by poly1305.
-	macSize = 16
+	macSize = aead.MACSize
 
 	// lengthHeaderSize is the number of bytes used to prefix encode the
 	// length of a message payload.
@@ -56,6 +60,30 @@ var (
 	// message because the prior message has not been fully flushed.
 	ErrMessageNotFlushed = errors.New("prior message not flushed")
 
+	// ErrInvalidExportLength signals that the requested amount of keying
+	// material is either non-positive or exceeds the maximum that can be
+	// derived via HKDF-SHA256.
+	ErrInvalidExportLength = errors.New("invalid keying material length")
+
+	// ErrUnsupportedHandshakeVersion is returned when a handshake act
+	// carries a version that the receiving side doesn't accept.
+	ErrUnsupportedHandshakeVersion = errors.New("unsupported handshake " +
+		"version")
+
+	// ErrMACVerificationFailed is returned when the MAC of the length
+	// header or body of a frame fails verification, signaling that the
+	// frame was corrupted in transit or forged by a third party.
+	ErrMACVerificationFailed = errors.New("frame mac verification failed")
+
+	// ErrInvalidHandshakeKey is returned when an ephemeral or static public
+	// key received during the handshake isn't a valid point on the curve.
+	ErrInvalidHandshakeKey = errors.New("invalid handshake public key")
+
+	// exporterLabelPrefix is prepended to the caller's label when
+	// exporting keying material, such that the derivation is domain
+	// separated from the one used to derive the session keys.
+	exporterLabelPrefix = []byte("brontide exporter ")
+
 	// lightningPrologue is the noise prologue that is used to initialize
 	// the brontide noise handshake.
 	lightningPrologue = []byte("lightning")
@@ -76,6 +104,21 @@ func ecdh(pub *btcec.PublicKey, priv keychain.SingleKeyECDH) ([]byte, error) {
 	return hash[:], err
 }
 
+// parseHandshakeKey parses a compressed public key received during the
+// handshake, returning an error wrapping ErrInvalidHandshakeKey if it isn't a
+// valid point on the curve. Parsing rejects encodings that don't decompress to
+// a curve point, which includes the point at infinity as it has no encoding,
+// such that no ECDH is ever performed with a malformed key. As secp256k1 has a
+// cofactor of one, there are no small subgroups left to check for.
+func parseHandshakeKey(key []byte) (*btcec.PublicKey, error) {
+	pub, err := btcec.ParsePubKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHandshakeKey, err)
+	}
+
+	return pub, nil
+}
+
 // cipherState encapsulates the state for the AEAD which will be used to
 // encrypt+authenticate any payloads sent during the handshake, and messages
 // sent once the handshake has completed.
@@ -99,42 +142,41 @@ type cipherState struct {
 
 	// cipher is an instance of the ChaCha20-Poly1305 AEAD construction
 	// created using the secretKey above.
-	cipher cipher.AEAD
+	cipher *aead.Cipher
 }
 
 // Encrypt returns a ciphertext which is the encryption of the plainText
 // observing the passed associatedData within the AEAD construction.
 func (c *cipherState) Encrypt(associatedData, cipherText, plainText []byte) []byte {
-	defer func() {
-		c.nonce++
-
-		if c.nonce == keyRotationInterval {
-			c.rotateKey()
-		}
-	}()
+	defer c.advanceNonce()
 
-	var nonce [12]byte
-	binary.LittleEndian.PutUint64(nonce[4:], c.nonce)
-
-	return c.cipher.Seal(cipherText, nonce[:], plainText, associatedData)
+	return c.cipher.SealCounter(
+		cipherText, c.nonce, plainText, associatedData,
+	)
 }
 
 // Decrypt attempts to decrypt the passed ciphertext observing the specified
 // associatedData within the AEAD construction. In the case that the final MAC
 // check fails, then a non-nil error will be returned.
 func (c *cipherState) Decrypt(associatedData, plainText, cipherText []byte) ([]byte, error) {
-	defer func() {
-		c.nonce++
+	defer c.advanceNonce()
 
-		if c.nonce == keyRotationInterval {
-			c.rotateKey()
-		}
-	}()
+	return c.cipher.OpenCounter(
+		plainText, c.nonce, cipherText, associatedData,
+	)
+}
 
-	var nonce [12]byte
-	binary.LittleEndian.PutUint64(nonce[4:], c.nonce)
+// advanceNonce increments the nonce after an encryption or decryption, and
+// rotates the key once keyRotationInterval messages have used it, which resets
+// the nonce. The rotation is triggered for any nonce at or beyond the interval,
+// such that the nonce is never able to run on towards wrapping around, which
+// would reuse a nonce under the same key.
+func (c *cipherState) advanceNonce() {
+	c.nonce++
 
-	return c.cipher.Open(plainText, nonce[:], cipherText, associatedData)
+	if c.nonce >= keyRotationInterval {
+		c.rotateKey()
+	}
 }
 
 // InitializeKey initializes the secret key and AEAD cipher scheme based off of
@@ -145,7 +187,7 @@ func (c *cipherState) InitializeKey(key [32]byte) {
 
 	// Safe to ignore the error here as our key is properly sized
 	// (32-bytes).
-	c.cipher, _ = chacha20poly1305.New(c.secretKey[:])
+	c.cipher, _ = aead.New(c.secretKey[:])
 }
 
 // InitializeKeyWithSalt is identical to InitializeKey however it also sets the
@@ -236,7 +278,7 @@ func (s *symmetricState) mixHash(data []byte) {
 	h.Write(s.handshakeDigest[:])
 	h.Write(data)
 
-	copy(s.handshakeDigest[:], h.Sum(nil))
+	h.Sum(s.handshakeDigest[:0])
 }
 
 // EncryptAndHash returns the authenticated encryption of the passed plaintext.
@@ -324,6 +366,23 @@ func newHandshakeState(initiator bool, prologue []byte,
 	return h
 }
 
+// newXXHandshakeState returns a new instance of the handshake state for the
+// Noise_XX handshake, in which neither side knows the other's static key
+// upfront, so only the prologue and protocol name are mixed in.
+func newXXHandshakeState(initiator bool, prologue []byte,
+	localKey keychain.SingleKeyECDH) handshakeState {
+
+	h := handshakeState{
+		initiator:   initiator,
+		localStatic: localKey,
+	}
+
+	h.InitializeSymmetric([]byte(protocolNameXX))
+	h.mixHash(prologue)
+
+	return h
+}
+
 // EphemeralGenerator is a functional option that allows callers to substitute
 // a custom function for use when generating ephemeral keys for ActOne or
 // ActTwo. The function closure returned by this function can be passed into
@@ -334,6 +393,27 @@ func EphemeralGenerator(gen func() (*btcec.PrivateKey, error)) func(*Machine) {
 	}
 }
 
+// WithHandshakeVersion is a functional option that sets the handshake version
+// requested by an initiator in act one, which is HandshakeVersion by default.
+// The responder echoes the version it accepted in acts two and three, such
+// that both sides agree on the version used for the session.
+func WithHandshakeVersion(version byte) func(*Machine) {
+	return func(m *Machine) {
+		m.version = version
+	}
+}
+
+// AcceptHandshakeVersions is a functional option that sets the handshake
+// versions a responder accepts in act one, which is only HandshakeVersion by
+// default. The version requested by the initiator is used for the session if
+// it's within the set, otherwise the handshake fails with
+// ErrUnsupportedHandshakeVersion.
+func AcceptHandshakeVersions(versions ...byte) func(*Machine) {
+	return func(m *Machine) {
+		m.acceptedVersions = versions
+	}
+}
+
 // Machine is a state-machine which implements Brontide: an
 // Authenticated-key Exchange in Three Acts. Brontide is derived from the Noise
 // framework, specifically implementing the Noise_XK handshake. Once the
@@ -361,12 +441,30 @@ func EphemeralGenerator(gen func() (*btcec.PrivateKey, error)) func(*Machine) {
 //	-> e, es
 //	<- e, ee
 //	-> s, se
+//
+// If the initiator requests HandshakeVersionXX, the responder's static key
+// isn't needed upfront, and the acts instead correspond to the Noise_XX
+// handshake, in which the responder sends its static key in act two using
+// GenActTwoXX and RecvActTwoXX:
+//
+//	-> e
+//	<- e, ee, s, es
+//	-> s, se
 type Machine struct {
 	sendCipher cipherState
 	recvCipher cipherState
 
 	ephemeralGen func() (*btcec.PrivateKey, error)
 
+	// version is the handshake version of the session. For the initiator
+	// it's the version requested in act one, while for the responder it's
+	// set to the version accepted from act one.
+	version byte
+
+	// acceptedVersions is the set of handshake versions accepted by the
+	// responder, or nil if only HandshakeVersion is accepted.
+	acceptedVersions []byte
+
 	handshakeState
 
 	// nextCipherHeader is a static buffer that we'll use to read in the
@@ -386,21 +484,17 @@ type Machine struct {
 }
 
 // NewBrontideMachine creates a new instance of the brontide state-machine. If
-// the responder (listener) is creating the object, then the remotePub should
-// be nil. The handshake state within brontide is initialized using the ascii
-// string "lightning" as the prologue. The last parameter is a set of variadic
-// arguments for adding additional options to the brontide Machine
-// initialization.
+// the responder (listener) is creating the object, or the initiator requests
+// HandshakeVersionXX, then the remotePub should be nil. The handshake state
+// within brontide is initialized using the ascii string "lightning" as the
+// prologue. The last parameter is a set of variadic arguments for adding
+// additional options to the brontide Machine initialization.
 func NewBrontideMachine(initiator bool, localKey keychain.SingleKeyECDH,
 	remotePub *btcec.PublicKey, options ...func(*Machine)) *Machine {
 
-	handshake := newHandshakeState(
-		initiator, lightningPrologue, localKey, remotePub,
-	)
-
 	m := &Machine{
-		handshakeState: handshake,
-		ephemeralGen:   ephemeralGen,
+		ephemeralGen: ephemeralGen,
+		version:      HandshakeVersion,
 	}
 
 	// With the default options established, we'll now process all the
@@ -409,6 +503,20 @@ func NewBrontideMachine(initiator bool, localKey keychain.SingleKeyECDH,
 		option(m)
 	}
 
+	// The handshake state depends on the requested version, as an
+	// initiator requesting the XX handshake doesn't know the responder's
+	// static key. A responder always starts out with the XK handshake
+	// state, which is replaced in act one if the initiator requests XX.
+	if initiator && m.isXX() {
+		m.handshakeState = newXXHandshakeState(
+			initiator, lightningPrologue, localKey,
+		)
+	} else {
+		m.handshakeState = newHandshakeState(
+			initiator, lightningPrologue, localKey, remotePub,
+		)
+	}
+
 	return m
 }
 
@@ -418,6 +526,14 @@ const (
 	// to abort immediately.
 	HandshakeVersion = byte(0)
 
+	// HandshakeVersionXX is the handshake version reserved for the
+	// Noise_XX handshake, which an initiator requests when it doesn't know
+	// the responder's static key, learning it in act two instead. Since
+	// this reveals the responder's static key to anyone connecting,
+	// listeners must explicitly accept the version using
+	// SetHandshakeVersion.
+	HandshakeVersionXX = byte(0x80)
+
 	// ActOneSize is the size of the packet sent from initiator to
 	// responder in ActOne. The packet consists of a handshake version, an
 	// ephemeral key in compressed format, and a 16-byte poly1305 tag.
@@ -432,6 +548,15 @@ const (
 	// 1 + 33 + 16
 	ActTwoSize = 50
 
+	// ActTwoXXSize is the size of the packet sent from responder to
+	// initiator in act two of the XX handshake. The packet consists of a
+	// handshake version, an ephemeral key in compressed format, the
+	// responder's static key encrypted with a 16-byte poly1305 tag, and a
+	// 16-byte poly1305 tag.
+	//
+	// 1 + 33 + 33 + 16 + 16
+	ActTwoXXSize = 99
+
 	// ActThreeSize is the size of the packet sent from initiator to
 	// responder in ActThree. The packet consists of a handshake version,
 	// the initiators static key encrypted with strong forward secrecy and
@@ -445,7 +570,9 @@ const (
 // to responder. During act one the initiator generates a fresh ephemeral key,
 // hashes it into the handshake digest, and performs an ECDH between this key
 // and the responder's static key. Future payloads are encrypted with a key
-// derived from this result.
+// derived from this result. For the XX handshake the ECDH is skipped, as the
+// responder's static key isn't known yet, while the act keeps the same size
+// such that responders can read it before knowing which handshake is used.
 //
 //	-> e, es
 func (b *Machine) GenActOne() ([ActOneSize]byte, error) {
@@ -464,15 +591,17 @@ func (b *Machine) GenActOne() ([ActOneSize]byte, error) {
 	b.mixHash(ephemeral)
 
 	// es
-	s, err := ecdh(b.remoteStatic, b.localEphemeral)
-	if err != nil {
-		return actOne, err
+	if !b.isXX() {
+		s, err := ecdh(b.remoteStatic, b.localEphemeral)
+		if err != nil {
+			return actOne, err
+		}
+		b.mixKey(s[:])
 	}
-	b.mixKey(s[:])
 
 	authPayload := b.EncryptAndHash([]byte{})
 
-	actOne[0] = HandshakeVersion
+	actOne[0] = b.version
 	copy(actOne[1:34], ephemeral)
 	copy(actOne[34:], authPayload)
 
@@ -490,30 +619,44 @@ func (b *Machine) RecvActOne(actOne [ActOneSize]byte) error {
 		p   [16]byte
 	)
 
-	// If the handshake version is unknown, then the handshake fails
-	// immediately.
-	if actOne[0] != HandshakeVersion {
-		return fmt.Errorf("act one: invalid handshake version: %v, "+
-			"only %v is valid, msg=%x", actOne[0], HandshakeVersion,
-			actOne[:])
+	// If the handshake version isn't one we accept, then the handshake
+	// fails immediately. Otherwise, the requested version is used for the
+	// remainder of the session.
+	if !b.acceptsVersion(actOne[0]) {
+		return fmt.Errorf("act one: %w: %v, msg=%x",
+			ErrUnsupportedHandshakeVersion, actOne[0], actOne[:])
+	}
+	b.version = actOne[0]
+
+	// If the initiator requested the XX handshake, then it doesn't know
+	// our static key, which therefore isn't part of the handshake digest.
+	if b.isXX() {
+		b.handshakeState = newXXHandshakeState(
+			false, lightningPrologue, b.localStatic,
+		)
 	}
 
 	copy(e[:], actOne[1:34])
 	copy(p[:], actOne[34:])
 
 	// e
-	b.remoteEphemeral, err = btcec.ParsePubKey(e[:])
+	b.remoteEphemeral, err = parseHandshakeKey(e[:])
 	if err != nil {
 		return err
 	}
-	b.mixHash(b.remoteEphemeral.SerializeCompressed())
+
+	// The parser only accepts the canonical compressed encoding, so the
+	// received bytes are mixed in as is rather than re-serialized.
+	b.mixHash(e[:])
 
 	// es
-	s, err := ecdh(b.remoteEphemeral, b.localStatic)
-	if err != nil {
-		return err
+	if !b.isXX() {
+		s, err := ecdh(b.remoteEphemeral, b.localStatic)
+		if err != nil {
+			return err
+		}
+		b.mixKey(s)
 	}
-	b.mixKey(s)
 
 	// If the initiator doesn't know our static key, then this operation
 	// will fail.
@@ -540,7 +683,7 @@ func (b *Machine) GenActTwo() ([ActTwoSize]byte, error) {
 	}
 
 	ephemeral := localEphemeral.PubKey().SerializeCompressed()
-	b.mixHash(localEphemeral.PubKey().SerializeCompressed())
+	b.mixHash(ephemeral)
 
 	// ee
 	s, err := ecdh(b.remoteEphemeral, b.localEphemeral)
@@ -551,7 +694,7 @@ func (b *Machine) GenActTwo() ([ActTwoSize]byte, error) {
 
 	authPayload := b.EncryptAndHash([]byte{})
 
-	actTwo[0] = HandshakeVersion
+	actTwo[0] = b.version
 	copy(actTwo[1:34], ephemeral)
 	copy(actTwo[34:], authPayload)
 
@@ -568,11 +711,11 @@ func (b *Machine) RecvActTwo(actTwo [ActTwoSize]byte) error {
 		p   [16]byte
 	)
 
-	// If the handshake version is unknown, then the handshake fails
-	// immediately.
-	if actTwo[0] != HandshakeVersion {
-		return fmt.Errorf("act two: invalid handshake version: %v, "+
-			"only %v is valid, msg=%x", actTwo[0], HandshakeVersion,
+	// If the responder didn't echo the handshake version we requested,
+	// then the handshake fails immediately.
+	if actTwo[0] != b.version {
+		return fmt.Errorf("act two: %w: %v, only %v is valid, msg=%x",
+			ErrUnsupportedHandshakeVersion, actTwo[0], b.version,
 			actTwo[:])
 	}
 
@@ -580,11 +723,103 @@ func (b *Machine) RecvActTwo(actTwo [ActTwoSize]byte) error {
 	copy(p[:], actTwo[34:])
 
 	// e
-	b.remoteEphemeral, err = btcec.ParsePubKey(e[:])
+	b.remoteEphemeral, err = parseHandshakeKey(e[:])
+	if err != nil {
+		return err
+	}
+	b.mixHash(e[:])
+
+	// ee
+	s, err := ecdh(b.remoteEphemeral, b.localEphemeral)
+	if err != nil {
+		return err
+	}
+	b.mixKey(s)
+
+	_, err = b.DecryptAndHash(p[:])
+	return err
+}
+
+// GenActTwoXX generates the second packet (act two) of the XX handshake, to be
+// sent from the responder to the initiator in place of GenActTwo. In addition
+// to the ephemeral key of act two, the packet carries the responder's static
+// key, encrypted under the ephemeral ECDH, followed by an ECDH between the
+// initiator's ephemeral key and the responder's static key.
+//
+//	<- e, ee, s, es
+func (b *Machine) GenActTwoXX() ([ActTwoXXSize]byte, error) {
+	var actTwo [ActTwoXXSize]byte
+
+	// e
+	localEphemeral, err := b.ephemeralGen()
+	if err != nil {
+		return actTwo, err
+	}
+	b.localEphemeral = &keychain.PrivKeyECDH{
+		PrivKey: localEphemeral,
+	}
+
+	ephemeral := localEphemeral.PubKey().SerializeCompressed()
+	b.mixHash(ephemeral)
+
+	// ee
+	s, err := ecdh(b.remoteEphemeral, b.localEphemeral)
+	if err != nil {
+		return actTwo, err
+	}
+	b.mixKey(s)
+
+	// s
+	ourPubkey := b.localStatic.PubKey().SerializeCompressed()
+	ciphertext := b.EncryptAndHash(ourPubkey)
+
+	// es
+	s, err = ecdh(b.remoteEphemeral, b.localStatic)
+	if err != nil {
+		return actTwo, err
+	}
+	b.mixKey(s)
+
+	authPayload := b.EncryptAndHash([]byte{})
+
+	actTwo[0] = b.version
+	copy(actTwo[1:34], ephemeral)
+	copy(actTwo[34:83], ciphertext)
+	copy(actTwo[83:], authPayload)
+
+	return actTwo, nil
+}
+
+// RecvActTwoXX processes the second packet (act two) of the XX handshake sent
+// from the responder to the initiator, in place of RecvActTwo. After
+// processing this act, the initiator learns of the responder's static public
+// key, which is authenticated by the final ECDH operation.
+func (b *Machine) RecvActTwoXX(actTwo [ActTwoXXSize]byte) error {
+	var (
+		err error
+		e   [33]byte
+		c   [33 + 16]byte
+		p   [16]byte
+	)
+
+	// If the responder didn't echo the handshake version we requested,
+	// then the handshake fails immediately.
+	if actTwo[0] != b.version {
+		return fmt.Errorf("act two: %w: %v, only %v is valid, msg=%x",
+			ErrUnsupportedHandshakeVersion, actTwo[0], b.version,
+			actTwo[:])
+	}
+
+	copy(e[:], actTwo[1:34])
+	copy(c[:], actTwo[34:83])
+	copy(p[:], actTwo[83:])
+
+	// e
+	b.remoteEphemeral, err = parseHandshakeKey(e[:])
 	if err != nil {
 		return err
 	}
-	b.mixHash(b.remoteEphemeral.SerializeCompressed())
+	b.mixHash(e[:])
 
 	// ee
 	s, err := ecdh(b.remoteEphemeral, b.localEphemeral)
@@ -593,6 +828,23 @@ func (b *Machine) RecvActTwo(actTwo [ActTwoSize]byte) error {
 	}
 	b.mixKey(s)
 
+	// s
+	remotePub, err := b.DecryptAndHash(c[:])
+	if err != nil {
+		return err
+	}
+	b.remoteStatic, err = parseHandshakeKey(remotePub)
+	if err != nil {
+		return err
+	}
+
+	// es
+	s, err = ecdh(b.remoteStatic, b.localEphemeral)
+	if err != nil {
+		return err
+	}
+	b.mixKey(s)
+
 	_, err = b.DecryptAndHash(p[:])
 	return err
 }
@@ -618,7 +870,7 @@ func (b *Machine) GenActThree() ([ActThreeSize]byte, error) {
 
 	authPayload := b.EncryptAndHash([]byte{})
 
-	actThree[0] = HandshakeVersion
+	actThree[0] = b.version
 	copy(actThree[1:50], ciphertext)
 	copy(actThree[50:], authPayload)
 
@@ -640,12 +892,12 @@ func (b *Machine) RecvActThree(actThree [ActThreeSize]byte) error {
 		p   [16]byte
 	)
 
-	// If the handshake version is unknown, then the handshake fails
-	// immediately.
-	if actThree[0] != HandshakeVersion {
-		return fmt.Errorf("act three: invalid handshake version: %v, "+
-			"only %v is valid, msg=%x", actThree[0], HandshakeVersion,
-			actThree[:])
+	// If the handshake version differs from the one accepted in act one,
+	// then the handshake fails immediately.
+	if actThree[0] != b.version {
+		return fmt.Errorf("act three: %w: %v, only %v is valid, "+
+			"msg=%x", ErrUnsupportedHandshakeVersion, actThree[0],
+			b.version, actThree[:])
 	}
 
 	copy(s[:], actThree[1:33+16+1])
@@ -656,7 +908,7 @@ func (b *Machine) RecvActThree(actThree [ActThreeSize]byte) error {
 	if err != nil {
 		return err
 	}
-	b.remoteStatic, err = btcec.ParsePubKey(remotePub)
+	b.remoteStatic, err = parseHandshakeKey(remotePub)
 	if err != nil {
 		return err
 	}
@@ -679,6 +931,33 @@ func (b *Machine) RecvActThree(actThree [ActThreeSize]byte) error {
 	return nil
 }
 
+// acceptsVersion returns true if the responder accepts the given handshake
+// version in act one.
+func (b *Machine) acceptsVersion(version byte) bool {
+	if b.acceptedVersions == nil {
+		return version == HandshakeVersion
+	}
+
+	for _, v := range b.acceptedVersions {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isXX returns true if the session uses the Noise_XX handshake.
+func (b *Machine) isXX() bool {
+	return b.version == HandshakeVersionXX
+}
+
+// HandshakeVersion returns the handshake version of the session, which is only
+// final once the handshake has completed.
+func (b *Machine) HandshakeVersion() byte {
+	return b.version
+}
+
 // split is the final wrap-up act to be executed at the end of a successful
 // three act handshake. This function creates two internal cipherState
 // instances: one which is used to encrypt messages from the initiator to the
@@ -715,6 +994,35 @@ func (b *Machine) split() {
 	}
 }
 
+// exportKeyingMaterial derives length bytes of keying material bound to the
+// completed handshake, using HKDF keyed with the final chaining key and salted
+// with the final handshake digest. Both values are identical for the two ends
+// of a session and unique to it, while neither is ever transmitted.
+//
+// NOTE: This MUST only be called after the handshake has completed.
+func (b *Machine) exportKeyingMaterial(label []byte,
+	length int) ([]byte, error) {
+
+	if length <= 0 || length > 255*sha256.Size {
+		return nil, ErrInvalidExportLength
+	}
+
+	info := make([]byte, 0, len(exporterLabelPrefix)+len(label))
+	info = append(info, exporterLabelPrefix...)
+	info = append(info, label...)
+
+	h := hkdf.New(
+		sha256.New, b.chainingKey[:], b.handshakeDigest[:], info,
+	)
+
+	material := make([]byte, length)
+	if _, err := io.ReadFull(h, material); err != nil {
+		return nil, err
+	}
+
+	return material, nil
+}
+
 // WriteMessage encrypts and buffers the next message p. The ciphertext of the
 // message is prepended with an encrypt+auth'd length which must be used as the
 // AD to the AEAD construction when being decrypted by the other side.
@@ -752,6 +1060,84 @@ func (b *Machine) WriteMessage(p []byte) error {
 	return nil
 }
 
+// appendFrame encrypts p into a frame, consisting of its encrypted length
+// prefix followed by its encrypted body, and appends it to dst. As with
+// WriteMessage, an error is returned if a message buffered using WriteMessage
+// hasn't been fully flushed, as the frames would otherwise be interleaved.
+func (b *Machine) appendFrame(dst, p []byte) ([]byte, error) {
+	if len(p) > math.MaxUint16 {
+		return dst, ErrMaxMessageLengthExceeded
+	}
+
+	if len(b.nextHeaderSend) > 0 || len(b.nextBodySend) > 0 {
+		return dst, ErrMessageNotFlushed
+	}
+
+	var pktLen [lengthHeaderSize]byte
+	binary.BigEndian.PutUint16(pktLen[:], uint16(len(p)))
+
+	dst = b.sendCipher.Encrypt(nil, dst, pktLen[:])
+
+	return b.sendCipher.Encrypt(nil, dst, p), nil
+}
+
+// WriteMessages encrypts each of the passed messages into its own frame, and
+// writes all resulting frames to the provided io.Writer using a single call to
+// Write. Each message still consumes its own nonces, such that the receiver
+// decodes them individually as if they had been written one at a time. The
+// number of bytes returned reflects the number of plaintext bytes in the
+// frames that were fully written, and does not account for the overhead of
+// the headers or MACs.
+//
+// NOTE: Unlike Flush, a partial write cannot be resumed as the frames are not
+// retained. If an error is returned, the connection should be torn down.
+func (b *Machine) WriteMessages(w io.Writer, msgs [][]byte) (int, error) {
+	// If a prior message was written but it hasn't been fully flushed,
+	// return an error as the frames would otherwise be interleaved.
+	if len(b.nextHeaderSend) > 0 || len(b.nextBodySend) > 0 {
+		return 0, ErrMessageNotFlushed
+	}
+
+	// Validate all messages up front, so that we don't advance the cipher
+	// for only a subset of the batch.
+	var totalSize int
+	for _, msg := range msgs {
+		if len(msg) > math.MaxUint16 {
+			return 0, ErrMaxMessageLengthExceeded
+		}
+
+		totalSize += encHeaderSize + len(msg) + macSize
+	}
+
+	// Encrypt each message into a single contiguous buffer, recording the
+	// offset at which each frame ends.
+	buf := make([]byte, 0, totalSize)
+	frameEnds := make([]int, 0, len(msgs))
+	for _, msg := range msgs {
+		var pktLen [lengthHeaderSize]byte
+		binary.BigEndian.PutUint16(pktLen[:], uint16(len(msg)))
+
+		buf = b.sendCipher.Encrypt(nil, buf, pktLen[:])
+		buf = b.sendCipher.Encrypt(nil, buf, msg)
+
+		frameEnds = append(frameEnds, len(buf))
+	}
+
+	n, err := writeFull(w, buf)
+
+	// Tally the plaintext bytes of all frames that were fully written.
+	var nn int
+	for i, end := range frameEnds {
+		if end > n {
+			break
+		}
+
+		nn += len(msgs[i])
+	}
+
+	return nn, err
+}
+
 // Flush attempts to write a message buffered using WriteMessage to the provided
 // io.Writer. If no buffered message exists, this will result in a NOP.
 // Otherwise, it will continue to write the remaining bytes, picking up where
@@ -768,7 +1154,7 @@ func (b *Machine) Flush(w io.Writer) (int, error) {
 		// to the next segment of unwritten bytes. If an error is
 		// encountered, we can continue to write the header from where
 		// we left off on a subsequent call to Flush.
-		n, err := w.Write(b.nextHeaderSend)
+		n, err := writeFull(w, b.nextHeaderSend)
 		b.nextHeaderSend = b.nextHeaderSend[n:]
 		if err != nil {
 			return 0, err
@@ -783,7 +1169,7 @@ func (b *Machine) Flush(w io.Writer) (int, error) {
 	if len(b.nextBodySend) > 0 {
 		// Write out all bytes excluding the mac and shift the body
 		// slice depending on the number of actual bytes written.
-		n, err := w.Write(b.nextBodySend)
+		n, err := writeFull(w, b.nextBodySend)
 		b.nextBodySend = b.nextBodySend[n:]
 
 		// If we partially or fully wrote any of the body's MAC, we'll
@@ -827,6 +1213,29 @@ func (b *Machine) Flush(w io.Writer) (int, error) {
 	return nn, nil
 }
 
+// writeFull writes all of p to w, retrying writes that return fewer bytes than
+// requested without an error. This ensures that the header of a frame is never
+// followed by its body while part of the header is still pending, which would
+// desynchronize the remote peer. If a write makes no progress without
+// returning an error, io.ErrShortWrite is returned so that the caller can
+// retry the remainder later.
+func writeFull(w io.Writer, p []byte) (int, error) {
+	var total int
+	for total < len(p) {
+		n, err := w.Write(p[total:])
+		total += n
+		if err != nil {
+			return total, err
+		}
+
+		if n == 0 {
+			return total, io.ErrShortWrite
+		}
+	}
+
+	return total, nil
+}
+
 // ReadMessage attempts to read the next message from the passed io.Reader. In
 // the case of an authentication error, a non-nil error is returned.
 func (b *Machine) ReadMessage(r io.Reader) ([]byte, error) {
@@ -841,8 +1250,8 @@ func (b *Machine) ReadMessage(r io.Reader) ([]byte, error) {
 
 // ReadHeader attempts to read the next message header from the passed
 // io.Reader. The header contains the length of the next body including
-// additional overhead of the MAC. In the case of an authentication error, a
-// non-nil error is returned.
+// additional overhead of the MAC. In the case of an authentication error,
+// ErrMACVerificationFailed is returned.
 //
 // NOTE: This method SHOULD NOT be used in the case that the io.Reader may be
 // adversarial and induce long delays. If the caller needs to set read deadlines
@@ -862,7 +1271,8 @@ func (b *Machine) ReadHeader(r io.Reader) (uint32, error) {
 		nil, b.nextCipherHeader[:0], b.nextCipherHeader[:],
 	)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("%w: header: %v",
+			ErrMACVerificationFailed, err)
 	}
 
 	// Compute the packet length that we will need to read off the wire.
@@ -874,7 +1284,7 @@ func (b *Machine) ReadHeader(r io.Reader) (uint32, error) {
 // ReadBody attempts to ready the next message body from the passed io.Reader.
 // The provided buffer MUST be the length indicated by the packet length
 // returned by the preceding call to ReadHeader. In the case of an
-// authentication error, a non-nil error is returned.
+// authentication error, ErrMACVerificationFailed is returned.
 func (b *Machine) ReadBody(r io.Reader, buf []byte) ([]byte, error) {
 	// Next, using the length read from the packet header, read the
 	// encrypted packet itself into the buffer allocated by the read
@@ -890,5 +1300,11 @@ func (b *Machine) ReadBody(r io.Reader, buf []byte) ([]byte, error) {
 	// By passing in the buf (the ciphertext) as the first argument, we end
 	// up re-using it as we don't force the library to allocate a new
 	// buffer to decode the plaintext.
-	return b.recvCipher.Decrypt(nil, buf[:0], buf)
+	plaintext, err := b.recvCipher.Decrypt(nil, buf[:0], buf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: body: %v",
+			ErrMACVerificationFailed, err)
+	}
+
+	return plaintext, nil
 }
@@ -2,15 +2,23 @@ package brontide
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"math"
 	"net"
+	"os"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"testing/iotest"
+	"time"
 
 	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/clock"
 	"github.com/lightningnetwork/lnd/keychain"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/tor"
@@ -304,6 +312,24 @@ func TestWriteMessageChunking(t *testing.T) {
 	}
 }
 
+// bolt8TransportVectors are the ciphertexts of the "hello" transport messages
+// sent by the initiator in the BOLT-8 test vectors, keyed by message number.
+// Messages 500/501 and 1000/1001 straddle the first two key rotations.
+var bolt8TransportVectors = map[int]string{
+	0: "cf2b30ddf0cf3f80e7c35a6e6730b59fe802473180f396d88a8fb0db8cb" +
+		"cf25d2f214cf9ea1d95",
+	1: "72887022101f0b6753e0c7de21657d35a4cb2a1f5cde2650528bbc8f837" +
+		"d0f0d7ad833b1a256a1",
+	500: "178cb9d7387190fa34db9c2d50027d21793c9bc2d40b1e14dcf30ebeeeb2" +
+		"20f48364f7a4c68bf8",
+	501: "1b186c57d44eb6de4c057c49940d79bb838a145cb528d6e8fd26dbe50a6" +
+		"0ca2c104b56b60e45bd",
+	1000: "4a2f3cc3b5e78ddb83dcb426d9863d9d9a723b0337c89dd0b005d89f8d3" +
+		"c05c52b76b29b740f09",
+	1001: "2ecd8c8a5629d0d02ab457a0fdd0f7b90a192cd46be5ecb6ca570bfc5e2" +
+		"68338b1a16cf4ef2d36",
+}
+
 // TestBolt0008TestVectors ensures that our implementation of brontide exactly
 // matches the test vectors within the specification.
 func TestBolt0008TestVectors(t *testing.T) {
@@ -470,20 +496,7 @@ func TestBolt0008TestVectors(t *testing.T) {
 	// to ensure that the key rotation algorithm is operating as expected.
 	// The starting point for enc/decr is already guaranteed correct from the
 	// above tests of sendingKey, receivingKey, chainingKey.
-	transportMessageVectors := map[int]string{
-		0: "cf2b30ddf0cf3f80e7c35a6e6730b59fe802473180f396d88a8fb0db8cb" +
-			"cf25d2f214cf9ea1d95",
-		1: "72887022101f0b6753e0c7de21657d35a4cb2a1f5cde2650528bbc8f837" +
-			"d0f0d7ad833b1a256a1",
-		500: "178cb9d7387190fa34db9c2d50027d21793c9bc2d40b1e14dcf30ebeeeb2" +
-			"20f48364f7a4c68bf8",
-		501: "1b186c57d44eb6de4c057c49940d79bb838a145cb528d6e8fd26dbe50a6" +
-			"0ca2c104b56b60e45bd",
-		1000: "4a2f3cc3b5e78ddb83dcb426d9863d9d9a723b0337c89dd0b005d89f8d3" +
-			"c05c52b76b29b740f09",
-		1001: "2ecd8c8a5629d0d02ab457a0fdd0f7b90a192cd46be5ecb6ca570bfc5e2" +
-			"68338b1a16cf4ef2d36",
-	}
+	transportMessageVectors := bolt8TransportVectors
 
 	// Payload for every message is the string "hello".
 	payload := []byte("hello")
@@ -700,3 +713,4017 @@ func assertFlush(t *testing.T, b *Machine, w io.Writer, n int64, expN int,
 		t.Fatalf("expected n: %d, got: %d", expN, nn)
 	}
 }
+
+// TestConnCloseIdempotent asserts that closing a brontide connection more than
+// once is a no-op, and that any reads or writes attempted after the
+// connection is closed fail with ErrConnClosed.
+func TestConnCloseIdempotent(t *testing.T) {
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err, "unable to establish test connection")
+
+	// The first call to Close should succeed, and every call thereafter
+	// should be a no-op.
+	require.NoError(t, localConn.Close())
+	require.NoError(t, localConn.Close())
+
+	// Any I/O on the closed connection should return the typed error,
+	// rather than the error from the underlying socket.
+	_, err = localConn.Write([]byte("hello"))
+	require.ErrorIs(t, err, ErrConnClosed)
+
+	_, err = localConn.Read(make([]byte, 5))
+	require.ErrorIs(t, err, ErrConnClosed)
+
+	_, err = localConn.(*Conn).ReadNextMessage()
+	require.ErrorIs(t, err, ErrConnClosed)
+
+	// Repeated attempts should continue to yield the same error.
+	_, err = localConn.Write([]byte("hello"))
+	require.ErrorIs(t, err, ErrConnClosed)
+
+	_, err = localConn.Read(make([]byte, 5))
+	require.ErrorIs(t, err, ErrConnClosed)
+
+	// The remote end should be unaffected by the double close.
+	require.NoError(t, remoteConn.Close())
+	require.NoError(t, remoteConn.Close())
+}
+
+// handshakeSnapshot captures the handshake digest (h) and chaining key (ck) of
+// a brontide Machine at a particular point in the handshake.
+type handshakeSnapshot struct {
+	h  string
+	ck string
+}
+
+// snapshotHandshake returns the hex-encoded handshake digest and chaining key
+// currently held by the passed Machine.
+func snapshotHandshake(m *Machine) handshakeSnapshot {
+	return handshakeSnapshot{
+		h:  hex.EncodeToString(m.handshakeDigest[:]),
+		ck: hex.EncodeToString(m.chainingKey[:]),
+	}
+}
+
+// newVectorMachine is a test-only constructor that creates a brontide Machine
+// whose ephemeral key is fixed to the passed private key. This allows the
+// handshake to be driven deterministically so that intermediate state can be
+// compared against the BOLT-8 test vectors.
+func newVectorMachine(t *testing.T, initiator bool, localKey string,
+	ephemeralKey string, remotePub *btcec.PublicKey) *Machine {
+
+	t.Helper()
+
+	localKeyBytes, err := hex.DecodeString(localKey)
+	require.NoError(t, err)
+	localPriv, _ := btcec.PrivKeyFromBytes(localKeyBytes)
+
+	ephemeralKeyBytes, err := hex.DecodeString(ephemeralKey)
+	require.NoError(t, err)
+	ephemeralPriv, _ := btcec.PrivKeyFromBytes(ephemeralKeyBytes)
+
+	return NewBrontideMachine(
+		initiator, &keychain.PrivKeyECDH{PrivKey: localPriv}, remotePub,
+		EphemeralGenerator(func() (*btcec.PrivateKey, error) {
+			return ephemeralPriv, nil
+		}),
+	)
+}
+
+// TestBolt0008IntermediateState asserts that the handshake digest (h) and
+// chaining key (ck) held by both the initiator and responder after each act
+// match the intermediate values published in the BOLT-8 test vectors, and that
+// the first transport message and those following each key rotation are
+// reproduced byte-for-byte.
+func TestBolt0008IntermediateState(t *testing.T) {
+	t.Parallel()
+
+	const (
+		initiatorKey = "1111111111111111111111111111111111111111111111" +
+			"111111111111111111"
+		initiatorEphemeral = "12121212121212121212121212121212121212" +
+			"12121212121212121212121212"
+		responderKey = "2121212121212121212121212121212121212121212121" +
+			"212121212121212121"
+		responderEphemeral = "22222222222222222222222222222222222222" +
+			"22222222222222222222222222"
+	)
+
+	responderKeyBytes, err := hex.DecodeString(responderKey)
+	require.NoError(t, err)
+	_, responderPub := btcec.PrivKeyFromBytes(responderKeyBytes)
+
+	initiator := newVectorMachine(
+		t, true, initiatorKey, initiatorEphemeral, responderPub,
+	)
+	responder := newVectorMachine(
+		t, false, responderKey, responderEphemeral, nil,
+	)
+
+	// Before any act has been processed, both sides should have mixed the
+	// protocol name, prologue and responder's static key into h, while ck
+	// is still the hash of the protocol name.
+	initState := handshakeSnapshot{
+		h: "8401b3fdcaaa710b5405400536a3d5fd7792fe8e7fe29cd8b687216fe" +
+			"323ecbd",
+		ck: "2640f52eebcd9e882958951c794250eedb28002c05d7dc2ea0f195406" +
+			"042caf1",
+	}
+	require.Equal(t, initState, snapshotHandshake(initiator))
+	require.Equal(t, initState, snapshotHandshake(responder))
+
+	// Act one: -> e, es
+	actOneState := handshakeSnapshot{
+		h: "9d1ffbb639e7e20021d9259491dc7b160aab270fb1339ef135053f6f2" +
+			"cebe9ce",
+		ck: "b61ec1191326fa240decc9564369dbb3ae2b34341d1e11ad64ed89f89" +
+			"180582f",
+	}
+	actOne, err := initiator.GenActOne()
+	require.NoError(t, err)
+	require.Equal(t, actOneState, snapshotHandshake(initiator))
+
+	require.NoError(t, responder.RecvActOne(actOne))
+	require.Equal(t, actOneState, snapshotHandshake(responder))
+
+	// Act two: <- e, ee
+	actTwoState := handshakeSnapshot{
+		h: "90578e247e98674e661013da3c5c1ca6a8c8f48c90b485c0dfa1494e2" +
+			"3d56d72",
+		ck: "e89d31033a1b6bf68c07d22e08ea4d7884646c4b60a9528598ccb4ee2" +
+			"c8f56ba",
+	}
+	actTwo, err := responder.GenActTwo()
+	require.NoError(t, err)
+	require.Equal(t, actTwoState, snapshotHandshake(responder))
+
+	require.NoError(t, initiator.RecvActTwo(actTwo))
+	require.Equal(t, actTwoState, snapshotHandshake(initiator))
+
+	// Act three: -> s, se
+	//
+	// The spec publishes h after the initiator's static key has been
+	// encrypted, but not after the final tag is mixed in. We extend the
+	// published h with the published tag to obtain the final digest.
+	actThreeH, err := hex.DecodeString("5dcb5ea9b4ccc755e0e3456af39906" +
+		"41276e1d5dc9afd82f974d90a47c918660")
+	require.NoError(t, err)
+	actThreeTag, err := hex.DecodeString("8dc68b1c466263b47fdf31e560e1" +
+		"39ba")
+	require.NoError(t, err)
+	finalH := sha256.Sum256(append(actThreeH, actThreeTag...))
+
+	actThreeState := handshakeSnapshot{
+		h: hex.EncodeToString(finalH[:]),
+		ck: "919219dbb2920afa8db80f9a51787a840bcf111ed8d588caf9ab4be71" +
+			"6e42b01",
+	}
+	actThree, err := initiator.GenActThree()
+	require.NoError(t, err)
+	require.Equal(t, actThreeState, snapshotHandshake(initiator))
+
+	require.NoError(t, responder.RecvActThree(actThree))
+	require.Equal(t, actThreeState, snapshotHandshake(responder))
+
+	// Finally, the first transport message, as well as those sent directly
+	// before and after each key rotation, should match the vectors.
+	var buf bytes.Buffer
+	for i := 0; i < 1002; i++ {
+		require.NoError(t, initiator.WriteMessage([]byte("hello")))
+		_, err := initiator.Flush(&buf)
+		require.NoError(t, err)
+
+		if expected, ok := bolt8TransportVectors[i]; ok {
+			require.Equal(t, expected, hex.EncodeToString(buf.Bytes()),
+				"transport message %d mismatch", i)
+		}
+
+		plaintext, err := responder.ReadMessage(&buf)
+		require.NoError(t, err)
+		require.Equal(t, []byte("hello"), plaintext)
+	}
+}
+
+// TestWriteMessages asserts that a batch of messages written with a single
+// call to WriteMessages is read back by the remote peer as individual
+// messages, in order.
+func TestWriteMessages(t *testing.T) {
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err, "unable to establish test connection")
+
+	msgs := [][]byte{
+		[]byte("hello"),
+		{},
+		bytes.Repeat([]byte("a"), 1000),
+		[]byte("world"),
+	}
+
+	var expN int
+	for _, msg := range msgs {
+		expN += len(msg)
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		n, err := localConn.(*Conn).WriteMessages(msgs)
+		if err == nil && n != expN {
+			err = fmt.Errorf("expected %d bytes written, got %d",
+				expN, n)
+		}
+		errChan <- err
+	}()
+
+	for _, msg := range msgs {
+		readMsg, err := remoteConn.(*Conn).ReadNextMessage()
+		require.NoError(t, err)
+		require.Equal(t, msg, readMsg)
+	}
+	require.NoError(t, <-errChan)
+
+	// A batch containing an oversized message should be rejected without
+	// writing any of the messages.
+	_, err = localConn.(*Conn).WriteMessages([][]byte{
+		[]byte("hello"), make([]byte, math.MaxUint16+1),
+	})
+	require.ErrorIs(t, err, ErrMaxMessageLengthExceeded)
+
+	// The connection should still be usable afterwards.
+	go func() {
+		_, err := localConn.Write([]byte("after"))
+		errChan <- err
+	}()
+
+	readMsg, err := remoteConn.(*Conn).ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, []byte("after"), readMsg)
+	require.NoError(t, <-errChan)
+}
+
+// TestNewPipe asserts that NewPipe returns two mutually authenticated ends of
+// an in-memory brontide connection, which can exchange messages in both
+// directions.
+func TestNewPipe(t *testing.T) {
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	local, remote, err := NewPipe(localPriv, remotePriv)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		local.Close()
+		remote.Close()
+	})
+
+	// Each end should have authenticated the other's static key.
+	require.True(t, local.RemotePub().IsEqual(remotePriv.PubKey()))
+	require.True(t, remote.RemotePub().IsEqual(localPriv.PubKey()))
+	require.True(t, local.LocalPub().IsEqual(localPriv.PubKey()))
+	require.True(t, remote.LocalPub().IsEqual(remotePriv.PubKey()))
+
+	// Since the pipe is synchronous, the writes are executed in their own
+	// goroutine.
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := local.Write([]byte("ping"))
+		errChan <- err
+	}()
+
+	msg, err := remote.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, []byte("ping"), msg)
+	require.NoError(t, <-errChan)
+
+	go func() {
+		_, err := remote.Write([]byte("pong"))
+		errChan <- err
+	}()
+
+	msg, err = local.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, []byte("pong"), msg)
+	require.NoError(t, <-errChan)
+}
+
+// TestNewDeterministicPipe asserts that two pipes created by
+// NewDeterministicPipe with the same static keys and randomness yield the same
+// handshake hash and ciphertext, and that using the ephemeral keys of the
+// BOLT-8 test vectors reproduces them.
+func TestNewDeterministicPipe(t *testing.T) {
+	t.Parallel()
+
+	localKey := bytes.Repeat([]byte{0x11}, 32)
+	remoteKey := bytes.Repeat([]byte{0x21}, 32)
+	localPriv, _ := btcec.PrivKeyFromBytes(localKey)
+	remotePriv, _ := btcec.PrivKeyFromBytes(remoteKey)
+
+	// The ephemeral keys of the initiator and responder in the vectors.
+	ephemeralKeys := append(
+		bytes.Repeat([]byte{0x12}, 32),
+		bytes.Repeat([]byte{0x22}, 32)...,
+	)
+
+	// run creates a deterministic pipe and returns its handshake hash
+	// along with the raw ciphertext of the first message written by the
+	// initiator.
+	run := func(rng []byte) ([32]byte, []byte) {
+		local, remote, hash, err := NewDeterministicPipe(
+			localPriv, remotePriv, bytes.NewReader(rng),
+		)
+		require.NoError(t, err)
+		defer local.Close()
+		defer remote.Close()
+
+		// Since the pipe is synchronous, the write is executed in its
+		// own goroutine while we read the raw frame from the
+		// underlying connection.
+		msg := []byte("hello")
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := local.Write(msg)
+			errChan <- err
+		}()
+
+		ciphertext := make([]byte, encHeaderSize+len(msg)+macSize)
+		_, err = io.ReadFull(remote.conn, ciphertext)
+		require.NoError(t, err)
+		require.NoError(t, <-errChan)
+
+		return hash, ciphertext
+	}
+
+	hash1, ciphertext1 := run(ephemeralKeys)
+	hash2, ciphertext2 := run(ephemeralKeys)
+	require.Equal(t, hash1, hash2)
+	require.Equal(t, ciphertext1, ciphertext2)
+
+	// The first message should match the vectors, and the handshake hash
+	// should match the final digest of TestBolt0008IntermediateState.
+	require.Equal(
+		t, bolt8TransportVectors[0], hex.EncodeToString(ciphertext1),
+	)
+	actThreeH, err := hex.DecodeString("5dcb5ea9b4ccc755e0e3456af39906" +
+		"41276e1d5dc9afd82f974d90a47c918660")
+	require.NoError(t, err)
+	actThreeTag, err := hex.DecodeString("8dc68b1c466263b47fdf31e560e1" +
+		"39ba")
+	require.NoError(t, err)
+	finalH := sha256.Sum256(append(actThreeH, actThreeTag...))
+	require.Equal(t, finalH, hash1)
+
+	// Different randomness should yield a different session.
+	otherKeys := append([]byte(nil), ephemeralKeys...)
+	otherKeys[0] ^= 0x01
+	hash3, ciphertext3 := run(otherKeys)
+	require.NotEqual(t, hash1, hash3)
+	require.NotEqual(t, ciphertext1, ciphertext3)
+
+	// Too little randomness should be rejected.
+	_, _, _, err = NewDeterministicPipe(
+		localPriv, remotePriv, bytes.NewReader(ephemeralKeys[:40]),
+	)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+// TestDialWithRetry asserts that DialWithRetry retries connection attempts
+// that are refused by the remote peer, but fails immediately if the
+// handshake itself fails.
+func TestDialWithRetry(t *testing.T) {
+	listener, netAddr, err := makeListener()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				select {
+				case <-listener.quit:
+					return
+				default:
+					continue
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	localKeyECDH := &keychain.PrivKeyECDH{PrivKey: localPriv}
+
+	// refusingDialer returns a dialer that refuses the first refusals
+	// connection attempts, and dials the real listener afterwards.
+	refusingDialer := func(refusals int, attempts *int) tor.DialFunc {
+		return func(network, address string,
+			timeout time.Duration) (net.Conn, error) {
+
+			*attempts++
+			if *attempts <= refusals {
+				return nil, &net.OpError{
+					Op:  "dial",
+					Net: network,
+					Err: syscall.ECONNREFUSED,
+				}
+			}
+
+			return net.DialTimeout(network, address, timeout)
+		}
+	}
+
+	policy := RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   time.Millisecond,
+		MaxJitter:   time.Millisecond,
+	}
+
+	// A connection that is refused fewer times than the maximum number of
+	// attempts should eventually succeed.
+	var attempts int
+	conn, err := DialWithRetry(
+		localKeyECDH, netAddr, tor.DefaultConnTimeout,
+		refusingDialer(3, &attempts), policy,
+	)
+	require.NoError(t, err)
+	require.Equal(t, 4, attempts)
+	conn.Close()
+
+	// Once the maximum number of attempts is exhausted, the last error
+	// should be returned.
+	attempts = 0
+	_, err = DialWithRetry(
+		localKeyECDH, netAddr, tor.DefaultConnTimeout,
+		refusingDialer(4, &attempts), policy,
+	)
+	require.ErrorIs(t, err, syscall.ECONNREFUSED)
+	require.Equal(t, 4, attempts)
+
+	// Dialing with the wrong identity key for the listener causes the
+	// handshake to fail, which must not be retried.
+	wrongPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	wrongAddr := &lnwire.NetAddress{
+		IdentityKey: wrongPriv.PubKey(),
+		Address:     netAddr.Address,
+	}
+
+	attempts = 0
+	_, err = DialWithRetry(
+		localKeyECDH, wrongAddr, tor.DefaultConnTimeout,
+		refusingDialer(0, &attempts), policy,
+	)
+	require.Error(t, err)
+	require.Equal(t, 1, attempts)
+}
+
+// TestSetKeepAlivePeriod asserts that TCP keep-alives can be configured on a
+// TCP backed connection, but not on one backed by an in-memory pipe.
+func TestSetKeepAlivePeriod(t *testing.T) {
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err, "unable to establish test connection")
+
+	for _, conn := range []net.Conn{localConn, remoteConn} {
+		err := conn.(*Conn).SetKeepAlivePeriod(30 * time.Second)
+		require.NoError(t, err)
+	}
+
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	pipeInitiator, pipeResponder, err := NewPipe(localPriv, remotePriv)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		pipeInitiator.Close()
+		pipeResponder.Close()
+	})
+
+	err = pipeInitiator.SetKeepAlivePeriod(30 * time.Second)
+	require.ErrorIs(t, err, ErrNotTCPConn)
+}
+
+// TestRemoteNetAddr asserts that RemoteNetAddr reports the authenticated
+// identity key of the remote peer along with the address of the underlying
+// connection.
+func TestRemoteNetAddr(t *testing.T) {
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err, "unable to establish test connection")
+
+	local := localConn.(*Conn)
+	remote := remoteConn.(*Conn)
+
+	localNetAddr := local.RemoteNetAddr()
+	require.True(t, localNetAddr.IdentityKey.IsEqual(remote.LocalPub()))
+	require.Equal(t, remote.LocalAddr(), localNetAddr.Address)
+
+	remoteNetAddr := remote.RemoteNetAddr()
+	require.True(t, remoteNetAddr.IdentityKey.IsEqual(local.LocalPub()))
+	require.Equal(t, local.LocalAddr(), remoteNetAddr.Address)
+}
+
+// TestConnReadHeaderBody asserts that messages can be read in two steps using
+// ReadHeader and ReadBody, and that misusing them fails without desyncing the
+// connection.
+func TestConnReadHeaderBody(t *testing.T) {
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err, "unable to establish test connection")
+
+	local := localConn.(*Conn)
+	remote := remoteConn.(*Conn)
+
+	msgs := [][]byte{
+		[]byte("hello"),
+		{},
+		bytes.Repeat([]byte{0x01}, math.MaxUint16),
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		for _, msg := range msgs {
+			if err := remote.WriteMessage(msg); err != nil {
+				errChan <- err
+				return
+			}
+			if _, err := remote.Flush(); err != nil {
+				errChan <- err
+				return
+			}
+		}
+		errChan <- nil
+	}()
+
+	// Reading a body before any header should fail.
+	_, err = local.ReadBody(make([]byte, 10))
+	require.ErrorIs(t, err, ErrNoPendingHeader)
+
+	for i, msg := range msgs {
+		length, err := local.ReadHeader()
+		require.NoError(t, err)
+		require.EqualValues(t, len(msg), length)
+
+		// Reading another header before the body should fail.
+		_, err = local.ReadHeader()
+		require.ErrorIs(t, err, ErrPendingBody)
+
+		// A buffer that is too small should be rejected, leaving the
+		// body to be read by a subsequent call.
+		if length > 0 {
+			_, err = local.ReadBody(make([]byte, length-1))
+			require.ErrorIs(t, err, ErrBodyBufferTooSmall)
+		}
+
+		// Use buffers with a varying amount of excess capacity, to
+		// ensure only the body is written to them.
+		buf := make([]byte, int(length)+i)
+		n, err := local.ReadBody(buf)
+		require.NoError(t, err)
+		require.Equal(t, msg, buf[:n])
+	}
+
+	require.NoError(t, <-errChan)
+}
+
+// TestServeEcho asserts that messages sent to the echo server are returned
+// unchanged, and that stopping the server closes its connections.
+func TestServeEcho(t *testing.T) {
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	listener, stop, err := ServeEcho(serverPriv, "localhost:0")
+	require.NoError(t, err)
+	t.Cleanup(stop)
+
+	clientPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	netAddr := &lnwire.NetAddress{
+		IdentityKey: serverPriv.PubKey(),
+		Address:     listener.Addr().(*net.TCPAddr),
+	}
+	conn, err := Dial(
+		&keychain.PrivKeyECDH{PrivKey: clientPriv}, netAddr,
+		tor.DefaultConnTimeout, net.DialTimeout,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	msgs := [][]byte{
+		[]byte("hello"),
+		{},
+		bytes.Repeat([]byte{0x02}, 1000),
+		bytes.Repeat([]byte{0x03}, math.MaxUint16),
+	}
+	for _, msg := range msgs {
+		require.NoError(t, conn.WriteMessage(msg))
+		_, err := conn.Flush()
+		require.NoError(t, err)
+
+		echoed, err := conn.ReadNextMessage()
+		require.NoError(t, err)
+		require.Equal(t, msg, echoed)
+	}
+
+	// Once the server is stopped, our connection should be closed by the
+	// remote end.
+	stop()
+
+	_, err = conn.ReadNextMessage()
+	require.Error(t, err)
+}
+
+// shortWriteConn is a net.Conn that accepts at most maxWrite bytes per call to
+// Write without returning an error, and stops accepting bytes entirely once
+// budget bytes have been written, if budget is non-negative.
+type shortWriteConn struct {
+	net.Conn
+
+	maxWrite int
+	budget   int
+}
+
+func (c *shortWriteConn) Write(p []byte) (int, error) {
+	if len(p) > c.maxWrite {
+		p = p[:c.maxWrite]
+	}
+	if c.budget >= 0 && len(p) > c.budget {
+		p = p[:c.budget]
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	n, err := c.Conn.Write(p)
+	if c.budget >= 0 {
+		c.budget -= n
+	}
+
+	return n, err
+}
+
+// TestConnShortWrite asserts that short writes by the underlying connection
+// never result in a partially delivered frame being reported as a success.
+// Either the full frame is delivered, or an error is returned and the frame
+// can be completed with a subsequent call to Flush.
+func TestConnShortWrite(t *testing.T) {
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	initiator, responder, err := NewPipe(localPriv, remotePriv)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		initiator.Close()
+		responder.Close()
+	})
+
+	msgChan := make(chan []byte, 2)
+	errChan := make(chan error, 1)
+	go func() {
+		for i := 0; i < 2; i++ {
+			msg, err := responder.ReadNextMessage()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			msgChan <- msg
+		}
+	}()
+
+	// First, write a message over a connection that only accepts a few
+	// bytes at a time. The full frame should be delivered.
+	shortConn := &shortWriteConn{
+		Conn:     initiator.conn,
+		maxWrite: 7,
+		budget:   -1,
+	}
+	initiator.conn = shortConn
+
+	msg := bytes.Repeat([]byte("short"), 100)
+	n, err := initiator.Write(msg)
+	require.NoError(t, err)
+	require.Equal(t, len(msg), n)
+
+	select {
+	case recvMsg := <-msgChan:
+		require.Equal(t, msg, recvMsg)
+	case err := <-errChan:
+		t.Fatalf("unable to read message: %v", err)
+	}
+
+	// Next, have the connection stall part way through the header of the
+	// next frame. This should result in an error rather than a success.
+	shortConn.budget = encHeaderSize / 2
+
+	_, err = initiator.Write(msg)
+	require.ErrorIs(t, err, io.ErrShortWrite)
+
+	// Once the connection accepts bytes again, flushing should complete
+	// the pending frame, which the peer should be able to decrypt.
+	shortConn.budget = -1
+
+	n, err = initiator.Flush()
+	require.NoError(t, err)
+	require.Equal(t, len(msg), n)
+
+	select {
+	case recvMsg := <-msgChan:
+		require.Equal(t, msg, recvMsg)
+	case err := <-errChan:
+		t.Fatalf("unable to read message: %v", err)
+	}
+}
+
+// shortReadConn is a net.Conn that returns at most maxRead bytes per call to
+// Read.
+type shortReadConn struct {
+	net.Conn
+
+	maxRead int
+}
+
+func (c *shortReadConn) Read(p []byte) (int, error) {
+	if len(p) > c.maxRead {
+		p = p[:c.maxRead]
+	}
+
+	return c.Conn.Read(p)
+}
+
+// TestHandshakeShortReadsAndWrites asserts that the handshake completes over
+// underlying connections that only accept or return a few bytes of each act at
+// a time, and that the resulting connections are in sync.
+func TestHandshakeShortReadsAndWrites(t *testing.T) {
+	initiatorPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	responderPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	newChoppyConn := func(conn net.Conn) net.Conn {
+		return &shortReadConn{
+			Conn: &shortWriteConn{
+				Conn:     conn,
+				maxWrite: 7,
+				budget:   -1,
+			},
+			maxRead: 5,
+		}
+	}
+
+	initiatorPipe, responderPipe := net.Pipe()
+
+	type result struct {
+		conn *Conn
+		err  error
+	}
+	resultChan := make(chan result, 1)
+	go func() {
+		conn, err := Server(newChoppyConn(responderPipe), responderPriv)
+		resultChan <- result{conn, err}
+	}()
+
+	initiator, err := Client(
+		newChoppyConn(initiatorPipe), initiatorPriv,
+		responderPriv.PubKey(),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		initiator.Close()
+	})
+
+	res := <-resultChan
+	require.NoError(t, res.err)
+	responder := res.conn
+	t.Cleanup(func() {
+		responder.Close()
+	})
+
+	require.True(t, responder.RemotePub().IsEqual(initiatorPriv.PubKey()))
+
+	// Both ends should have derived the same keys, such that messages
+	// written by one are decrypted by the other.
+	msg := []byte("hello over a choppy connection")
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := initiator.Write(msg)
+		errChan <- err
+	}()
+
+	recv, err := responder.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, msg, recv)
+	require.NoError(t, <-errChan)
+}
+
+// noDelayConn wraps a net.Conn, recording calls to SetNoDelay.
+type noDelayConn struct {
+	net.Conn
+
+	noDelay *bool
+}
+
+func (c *noDelayConn) SetNoDelay(noDelay bool) error {
+	*c.noDelay = noDelay
+	return c.Conn.(*net.TCPConn).SetNoDelay(noDelay)
+}
+
+// TestNoDelayOption asserts that the NoDelay option is applied to the
+// underlying connection before the handshake, and that the handshake still
+// succeeds on both ends.
+func TestNoDelayOption(t *testing.T) {
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	listener, err := NewListener(
+		&keychain.PrivKeyECDH{PrivKey: serverPriv}, "localhost:0",
+		NoDelay(true),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	acceptChan := make(chan maybeNetConn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		acceptChan <- maybeNetConn{conn, err}
+	}()
+
+	var noDelay bool
+	dialer := func(network, address string,
+		timeout time.Duration) (net.Conn, error) {
+
+		conn, err := net.DialTimeout(network, address, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		return &noDelayConn{Conn: conn, noDelay: &noDelay}, nil
+	}
+
+	clientPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	netAddr := &lnwire.NetAddress{
+		IdentityKey: serverPriv.PubKey(),
+		Address:     listener.Addr().(*net.TCPAddr),
+	}
+	conn, err := Dial(
+		&keychain.PrivKeyECDH{PrivKey: clientPriv}, netAddr,
+		tor.DefaultConnTimeout, dialer, NoDelay(true),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	require.True(t, noDelay)
+
+	accepted := <-acceptChan
+	require.NoError(t, accepted.err)
+	t.Cleanup(func() {
+		accepted.conn.Close()
+	})
+
+	// Messages should flow over the established connection.
+	msg := []byte("no delay")
+	require.NoError(t, conn.WriteMessage(msg))
+	_, err = conn.Flush()
+	require.NoError(t, err)
+
+	recvMsg, err := accepted.conn.(*Conn).ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, msg, recvMsg)
+}
+
+// TestClientServerUpgrade asserts that Client and Server upgrade both ends of
+// an existing connection to a brontide connection, and that a server upgrade
+// fails if the client doesn't know its static key.
+func TestClientServerUpgrade(t *testing.T) {
+	clientPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	clientPipe, serverPipe := net.Pipe()
+
+	// Since the pipe is synchronous, the client's side of the handshake is
+	// executed in its own goroutine.
+	type clientResult struct {
+		conn *Conn
+		err  error
+	}
+	clientChan := make(chan clientResult, 1)
+	go func() {
+		conn, err := Client(clientPipe, clientPriv, serverPriv.PubKey())
+		clientChan <- clientResult{conn, err}
+	}()
+
+	server, err := Server(serverPipe, serverPriv)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		server.Close()
+	})
+
+	result := <-clientChan
+	require.NoError(t, result.err)
+	client := result.conn
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	require.True(t, client.RemotePub().IsEqual(serverPriv.PubKey()))
+	require.True(t, server.RemotePub().IsEqual(clientPriv.PubKey()))
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := client.Write([]byte("ping"))
+		errChan <- err
+	}()
+
+	msg, err := server.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, []byte("ping"), msg)
+	require.NoError(t, <-errChan)
+
+	go func() {
+		_, err := server.Write([]byte("pong"))
+		errChan <- err
+	}()
+
+	msg, err = client.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, []byte("pong"), msg)
+	require.NoError(t, <-errChan)
+
+	// A client that expects a different static key should cause the
+	// server's upgrade to fail.
+	wrongPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	clientPipe, serverPipe = net.Pipe()
+	go func() {
+		conn, err := Client(clientPipe, clientPriv, wrongPriv.PubKey())
+		clientChan <- clientResult{conn, err}
+	}()
+
+	_, err = Server(serverPipe, serverPriv)
+	require.Error(t, err)
+	require.Error(t, (<-clientChan).err)
+}
+
+// TestClientServerOptions asserts that the connection options passed to Client
+// and Server configure the upgraded connections just like those created by
+// Dial and NewListener.
+func TestClientServerOptions(t *testing.T) {
+	const budget = 4
+
+	clientPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	var ephemeralGens int32
+	opts := []ConnOption{
+		MaxLifetimeBytes(budget),
+		EnablePing(),
+		WriteCoalesce(time.Hour, 100),
+		MaxBufferedBytes(1000),
+		EphemeralGen(func() (*btcec.PrivateKey, error) {
+			atomic.AddInt32(&ephemeralGens, 1)
+			return btcec.NewPrivateKey()
+		}),
+	}
+
+	clientPipe, serverPipe := net.Pipe()
+
+	type clientResult struct {
+		conn *Conn
+		err  error
+	}
+	clientChan := make(chan clientResult, 1)
+	go func() {
+		conn, err := Client(
+			clientPipe, clientPriv, serverPriv.PubKey(), opts...,
+		)
+		clientChan <- clientResult{conn, err}
+	}()
+
+	server, err := Server(serverPipe, serverPriv, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		server.Close()
+	})
+
+	result := <-clientChan
+	require.NoError(t, result.err)
+	client := result.conn
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	// Both sides should have drawn their ephemeral key from the
+	// generator.
+	require.EqualValues(t, 2, atomic.LoadInt32(&ephemeralGens))
+
+	for _, conn := range []*Conn{client, server} {
+		require.EqualValues(t, budget, conn.maxLifetimeBytes)
+		require.True(t, conn.pingEnabled)
+		require.Equal(t, time.Hour, conn.coalesceDelay)
+		require.Equal(t, 100, conn.coalesceBytes)
+		require.Equal(t, 1000, conn.maxBufferedBytes)
+	}
+}
+
+// TestConnMessages asserts that Messages delivers each message read from the
+// connection in order, and that the read loop exits once its context is
+// cancelled.
+func TestConnMessages(t *testing.T) {
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	local, remote, err := NewPipe(localPriv, remotePriv)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		local.Close()
+		remote.Close()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan, errChan := remote.Messages(ctx)
+
+	// Since the pipe is synchronous, the writes are executed in their own
+	// goroutine.
+	msgs := [][]byte{[]byte("one"), []byte("two"), []byte("three")}
+	writeErrChan := make(chan error, 1)
+	go func() {
+		for _, msg := range msgs {
+			if _, err := local.Write(msg); err != nil {
+				writeErrChan <- err
+				return
+			}
+		}
+		writeErrChan <- nil
+	}()
+
+	for _, expMsg := range msgs {
+		select {
+		case msg := <-msgChan:
+			require.Equal(t, expMsg, msg)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("message not received")
+		}
+	}
+	require.NoError(t, <-writeErrChan)
+
+	// Cancelling the context should unblock the pending read, causing the
+	// loop to exit with the context's error and close the message channel.
+	cancel()
+
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("read loop did not exit")
+	}
+
+	_, ok := <-msgChan
+	require.False(t, ok)
+}
+
+// TestListenerHandshakeErrors asserts that connections failing the handshake
+// are never returned from Accept, and are instead reported to the
+// OnHandshakeError callback.
+func TestListenerHandshakeErrors(t *testing.T) {
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	type handshakeErr struct {
+		addr net.Addr
+		err  error
+	}
+	errChan := make(chan handshakeErr, 10)
+	onHandshakeError := func(addr net.Addr, err error) {
+		errChan <- handshakeErr{addr, err}
+	}
+
+	listener, err := NewListener(
+		&keychain.PrivKeyECDH{PrivKey: serverPriv}, "localhost:0",
+		OnHandshakeError(onHandshakeError),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	acceptChan := make(chan maybeNetConn, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			select {
+			case <-listener.quit:
+				return
+			default:
+			}
+			acceptChan <- maybeNetConn{conn, err}
+		}
+	}()
+
+	listenAddr := listener.Addr().(*net.TCPAddr)
+	dial := func(remotePub *btcec.PublicKey) (*Conn, error) {
+		clientPriv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return Dial(
+			&keychain.PrivKeyECDH{PrivKey: clientPriv},
+			&lnwire.NetAddress{
+				IdentityKey: remotePub,
+				Address:     listenAddr,
+			},
+			tor.DefaultConnTimeout, net.DialTimeout,
+		)
+	}
+
+	// Dialers that don't know the server's static key will fail the
+	// handshake, interleaved with one that succeeds.
+	wrongPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	_, err = dial(wrongPriv.PubKey())
+	require.Error(t, err)
+
+	goodConn, err := dial(serverPriv.PubKey())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		goodConn.Close()
+	})
+
+	_, err = dial(wrongPriv.PubKey())
+	require.Error(t, err)
+
+	// Both failed handshakes should be reported to the callback.
+	for i := 0; i < 2; i++ {
+		select {
+		case hsErr := <-errChan:
+			require.Error(t, hsErr.err)
+			require.NotNil(t, hsErr.addr)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("handshake error %d not reported", i)
+		}
+	}
+
+	// Only the good connection should be returned from Accept.
+	select {
+	case accepted := <-acceptChan:
+		require.NoError(t, accepted.err)
+		require.True(t, accepted.conn.(*Conn).RemotePub().IsEqual(
+			goodConn.LocalPub(),
+		))
+		accepted.conn.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatalf("good connection not accepted")
+	}
+
+	select {
+	case accepted := <-acceptChan:
+		t.Fatalf("unexpected accept result: %v", accepted.err)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestListenerReturnHandshakeErrors asserts that a Listener created with the
+// ReturnHandshakeErrors option returns a *HandshakeError from Accept for each
+// connection failing the handshake, and keeps serving subsequent connections.
+func TestListenerReturnHandshakeErrors(t *testing.T) {
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	listener, err := NewListener(
+		&keychain.PrivKeyECDH{PrivKey: serverPriv}, "localhost:0",
+		ReturnHandshakeErrors(),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	listenAddr := listener.Addr().(*net.TCPAddr)
+	dial := func(remotePub *btcec.PublicKey) (*Conn, error) {
+		clientPriv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return Dial(
+			&keychain.PrivKeyECDH{PrivKey: clientPriv},
+			&lnwire.NetAddress{
+				IdentityKey: remotePub,
+				Address:     listenAddr,
+			},
+			tor.DefaultConnTimeout, net.DialTimeout,
+		)
+	}
+
+	accept := func() maybeNetConn {
+		acceptChan := make(chan maybeNetConn, 1)
+		go func() {
+			conn, err := listener.Accept()
+			acceptChan <- maybeNetConn{conn, err}
+		}()
+
+		select {
+		case accepted := <-acceptChan:
+			return accepted
+		case <-time.After(5 * time.Second):
+			t.Fatalf("accept timed out")
+			return maybeNetConn{}
+		}
+	}
+
+	// A dialer that doesn't know the server's static key fails the
+	// handshake, which should be returned from Accept.
+	wrongPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	_, err = dial(wrongPriv.PubKey())
+	require.Error(t, err)
+
+	accepted := accept()
+	require.Nil(t, accepted.conn)
+
+	var hsErr *HandshakeError
+	require.ErrorAs(t, accepted.err, &hsErr)
+	require.NotNil(t, hsErr.RemoteAddr)
+	require.Error(t, hsErr.Err)
+
+	// The listener should still serve a dialer completing the handshake.
+	goodConn, err := dial(serverPriv.PubKey())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		goodConn.Close()
+	})
+
+	accepted = accept()
+	require.NoError(t, accepted.err)
+	require.True(t, accepted.conn.(*Conn).RemotePub().IsEqual(
+		goodConn.LocalPub(),
+	))
+	accepted.conn.Close()
+
+	// Subsequent failures should be returned as well.
+	_, err = dial(wrongPriv.PubKey())
+	require.Error(t, err)
+
+	accepted = accept()
+	require.ErrorAs(t, accepted.err, &hsErr)
+}
+
+// TestListenerSetIdentity asserts that rotating the static key of a live
+// listener using SetIdentity applies to subsequent handshakes only, such that
+// dialers must use the new public key, while connections established under the
+// previous key remain usable.
+func TestListenerSetIdentity(t *testing.T) {
+	oldPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	listener, err := NewListener(
+		&keychain.PrivKeyECDH{PrivKey: oldPriv}, "localhost:0",
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	acceptChan := make(chan maybeNetConn, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			select {
+			case <-listener.quit:
+				return
+			default:
+			}
+			acceptChan <- maybeNetConn{conn, err}
+		}
+	}()
+
+	listenAddr := listener.Addr().(*net.TCPAddr)
+	dial := func(remotePub *btcec.PublicKey) (*Conn, error) {
+		clientPriv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return Dial(
+			&keychain.PrivKeyECDH{PrivKey: clientPriv},
+			&lnwire.NetAddress{
+				IdentityKey: remotePub,
+				Address:     listenAddr,
+			},
+			tor.DefaultConnTimeout, net.DialTimeout,
+		)
+	}
+
+	accept := func() *Conn {
+		select {
+		case accepted := <-acceptChan:
+			require.NoError(t, accepted.err)
+			return accepted.conn.(*Conn)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("connection not accepted")
+			return nil
+		}
+	}
+
+	// Establish a connection under the original key.
+	oldConn, err := dial(oldPriv.PubKey())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		oldConn.Close()
+	})
+
+	oldAccepted := accept()
+	t.Cleanup(func() {
+		oldAccepted.Close()
+	})
+	require.True(t, oldAccepted.LocalPub().IsEqual(oldPriv.PubKey()))
+
+	// Rotate the listener's key.
+	newPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	listener.SetIdentity(newPriv)
+
+	// A dialer still using the old key should now fail the handshake.
+	_, err = dial(oldPriv.PubKey())
+	require.Error(t, err)
+
+	// While a dialer using the new key should succeed.
+	newConn, err := dial(newPriv.PubKey())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		newConn.Close()
+	})
+
+	newAccepted := accept()
+	t.Cleanup(func() {
+		newAccepted.Close()
+	})
+	require.True(t, newAccepted.LocalPub().IsEqual(newPriv.PubKey()))
+	require.True(t, newAccepted.RemotePub().IsEqual(newConn.LocalPub()))
+
+	// The connection established under the old key should be unaffected.
+	msg := []byte("still here")
+	_, err = oldConn.Write(msg)
+	require.NoError(t, err)
+
+	buf := make([]byte, len(msg))
+	_, err = io.ReadFull(oldAccepted, buf)
+	require.NoError(t, err)
+	require.Equal(t, msg, buf)
+}
+
+// TestHandshakeTimeoutClock asserts that the handshake read timeout is driven
+// by the clock passed using WithClock, such that advancing a mock clock fails
+// a stalled handshake right away rather than after the wall clock timeout.
+func TestHandshakeTimeoutClock(t *testing.T) {
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	startTime := time.Unix(1_000_000, 0)
+	tickSignal := make(chan time.Duration)
+	testClock := clock.NewTestClockWithTickSignal(startTime, tickSignal)
+
+	errChan := make(chan error, 1)
+	listener, err := NewListener(
+		&keychain.PrivKeyECDH{PrivKey: serverPriv}, "localhost:0",
+		WithClock(testClock),
+		OnHandshakeError(func(_ net.Addr, err error) {
+			errChan <- err
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	// Connect to the listener without ever sending the first act, and wait
+	// for the listener to start the timer for it.
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	select {
+	case d := <-tickSignal:
+		require.Equal(t, handshakeReadTimeout, d)
+	case <-time.After(time.Second):
+		t.Fatalf("handshake timer not started")
+	}
+
+	// The handshake shouldn't time out before the mock clock has reached
+	// the timeout.
+	testClock.SetTime(startTime.Add(handshakeReadTimeout - 1))
+	select {
+	case err := <-errChan:
+		t.Fatalf("handshake failed early: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Once it has, the handshake should fail well before the wall clock
+	// timeout could have expired.
+	testClock.SetTime(startTime.Add(handshakeReadTimeout))
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	case <-time.After(handshakeReadTimeout / 2):
+		t.Fatalf("handshake didn't time out")
+	}
+}
+
+// TestMaxLifetimeBytes asserts that a connection with a byte budget can
+// transfer up to the budget, after which reads and writes fail and the
+// connection is closed.
+func TestMaxLifetimeBytes(t *testing.T) {
+	const budget = 10
+
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	listener, err := NewListener(
+		&keychain.PrivKeyECDH{PrivKey: serverPriv}, "localhost:0",
+		MaxLifetimeBytes(budget),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	acceptChan := make(chan maybeNetConn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		acceptChan <- maybeNetConn{conn, err}
+	}()
+
+	clientPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	client, err := Dial(
+		&keychain.PrivKeyECDH{PrivKey: clientPriv}, &lnwire.NetAddress{
+			IdentityKey: serverPriv.PubKey(),
+			Address:     listener.Addr().(*net.TCPAddr),
+		}, tor.DefaultConnTimeout, net.DialTimeout,
+		MaxLifetimeBytes(budget),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	accepted := <-acceptChan
+	require.NoError(t, accepted.err)
+	server := accepted.conn.(*Conn)
+	t.Cleanup(func() {
+		server.Close()
+	})
+
+	// Transfer exactly the budget, split across two messages.
+	for _, msg := range [][]byte{[]byte("1234"), []byte("567890")} {
+		n, err := client.Write(msg)
+		require.NoError(t, err)
+		require.Equal(t, len(msg), n)
+
+		buf := make([]byte, len(msg))
+		_, err = io.ReadFull(server, buf)
+		require.NoError(t, err)
+		require.Equal(t, msg, buf)
+	}
+
+	require.EqualValues(t, budget, client.BytesTransferred())
+	require.EqualValues(t, budget, server.BytesTransferred())
+
+	// With the budget used up, the next write and read should fail, and
+	// both connections should be closed.
+	_, err = client.Write([]byte("x"))
+	require.ErrorIs(t, err, ErrConnByteBudgetExceeded)
+	require.True(t, client.isClosed())
+
+	_, err = server.Read(make([]byte, 1))
+	require.ErrorIs(t, err, ErrConnByteBudgetExceeded)
+	require.True(t, server.isClosed())
+
+	_, err = client.Write([]byte("x"))
+	require.ErrorIs(t, err, ErrConnClosed)
+}
+
+// TestWriteMessageBytesTransferred asserts that a message buffered using
+// WriteMessage only counts towards the bytes transferred once Flush writes it
+// out, and that WriteMessage and ReadNextHeader fail on a closed connection.
+func TestWriteMessageBytesTransferred(t *testing.T) {
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	local, remote, err := NewPipe(localPriv, remotePriv)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		remote.Close()
+	})
+
+	msg := []byte("hello")
+	require.NoError(t, local.WriteMessage(msg))
+	require.Zero(t, local.BytesTransferred())
+
+	type readResult struct {
+		msg []byte
+		err error
+	}
+	readChan := make(chan readResult, 1)
+	go func() {
+		msg, err := remote.ReadNextMessage()
+		readChan <- readResult{msg, err}
+	}()
+
+	n, err := local.Flush()
+	require.NoError(t, err)
+	require.Equal(t, len(msg), n)
+	require.EqualValues(t, len(msg), local.BytesTransferred())
+
+	read := <-readChan
+	require.NoError(t, read.err)
+	require.Equal(t, msg, read.msg)
+
+	// Flushing again is a no-op that shouldn't count the message twice.
+	_, err = local.Flush()
+	require.NoError(t, err)
+	require.EqualValues(t, len(msg), local.BytesTransferred())
+
+	require.NoError(t, local.Close())
+	require.ErrorIs(t, local.WriteMessage(msg), ErrConnClosed)
+
+	_, err = local.ReadNextHeader()
+	require.ErrorIs(t, err, ErrConnClosed)
+}
+
+// TestExportKeyingMaterial asserts that both ends of a session derive the same
+// keying material for a label, while other labels and sessions don't.
+func TestExportKeyingMaterial(t *testing.T) {
+	label := []byte("channel binding")
+
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err)
+
+	local := localConn.(*Conn)
+	remote := remoteConn.(*Conn)
+
+	localMaterial, err := local.ExportKeyingMaterial(label, 32)
+	require.NoError(t, err)
+	require.Len(t, localMaterial, 32)
+
+	remoteMaterial, err := remote.ExportKeyingMaterial(label, 32)
+	require.NoError(t, err)
+	require.Equal(t, localMaterial, remoteMaterial)
+
+	// A different label should yield different material.
+	otherLabel, err := local.ExportKeyingMaterial([]byte("other"), 32)
+	require.NoError(t, err)
+	require.NotEqual(t, localMaterial, otherLabel)
+
+	// A different session should yield different material for the same
+	// label.
+	otherConn, _, err := establishTestConnection(t)
+	require.NoError(t, err)
+
+	otherMaterial, err := otherConn.(*Conn).ExportKeyingMaterial(label, 32)
+	require.NoError(t, err)
+	require.NotEqual(t, localMaterial, otherMaterial)
+
+	// Lengths that can't be derived via HKDF should be rejected.
+	_, err = local.ExportKeyingMaterial(label, 0)
+	require.ErrorIs(t, err, ErrInvalidExportLength)
+
+	_, err = local.ExportKeyingMaterial(label, 255*32+1)
+	require.ErrorIs(t, err, ErrInvalidExportLength)
+}
+
+// TestReadNextMessageInto asserts that messages are decrypted into the
+// caller's buffer, and that a buffer that is too small is rejected with the
+// required size without losing the message.
+func TestReadNextMessageInto(t *testing.T) {
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err, "unable to establish test connection")
+
+	local := localConn.(*Conn)
+	remote := remoteConn.(*Conn)
+
+	msgs := [][]byte{
+		[]byte("hello"),
+		{},
+		bytes.Repeat([]byte{0x01}, math.MaxUint16),
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		for _, msg := range msgs {
+			if err := remote.WriteMessage(msg); err != nil {
+				errChan <- err
+				return
+			}
+			if _, err := remote.Flush(); err != nil {
+				errChan <- err
+				return
+			}
+		}
+		errChan <- nil
+	}()
+
+	// The happy path decrypts the message into the provided buffer.
+	buf := make([]byte, 64)
+	n, err := local.ReadNextMessageInto(buf)
+	require.NoError(t, err)
+	require.Equal(t, msgs[0], buf[:n])
+
+	// An empty message only requires room for the MAC.
+	n, err = local.ReadNextMessageInto(buf)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// A buffer that is too small should be rejected along with the size
+	// required to read the message, which must remain pending.
+	expSize := len(msgs[2]) + macSize
+	n, err = local.ReadNextMessageInto(buf)
+	require.ErrorIs(t, err, io.ErrShortBuffer)
+	require.Equal(t, expSize, n)
+
+	n, err = local.ReadNextMessageInto(make([]byte, expSize-1))
+	require.ErrorIs(t, err, io.ErrShortBuffer)
+	require.Equal(t, expSize, n)
+
+	// A subsequent call with a large enough buffer reads the same message.
+	buf = make([]byte, expSize)
+	n, err = local.ReadNextMessageInto(buf)
+	require.NoError(t, err)
+	require.Equal(t, msgs[2], buf[:n])
+
+	require.NoError(t, <-errChan)
+}
+
+// TestListenerAllowedRemote asserts that a Listener with the AllowedRemote
+// option only accepts peers whose static key is allowed, and disconnects the
+// others with ErrPeerNotAllowed.
+func TestListenerAllowedRemote(t *testing.T) {
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	knownPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	unknownPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	errChan := make(chan error, 1)
+	listener, err := NewListener(
+		&keychain.PrivKeyECDH{PrivKey: serverPriv}, "localhost:0",
+		AllowedRemote(func(pub *btcec.PublicKey) bool {
+			return pub.IsEqual(knownPriv.PubKey())
+		}),
+		OnHandshakeError(func(_ net.Addr, err error) {
+			errChan <- err
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	acceptChan := make(chan maybeNetConn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		acceptChan <- maybeNetConn{conn, err}
+	}()
+
+	dial := func(priv *btcec.PrivateKey) *Conn {
+		conn, err := Dial(
+			&keychain.PrivKeyECDH{PrivKey: priv},
+			&lnwire.NetAddress{
+				IdentityKey: serverPriv.PubKey(),
+				Address:     listener.Addr().(*net.TCPAddr),
+			},
+			tor.DefaultConnTimeout, net.DialTimeout,
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			conn.Close()
+		})
+
+		return conn
+	}
+
+	// The unknown dialer should be rejected once its static key is known,
+	// and disconnected by the listener.
+	unknownConn := dial(unknownPriv)
+
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, ErrPeerNotAllowed)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("rejected peer not reported")
+	}
+
+	_, err = unknownConn.ReadNextMessage()
+	require.Error(t, err)
+
+	// The known dialer should be admitted.
+	knownConn := dial(knownPriv)
+
+	select {
+	case accepted := <-acceptChan:
+		require.NoError(t, accepted.err)
+		require.True(t, accepted.conn.(*Conn).RemotePub().IsEqual(
+			knownConn.LocalPub(),
+		))
+		accepted.conn.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatalf("known peer not accepted")
+	}
+}
+
+// TestSetReadLimit asserts that messages exceeding the read limit are rejected
+// without consuming their body, while messages within the limit are read.
+func TestSetReadLimit(t *testing.T) {
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err, "unable to establish test connection")
+
+	local := localConn.(*Conn)
+	remote := remoteConn.(*Conn)
+
+	const limit = 16
+	local.SetReadLimit(limit)
+
+	msgs := [][]byte{
+		bytes.Repeat([]byte{0x01}, limit),
+		bytes.Repeat([]byte{0x02}, limit+1),
+		[]byte("after"),
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		for _, msg := range msgs {
+			if err := remote.WriteMessage(msg); err != nil {
+				errChan <- err
+				return
+			}
+			if _, err := remote.Flush(); err != nil {
+				errChan <- err
+				return
+			}
+		}
+		errChan <- nil
+	}()
+
+	// A message at the limit should be read.
+	msg, err := local.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, msgs[0], msg)
+
+	// A message over the limit should be rejected by both ReadNextMessage
+	// and Read, leaving its body unread.
+	_, err = local.ReadNextMessage()
+	require.ErrorIs(t, err, ErrMessageTooLarge)
+
+	_, err = local.Read(make([]byte, 64))
+	require.ErrorIs(t, err, ErrMessageTooLarge)
+
+	// Restoring the protocol maximum allows the pending message to be
+	// read, followed by the rest of the stream.
+	local.SetReadLimit(0)
+
+	msg, err = local.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, msgs[1], msg)
+
+	msg, err = local.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, msgs[2], msg)
+
+	require.NoError(t, <-errChan)
+}
+
+// delayedWriteConn is a net.Conn that delays each write by a fixed duration.
+type delayedWriteConn struct {
+	net.Conn
+
+	delay time.Duration
+}
+
+// Write sleeps for the configured delay before writing p.
+func (c *delayedWriteConn) Write(p []byte) (int, error) {
+	time.Sleep(c.delay)
+
+	return c.Conn.Write(p)
+}
+
+// TestHandshakeTimings asserts that the recorded handshake timings reflect a
+// delay injected into the responder's act two.
+func TestHandshakeTimings(t *testing.T) {
+	const delay = 100 * time.Millisecond
+
+	clientPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	clientPipe, serverPipe := net.Pipe()
+
+	type clientResult struct {
+		conn *Conn
+		err  error
+	}
+	clientChan := make(chan clientResult, 1)
+	go func() {
+		conn, err := Client(clientPipe, clientPriv, serverPriv.PubKey())
+		clientChan <- clientResult{conn, err}
+	}()
+
+	// The responder only writes act two, so delaying its writes delays
+	// the second act on both ends.
+	server, err := Server(
+		&delayedWriteConn{Conn: serverPipe, delay: delay}, serverPriv,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		server.Close()
+	})
+
+	result := <-clientChan
+	require.NoError(t, result.err)
+	client := result.conn
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	for _, conn := range []*Conn{client, server} {
+		act1, act2, act3 := conn.HandshakeTimings()
+		require.Positive(t, act1)
+		require.GreaterOrEqual(t, act2, delay)
+		require.Positive(t, act3)
+	}
+
+	// The timings are frozen once the handshake completes.
+	act1, act2, act3 := client.HandshakeTimings()
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := client.Write([]byte("ping"))
+		errChan <- err
+	}()
+	_, err = server.ReadNextMessage()
+	require.NoError(t, err)
+	require.NoError(t, <-errChan)
+
+	act1b, act2b, act3b := client.HandshakeTimings()
+	require.Equal(t, act1, act1b)
+	require.Equal(t, act2, act2b)
+	require.Equal(t, act3, act3b)
+}
+
+// TestReadNextMessageTimeout asserts that a read which times out, including
+// part way through a frame, doesn't desynchronize the stream, and that the
+// prior read deadline is restored afterward.
+func TestReadNextMessageTimeout(t *testing.T) {
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err, "unable to establish test connection")
+
+	local := localConn.(*Conn)
+	remote := remoteConn.(*Conn)
+
+	const timeout = 50 * time.Millisecond
+
+	// With nothing sent, the read should time out.
+	_, err = local.ReadNextMessageTimeout(timeout)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	// Encrypt a message, but only deliver part of its body, such that the
+	// next read times out in the middle of the frame.
+	msg := []byte("hello after timeout")
+	require.NoError(t, remote.noise.WriteMessage(msg))
+
+	var frame bytes.Buffer
+	_, err = remote.noise.Flush(&frame)
+	require.NoError(t, err)
+
+	split := encHeaderSize + 4
+	_, err = remote.conn.Write(frame.Bytes()[:split])
+	require.NoError(t, err)
+
+	_, err = local.ReadNextMessageTimeout(timeout)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	// Delivering the rest of the frame allows a later read to complete
+	// the message.
+	_, err = remote.conn.Write(frame.Bytes()[split:])
+	require.NoError(t, err)
+
+	got, err := local.ReadNextMessageTimeout(time.Second)
+	require.NoError(t, err)
+	require.Equal(t, msg, got)
+
+	// A deadline set prior to the call should be restored afterward, so
+	// a subsequent plain read times out once it expires.
+	require.NoError(t, local.SetReadDeadline(time.Now().Add(timeout)))
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := remote.Write([]byte("ping"))
+		errChan <- err
+	}()
+
+	got, err = local.ReadNextMessageTimeout(time.Second)
+	require.NoError(t, err)
+	require.Equal(t, []byte("ping"), got)
+	require.NoError(t, <-errChan)
+
+	_, err = local.ReadNextMessage()
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+// TestEphemeralGen asserts that the ephemeral keys supplied via the
+// EphemeralGen option are used by both the dialer and the listener, and that
+// the handshake between them still completes.
+func TestEphemeralGen(t *testing.T) {
+	fixedKey := func(b byte) *btcec.PrivateKey {
+		priv, _ := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{b}, 32))
+		return priv
+	}
+
+	var (
+		initiatorEphemeral = fixedKey(0x12)
+		responderEphemeral = fixedKey(0x22)
+	)
+
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	clientPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	listener, err := NewListener(
+		&keychain.PrivKeyECDH{PrivKey: serverPriv}, "localhost:0",
+		EphemeralGen(func() (*btcec.PrivateKey, error) {
+			return responderEphemeral, nil
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	acceptChan := make(chan maybeNetConn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		acceptChan <- maybeNetConn{conn, err}
+	}()
+
+	client, err := Dial(
+		&keychain.PrivKeyECDH{PrivKey: clientPriv},
+		&lnwire.NetAddress{
+			IdentityKey: serverPriv.PubKey(),
+			Address:     listener.Addr().(*net.TCPAddr),
+		},
+		tor.DefaultConnTimeout, net.DialTimeout,
+		EphemeralGen(func() (*btcec.PrivateKey, error) {
+			return initiatorEphemeral, nil
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	var server *Conn
+	select {
+	case accepted := <-acceptChan:
+		require.NoError(t, accepted.err)
+		server = accepted.conn.(*Conn)
+		t.Cleanup(func() {
+			server.Close()
+		})
+	case <-time.After(5 * time.Second):
+		t.Fatalf("connection not accepted")
+	}
+
+	// Each side should have used its fixed ephemeral key, and learned
+	// that of its peer.
+	require.True(t, client.noise.localEphemeral.PubKey().IsEqual(
+		initiatorEphemeral.PubKey(),
+	))
+	require.True(t, server.noise.localEphemeral.PubKey().IsEqual(
+		responderEphemeral.PubKey(),
+	))
+	require.True(t, server.noise.remoteEphemeral.IsEqual(
+		initiatorEphemeral.PubKey(),
+	))
+	require.True(t, client.noise.remoteEphemeral.IsEqual(
+		responderEphemeral.PubKey(),
+	))
+
+	// Finally, the resulting session should be usable.
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := client.Write([]byte("ping"))
+		errChan <- err
+	}()
+
+	msg, err := server.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, []byte("ping"), msg)
+	require.NoError(t, <-errChan)
+}
+
+// TestListenerOnAuthenticated asserts that the identity of an inbound peer is
+// delivered to the OnAuthenticated callback before the connection is accepted
+// and any application bytes are read, and that accepted connections expose it
+// via the IdentifiedConn interface.
+func TestListenerOnAuthenticated(t *testing.T) {
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	clientPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	type authEvent struct {
+		pub       *btcec.PublicKey
+		bytesRead uint64
+	}
+
+	authChan := make(chan authEvent, 1)
+	listener, err := NewListener(
+		&keychain.PrivKeyECDH{PrivKey: serverPriv}, "localhost:0",
+		OnAuthenticated(func(conn IdentifiedConn) {
+			authChan <- authEvent{
+				pub:       conn.RemotePub(),
+				bytesRead: conn.(*Conn).BytesTransferred(),
+			}
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	client, err := Dial(
+		&keychain.PrivKeyECDH{PrivKey: clientPriv},
+		&lnwire.NetAddress{
+			IdentityKey: serverPriv.PubKey(),
+			Address:     listener.Addr().(*net.TCPAddr),
+		},
+		tor.DefaultConnTimeout, net.DialTimeout,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	// Send application data right away, such that it's already buffered
+	// by the time the connection is accepted.
+	_, err = client.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	// The callback must have run before Accept returned.
+	select {
+	case event := <-authChan:
+		require.True(t, event.pub.IsEqual(clientPriv.PubKey()))
+		require.Zero(t, event.bytesRead)
+	default:
+		t.Fatalf("identity not delivered before accept")
+	}
+
+	// The identity is also available to holders of a plain net.Conn.
+	identified, ok := conn.(IdentifiedConn)
+	require.True(t, ok)
+	require.True(t, identified.RemotePub().IsEqual(clientPriv.PubKey()))
+
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), buf)
+}
+
+// TestEmptyMessage asserts that empty writes produce zero-length frames that
+// are read back as empty messages, which are distinguishable from a closed
+// connection, and are skipped over by the stream-oriented Read.
+func TestEmptyMessage(t *testing.T) {
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err, "unable to establish test connection")
+
+	local := localConn.(*Conn)
+	remote := remoteConn.(*Conn)
+
+	// Both an empty Write and an empty WriteMessage should emit a frame.
+	n, err := remote.Write(nil)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	require.NoError(t, remote.WriteMessage([]byte{}))
+	_, err = remote.Flush()
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		msg, err := local.ReadNextMessage()
+		require.NoError(t, err)
+		require.NotNil(t, msg)
+		require.Empty(t, msg)
+	}
+
+	// Read should skip over an empty message to the next bytes of the
+	// stream, rather than reporting io.EOF.
+	_, err = remote.Write(nil)
+	require.NoError(t, err)
+	_, err = remote.Write([]byte("data"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(local, buf)
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), buf)
+
+	// Once the remote end is closed, an error is returned instead of an
+	// empty message.
+	_, err = remote.Write(nil)
+	require.NoError(t, err)
+	require.NoError(t, remote.Close())
+
+	msg, err := local.ReadNextMessage()
+	require.NoError(t, err)
+	require.Empty(t, msg)
+
+	_, err = local.ReadNextMessage()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+// TestExchangeFeatures asserts that both ends of a connection receive the
+// feature vector of their peer, and that the exchange can only be carried out
+// once.
+func TestExchangeFeatures(t *testing.T) {
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err, "unable to establish test connection")
+
+	local := localConn.(*Conn)
+	remote := remoteConn.(*Conn)
+
+	localFeatures := lnwire.NewFeatureVector(
+		lnwire.NewRawFeatureVector(
+			lnwire.DataLossProtectRequired,
+			lnwire.StaticRemoteKeyOptional,
+		), lnwire.Features,
+	)
+	remoteFeatures := lnwire.NewFeatureVector(
+		lnwire.NewRawFeatureVector(lnwire.AnchorsZeroFeeHtlcTxOptional),
+		lnwire.Features,
+	)
+
+	type result struct {
+		features lnwire.FeatureVector
+		err      error
+	}
+	resultChan := make(chan result, 1)
+	go func() {
+		features, err := remote.ExchangeFeatures(*remoteFeatures)
+		resultChan <- result{features, err}
+	}()
+
+	gotRemote, err := local.ExchangeFeatures(*localFeatures)
+	require.NoError(t, err)
+	require.True(t, gotRemote.Equals(remoteFeatures.RawFeatureVector))
+
+	res := <-resultChan
+	require.NoError(t, res.err)
+	require.True(t, res.features.Equals(localFeatures.RawFeatureVector))
+
+	// A second exchange should be refused.
+	_, err = local.ExchangeFeatures(*localFeatures)
+	require.ErrorIs(t, err, ErrFeatureExchangeNotFirst)
+
+	// The connection should remain usable for regular traffic.
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := remote.Write([]byte("ping"))
+		errChan <- err
+	}()
+
+	msg, err := local.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, []byte("ping"), msg)
+	require.NoError(t, <-errChan)
+}
+
+// TestRequiredPeerFeatures asserts that Dial fails with
+// ErrMissingRequiredFeature and closes the connection if the peer doesn't
+// support a feature required with RequiredPeerFeatures, and otherwise returns
+// a connection exposing the features of the peer.
+func TestRequiredPeerFeatures(t *testing.T) {
+	listener, netAddr, err := makeListener()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	clientPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	localFeatures := lnwire.NewFeatureVector(
+		lnwire.NewRawFeatureVector(lnwire.StaticRemoteKeyOptional),
+		lnwire.Features,
+	)
+	requiredFeatures := lnwire.NewFeatureVector(
+		lnwire.NewRawFeatureVector(lnwire.DataLossProtectRequired),
+		lnwire.Features,
+	)
+
+	// serve accepts the next connection, exchanges the given features on
+	// it, and then returns the error of the next read.
+	serve := func(features *lnwire.FeatureVector) chan error {
+		errChan := make(chan error, 1)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			defer conn.Close()
+
+			_, err = conn.(*Conn).ExchangeFeatures(*features)
+			if err != nil {
+				errChan <- err
+				return
+			}
+
+			_, err = conn.(*Conn).ReadNextMessage()
+			errChan <- err
+		}()
+
+		return errChan
+	}
+
+	dial := func() (*Conn, error) {
+		return Dial(
+			&keychain.PrivKeyECDH{PrivKey: clientPriv}, netAddr,
+			tor.DefaultConnTimeout, net.DialTimeout,
+			RequiredPeerFeatures(*localFeatures, *requiredFeatures),
+		)
+	}
+
+	// A peer lacking the required feature is rejected, and the
+	// connection is closed.
+	errChan := serve(lnwire.NewFeatureVector(
+		lnwire.NewRawFeatureVector(lnwire.StaticRemoteKeyOptional),
+		lnwire.Features,
+	))
+	_, err = dial()
+	require.ErrorIs(t, err, ErrMissingRequiredFeature)
+	require.Contains(t, err.Error(), "data-loss-protect")
+	require.Error(t, <-errChan)
+
+	// A peer supporting the required feature, even as optional, is
+	// accepted, and its features are exposed by the connection.
+	remoteFeatures := lnwire.NewFeatureVector(
+		lnwire.NewRawFeatureVector(lnwire.DataLossProtectOptional),
+		lnwire.Features,
+	)
+	errChan = serve(remoteFeatures)
+	conn, err := dial()
+	require.NoError(t, err)
+	require.NotNil(t, conn.RemoteFeatures())
+	require.True(t, conn.RemoteFeatures().Equals(
+		remoteFeatures.RawFeatureVector,
+	))
+
+	// The features can't be exchanged a second time.
+	_, err = conn.ExchangeFeatures(*localFeatures)
+	require.ErrorIs(t, err, ErrFeatureExchangeNotFirst)
+
+	// The connection remains usable.
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, <-errChan)
+	conn.Close()
+}
+
+// TestPing asserts that Ping measures the round-trip time of a connection
+// through ping and pong frames that are matched by their nonce, and which are
+// never delivered to the application.
+func TestPing(t *testing.T) {
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	// Without the option, the connection doesn't support pings.
+	local, remote, err := NewPipe(localPriv, remotePriv)
+	require.NoError(t, err)
+	_, err = local.Ping(context.Background())
+	require.ErrorIs(t, err, ErrPingDisabled)
+	local.Close()
+	remote.Close()
+
+	local, remote, err = NewPipe(localPriv, remotePriv, EnablePing())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(func() {
+		cancel()
+		local.Close()
+		remote.Close()
+	})
+
+	// Pongs are consumed by the read path, so both ends must be reading
+	// from the connection.
+	localMsgs, _ := local.Messages(ctx)
+	remoteMsgs, _ := remote.Messages(ctx)
+
+	rtt, err := local.Ping(ctx)
+	require.NoError(t, err)
+	require.Positive(t, rtt)
+
+	// The responder can ping the initiator just the same, and several
+	// pings may be outstanding at once.
+	errChan := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := remote.Ping(ctx)
+			errChan <- err
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, <-errChan)
+	}
+
+	// A pong only completes the ping carrying the same nonce, and pongs
+	// with an unknown nonce are ignored.
+	pong := make(chan struct{})
+	nonce := pingNonce{0x01}
+	local.pingMtx.Lock()
+	local.pendingPings[nonce] = pong
+	local.pingMtx.Unlock()
+
+	require.NoError(t, remote.writeControlFrame(
+		encodeControlFrame(pongFrameType, pingNonce{0x02}),
+	))
+	require.NoError(t, remote.writeControlFrame(
+		encodeControlFrame(pongFrameType, nonce),
+	))
+
+	select {
+	case <-pong:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pong with matching nonce not delivered")
+	}
+
+	// None of the control frames should have been delivered to the
+	// application, so the next messages read are the ones written by the
+	// application.
+	go func() {
+		_, err := remote.Write([]byte("hello"))
+		errChan <- err
+	}()
+	require.Equal(t, []byte("hello"), <-localMsgs)
+	require.NoError(t, <-errChan)
+
+	go func() {
+		_, err := local.Write([]byte("world"))
+		errChan <- err
+	}()
+	require.Equal(t, []byte("world"), <-remoteMsgs)
+	require.NoError(t, <-errChan)
+}
+
+// TestListenerSetHandshakeVersion asserts that a listener accepting several
+// handshake versions negotiates the version requested by each dialer, and
+// rejects dialers requesting a version outside of the set.
+func TestListenerSetHandshakeVersion(t *testing.T) {
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	errChan := make(chan error, 1)
+	listener, err := NewListener(
+		&keychain.PrivKeyECDH{PrivKey: serverPriv}, "localhost:0",
+		OnHandshakeError(func(_ net.Addr, err error) {
+			errChan <- err
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	listener.SetHandshakeVersion(HandshakeVersion, 1)
+
+	dial := func(version byte) (*Conn, error) {
+		clientPriv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return Dial(
+			&keychain.PrivKeyECDH{PrivKey: clientPriv},
+			&lnwire.NetAddress{
+				IdentityKey: serverPriv.PubKey(),
+				Address:     listener.Addr().(*net.TCPAddr),
+			},
+			tor.DefaultConnTimeout, net.DialTimeout,
+			RequestHandshakeVersion(version),
+		)
+	}
+
+	for _, version := range []byte{HandshakeVersion, 1} {
+		conn, err := dial(version)
+		require.NoError(t, err, "version %d", version)
+		require.Equal(t, version, conn.HandshakeVersion())
+
+		accepted, err := listener.Accept()
+		require.NoError(t, err)
+		require.Equal(
+			t, version, accepted.(*Conn).HandshakeVersion(),
+		)
+
+		// The session should be usable with the negotiated version.
+		_, err = conn.Write([]byte("hello"))
+		require.NoError(t, err)
+
+		msg, err := accepted.(*Conn).ReadNextMessage()
+		require.NoError(t, err)
+		require.Equal(t, []byte("hello"), msg)
+
+		conn.Close()
+		accepted.Close()
+	}
+
+	// A dialer requesting a version outside of the set should be
+	// rejected.
+	_, err = dial(2)
+	require.Error(t, err)
+
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, ErrUnsupportedHandshakeVersion)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("rejected dialer not reported")
+	}
+}
+
+// TestSecurityPolicy asserts that a listener with a SecurityPolicy rejects a
+// dialer whose handshake falls below its floor with ErrPolicyViolation, while
+// still accepting a compliant dialer, and that a dialer refuses to initiate a
+// handshake that violates its own policy.
+func TestSecurityPolicy(t *testing.T) {
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	policy := SecurityPolicy{
+		MinVersion:   1,
+		RequireRekey: true,
+	}
+
+	errChan := make(chan error, 1)
+	listener, err := NewListener(
+		&keychain.PrivKeyECDH{PrivKey: serverPriv}, "localhost:0",
+		WithSecurityPolicy(policy),
+		OnHandshakeError(func(_ net.Addr, err error) {
+			errChan <- err
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	// Accept both versions, such that only the policy rejects the older
+	// one.
+	listener.SetHandshakeVersion(HandshakeVersion, 1)
+
+	dial := func(opts ...ConnOption) (*Conn, error) {
+		clientPriv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return Dial(
+			&keychain.PrivKeyECDH{PrivKey: clientPriv},
+			&lnwire.NetAddress{
+				IdentityKey: serverPriv.PubKey(),
+				Address:     listener.Addr().(*net.TCPAddr),
+			},
+			tor.DefaultConnTimeout, net.DialTimeout, opts...,
+		)
+	}
+
+	// A dialer offering a version below the floor is rejected before the
+	// listener responds to it.
+	_, err = dial(RequestHandshakeVersion(HandshakeVersion))
+	require.Error(t, err)
+
+	select {
+	case err := <-errChan:
+		require.ErrorIs(t, err, ErrPolicyViolation)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("rejected dialer not reported")
+	}
+
+	// A compliant dialer connects.
+	conn, err := dial(RequestHandshakeVersion(1))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	accepted, err := listener.Accept()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		accepted.Close()
+	})
+	require.EqualValues(t, 1, accepted.(*Conn).HandshakeVersion())
+
+	// A dialer whose own policy is violated by the version it requests
+	// fails without carrying out the handshake.
+	_, err = dial(
+		RequestHandshakeVersion(HandshakeVersion),
+		WithSecurityPolicy(policy),
+	)
+	require.ErrorIs(t, err, ErrPolicyViolation)
+
+	// A session without rekeying would violate a policy requiring it.
+	err = policy.check(ConnectionState{Version: 1})
+	require.ErrorIs(t, err, ErrPolicyViolation)
+}
+
+// TestSkipMessages asserts that skipped messages are discarded while keeping
+// the stream in sync, such that the following message is read correctly, even
+// if a skipped message exceeds the read limit.
+func TestSkipMessages(t *testing.T) {
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err, "unable to establish test connection")
+
+	local := localConn.(*Conn)
+	remote := remoteConn.(*Conn)
+
+	const limit = 16
+	msgs := [][]byte{
+		[]byte("first"),
+		[]byte("second"),
+		[]byte("third"),
+		bytes.Repeat([]byte{0x01}, limit+1),
+		[]byte("fifth"),
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		for _, msg := range msgs {
+			if err := remote.WriteMessage(msg); err != nil {
+				errChan <- err
+				return
+			}
+			if _, err := remote.Flush(); err != nil {
+				errChan <- err
+				return
+			}
+		}
+		errChan <- nil
+	}()
+
+	// Skipping the first two messages should leave the third as the next
+	// message to be read.
+	require.NoError(t, local.SkipMessages(2))
+
+	msg, err := local.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, msgs[2], msg)
+
+	// A message rejected for exceeding the read limit should be skipped
+	// along with its body.
+	local.SetReadLimit(limit)
+
+	_, err = local.ReadNextMessage()
+	require.ErrorIs(t, err, ErrMessageTooLarge)
+
+	require.NoError(t, local.SkipMessages(1))
+
+	msg, err = local.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, msgs[4], msg)
+
+	require.NoError(t, <-errChan)
+}
+
+// TestWriteCoalesce asserts that the frames of writes made on a connection
+// created with WriteCoalesce are buffered until they're flushed, either
+// explicitly, by the timer, or once the byte threshold is reached, and that
+// the receiver reads back each coalesced message individually.
+func TestWriteCoalesce(t *testing.T) {
+	msgs := [][]byte{
+		[]byte("first"),
+		{},
+		bytes.Repeat([]byte{0x01}, 1000),
+		[]byte("last"),
+	}
+
+	newPipe := func(t *testing.T, opt ConnOption) (*Conn, *Conn) {
+		localPriv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		remotePriv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		local, remote, err := NewPipe(localPriv, remotePriv, opt)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			local.Close()
+			remote.Close()
+		})
+
+		return local, remote
+	}
+
+	// writeAll writes each of the passed messages using a separate call
+	// to Write, followed by a Flush if flush is true.
+	writeAll := func(conn *Conn, msgs [][]byte, flush bool) error {
+		for _, msg := range msgs {
+			n, err := conn.Write(msg)
+			if err != nil {
+				return err
+			}
+			if n != len(msg) {
+				return fmt.Errorf("wrote %d bytes, expected "+
+					"%d", n, len(msg))
+			}
+		}
+
+		if flush {
+			_, err := conn.Flush()
+			return err
+		}
+
+		return nil
+	}
+
+	// readAll asserts that the passed messages are read back in order.
+	readAll := func(t *testing.T, conn *Conn, msgs [][]byte) {
+		for _, expMsg := range msgs {
+			msg, err := conn.ReadNextMessage()
+			require.NoError(t, err)
+			require.Equal(t, expMsg, msg)
+		}
+	}
+
+	t.Run("flush", func(t *testing.T) {
+		local, remote := newPipe(t, WriteCoalesce(time.Hour, 0))
+
+		// As the pipe is synchronous, the writes only return without
+		// a reader if they're buffered.
+		require.NoError(t, writeAll(local, msgs, false))
+		require.NotEmpty(t, local.coalesceBuf)
+
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := local.Flush()
+			errChan <- err
+		}()
+
+		readAll(t, remote, msgs)
+		require.NoError(t, <-errChan)
+		require.Empty(t, local.coalesceBuf)
+	})
+
+	t.Run("timer", func(t *testing.T) {
+		local, remote := newPipe(
+			t, WriteCoalesce(10*time.Millisecond, 0),
+		)
+
+		require.NoError(t, writeAll(local, msgs, false))
+		readAll(t, remote, msgs)
+
+		// A subsequent write should arm the timer again.
+		require.NoError(t, writeAll(local, msgs[:1], false))
+		readAll(t, remote, msgs[:1])
+	})
+
+	t.Run("max bytes", func(t *testing.T) {
+		local, remote := newPipe(t, WriteCoalesce(time.Hour, 1000))
+
+		// The third message pushes the buffer past the threshold, so
+		// the first three messages should be received without an
+		// explicit flush.
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- writeAll(local, msgs[:3], false)
+		}()
+
+		readAll(t, remote, msgs[:3])
+		require.NoError(t, <-errChan)
+
+		go func() {
+			errChan <- writeAll(local, msgs[3:], true)
+		}()
+
+		readAll(t, remote, msgs[3:])
+		require.NoError(t, <-errChan)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		local, remote := newPipe(t, WriteCoalesce(time.Hour, 1000))
+
+		// Writes below the threshold are buffered, and should be
+		// flushed by Close rather than dropped.
+		require.NoError(t, writeAll(local, msgs[:1], false))
+		require.True(t, local.coalesceArmed)
+
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- local.Close()
+		}()
+
+		readAll(t, remote, msgs[:1])
+		require.NoError(t, <-errChan)
+		require.Empty(t, local.coalesceBuf)
+		require.False(t, local.coalesceArmed)
+	})
+}
+
+// TestMaxBufferedBytes asserts that a connection created with MaxBufferedBytes
+// doesn't buffer more than a frame's worth of bytes when its peer far outpaces
+// the application, only buffers the bytes of a stalled frame that have
+// actually been received, and rejects frames exceeding the limit.
+func TestMaxBufferedBytes(t *testing.T) {
+	newConns := func(t *testing.T, maxBytes int) (*Conn, *Conn) {
+		localConn, remoteConn, err := establishTestConnection(t)
+		require.NoError(t, err, "unable to establish test connection")
+
+		local := localConn.(*Conn)
+		local.maxBufferedBytes = maxBytes
+
+		return local, remoteConn.(*Conn)
+	}
+
+	t.Run("fast producer", func(t *testing.T) {
+		const (
+			maxBytes = 4096
+			msgSize  = 1000
+			numMsgs  = 1000
+		)
+		local, remote := newConns(t, maxBytes)
+
+		// The producer writes all of its messages as fast as the
+		// transport allows.
+		errChan := make(chan error, 1)
+		go func() {
+			for i := 0; i < numMsgs; i++ {
+				msg := bytes.Repeat([]byte{byte(i)}, msgSize)
+				if _, err := remote.Write(msg); err != nil {
+					errChan <- err
+					return
+				}
+			}
+			errChan <- nil
+		}()
+
+		// The consumer reads the stream in small increments, such
+		// that it's far slower than the producer. The connection
+		// should never hold more than the remainder of the current
+		// message.
+		buf := make([]byte, 100)
+		for i := 0; i < numMsgs*msgSize/len(buf); i++ {
+			_, err := io.ReadFull(local, buf)
+			require.NoError(t, err)
+
+			expByte := byte(i * len(buf) / msgSize)
+			expBuf := bytes.Repeat([]byte{expByte}, len(buf))
+			require.Equal(t, expBuf, buf)
+
+			require.Less(t, local.readBuf.Len(), msgSize)
+			require.LessOrEqual(
+				t, cap(local.partialFrame), maxBytes,
+			)
+		}
+
+		require.NoError(t, <-errChan)
+	})
+
+	t.Run("stalled frame", func(t *testing.T) {
+		const msgSize = 60_000
+		local, remote := newConns(t, math.MaxUint16+macSize)
+
+		msg := bytes.Repeat([]byte{0x01}, msgSize)
+		require.NoError(t, remote.noise.WriteMessage(msg))
+
+		// Send the header announcing the large message, followed by
+		// only a small part of its body.
+		header := remote.noise.nextHeaderSend
+		body := remote.noise.nextBodySend
+		remote.noise.nextHeaderSend = nil
+		remote.noise.nextBodySend = nil
+
+		_, err := remote.conn.Write(header)
+		require.NoError(t, err)
+		_, err = remote.conn.Write(body[:100])
+		require.NoError(t, err)
+
+		_, err = local.ReadNextMessageTimeout(100 * time.Millisecond)
+		require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+		// Only the received part of the body should be buffered,
+		// rather than the full length announced by the header.
+		require.Len(t, local.partialFrame, 100)
+		require.Less(t, cap(local.partialFrame), 2*minFrameChunkSize)
+
+		// Once the rest of the body arrives, the message should be
+		// read in full.
+		_, err = remote.conn.Write(body[100:])
+		require.NoError(t, err)
+
+		readMsg, err := local.ReadNextMessage()
+		require.NoError(t, err)
+		require.Equal(t, msg, readMsg)
+	})
+
+	t.Run("message too large", func(t *testing.T) {
+		const maxBytes = 1024
+		local, remote := newConns(t, maxBytes)
+
+		msg := bytes.Repeat([]byte{0x01}, maxBytes-macSize+1)
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := remote.Write(msg)
+			errChan <- err
+		}()
+
+		_, err := local.ReadNextMessage()
+		require.ErrorIs(t, err, ErrMessageTooLarge)
+
+		// The header remains pending, so the body can still be read
+		// into a buffer supplied by the caller.
+		buf := make([]byte, len(msg)+macSize)
+		n, err := local.ReadNextMessageInto(buf)
+		require.NoError(t, err)
+		require.Equal(t, msg, buf[:n])
+
+		require.NoError(t, <-errChan)
+	})
+}
+
+// TestReconnectingConn asserts that a ReconnectingConn transparently recovers
+// from the loss of its underlying connection, running the setup function over
+// each new connection.
+func TestReconnectingConn(t *testing.T) {
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	listener, stop, err := ServeEcho(serverPriv, "localhost:0")
+	require.NoError(t, err)
+	t.Cleanup(stop)
+
+	netAddr := &lnwire.NetAddress{
+		IdentityKey: serverPriv.PubKey(),
+		Address:     listener.Addr(),
+	}
+
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	localKeyECDH := &keychain.PrivKeyECDH{PrivKey: localPriv}
+
+	// The setup function performs a round trip with the echo server,
+	// standing in for a protocol level handshake.
+	var setups int32
+	hello := []byte("hello")
+	setup := func(conn *Conn) error {
+		atomic.AddInt32(&setups, 1)
+
+		if _, err := conn.Write(hello); err != nil {
+			return err
+		}
+
+		msg, err := conn.ReadNextMessage()
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(msg, hello) {
+			return fmt.Errorf("unexpected setup reply: %x", msg)
+		}
+
+		return nil
+	}
+
+	dialer := func(network, address string,
+		timeout time.Duration) (net.Conn, error) {
+
+		return net.DialTimeout(network, address, timeout)
+	}
+
+	policy := RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+	}
+
+	conn, err := DialReconnecting(
+		localKeyECDH, netAddr, tor.DefaultConnTimeout, dialer, setup,
+		policy,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	require.EqualValues(t, 1, atomic.LoadInt32(&setups))
+
+	// dropConn severs the active transport without the ReconnectingConn
+	// being aware of it.
+	dropConn := func() {
+		active, _ := conn.current()
+		require.NoError(t, active.conn.Close())
+	}
+
+	echo := func(msg []byte) {
+		_, err := conn.Write(msg)
+		require.NoError(t, err)
+
+		buf := make([]byte, len(msg))
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		require.Equal(t, msg, buf)
+	}
+
+	echo([]byte("first"))
+
+	// Dropping the connection before a write should go unnoticed by the
+	// caller, with the write being made over a new connection.
+	dropConn()
+	echo([]byte("second"))
+	require.EqualValues(t, 2, atomic.LoadInt32(&setups))
+
+	// Dropping the connection while a read is blocked should cause the
+	// read to be resumed over the new connection.
+	readChan := make(chan []byte, 1)
+	go func() {
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			readChan <- nil
+			return
+		}
+		readChan <- buf
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	dropConn()
+
+	third := []byte("third")
+	_, err = conn.Write(third)
+	require.NoError(t, err)
+
+	select {
+	case msg := <-readChan:
+		require.Equal(t, third, msg)
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("read not resumed after reconnect")
+	}
+	require.EqualValues(t, 3, atomic.LoadInt32(&setups))
+
+	// Once closed, no further reconnection attempts are made.
+	require.NoError(t, conn.Close())
+	_, err = conn.Write(third)
+	require.ErrorIs(t, err, ErrConnClosed)
+	require.EqualValues(t, 3, atomic.LoadInt32(&setups))
+}
+
+// TestDialTOFU asserts that dialing with TOFU learns the static key of a peer
+// using the XX handshake on first use, authenticates it against the pinned key
+// using the XK handshake on subsequent dials, and rejects a peer whose key
+// differs from the pinned one.
+func TestDialTOFU(t *testing.T) {
+	listener, netAddr, err := makeListener()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	serverPub := netAddr.IdentityKey
+
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	localKeyECDH := &keychain.PrivKeyECDH{PrivKey: localPriv}
+
+	store := NewMemoryTOFUStore()
+	tofuAddr := &lnwire.NetAddress{
+		Address: netAddr.Address,
+	}
+
+	dial := func() (*Conn, error) {
+		return Dial(
+			localKeyECDH, tofuAddr, tor.DefaultConnTimeout,
+			net.DialTimeout, TOFU(store),
+		)
+	}
+
+	accept := func() *Conn {
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+
+		return conn.(*Conn)
+	}
+
+	// By default, the listener doesn't accept the XX handshake, so the
+	// key can't be learned.
+	_, err = dial()
+	require.Error(t, err)
+	_, ok := store.Get(netAddr.Address)
+	require.False(t, ok)
+
+	listener.SetHandshakeVersion(HandshakeVersion, HandshakeVersionXX)
+
+	// The first dial should learn the listener's key using the XX
+	// handshake and pin it.
+	conn, err := dial()
+	require.NoError(t, err)
+	require.Equal(t, HandshakeVersionXX, conn.HandshakeVersion())
+	require.True(t, serverPub.IsEqual(conn.RemotePub()))
+
+	pinned, ok := store.Get(netAddr.Address)
+	require.True(t, ok)
+	require.True(t, serverPub.IsEqual(pinned))
+
+	// The listener should have authenticated us, and the session should
+	// be usable in both directions.
+	remote := accept()
+	require.True(t, localPriv.PubKey().IsEqual(remote.RemotePub()))
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	msg, err := remote.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), msg)
+
+	_, err = remote.Write([]byte("world"))
+	require.NoError(t, err)
+	msg, err = conn.ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, []byte("world"), msg)
+
+	conn.Close()
+	remote.Close()
+
+	// Subsequent dials should use the XK handshake against the pinned
+	// key.
+	conn, err = dial()
+	require.NoError(t, err)
+	require.Equal(t, HandshakeVersion, conn.HandshakeVersion())
+	require.True(t, serverPub.IsEqual(conn.RemotePub()))
+
+	conn.Close()
+	accept().Close()
+
+	// If the pinned key no longer matches the listener's, the dial should
+	// be rejected.
+	otherPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	store.Put(netAddr.Address, otherPriv.PubKey())
+
+	_, err = dial()
+	require.ErrorIs(t, err, ErrIdentityMismatch)
+
+	// The mismatching key should remain pinned.
+	pinned, ok = store.Get(netAddr.Address)
+	require.True(t, ok)
+	require.True(t, otherPriv.PubKey().IsEqual(pinned))
+}
+
+// recordingConn is a net.Conn that records the size of each write made to the
+// wrapped connection.
+type recordingConn struct {
+	net.Conn
+
+	writeSizes []int
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	c.writeSizes = append(c.writeSizes, len(p))
+	return c.Conn.Write(p)
+}
+
+// TestSetWriteChunkSize asserts that a connection with a write chunk size
+// splits its writes to the underlying connection accordingly, while the
+// remote peer reassembles the message transparently.
+func TestSetWriteChunkSize(t *testing.T) {
+	localConn, remoteConn, err := establishTestConnection(t)
+	require.NoError(t, err)
+
+	local := localConn.(*Conn)
+	recorder := &recordingConn{Conn: local.conn}
+	local.conn = recorder
+
+	const chunkSize = 1000
+	local.SetWriteChunkSize(chunkSize)
+
+	// The message spans several frames, each of which is larger than the
+	// chunk size.
+	msg := make([]byte, 3*math.MaxUint16)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := local.Write(msg)
+		errChan <- err
+	}()
+
+	buf := make([]byte, len(msg))
+	_, err = io.ReadFull(remoteConn, buf)
+	require.NoError(t, err)
+	require.Equal(t, msg, buf)
+	require.NoError(t, <-errChan)
+
+	// No write should have exceeded the chunk size, and all of the frames
+	// should have been written.
+	var total int
+	for _, size := range recorder.writeSizes {
+		require.LessOrEqual(t, size, chunkSize)
+		total += size
+	}
+	require.Equal(t, len(msg)+3*(encHeaderSize+macSize), total)
+
+	// Restoring the default should write each frame in full again.
+	local.SetWriteChunkSize(0)
+	recorder.writeSizes = nil
+
+	go func() {
+		_, err := local.Write(msg[:math.MaxUint16])
+		errChan <- err
+	}()
+
+	_, err = io.ReadFull(remoteConn, buf[:math.MaxUint16])
+	require.NoError(t, err)
+	require.Equal(t, msg[:math.MaxUint16], buf[:math.MaxUint16])
+	require.NoError(t, <-errChan)
+	require.Equal(
+		t, []int{encHeaderSize, math.MaxUint16 + macSize},
+		recorder.writeSizes,
+	)
+}
+
+// TestCipherStateNonceRotation asserts that the key of a cipherState is rotated
+// before its nonce can be reused, including when the nonce has been advanced
+// beyond the rotation interval, and that the peer stays in sync across the
+// rotation.
+func TestCipherStateNonceRotation(t *testing.T) {
+	var key, salt [32]byte
+	copy(key[:], bytes.Repeat([]byte{0x01}, 32))
+	copy(salt[:], bytes.Repeat([]byte{0x02}, 32))
+
+	startNonces := []uint64{
+		0, keyRotationInterval - 1, keyRotationInterval,
+		keyRotationInterval + 10, math.MaxUint64 - 1,
+	}
+	for _, start := range startNonces {
+		start := start
+		t.Run(fmt.Sprintf("start %d", start), func(t *testing.T) {
+			var send, recv cipherState
+			send.InitializeKeyWithSalt(salt, key)
+			recv.InitializeKeyWithSalt(salt, key)
+			send.nonce, recv.nonce = start, start
+
+			type keyNonce struct {
+				key   [32]byte
+				nonce uint64
+			}
+			used := make(map[keyNonce]struct{})
+
+			msg := []byte("hello")
+			for i := 0; i < 3*keyRotationInterval; i++ {
+				// No key and nonce pair may be used twice.
+				kn := keyNonce{send.secretKey, send.nonce}
+				_, ok := used[kn]
+				require.False(
+					t, ok, "nonce %d reused", kn.nonce,
+				)
+				used[kn] = struct{}{}
+
+				ctxt := send.Encrypt(nil, nil, msg)
+				ptxt, err := recv.Decrypt(nil, nil, ctxt)
+				require.NoError(t, err)
+				require.Equal(t, msg, ptxt)
+
+				require.Less(
+					t, send.nonce,
+					uint64(keyRotationInterval),
+				)
+			}
+		})
+	}
+}
+
+// TestOnDecryptError asserts that the OnDecryptError callback is invoked with
+// an integrity error when a frame is corrupted in transit, before the error is
+// returned by Read, and that it isn't invoked when the peer closes the
+// connection cleanly.
+func TestOnDecryptError(t *testing.T) {
+	t.Parallel()
+
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	msg := []byte("hello")
+
+	tests := []struct {
+		name string
+
+		// offset is the index of the byte of the frame that is flipped
+		// in transit, or -1 if the peer instead closes the connection.
+		offset int
+
+		expErr error
+	}{
+		{
+			name:   "corrupt header",
+			offset: 0,
+			expErr: ErrMACVerificationFailed,
+		},
+		{
+			name:   "corrupt body",
+			offset: encHeaderSize,
+			expErr: ErrMACVerificationFailed,
+		},
+		{
+			name:   "corrupt body mac",
+			offset: encHeaderSize + len(msg) + macSize - 1,
+			expErr: ErrMACVerificationFailed,
+		},
+		{
+			name:   "clean close",
+			offset: -1,
+			expErr: io.EOF,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var callbackErrs []error
+			local, remote, err := NewPipe(
+				localPriv, remotePriv,
+				OnDecryptError(func(err error) {
+					callbackErrs = append(callbackErrs, err)
+				}),
+			)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				local.Close()
+				remote.Close()
+			})
+
+			// Encrypt the frame as the local end would, then write
+			// it to the underlying connection with a single bit
+			// flipped. Since the pipe is synchronous, the write is
+			// executed in its own goroutine.
+			var frame bytes.Buffer
+			require.NoError(t, local.noise.WriteMessage(msg))
+			_, err = local.noise.Flush(&frame)
+			require.NoError(t, err)
+
+			errChan := make(chan error, 1)
+			go func() {
+				if test.offset < 0 {
+					errChan <- local.conn.Close()
+					return
+				}
+
+				raw := frame.Bytes()
+				raw[test.offset] ^= 0x01
+				_, err := local.conn.Write(raw)
+				errChan <- err
+			}()
+
+			_, err = remote.Read(make([]byte, len(msg)))
+			require.ErrorIs(t, err, test.expErr)
+
+			// A failed read may leave part of the frame unread, so
+			// we close our end to unblock the writer.
+			remote.Close()
+			<-errChan
+
+			// The callback should have fired exactly once before
+			// Read returned, with the same integrity error, and
+			// not at all for a clean close.
+			if test.expErr != ErrMACVerificationFailed {
+				require.Empty(t, callbackErrs)
+				return
+			}
+
+			require.Len(t, callbackErrs, 1)
+			require.ErrorIs(
+				t, callbackErrs[0], ErrMACVerificationFailed,
+			)
+			require.Equal(t, err, callbackErrs[0])
+		})
+	}
+}
+
+// TestGlobalStats asserts that the global statistics reflect the connections
+// established, the bytes exchanged over them and the handshakes that failed,
+// and that closed connections are no longer counted as active.
+func TestGlobalStats(t *testing.T) {
+	newPriv := func() *btcec.PrivateKey {
+		priv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return priv
+	}
+
+	before := Stats()
+
+	const numPipes = 3
+	var conns []*Conn
+	for i := 0; i < numPipes; i++ {
+		local, remote, err := NewPipe(newPriv(), newPriv())
+		require.NoError(t, err)
+
+		conns = append(conns, local, remote)
+	}
+
+	stats := Stats()
+	require.Equal(t, before.ActiveConns+2*numPipes, stats.ActiveConns)
+	require.Equal(
+		t, before.HandshakeSuccesses+2*numPipes,
+		stats.HandshakeSuccesses,
+	)
+	require.Equal(t, before.HandshakeFailures, stats.HandshakeFailures)
+
+	// Bytes written by one end and read by the other are counted once in
+	// each direction.
+	msg := []byte("hello")
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := conns[0].Write(msg)
+		errChan <- err
+	}()
+
+	recv, err := conns[1].ReadNextMessage()
+	require.NoError(t, err)
+	require.Equal(t, msg, recv)
+	require.NoError(t, <-errChan)
+
+	stats = Stats()
+	require.Equal(t, before.BytesOut+uint64(len(msg)), stats.BytesOut)
+	require.Equal(t, before.BytesIn+uint64(len(msg)), stats.BytesIn)
+
+	// Closing a connection deregisters it, even if closed repeatedly.
+	for _, conn := range conns {
+		require.NoError(t, conn.Close())
+		require.NoError(t, conn.Close())
+	}
+	require.Equal(t, before.ActiveConns, Stats().ActiveConns)
+
+	// A dialer that doesn't know the responder's static key fails the
+	// handshake, as does the responder, and neither is counted as active.
+	initiatorPipe, responderPipe := net.Pipe()
+	go func() {
+		_, err := Server(responderPipe, newPriv())
+		errChan <- err
+	}()
+
+	_, err = Client(initiatorPipe, newPriv(), newPriv().PubKey())
+	require.Error(t, err)
+	require.Error(t, <-errChan)
+
+	stats = Stats()
+	require.Equal(t, before.ActiveConns, stats.ActiveConns)
+	require.Equal(t, before.HandshakeFailures+2, stats.HandshakeFailures)
+}
+
+// TestConnIOCopy asserts that io.Copy can stream data into a connection using
+// ReadFrom, and out of a connection using WriteTo, splitting the data into
+// frames and reassembling it on the other end.
+func TestConnIOCopy(t *testing.T) {
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	local, remote, err := NewPipe(localPriv, remotePriv)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		remote.Close()
+	})
+
+	// Send enough data to span several frames.
+	data := make([]byte, 3*math.MaxUint16+100)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+
+	// Hide the WriterTo implementation of the source, such that io.Copy
+	// has to use the connection's ReadFrom.
+	errChan := make(chan error, 1)
+	go func() {
+		src := struct{ io.Reader }{bytes.NewReader(data)}
+
+		n, err := io.Copy(local, src)
+		if err == nil && n != int64(len(data)) {
+			err = fmt.Errorf("copied %d of %d bytes", n, len(data))
+		}
+		errChan <- err
+
+		local.Close()
+	}()
+
+	// Read the start of the first frame directly, such that WriteTo has
+	// to pick up the remainder of the partially read message.
+	head := make([]byte, 10)
+	_, err = io.ReadFull(remote, head)
+	require.NoError(t, err)
+
+	// The remote end is drained using WriteTo, which returns without an
+	// error once the sender closes the connection.
+	recv := bytes.NewBuffer(head)
+	n, err := io.Copy(recv, remote)
+	require.NoError(t, err)
+	require.NoError(t, <-errChan)
+	require.EqualValues(t, len(data)-len(head), n)
+	require.Equal(t, data, recv.Bytes())
+	require.EqualValues(t, len(data), remote.BytesTransferred())
+}
+
+// exchangeFeaturesPipe runs ExchangeFeatures with empty feature vectors on
+// both ends of a pipe.
+func exchangeFeaturesPipe(t *testing.T, local, remote *Conn) {
+	t.Helper()
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := remote.ExchangeFeatures(lnwire.FeatureVector{})
+		errChan <- err
+	}()
+
+	_, err := local.ExchangeFeatures(lnwire.FeatureVector{})
+	require.NoError(t, err)
+	require.NoError(t, <-errChan)
+}
+
+// TestCompression asserts that connections created with the Compression option
+// negotiate compression through ExchangeFeatures, after which large messages
+// are compressed on the wire and small ones are sent as is, while peers that
+// didn't opt in keep sending messages uncompressed.
+func TestCompression(t *testing.T) {
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	// sendMessage writes msg from local to remote, returning the number
+	// of bytes written to the wire.
+	sendMessage := func(local, remote *Conn, msg []byte) int {
+		recorder := &recordingConn{Conn: local.conn}
+		local.conn = recorder
+		defer func() {
+			local.conn = recorder.Conn
+		}()
+
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := local.Write(msg)
+			errChan <- err
+		}()
+
+		recv, err := remote.ReadNextMessage()
+		require.NoError(t, err)
+		require.NoError(t, <-errChan)
+		require.Equal(t, msg, recv)
+
+		var wireLen int
+		for _, size := range recorder.writeSizes {
+			wireLen += size
+		}
+
+		return wireLen
+	}
+
+	const overhead = encHeaderSize + macSize
+	large := bytes.Repeat([]byte("compressible "), 2000)
+	small := []byte("hello")
+
+	t.Run("negotiated", func(t *testing.T) {
+		local, remote, err := NewPipe(
+			localPriv, remotePriv, Compression(128),
+		)
+		require.NoError(t, err)
+		defer local.Close()
+		defer remote.Close()
+
+		exchangeFeaturesPipe(t, local, remote)
+		require.True(t, local.compress)
+		require.True(t, remote.compress)
+
+		// The large message should shrink well below its plaintext
+		// size on the wire.
+		wireLen := sendMessage(local, remote, large)
+		require.Less(t, wireLen, len(large)/10)
+
+		// The small message should be sent as is, prefixed by its
+		// marker.
+		wireLen = sendMessage(remote, local, small)
+		require.Equal(t, overhead+1+len(small), wireLen)
+
+		// Messages written in a batch should be decoded individually.
+		errChan := make(chan error, 1)
+		go func() {
+			n, err := local.WriteMessages([][]byte{large, small})
+			if err == nil && n != len(large)+len(small) {
+				err = fmt.Errorf("wrote %d bytes", n)
+			}
+			errChan <- err
+		}()
+
+		for _, want := range [][]byte{large, small} {
+			msg, err := remote.ReadNextMessage()
+			require.NoError(t, err)
+			require.Equal(t, want, msg)
+		}
+		require.NoError(t, <-errChan)
+
+		// The raw frame readers can't decode the markers, and should
+		// be refused.
+		_, err = remote.ReadHeader()
+		require.ErrorIs(t, err, ErrCompressionNegotiated)
+	})
+
+	t.Run("one sided", func(t *testing.T) {
+		local, remote, err := NewPipe(localPriv, remotePriv)
+		require.NoError(t, err)
+		defer local.Close()
+		defer remote.Close()
+
+		local.compressThreshold = 128
+
+		exchangeFeaturesPipe(t, local, remote)
+		require.False(t, local.compress)
+		require.False(t, remote.compress)
+
+		wireLen := sendMessage(local, remote, large)
+		require.Equal(t, overhead+len(large), wireLen)
+	})
+}
+
+// TestConnectionState asserts that the state returned by ConnectionState
+// reflects the handshake version and options the connection was established
+// with, on both ends of the connection.
+func TestConnectionState(t *testing.T) {
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		opts          []ConnOption
+		localOpts     []func(*Machine)
+		remoteOpts    []func(*Machine)
+		exchange      bool
+		expectedState ConnectionState
+	}{
+		{
+			name: "xk",
+			expectedState: ConnectionState{
+				Version:          HandshakeVersion,
+				HandshakePattern: HandshakePatternXK,
+				ProtocolName:     protocolName,
+				Cipher:           CipherChaChaPoly,
+				RekeyInterval:    keyRotationInterval,
+			},
+		},
+		{
+			name: "xx",
+			localOpts: []func(*Machine){
+				WithHandshakeVersion(HandshakeVersionXX),
+			},
+			remoteOpts: []func(*Machine){
+				AcceptHandshakeVersions(
+					HandshakeVersion, HandshakeVersionXX,
+				),
+			},
+			expectedState: ConnectionState{
+				Version:          HandshakeVersionXX,
+				HandshakePattern: HandshakePatternXX,
+				ProtocolName:     protocolNameXX,
+				Cipher:           CipherChaChaPoly,
+				RekeyInterval:    keyRotationInterval,
+			},
+		},
+		{
+			name:     "compression",
+			opts:     []ConnOption{Compression(64)},
+			exchange: true,
+			expectedState: ConnectionState{
+				Version:          HandshakeVersion,
+				HandshakePattern: HandshakePatternXK,
+				ProtocolName:     protocolName,
+				Cipher:           CipherChaChaPoly,
+				RekeyInterval:    keyRotationInterval,
+				Compression:      true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			local, remote, err := newPipe(
+				localPriv, remotePriv, newConnConfig(test.opts),
+				test.localOpts, test.remoteOpts,
+			)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				local.Close()
+				remote.Close()
+			})
+
+			if test.exchange {
+				exchangeFeaturesPipe(t, local, remote)
+			}
+
+			require.Equal(
+				t, test.expectedState, local.ConnectionState(),
+			)
+			require.Equal(
+				t, test.expectedState, remote.ConnectionState(),
+			)
+		})
+	}
+}
+
+// TestConnNonces asserts that the send and receive nonces of both ends of a
+// connection advance in lockstep as messages are exchanged, and that a desync
+// shows up as a mismatch between the sender's SendNonce and the receiver's
+// RecvNonce.
+func TestConnNonces(t *testing.T) {
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	local, remote, err := NewPipe(localPriv, remotePriv)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		local.Close()
+		remote.Close()
+	})
+
+	// A freshly established connection hasn't used any nonces yet.
+	require.Zero(t, local.SendNonce())
+	require.Zero(t, local.RecvNonce())
+	require.Zero(t, remote.SendNonce())
+	require.Zero(t, remote.RecvNonce())
+
+	// Exchange a number of messages in both directions, each of which
+	// consumes a nonce for its header and one for its body.
+	const numMsgs = 10
+	msg := []byte("hello")
+	for i := 0; i < numMsgs; i++ {
+		writeErr := make(chan error, 1)
+		go func() {
+			_, err := local.Write(msg)
+			writeErr <- err
+		}()
+
+		readMsg, err := remote.ReadNextMessage()
+		require.NoError(t, err)
+		require.Equal(t, msg, readMsg)
+		require.NoError(t, <-writeErr)
+
+		go func() {
+			_, err := remote.Write(msg)
+			writeErr <- err
+		}()
+
+		readMsg, err = local.ReadNextMessage()
+		require.NoError(t, err)
+		require.Equal(t, msg, readMsg)
+		require.NoError(t, <-writeErr)
+	}
+
+	require.EqualValues(t, 2*numMsgs, local.SendNonce())
+	require.EqualValues(t, 2*numMsgs, local.RecvNonce())
+	require.EqualValues(t, 2*numMsgs, remote.SendNonce())
+	require.EqualValues(t, 2*numMsgs, remote.RecvNonce())
+
+	// Desync the connection by encrypting a message on the local end that
+	// never makes it onto the wire.
+	local.noise.sendCipher.Encrypt(nil, nil, msg)
+	require.NotEqual(t, local.SendNonce(), remote.RecvNonce())
+
+	// The remote end now fails to decrypt the next message, which was
+	// encrypted under a nonce it doesn't expect.
+	go func() {
+		_, _ = local.Write(msg)
+	}()
+
+	_, err = remote.ReadNextMessage()
+	require.Error(t, err)
+}
+
+// TestCloseUnblocksIO asserts that closing a connection promptly unblocks a
+// Read waiting on an idle connection, and a Write waiting on the peer, with
+// both failing with ErrConnClosed.
+func TestCloseUnblocksIO(t *testing.T) {
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		op   func(c *Conn) error
+	}{
+		{
+			name: "read",
+			op: func(c *Conn) error {
+				_, err := c.Read(make([]byte, 10))
+				return err
+			},
+		},
+		{
+			name: "read next message",
+			op: func(c *Conn) error {
+				_, err := c.ReadNextMessage()
+				return err
+			},
+		},
+		{
+			// As the pipe is synchronous, the write blocks until
+			// the peer reads it, which it never does.
+			name: "write",
+			op: func(c *Conn) error {
+				_, err := c.Write([]byte("hello"))
+				return err
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			local, remote, err := NewPipe(localPriv, remotePriv)
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				remote.Close()
+			})
+
+			errChan := make(chan error, 1)
+			go func() {
+				errChan <- test.op(local)
+			}()
+
+			// Give the operation time to block before closing the
+			// connection from another goroutine.
+			select {
+			case err := <-errChan:
+				t.Fatalf("operation didn't block: %v", err)
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			require.NoError(t, local.Close())
+
+			select {
+			case err := <-errChan:
+				require.ErrorIs(t, err, ErrConnClosed)
+			case <-time.After(time.Second):
+				t.Fatalf("operation not unblocked by close")
+			}
+		})
+	}
+}
+
+// TestNewListenerWithConfig asserts that a listener created with a custom
+// net.ListenConfig binds its socket through it, restricted to the configured
+// network, and accepts connections as usual.
+func TestNewListenerWithConfig(t *testing.T) {
+	serverPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	serverKey := &keychain.PrivKeyECDH{PrivKey: serverPriv}
+
+	// The control function records the socket it is invoked for, which
+	// happens before the socket is bound.
+	var (
+		controlNetwork string
+		controlFd      uintptr
+		controlCalls   int
+	)
+	listenCfg := ListenerConfig{
+		ListenConfig: &net.ListenConfig{
+			Control: func(network, _ string,
+				c syscall.RawConn) error {
+
+				controlCalls++
+				controlNetwork = network
+
+				return c.Control(func(fd uintptr) {
+					controlFd = fd
+				})
+			},
+		},
+		Network: "tcp4",
+	}
+
+	listener, err := NewListenerWithConfig(
+		serverKey, "127.0.0.1:0", listenCfg,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	require.Equal(t, 1, controlCalls)
+	require.Equal(t, "tcp4", controlNetwork)
+	require.NotZero(t, controlFd)
+
+	listenAddr := listener.Addr().(*net.TCPAddr)
+	require.NotNil(t, listenAddr.IP.To4())
+
+	acceptChan := make(chan maybeNetConn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		acceptChan <- maybeNetConn{conn, err}
+	}()
+
+	clientPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	conn, err := Dial(
+		&keychain.PrivKeyECDH{PrivKey: clientPriv},
+		&lnwire.NetAddress{
+			IdentityKey: serverPriv.PubKey(),
+			Address:     listenAddr,
+		},
+		tor.DefaultConnTimeout, net.DialTimeout,
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	select {
+	case accepted := <-acceptChan:
+		require.NoError(t, accepted.err)
+		require.True(t, accepted.conn.(*Conn).RemotePub().IsEqual(
+			clientPriv.PubKey(),
+		))
+		accepted.conn.Close()
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("connection not accepted")
+	}
+
+	// Networks other than TCP ones should be rejected.
+	_, err = NewListenerWithConfig(
+		serverKey, "127.0.0.1:0", ListenerConfig{Network: "udp"},
+	)
+	require.ErrorIs(t, err, ErrUnsupportedListenNetwork)
+}
+
+// TestInvalidHandshakeKey asserts that a malformed ephemeral key received in
+// act one or act two aborts the handshake with ErrInvalidHandshakeKey before
+// any ECDH is performed with it.
+func TestInvalidHandshakeKey(t *testing.T) {
+	t.Parallel()
+
+	// An x coordinate of all ones exceeds the field prime, such that it
+	// can't be a point on the curve.
+	invalidKeys := map[string][]byte{
+		"zero": make([]byte, 33),
+		"bad prefix": append(
+			[]byte{0x04}, bytes.Repeat([]byte{0x01}, 32)...,
+		),
+		"x above prime": append(
+			[]byte{0x02}, bytes.Repeat([]byte{0xff}, 32)...,
+		),
+	}
+
+	newMachines := func(t *testing.T) (*Machine, *Machine) {
+		initiatorPriv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		responderPriv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		initiator := NewBrontideMachine(
+			true, &keychain.PrivKeyECDH{PrivKey: initiatorPriv},
+			responderPriv.PubKey(),
+		)
+		responder := NewBrontideMachine(
+			false, &keychain.PrivKeyECDH{PrivKey: responderPriv},
+			nil,
+		)
+
+		return initiator, responder
+	}
+
+	for name, key := range invalidKeys {
+		key := key
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// The responder should reject a malformed ephemeral
+			// key in act one.
+			initiator, responder := newMachines(t)
+
+			actOne, err := initiator.GenActOne()
+			require.NoError(t, err)
+			copy(actOne[1:34], key)
+
+			err = responder.RecvActOne(actOne)
+			require.ErrorIs(t, err, ErrInvalidHandshakeKey)
+			require.Nil(t, responder.remoteEphemeral)
+
+			// Likewise, the initiator should reject one in act
+			// two.
+			initiator, responder = newMachines(t)
+
+			actOne, err = initiator.GenActOne()
+			require.NoError(t, err)
+			require.NoError(t, responder.RecvActOne(actOne))
+
+			actTwo, err := responder.GenActTwo()
+			require.NoError(t, err)
+			copy(actTwo[1:34], key)
+
+			err = initiator.RecvActTwo(actTwo)
+			require.ErrorIs(t, err, ErrInvalidHandshakeKey)
+			require.Nil(t, initiator.remoteEphemeral)
+		})
+	}
+}
+
+// TestPacketConn asserts that each message written through a MessageConn is
+// read back whole by exactly one ReadMessage, in order, and that messages too
+// large for a single frame are rejected rather than split.
+func TestPacketConn(t *testing.T) {
+	localPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	remotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	local, remote, err := NewPipe(localPriv, remotePriv)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		local.Close()
+		remote.Close()
+	})
+
+	sender, receiver := local.PacketConn(), remote.PacketConn()
+
+	sizes := []int{0, 1, 17, 1000, 0, 4096, math.MaxUint16, 2}
+	msgs := make([][]byte, len(sizes))
+	for i, size := range sizes {
+		msgs[i] = make([]byte, size)
+		_, err := rand.Read(msgs[i])
+		require.NoError(t, err)
+	}
+
+	// Since the pipe is synchronous, the writes are executed in their own
+	// goroutine.
+	errChan := make(chan error, 1)
+	go func() {
+		for _, msg := range msgs {
+			if err := sender.WriteMessage(msg); err != nil {
+				errChan <- err
+				return
+			}
+		}
+		errChan <- nil
+	}()
+
+	for i, msg := range msgs {
+		readMsg, err := receiver.ReadMessage()
+		require.NoError(t, err)
+		require.NotNil(t, readMsg, "message %d", i)
+		require.Equal(t, msg, readMsg, "message %d", i)
+	}
+	require.NoError(t, <-errChan)
+
+	// A message that doesn't fit in a single frame should be rejected
+	// without writing anything.
+	err = sender.WriteMessage(make([]byte, math.MaxUint16+1))
+	require.ErrorIs(t, err, ErrMaxMessageLengthExceeded)
+
+	// The connection should remain usable in the other direction too.
+	go func() {
+		errChan <- receiver.WriteMessage([]byte("pong"))
+	}()
+
+	readMsg, err := sender.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, []byte("pong"), readMsg)
+	require.NoError(t, <-errChan)
+}
@@ -0,0 +1,162 @@
+package brontide
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+const (
+	// pingFrameType is the type prefixing a ping control frame. It is odd,
+	// such that a peer speaking the Lightning wire protocol that doesn't
+	// support ping frames ignores it as an unknown optional message.
+	pingFrameType uint16 = 0xfffd
+
+	// pongFrameType is the type prefixing a pong control frame, sent in
+	// response to a ping frame.
+	pongFrameType uint16 = 0xffff
+
+	// pingNonceSize is the size of the nonce carried by ping and pong
+	// frames, used to match each pong to its ping.
+	pingNonceSize = 8
+
+	// controlFrameSize is the size of the plaintext of a ping or pong
+	// frame, a 2-byte type followed by the nonce.
+	controlFrameSize = 2 + pingNonceSize
+)
+
+// ErrPingDisabled is returned by Ping if the connection wasn't created with
+// the EnablePing option.
+var ErrPingDisabled = errors.New("ping not enabled on brontide connection")
+
+// pingNonce is the nonce carried by a ping frame and echoed by its pong.
+type pingNonce [pingNonceSize]byte
+
+// encodeControlFrame returns the plaintext of a control frame of the given
+// type carrying nonce.
+func encodeControlFrame(frameType uint16, nonce pingNonce) []byte {
+	frame := make([]byte, controlFrameSize)
+	binary.BigEndian.PutUint16(frame[:2], frameType)
+	copy(frame[2:], nonce[:])
+
+	return frame
+}
+
+// Ping measures the round-trip time of the connection by sending a ping frame
+// carrying a random nonce, and waiting for the pong echoing it. The elapsed
+// time between writing the ping and receiving the matching pong is returned.
+// If ctx is done before then, ctx.Err() is returned.
+//
+// Both peers must enable ping frames using the EnablePing option, otherwise
+// ErrPingDisabled is returned. The pong is consumed by the read path of the
+// connection, so the application must concurrently be reading from it using
+// ReadNextMessage, ReadNextMessageTimeout, Messages or Read for Ping to
+// return. Multiple pings may be outstanding at once.
+func (c *Conn) Ping(ctx context.Context) (time.Duration, error) {
+	if c.isClosed() {
+		return 0, ErrConnClosed
+	}
+
+	if !c.pingEnabled {
+		return 0, ErrPingDisabled
+	}
+
+	var nonce pingNonce
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return 0, err
+	}
+
+	pong := make(chan struct{})
+
+	c.pingMtx.Lock()
+	if c.pendingPings == nil {
+		c.pendingPings = make(map[pingNonce]chan struct{})
+	}
+	c.pendingPings[nonce] = pong
+	c.pingMtx.Unlock()
+
+	defer func() {
+		c.pingMtx.Lock()
+		delete(c.pendingPings, nonce)
+		c.pingMtx.Unlock()
+	}()
+
+	start := c.clock.Now()
+	err := c.writeControlFrame(encodeControlFrame(pingFrameType, nonce))
+	if err != nil {
+		return 0, err
+	}
+
+	select {
+	case <-pong:
+		return c.clock.Now().Sub(start), nil
+
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// handleControlFrame processes msg if it is a ping or pong frame, returning
+// true if it was consumed such that it isn't delivered to the application. A
+// ping is answered with a pong echoing its nonce, while a pong completes the
+// outstanding Ping with the matching nonce, if any.
+func (c *Conn) handleControlFrame(msg []byte) (bool, error) {
+	if !c.pingEnabled || len(msg) != controlFrameSize {
+		return false, nil
+	}
+
+	var nonce pingNonce
+	copy(nonce[:], msg[2:])
+
+	switch binary.BigEndian.Uint16(msg[:2]) {
+	case pingFrameType:
+		pong := encodeControlFrame(pongFrameType, nonce)
+		return true, c.writeControlFrame(pong)
+
+	case pongFrameType:
+		c.pingMtx.Lock()
+		pong, ok := c.pendingPings[nonce]
+		if ok {
+			close(pong)
+			delete(c.pendingPings, nonce)
+		}
+		c.pingMtx.Unlock()
+
+		return true, nil
+
+	default:
+		return false, nil
+	}
+}
+
+// writeControlFrame writes a control frame to the connection. If a message
+// buffered using WriteMessage is awaiting Flush, the frame can't be written
+// without interleaving the two, and is instead written by that Flush.
+func (c *Conn) writeControlFrame(frame []byte) error {
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+
+	noise := c.noise
+	if len(noise.nextHeaderSend) > 0 || len(noise.nextBodySend) > 0 {
+		c.pendingControl = append(c.pendingControl, frame)
+		return nil
+	}
+
+	// If frames are awaiting a coalesced flush, the control frame must
+	// follow them, so it's appended and written along with them.
+	if len(c.coalesceBuf) > 0 {
+		var err error
+		c.coalesceBuf, err = noise.appendFrame(c.coalesceBuf, frame)
+		if err != nil {
+			return err
+		}
+
+		return c.flushCoalesced()
+	}
+
+	_, err := c.noise.WriteMessages(c.wireWriter(), [][]byte{frame})
+
+	return err
+}
@@ -0,0 +1,61 @@
+package brontide
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPolicyViolation is returned when the parameters of a handshake don't
+// meet the SecurityPolicy configured using WithSecurityPolicy.
+var ErrPolicyViolation = errors.New("connection violates security policy")
+
+// SecurityPolicy describes the minimum requirements the parameters of a
+// connection must meet, such that an operator can refuse to silently
+// downgrade to weaker modes of the protocol.
+type SecurityPolicy struct {
+	// MinVersion is the lowest handshake version a connection may use.
+	MinVersion byte
+
+	// RequireRekey, if true, requires the keys of the session to be
+	// rotated periodically. Rekeying is currently always part of the
+	// protocol, such that this only guards against future modes without
+	// it.
+	RequireRekey bool
+}
+
+// check returns an error wrapping ErrPolicyViolation if the given connection
+// parameters don't meet the policy.
+func (p *SecurityPolicy) check(state ConnectionState) error {
+	if state.Version < p.MinVersion {
+		return fmt.Errorf("%w: handshake version %d below minimum %d",
+			ErrPolicyViolation, state.Version, p.MinVersion)
+	}
+
+	if p.RequireRekey && state.RekeyInterval == 0 {
+		return fmt.Errorf("%w: rekeying disabled", ErrPolicyViolation)
+	}
+
+	return nil
+}
+
+// WithSecurityPolicy is a functional option that aborts handshakes whose
+// parameters don't meet the given policy with ErrPolicyViolation. Dial
+// refuses to initiate a handshake that would violate it, while a Listener
+// rejects a dialer as soon as its choice of handshake is known, before
+// responding to it.
+func WithSecurityPolicy(policy SecurityPolicy) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.securityPolicy = &policy
+	}
+}
+
+// checkSecurityPolicy returns an error wrapping ErrPolicyViolation if the
+// handshake parameters of the connection don't meet its security policy, if
+// any.
+func (c *Conn) checkSecurityPolicy() error {
+	if c.securityPolicy == nil {
+		return nil
+	}
+
+	return c.securityPolicy.check(c.ConnectionState())
+}
@@ -0,0 +1,337 @@
+package brontide
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/tor"
+)
+
+// ReconnectingConn is a brontide connection which transparently recovers from
+// transport failures. When a read or write fails because the underlying
+// connection was lost, the same NetAddress is dialed again, a fresh handshake
+// is performed, the caller's setup function is run over the new connection,
+// and the failed operation is retried. Failures are only surfaced to the
+// caller once the RetryPolicy has been exhausted.
+//
+// Only the transport is recovered. Any bytes that were in flight when the
+// connection was lost are gone, and a write interrupted by the failure is
+// retried in full over the new connection, so the peer may have seen it
+// twice. Any message-level state, such as feature negotiation, subscriptions
+// or the retransmission of unacknowledged messages, is the responsibility of
+// the caller, and must be re-established by the setup function.
+type ReconnectingConn struct {
+	local   keychain.SingleKeyECDH
+	netAddr *lnwire.NetAddress
+	timeout time.Duration
+	dialer  tor.DialFunc
+	setup   func(*Conn) error
+	policy  RetryPolicy
+	opts    []ConnOption
+
+	// reconnectMtx serializes reconnection attempts, such that concurrent
+	// readers and writers observing the same failure only redial once.
+	reconnectMtx sync.Mutex
+
+	// mtx guards the fields below it.
+	mtx           sync.Mutex
+	conn          *Conn
+	gen           uint64
+	readDeadline  time.Time
+	writeDeadline time.Time
+
+	closed int32
+}
+
+// A compile-time assertion to ensure that ReconnectingConn meets the net.Conn
+// interface.
+var _ net.Conn = (*ReconnectingConn)(nil)
+
+// DialReconnecting dials the remote peer as done by DialWithRetry, runs setup
+// over the established connection, and returns a ReconnectingConn which
+// repeats both steps whenever the connection is lost. The setup function is
+// invoked with each new connection before it's handed to any reader or
+// writer, and should restore whatever message-level state the caller's
+// protocol requires. A nil setup function is permitted.
+//
+// The policy governs each reconnection, such that up to MaxAttempts dials
+// are made before the failure is returned to the caller. A setup function
+// failing due to a transport error counts as a failed attempt, while any
+// other error is returned immediately.
+func DialReconnecting(local keychain.SingleKeyECDH,
+	netAddr *lnwire.NetAddress, timeout time.Duration, dialer tor.DialFunc,
+	setup func(*Conn) error, policy RetryPolicy,
+	opts ...ConnOption) (*ReconnectingConn, error) {
+
+	c := &ReconnectingConn{
+		local:   local,
+		netAddr: netAddr,
+		timeout: timeout,
+		dialer:  dialer,
+		setup:   setup,
+		policy:  policy,
+		opts:    opts,
+	}
+
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+
+	return c, nil
+}
+
+// dial establishes a new connection to the remote peer and runs the setup
+// function over it, retrying according to the policy.
+func (c *ReconnectingConn) dial() (*Conn, error) {
+	var (
+		clk  = newConnConfig(c.opts).clock
+		conn *Conn
+		err  error
+	)
+	for attempt := 0; attempt == 0 || attempt < c.policy.MaxAttempts; {
+		conn, err = Dial(
+			c.local, c.netAddr, c.timeout, c.dialer, c.opts...,
+		)
+		if err == nil && c.setup != nil {
+			if err = c.setup(conn); err != nil {
+				conn.Close()
+			}
+		}
+
+		switch {
+		case err == nil:
+			return conn, nil
+
+		case !isRetriableErr(err) && !isTransportErr(err):
+			return nil, err
+		}
+
+		attempt++
+		if attempt < c.policy.MaxAttempts {
+			<-clk.TickAfter(c.policy.backoff(attempt))
+		}
+	}
+
+	return nil, err
+}
+
+// current returns the active connection along with its generation, which is
+// incremented each time the connection is replaced.
+func (c *ReconnectingConn) current() (*Conn, uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.conn, c.gen
+}
+
+// reconnect replaces the connection of the given generation, which has
+// failed with err, returning the new connection and its generation. If the
+// connection was already replaced by a concurrent caller, that connection is
+// returned instead. The original error is returned if it isn't the result of
+// a transport failure, and ErrConnClosed if the ReconnectingConn has been
+// closed.
+func (c *ReconnectingConn) reconnect(gen uint64, err error) (*Conn, uint64,
+	error) {
+
+	if c.isClosed() {
+		return nil, 0, ErrConnClosed
+	}
+	if !isTransportErr(err) {
+		return nil, 0, err
+	}
+
+	c.reconnectMtx.Lock()
+	defer c.reconnectMtx.Unlock()
+
+	if conn, curGen := c.current(); curGen != gen {
+		return conn, curGen, nil
+	}
+
+	c.mtx.Lock()
+	c.conn.Close()
+	c.mtx.Unlock()
+
+	conn, dialErr := c.dial()
+	if dialErr != nil {
+		return nil, 0, dialErr
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// If we were closed while dialing, Close will have missed the new
+	// connection, so we close it ourselves.
+	if c.isClosed() {
+		conn.Close()
+		return nil, 0, ErrConnClosed
+	}
+
+	// Carry over any deadlines set by the caller.
+	if !c.readDeadline.IsZero() {
+		conn.SetReadDeadline(c.readDeadline)
+	}
+	if !c.writeDeadline.IsZero() {
+		conn.SetWriteDeadline(c.writeDeadline)
+	}
+
+	c.conn = conn
+	c.gen++
+
+	return conn, c.gen, nil
+}
+
+// isTransportErr returns true if the error indicates that the underlying
+// connection has been lost, rather than the operation having timed out or the
+// peer misbehaving. ErrConnClosed is included, as a connection may be closed
+// by a concurrent reconnection while another caller is using it.
+func isTransportErr(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return false
+	}
+
+	return errors.Is(err, ErrConnClosed) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// Read reads data from the connection, reconnecting and retrying the read if
+// the connection is lost.
+//
+// Part of the net.Conn interface.
+func (c *ReconnectingConn) Read(b []byte) (int, error) {
+	if c.isClosed() {
+		return 0, ErrConnClosed
+	}
+
+	conn, gen := c.current()
+	for {
+		n, err := conn.Read(b)
+		if err == nil {
+			return n, nil
+		}
+
+		conn, gen, err = c.reconnect(gen, err)
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// Write writes data to the connection, reconnecting and writing the data in
+// full over the new connection if the connection is lost.
+//
+// Part of the net.Conn interface.
+func (c *ReconnectingConn) Write(b []byte) (int, error) {
+	if c.isClosed() {
+		return 0, ErrConnClosed
+	}
+
+	conn, gen := c.current()
+	for {
+		n, err := conn.Write(b)
+		if err == nil {
+			return n, nil
+		}
+
+		conn, gen, err = c.reconnect(gen, err)
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// Close closes the connection, after which no further reconnection attempts
+// are made. Close is idempotent.
+//
+// Part of the net.Conn interface.
+func (c *ReconnectingConn) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return nil
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.conn.Close()
+}
+
+// isClosed returns true if Close has been called on the connection.
+func (c *ReconnectingConn) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
+
+// LocalAddr returns the local network address of the active connection.
+//
+// Part of the net.Conn interface.
+func (c *ReconnectingConn) LocalAddr() net.Addr {
+	conn, _ := c.current()
+	return conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address of the active connection.
+//
+// Part of the net.Conn interface.
+func (c *ReconnectingConn) RemoteAddr() net.Addr {
+	conn, _ := c.current()
+	return conn.RemoteAddr()
+}
+
+// RemotePub returns the remote peer's static public key.
+func (c *ReconnectingConn) RemotePub() *btcec.PublicKey {
+	return c.netAddr.IdentityKey
+}
+
+// SetDeadline sets the read and write deadlines of the connection. The
+// deadlines are carried over to any new connection.
+//
+// Part of the net.Conn interface.
+func (c *ReconnectingConn) SetDeadline(t time.Time) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.readDeadline = t
+	c.writeDeadline = t
+
+	return c.conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the deadline for future Read calls. The deadline is
+// carried over to any new connection.
+//
+// Part of the net.Conn interface.
+func (c *ReconnectingConn) SetReadDeadline(t time.Time) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.readDeadline = t
+
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for future Write calls. The deadline is
+// carried over to any new connection.
+//
+// Part of the net.Conn interface.
+func (c *ReconnectingConn) SetWriteDeadline(t time.Time) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.writeDeadline = t
+
+	return c.conn.SetWriteDeadline(t)
+}
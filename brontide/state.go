@@ -0,0 +1,104 @@
+package brontide
+
+const (
+	// HandshakePatternXK is the Noise handshake pattern used when the
+	// initiator knows the responder's static key upfront, which is the case
+	// for HandshakeVersion.
+	HandshakePatternXK = "XK"
+
+	// HandshakePatternXX is the Noise handshake pattern used when neither
+	// side knows the other's static key upfront, which is the case for
+	// HandshakeVersionXX.
+	HandshakePatternXX = "XX"
+
+	// CipherChaChaPoly is the name of the AEAD used to encrypt every
+	// brontide session, ChaCha20-Poly1305 as specified in RFC 8439.
+	CipherChaChaPoly = "ChaCha20-Poly1305"
+)
+
+// ConnectionState describes the parameters a brontide connection was
+// established with, analogous to tls.ConnectionState. It is intended for
+// diagnostics and auditing, and is returned by Conn.ConnectionState.
+type ConnectionState struct {
+	// Version is the handshake version negotiated for the connection.
+	Version byte
+
+	// HandshakePattern is the Noise handshake pattern carried out to
+	// establish the connection, either HandshakePatternXK or
+	// HandshakePatternXX.
+	HandshakePattern string
+
+	// ProtocolName is the full name of the Noise protocol instantiated by
+	// the handshake, which both sides mix into the handshake digest.
+	ProtocolName string
+
+	// Cipher is the name of the AEAD encrypting the messages of the
+	// session, which is always CipherChaChaPoly.
+	Cipher string
+
+	// RekeyInterval is the number of encryptions, or decryptions,
+	// performed using a key before it is rotated forwards. As the length
+	// header and body of a message are encrypted separately, this amounts
+	// to half as many messages. Rekeying is part of the protocol, and as
+	// such is always enabled, independently for each direction.
+	RekeyInterval uint64
+
+	// Compression is true if the connection negotiated payload compression
+	// using ExchangeFeatures, see the Compression option.
+	Compression bool
+}
+
+// protocolName returns the name of the Noise protocol instantiated by the
+// session's handshake.
+func (b *Machine) protocolName() string {
+	if b.isXX() {
+		return protocolNameXX
+	}
+
+	return protocolName
+}
+
+// ConnectionState returns the parameters the connection was established with.
+// The handshake parameters are fixed once the connection is returned by Dial
+// or Accept, while Compression is only final once ExchangeFeatures has
+// returned, and MUST NOT be queried concurrently with it.
+func (c *Conn) ConnectionState() ConnectionState {
+	pattern := HandshakePatternXK
+	if c.noise.isXX() {
+		pattern = HandshakePatternXX
+	}
+
+	return ConnectionState{
+		Version:          c.noise.HandshakeVersion(),
+		HandshakePattern: pattern,
+		ProtocolName:     c.noise.protocolName(),
+		Cipher:           CipherChaChaPoly,
+		RekeyInterval:    keyRotationInterval,
+		Compression:      c.compress,
+	}
+}
+
+// SendNonce returns the nonce that will be used for the next encryption under
+// the connection's current sending key. It is intended for diagnosing peers
+// that have fallen out of sync: on a healthy connection, it matches the
+// RecvNonce of the remote end once all messages in flight have been read.
+// Querying it has no effect on the connection.
+//
+// NOTE: As the length header and body of each message are encrypted
+// separately, the nonce advances by two per message written, and is reset to
+// zero whenever the key is rotated, every RekeyInterval encryptions.
+func (c *Conn) SendNonce() uint64 {
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+
+	return c.noise.sendCipher.nonce
+}
+
+// RecvNonce returns the nonce that will be used for the next decryption under
+// the connection's current receiving key. Like SendNonce, it
+// advances by two per message read and is reset on every key rotation. It is
+// intended for diagnostics only, and MUST NOT be called concurrently with
+// reads from the connection.
+func (c *Conn) RecvNonce() uint64 {
+	return c.noise.recvCipher.nonce
+}
@@ -0,0 +1,81 @@
+package brontide
+
+import "sync/atomic"
+
+// GlobalStats is a snapshot of the aggregate state of all brontide connections
+// in the process, as returned by Stats. It is suitable for export as gauges
+// and counters to a metrics system such as Prometheus.
+type GlobalStats struct {
+	// ActiveConns is the number of connections that completed the
+	// handshake and haven't been closed yet.
+	ActiveConns int64
+
+	// BytesIn is the total number of plaintext bytes read from all
+	// connections.
+	BytesIn uint64
+
+	// BytesOut is the total number of plaintext bytes written to all
+	// connections.
+	BytesOut uint64
+
+	// HandshakeSuccesses is the total number of handshakes completed by
+	// either side.
+	HandshakeSuccesses uint64
+
+	// HandshakeFailures is the total number of handshakes that failed on
+	// either side, including those that timed out.
+	HandshakeFailures uint64
+}
+
+// globalStats holds the counters reported by Stats. They are updated on the
+// read and write paths of every connection, and are therefore maintained
+// using atomic operations rather than a mutex, so that connections don't
+// contend with each other. All fields MUST be used atomically.
+var globalStats struct {
+	activeConns        int64
+	bytesIn            uint64
+	bytesOut           uint64
+	handshakeSuccesses uint64
+	handshakeFailures  uint64
+}
+
+// Stats returns a snapshot of the aggregate statistics of all brontide
+// connections in the process. As the counters are read individually, a
+// snapshot taken while connections are in use may not reflect a single
+// instant.
+func Stats() GlobalStats {
+	return GlobalStats{
+		ActiveConns: atomic.LoadInt64(&globalStats.activeConns),
+		BytesIn:     atomic.LoadUint64(&globalStats.bytesIn),
+		BytesOut:    atomic.LoadUint64(&globalStats.bytesOut),
+		HandshakeSuccesses: atomic.LoadUint64(
+			&globalStats.handshakeSuccesses,
+		),
+		HandshakeFailures: atomic.LoadUint64(
+			&globalStats.handshakeFailures,
+		),
+	}
+}
+
+// recordHandshake records the outcome of the connection's handshake in the
+// global statistics, registering the connection as active if it succeeded.
+func (c *Conn) recordHandshake(err error) {
+	if err != nil {
+		atomic.AddUint64(&globalStats.handshakeFailures, 1)
+		return
+	}
+
+	atomic.AddUint64(&globalStats.handshakeSuccesses, 1)
+	atomic.AddInt64(&globalStats.activeConns, 1)
+	c.tracked = true
+}
+
+// untrack deregisters the connection from the global count of active
+// connections, if it was registered by a successful handshake.
+//
+// NOTE: This method MUST only be called once, when the connection is closed.
+func (c *Conn) untrack() {
+	if c.tracked {
+		atomic.AddInt64(&globalStats.activeConns, -1)
+	}
+}
@@ -0,0 +1,146 @@
+package brontide
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/lightningnetwork/lnd/keychain"
+	"github.com/lightningnetwork/lnd/tor"
+)
+
+// ErrIdentityMismatch is returned by Dial when using TOFU if the remote peer
+// presents a static key that differs from the one pinned for its address.
+var ErrIdentityMismatch = errors.New("remote static key does not match " +
+	"pinned key")
+
+// TOFUStore pins the static keys of remote peers to their addresses, allowing
+// Dial to trust a peer's key on first use, and to require the same key on all
+// subsequent dials.
+type TOFUStore interface {
+	// Get returns the static key pinned for the address, if any.
+	Get(addr net.Addr) (*btcec.PublicKey, bool)
+
+	// Put pins the static key for the address.
+	Put(addr net.Addr, pub *btcec.PublicKey)
+}
+
+// TOFU is a functional option that enables trust-on-first-use when dialing a
+// remote peer whose static key isn't known, that is, with a nil IdentityKey.
+// If a key is pinned for the address in the store, the regular XK handshake is
+// carried out against it. Otherwise, the XX handshake is used to learn the
+// peer's key, which is then pinned in the store. Should the handshake against
+// a pinned key fail, the key is learned once more, and ErrIdentityMismatch is
+// returned if it differs.
+//
+// The listener must accept HandshakeVersionXX for the first dial to succeed.
+// As the first connection to each address is unauthenticated, this is only
+// suitable for controlled environments such as lab setups. The option is
+// ignored by NewListener.
+func TOFU(store TOFUStore) ConnOption {
+	return func(cfg *connConfig) {
+		cfg.tofuStore = store
+	}
+}
+
+// dialTOFU dials the remote peer at addr, authenticating it against the key
+// pinned in the config's TOFU store, or learning and pinning its key if none
+// is pinned yet.
+func dialTOFU(local keychain.SingleKeyECDH, addr net.Addr,
+	timeout time.Duration, dialer tor.DialFunc,
+	cfg *connConfig) (*Conn, error) {
+
+	store := cfg.tofuStore
+
+	pinned, ok := store.Get(addr)
+	if !ok {
+		conn, err := dialXX(local, addr, timeout, dialer, cfg)
+		if err != nil {
+			return nil, err
+		}
+		store.Put(addr, conn.RemotePub())
+
+		return conn, nil
+	}
+
+	conn, err := dialConn(local, pinned, addr, timeout, dialer, cfg)
+	if err == nil {
+		return conn, nil
+	}
+
+	// If the peer couldn't be reached, then there's nothing to learn about
+	// its identity.
+	if isRetriableErr(err) {
+		return nil, err
+	}
+
+	// Otherwise, the handshake may have failed because the peer's static
+	// key changed, so we'll learn its current key to find out.
+	probe, probeErr := dialXX(local, addr, timeout, dialer, cfg)
+	if probeErr != nil {
+		return nil, err
+	}
+	probe.Close()
+
+	if remotePub := probe.RemotePub(); !remotePub.IsEqual(pinned) {
+		return nil, fmt.Errorf("%w: pinned %x, got %x",
+			ErrIdentityMismatch, pinned.SerializeCompressed(),
+			remotePub.SerializeCompressed())
+	}
+
+	return nil, err
+}
+
+// dialXX dials the remote peer at addr using the XX handshake, learning its
+// static key in the process.
+func dialXX(local keychain.SingleKeyECDH, addr net.Addr,
+	timeout time.Duration, dialer tor.DialFunc,
+	cfg *connConfig) (*Conn, error) {
+
+	xxCfg := *cfg
+	xxCfg.handshakeVersion = HandshakeVersionXX
+
+	return dialConn(local, nil, addr, timeout, dialer, &xxCfg)
+}
+
+// MemoryTOFUStore is a TOFUStore that pins static keys in memory, keyed by the
+// string form of the address.
+type MemoryTOFUStore struct {
+	mu   sync.Mutex
+	pins map[string]*btcec.PublicKey
+}
+
+// A compile-time assertion to ensure that MemoryTOFUStore meets the TOFUStore
+// interface.
+var _ TOFUStore = (*MemoryTOFUStore)(nil)
+
+// NewMemoryTOFUStore returns an empty MemoryTOFUStore.
+func NewMemoryTOFUStore() *MemoryTOFUStore {
+	return &MemoryTOFUStore{
+		pins: make(map[string]*btcec.PublicKey),
+	}
+}
+
+// Get returns the static key pinned for the address, if any.
+//
+// NOTE: This is part of the TOFUStore interface.
+func (s *MemoryTOFUStore) Get(addr net.Addr) (*btcec.PublicKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pub, ok := s.pins[addr.String()]
+	return pub, ok
+}
+
+// Put pins the static key for the address.
+//
+// NOTE: This is part of the TOFUStore interface.
+func (s *MemoryTOFUStore) Put(addr net.Addr, pub *btcec.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pins[addr.String()] = pub
+}
@@ -0,0 +1,129 @@
+// Package aead provides the ChaCha20-Poly1305 helpers shared by the brontide
+// transport and the watchtower blob encoding, such that a single
+// implementation handles nonce construction and sealing/opening for both.
+package aead
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// KeySize is the size of a ChaCha20-Poly1305 key, 32 bytes.
+	KeySize = chacha20poly1305.KeySize
+
+	// MACSize is the size of the Poly1305 tag appended to each ciphertext,
+	// 16 bytes.
+	MACSize = chacha20poly1305.Overhead
+)
+
+// ErrCiphertextTooShort is returned when opening a nonce-prefixed ciphertext
+// that is too short to contain both the nonce and the MAC.
+var ErrCiphertextTooShort = errors.New("ciphertext too short")
+
+// Cipher wraps a ChaCha20-Poly1305 AEAD, either the IETF variant with a
+// 12-byte nonce or the extended XChaCha20 variant with a 24-byte nonce.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New creates a Cipher using the IETF ChaCha20-Poly1305 construction, which is
+// meant to be used with counter-based nonces via SealCounter and OpenCounter.
+func New(key []byte) (*Cipher, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// NewX creates a Cipher using the XChaCha20-Poly1305 construction, whose
+// 24-byte nonce is large enough to be chosen at random via SealRandom.
+func NewX(key []byte) (*Cipher, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Wrap creates a Cipher from an existing AEAD implementation, allowing callers
+// to substitute an optimized or alternative construction for the ones returned
+// by New and NewX.
+func Wrap(aead cipher.AEAD) *Cipher {
+	return &Cipher{aead: aead}
+}
+
+// NonceSize returns the size of the nonce used by the cipher.
+func (c *Cipher) NonceSize() int {
+	return c.aead.NonceSize()
+}
+
+// counterNonce encodes a message counter as a 12-byte nonce, consisting of
+// four zero bytes followed by the little-endian counter, as specified by
+// BOLT 8.
+func counterNonce(counter uint64) [chacha20poly1305.NonceSize]byte {
+	var nonce [chacha20poly1305.NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[4:], counter)
+
+	return nonce
+}
+
+// SealCounter encrypts and authenticates plaintext and ad using a nonce
+// derived from the given counter, appending the result to dst.
+//
+// NOTE: This is only valid for ciphers created with New.
+func (c *Cipher) SealCounter(dst []byte, counter uint64, plaintext,
+	ad []byte) []byte {
+
+	nonce := counterNonce(counter)
+
+	return c.aead.Seal(dst, nonce[:], plaintext, ad)
+}
+
+// OpenCounter authenticates and decrypts ciphertext and ad using a nonce
+// derived from the given counter, appending the result to dst.
+//
+// NOTE: This is only valid for ciphers created with New.
+func (c *Cipher) OpenCounter(dst []byte, counter uint64, ciphertext,
+	ad []byte) ([]byte, error) {
+
+	nonce := counterNonce(counter)
+
+	return c.aead.Open(dst, nonce[:], ciphertext, ad)
+}
+
+// SealRandom encrypts and authenticates plaintext and ad using a nonce read
+// from rand, appending the nonce followed by the ciphertext to dst.
+func (c *Cipher) SealRandom(dst []byte, rand io.Reader, plaintext,
+	ad []byte) ([]byte, error) {
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand, nonce); err != nil {
+		return nil, err
+	}
+
+	dst = append(dst, nonce...)
+
+	return c.aead.Seal(dst, nonce, plaintext, ad), nil
+}
+
+// OpenPrefixed authenticates and decrypts a ciphertext produced by SealRandom,
+// whose nonce is stored in its first NonceSize bytes, appending the result to
+// dst.
+func (c *Cipher) OpenPrefixed(dst, ciphertext, ad []byte) ([]byte, error) {
+	nonceSize := c.aead.NonceSize()
+	if len(ciphertext) < nonceSize+MACSize {
+		return nil, ErrCiphertextTooShort
+	}
+
+	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
+
+	return c.aead.Open(dst, nonce, ciphertext, ad)
+}
@@ -0,0 +1,75 @@
+package aead
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+var (
+	testKey       = bytes.Repeat([]byte{0x42}, KeySize)
+	testPlaintext = []byte("hello, world")
+	testAD        = []byte("associated data")
+)
+
+// TestCounterCipher asserts that SealCounter and OpenCounter produce the same
+// ciphertext as the BOLT 8 nonce construction used by brontide: four zero
+// bytes followed by the little-endian message counter.
+func TestCounterCipher(t *testing.T) {
+	c, err := New(testKey)
+	require.NoError(t, err)
+
+	ref, err := chacha20poly1305.New(testKey)
+	require.NoError(t, err)
+
+	for _, counter := range []uint64{0, 1, 999, 1 << 40} {
+		var nonce [12]byte
+		binary.LittleEndian.PutUint64(nonce[4:], counter)
+		expCiphertext := ref.Seal(nil, nonce[:], testPlaintext, testAD)
+
+		ciphertext := c.SealCounter(nil, counter, testPlaintext, testAD)
+		require.Equal(t, expCiphertext, ciphertext)
+
+		plaintext, err := c.OpenCounter(nil, counter, ciphertext, testAD)
+		require.NoError(t, err)
+		require.Equal(t, testPlaintext, plaintext)
+
+		// Opening with the wrong counter should fail authentication.
+		_, err = c.OpenCounter(nil, counter+1, ciphertext, testAD)
+		require.Error(t, err)
+	}
+}
+
+// TestRandomCipher asserts that SealRandom prefixes the ciphertext with the
+// nonce read from the reader, matching the encoding used by watchtower blobs,
+// and that OpenPrefixed reverses it.
+func TestRandomCipher(t *testing.T) {
+	c, err := NewX(testKey)
+	require.NoError(t, err)
+	require.Equal(t, chacha20poly1305.NonceSizeX, c.NonceSize())
+
+	ref, err := chacha20poly1305.NewX(testKey)
+	require.NoError(t, err)
+
+	nonce := bytes.Repeat([]byte{0x01}, chacha20poly1305.NonceSizeX)
+	expCiphertext := ref.Seal(
+		append([]byte{}, nonce...), nonce, testPlaintext, testAD,
+	)
+
+	ciphertext, err := c.SealRandom(
+		nil, bytes.NewReader(nonce), testPlaintext, testAD,
+	)
+	require.NoError(t, err)
+	require.Equal(t, expCiphertext, ciphertext)
+
+	plaintext, err := c.OpenPrefixed(nil, ciphertext, testAD)
+	require.NoError(t, err)
+	require.Equal(t, testPlaintext, plaintext)
+
+	// A ciphertext too short to hold the nonce and MAC is rejected.
+	_, err = c.OpenPrefixed(nil, ciphertext[:c.NonceSize()], testAD)
+	require.ErrorIs(t, err, ErrCiphertextTooShort)
+}
@@ -495,6 +495,13 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		// since we are resolving a local address.
 		listeners[i], err = brontide.NewListener(
 			nodeKeyECDH, listenAddr.String(),
+			brontide.OnHandshakeError(
+				func(addr net.Addr, err error) {
+					srvrLog.Debugf("Unable to complete "+
+						"handshake with %v: %v", addr,
+						err)
+				},
+			),
 		)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,125 @@
+package blob
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"sync"
+
+	"github.com/lightningnetwork/lnd/internal/aead"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ErrIncompatibleAEAD is returned when encrypting or decrypting a blob with an
+// AEAD produced by the factory set via SetAEADFactory whose nonce or MAC size
+// differs from that of xchacha20poly1305, or with an AEADProvider whose output
+// differs in size from that of xchacha20poly1305, as blobs of the same type
+// must have a constant size.
+var ErrIncompatibleAEAD = errors.New("aead nonce or mac size does not " +
+	"match blob encoding")
+
+// AEADFactory constructs the AEAD used to encrypt and decrypt blobs under the
+// given 32-byte key.
+type AEADFactory func(key []byte) (cipher.AEAD, error)
+
+var (
+	// aeadFactoryMtx guards aeadFactory.
+	aeadFactoryMtx sync.RWMutex
+
+	// aeadFactory is the AEAD constructor used by Encrypt and Decrypt.
+	aeadFactory AEADFactory = chacha20poly1305.NewX
+)
+
+// SetAEADFactory replaces the AEAD constructor used to encrypt and decrypt
+// blobs, allowing hardware accelerated or otherwise specialized builds, as well
+// as benchmarks, to substitute their own implementation of xchacha20poly1305.
+// Passing nil restores the default implementation. It is safe to call
+// concurrently with Encrypt and Decrypt.
+//
+// NOTE: The produced AEAD must be interoperable with xchacha20poly1305, or
+// blobs encrypted with it can't be decrypted by towers and clients using the
+// default implementation.
+func SetAEADFactory(f AEADFactory) {
+	if f == nil {
+		f = chacha20poly1305.NewX
+	}
+
+	aeadFactoryMtx.Lock()
+	aeadFactory = f
+	aeadFactoryMtx.Unlock()
+}
+
+// newCipher constructs the cipher for the given key using the configured AEAD
+// factory.
+func newCipher(key BreachKey) (*aead.Cipher, error) {
+	aeadFactoryMtx.RLock()
+	factory := aeadFactory
+	aeadFactoryMtx.RUnlock()
+
+	a, err := factory(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	if a.NonceSize() != NonceSize || a.Overhead() != MACSize {
+		return nil, ErrIncompatibleAEAD
+	}
+
+	return aead.Wrap(a), nil
+}
+
+// AEADProvider seals and opens the plaintext of blobs on behalf of
+// EncryptWithProvider and DecryptWithProvider, allowing the breach key to be
+// held outside of the process, for instance wrapped by a key management
+// service. A sealed ciphertext must have the same layout and size as one
+// produced by Encrypt, namely a 24-byte nonce followed by the encrypted
+// plaintext and a 16-byte MAC, which Open must accept in turn. Providers
+// wrapping xchacha20poly1305 under the breach key therefore remain
+// interoperable with Encrypt and Decrypt.
+type AEADProvider interface {
+	// Seal encrypts and authenticates the plaintext along with the
+	// associated data, returning the nonce followed by the ciphertext.
+	Seal(plaintext, aad []byte) ([]byte, error)
+
+	// Open authenticates and decrypts a ciphertext returned by Seal along
+	// with the associated data, returning the plaintext.
+	Open(ciphertext, aad []byte) ([]byte, error)
+}
+
+// keyProvider is an AEADProvider encrypting in process under a breach key,
+// using the AEAD produced by the configured factory.
+type keyProvider struct {
+	key BreachKey
+}
+
+// NewKeyProvider returns an AEADProvider that encrypts in process under the
+// given breach key, using the AEAD factory set via SetAEADFactory. Blobs
+// encrypted with it are identical in format to those returned by Encrypt.
+func NewKeyProvider(key BreachKey) AEADProvider {
+	return &keyProvider{key: key}
+}
+
+// Seal encrypts the plaintext under a random nonce, which prefixes the
+// returned ciphertext.
+//
+// NOTE: Part of the AEADProvider interface.
+func (p *keyProvider) Seal(plaintext, aad []byte) ([]byte, error) {
+	cipher, err := newCipher(p.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.SealRandom(nil, rand.Reader, plaintext, aad)
+}
+
+// Open decrypts a ciphertext prefixed by its nonce.
+//
+// NOTE: Part of the AEADProvider interface.
+func (p *keyProvider) Open(ciphertext, aad []byte) ([]byte, error) {
+	cipher, err := newCipher(p.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.OpenPrefixed(nil, ciphertext, aad)
+}
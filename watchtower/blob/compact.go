@@ -0,0 +1,292 @@
+package blob
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/lnwire"
+)
+
+// ErrCompactTrailingBytes signals that a compact JusticeKit encoding
+// contained extra bytes after the final field.
+var ErrCompactTrailingBytes = errors.New("trailing bytes in compact kit")
+
+// SerializeCompact encodes the JusticeKit using a compact encoding meant for
+// at-rest storage only. Unlike the constant-size plaintext that is encrypted
+// and sent to a tower, the compact encoding omits all padding and any blank
+// commit to-remote fields, and encodes the CSV delay as a varint. It must
+// never be used on the wire, since its length leaks the contents of the kit.
+//
+// compact encoding:
+//
+//	blob type:                       2 bytes
+//	sweep address length:            1 byte
+//	sweep address:                   n bytes
+//	revocation pubkey:              33 bytes
+//	local delay pubkey:             33 bytes
+//	csv delay:                       1-5 bytes, varint
+//	commit to-local revocation sig: 64 bytes
+//	has commit to-remote:            1 byte
+//	commit to-remote pubkey:        33 bytes, if present
+//	commit to-remote sig:           64 bytes, if present
+//	number of second-level sigs:     1 byte, if supported by the type
+//	second-level revocation sigs:   64 bytes each
+//	data commitment length:          1 byte, if supported by the type
+//	data commitment:                 n bytes
+//	lease expiry:                    4 bytes, if supported by the type
+//	tlv trailer length:              2 bytes, if supported by the type
+//	tlv trailer:                     n bytes
+func (b *JusticeKit) SerializeCompact() ([]byte, error) {
+	if len(b.SweepAddress) > MaxSweepAddrSize {
+		return nil, ErrSweepAddressToLong
+	}
+
+	var w bytes.Buffer
+
+	err := binary.Write(&w, byteOrder, uint16(b.BlobType))
+	if err != nil {
+		return nil, err
+	}
+
+	w.WriteByte(uint8(len(b.SweepAddress)))
+	w.Write(b.SweepAddress)
+	w.Write(b.RevocationPubKey[:])
+	w.Write(b.LocalDelayPubKey[:])
+
+	err = wire.WriteVarInt(&w, 0, uint64(b.CSVDelay))
+	if err != nil {
+		return nil, err
+	}
+
+	w.Write(b.CommitToLocalSig.RawBytes())
+
+	// Only write the commit to-remote fields if the kit has a commit
+	// to-remote output, mirroring which fields are populated on decode.
+	if b.HasCommitToRemoteOutput() {
+		w.WriteByte(1)
+		w.Write(b.CommitToRemotePubKey[:])
+		w.Write(b.CommitToRemoteSig.RawBytes())
+	} else {
+		w.WriteByte(0)
+	}
+
+	// Finally, write only the second-level HTLC signatures that are
+	// present, if the blob type carries them.
+	if b.BlobType.Has(FlagSecondLevelHtlcs) {
+		if len(b.SecondLevelHtlcSigs) > MaxNumHTLCs(b.BlobType) {
+			return nil, ErrTooManyHTLCs
+		}
+
+		w.WriteByte(uint8(len(b.SecondLevelHtlcSigs)))
+		for _, sig := range b.SecondLevelHtlcSigs {
+			w.Write(sig.RawBytes())
+		}
+	}
+
+	if b.BlobType.Has(FlagDataCommitment) {
+		if len(b.DataCommitment) > MaxDataCommitmentSize {
+			return nil, ErrDataCommitmentTooLong
+		}
+
+		w.WriteByte(uint8(len(b.DataCommitment)))
+		w.Write(b.DataCommitment)
+	}
+
+	if b.BlobType.Has(FlagLeaseChannel) {
+		var leaseExpiry [4]byte
+		byteOrder.PutUint32(leaseExpiry[:], b.LeaseExpiry)
+		w.Write(leaseExpiry[:])
+	}
+
+	if b.BlobType.Has(FlagTLVTrailer) {
+		trailer, err := b.serializeTLVTrailer()
+		if err != nil {
+			return nil, err
+		}
+
+		var trailerLen [2]byte
+		byteOrder.PutUint16(trailerLen[:], uint16(len(trailer)))
+		w.Write(trailerLen[:])
+		w.Write(trailer)
+	}
+
+	return w.Bytes(), nil
+}
+
+// DeserializeCompact reconstructs a JusticeKit from the compact encoding
+// produced by SerializeCompact.
+func DeserializeCompact(compact []byte) (*JusticeKit, error) {
+	r := bytes.NewReader(compact)
+
+	var (
+		kit      JusticeKit
+		blobType uint16
+	)
+	if err := binary.Read(r, byteOrder, &blobType); err != nil {
+		return nil, err
+	}
+	kit.BlobType = Type(blobType)
+
+	sweepAddrLen, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if sweepAddrLen > MaxSweepAddrSize {
+		return nil, malformedBlob(ErrSweepAddressToLong)
+	}
+	if int(sweepAddrLen) > r.Len() {
+		return nil, malformedBlob(io.ErrUnexpectedEOF)
+	}
+
+	kit.SweepAddress = make([]byte, sweepAddrLen)
+	if _, err := io.ReadFull(r, kit.SweepAddress); err != nil {
+		return nil, err
+	}
+
+	if _, err := io.ReadFull(r, kit.RevocationPubKey[:]); err != nil {
+		return nil, err
+	}
+
+	if _, err := io.ReadFull(r, kit.LocalDelayPubKey[:]); err != nil {
+		return nil, err
+	}
+
+	csvDelay, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if csvDelay > math.MaxUint32 {
+		return nil, fmt.Errorf("invalid csv delay: %d", csvDelay)
+	}
+	kit.CSVDelay = uint32(csvDelay)
+
+	kit.CommitToLocalSig, err = readCompactSig(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Taproot channels use a schnorr signature to spend the revocation
+	// path of the to-local output.
+	if kit.BlobType.IsTaprootChannel() {
+		kit.CommitToLocalSig.ForceSchnorr()
+	}
+
+	hasCommitToRemote, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	if hasCommitToRemote != 0 {
+		_, err := io.ReadFull(r, kit.CommitToRemotePubKey[:])
+		if err != nil {
+			return nil, err
+		}
+
+		kit.CommitToRemoteSig, err = readCompactSig(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// Taproot channels use a schnorr signature to spend the
+		// to-remote output.
+		if kit.BlobType.IsTaprootChannel() {
+			kit.CommitToRemoteSig.ForceSchnorr()
+		}
+	}
+
+	if kit.BlobType.Has(FlagSecondLevelHtlcs) {
+		numSigs, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if int(numSigs) > MaxNumHTLCs(kit.BlobType) {
+			return nil, malformedBlob(ErrTooManyHTLCs)
+		}
+		if int(numSigs)*64 > r.Len() {
+			return nil, malformedBlob(io.ErrUnexpectedEOF)
+		}
+
+		for i := 0; i < int(numSigs); i++ {
+			sig, err := readCompactSig(r)
+			if err != nil {
+				return nil, err
+			}
+
+			kit.SecondLevelHtlcSigs = append(
+				kit.SecondLevelHtlcSigs, sig,
+			)
+		}
+	}
+
+	if kit.BlobType.Has(FlagDataCommitment) {
+		dataLen, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if dataLen > MaxDataCommitmentSize {
+			return nil, malformedBlob(ErrDataCommitmentTooLong)
+		}
+		if int(dataLen) > r.Len() {
+			return nil, malformedBlob(io.ErrUnexpectedEOF)
+		}
+
+		if dataLen > 0 {
+			kit.DataCommitment = make([]byte, dataLen)
+			_, err := io.ReadFull(r, kit.DataCommitment)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if kit.BlobType.Has(FlagLeaseChannel) {
+		err := binary.Read(r, byteOrder, &kit.LeaseExpiry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if kit.BlobType.Has(FlagTLVTrailer) {
+		var trailerLen uint16
+		err := binary.Read(r, byteOrder, &trailerLen)
+		if err != nil {
+			return nil, err
+		}
+		if trailerLen > MaxTLVTrailerSize {
+			return nil, malformedBlob(ErrTLVTrailerTooLong)
+		}
+		if int(trailerLen) > r.Len() {
+			return nil, malformedBlob(io.ErrUnexpectedEOF)
+		}
+
+		trailer := make([]byte, trailerLen)
+		if _, err := io.ReadFull(r, trailer); err != nil {
+			return nil, err
+		}
+
+		if err := kit.deserializeTLVTrailer(trailer); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Len() != 0 {
+		return nil, ErrCompactTrailingBytes
+	}
+
+	return &kit, nil
+}
+
+// readCompactSig reads a 64-byte fixed-size signature from the reader.
+func readCompactSig(r io.Reader) (lnwire.Sig, error) {
+	var sig [64]byte
+	if _, err := io.ReadFull(r, sig[:]); err != nil {
+		return lnwire.Sig{}, err
+	}
+
+	return lnwire.NewSigFromWireECDSA(sig[:])
+}
@@ -0,0 +1,79 @@
+package blob_test
+
+import (
+	"testing"
+
+	"github.com/lightningnetwork/lnd/watchtower/blob"
+	"github.com/stretchr/testify/require"
+)
+
+// TestJusticeKitCompactRoundTrip asserts that a JusticeKit survives a round
+// trip through the compact encoding, and that the compact encoding is smaller
+// than the padded plaintext.
+func TestJusticeKitCompactRoundTrip(t *testing.T) {
+	for _, test := range descriptorTests {
+		if test.encErr != nil || test.decErr != nil {
+			continue
+		}
+
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kit := &blob.JusticeKit{
+				BlobType:             test.encVersion,
+				SweepAddress:         test.sweepAddr,
+				RevocationPubKey:     test.revPubKey,
+				LocalDelayPubKey:     test.delayPubKey,
+				CSVDelay:             test.csvDelay,
+				CommitToLocalSig:     test.commitToLocalSig,
+				CommitToRemotePubKey: test.commitToRemotePubKey,
+				CommitToRemoteSig:    test.commitToRemoteSig,
+				DataCommitment:       test.dataCommitment,
+				TrailerRecords:       test.trailerRecords,
+			}
+
+			compact, err := kit.SerializeCompact()
+			require.NoError(t, err)
+			require.Less(
+				t, len(compact), blob.PlaintextSize(kit.BlobType),
+			)
+
+			kit2, err := blob.DeserializeCompact(compact)
+			require.NoError(t, err)
+			require.Equal(t, kit, kit2)
+
+			// Any trailing bytes should be rejected.
+			_, err = blob.DeserializeCompact(append(compact, 0x00))
+			require.ErrorIs(t, err, blob.ErrCompactTrailingBytes)
+		})
+	}
+}
+
+// TestJusticeKitCompactSweepAddrTooLong asserts that kits with an oversized
+// sweep address are rejected by the compact encoding.
+func TestJusticeKitCompactSweepAddrTooLong(t *testing.T) {
+	kit := &blob.JusticeKit{
+		BlobType:     blob.TypeAltruistCommit,
+		SweepAddress: makeAddr(blob.MaxSweepAddrSize + 1),
+	}
+
+	_, err := kit.SerializeCompact()
+	require.ErrorIs(t, err, blob.ErrSweepAddressToLong)
+}
+
+// TestJusticeKitCompactMalformedLengths asserts that the compact decoding
+// rejects length prefixes exceeding the remaining bytes with ErrMalformedBlob.
+func TestJusticeKitCompactMalformedLengths(t *testing.T) {
+	// A sweep address length within bounds, but past the end of the
+	// encoding.
+	truncated := []byte{0x00, byte(blob.TypeAltruistCommit), 22, 0x00}
+
+	_, err := blob.DeserializeCompact(truncated)
+	require.ErrorIs(t, err, blob.ErrMalformedBlob)
+
+	// A sweep address length exceeding the maximum.
+	tooLong := []byte{0x00, byte(blob.TypeAltruistCommit), 0xff}
+
+	_, err = blob.DeserializeCompact(tooLong)
+	require.ErrorIs(t, err, blob.ErrMalformedBlob)
+	require.ErrorIs(t, err, blob.ErrSweepAddressToLong)
+}
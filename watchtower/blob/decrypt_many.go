@@ -0,0 +1,64 @@
+package blob
+
+import (
+	"runtime"
+	"sync"
+)
+
+// KeyCiphertext pairs an encrypted blob with the breach key it was encrypted
+// under, as consumed by DecryptMany.
+type KeyCiphertext struct {
+	// Key is the breach key used to decrypt the ciphertext.
+	Key BreachKey
+
+	// Ciphertext is the encrypted blob, as returned by Encrypt.
+	Ciphertext []byte
+}
+
+// DecryptMany decrypts each of the given blobs using Decrypt, fanning out the
+// work across the given number of goroutines, or one per CPU if workers is
+// zero or negative. This allows towers recovering their database to decrypt
+// large numbers of blobs in parallel. The returned slices are parallel to
+// pairs: the i-th kit is the decryption of the i-th blob, or nil if its
+// decryption failed with the i-th error.
+func DecryptMany(pairs []KeyCiphertext, version Type,
+	workers int) ([]*JusticeKit, []error) {
+
+	kits := make([]*JusticeKit, len(pairs))
+	errs := make([]error, len(pairs))
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(pairs) {
+		workers = len(pairs)
+	}
+
+	// Each worker only ever writes to the entries of the indexes it
+	// receives, so the results don't need to be guarded by a mutex.
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				kits[i], errs[i] = Decrypt(
+					pairs[i].Key, pairs[i].Ciphertext,
+					version,
+				)
+			}
+		}()
+	}
+
+	for i := range pairs {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+
+	return kits, errs
+}
@@ -0,0 +1,109 @@
+package blob_test
+
+import (
+	"crypto/rand"
+	"sync"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/watchtower/blob"
+	"github.com/stretchr/testify/require"
+)
+
+// makeDecryptManyBatch encrypts numBlobs distinct kits, each under its own
+// key, corrupting every seventh ciphertext such that its decryption fails.
+func makeDecryptManyBatch(t *testing.T, numBlobs int) []blob.KeyCiphertext {
+	t.Helper()
+
+	pairs := make([]blob.KeyCiphertext, numBlobs)
+	for i := range pairs {
+		kit := &blob.JusticeKit{
+			BlobType:         blob.TypeAltruistAnchorCommit,
+			SweepAddress:     makeAddr(22),
+			RevocationPubKey: makePubKey(uint64(2 * i)),
+			LocalDelayPubKey: makePubKey(uint64(2*i + 1)),
+			CSVDelay:         uint32(i + 1),
+			CommitToLocalSig: makeSig(i + 1),
+		}
+
+		_, err := rand.Read(pairs[i].Key[:])
+		require.NoError(t, err)
+
+		pairs[i].Ciphertext, err = kit.Encrypt(pairs[i].Key)
+		require.NoError(t, err)
+
+		if i%7 == 0 {
+			pairs[i].Ciphertext[len(pairs[i].Ciphertext)-1] ^= 0x01
+		}
+	}
+
+	return pairs
+}
+
+// TestDecryptMany asserts that DecryptMany returns the same kits and errors as
+// decrypting each blob sequentially using Decrypt, in the order of the input,
+// for any number of workers.
+func TestDecryptMany(t *testing.T) {
+	const blobType = blob.TypeAltruistAnchorCommit
+
+	pairs := makeDecryptManyBatch(t, 1000)
+
+	expKits := make([]*blob.JusticeKit, len(pairs))
+	expErrs := make([]error, len(pairs))
+	for i, pair := range pairs {
+		expKits[i], expErrs[i] = blob.Decrypt(
+			pair.Key, pair.Ciphertext, blobType,
+		)
+	}
+
+	for _, workers := range []int{-1, 0, 1, 4, 2 * len(pairs)} {
+		kits, errs := blob.DecryptMany(pairs, blobType, workers)
+		require.Equal(t, expKits, kits, "workers=%d", workers)
+		require.Equal(t, expErrs, errs, "workers=%d", workers)
+	}
+
+	for i, err := range expErrs {
+		if i%7 == 0 {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+		}
+	}
+
+	// An empty batch yields empty results.
+	kits, errs := blob.DecryptMany(nil, blobType, 4)
+	require.Empty(t, kits)
+	require.Empty(t, errs)
+}
+
+// TestDecryptManyConcurrent runs several batches over the same input at once,
+// such that any state shared between decryptions is flagged by the race
+// detector.
+func TestDecryptManyConcurrent(t *testing.T) {
+	const (
+		blobType   = blob.TypeAltruistAnchorCommit
+		numBatches = 4
+	)
+
+	pairs := makeDecryptManyBatch(t, 200)
+	expKits, expErrs := blob.DecryptMany(pairs, blobType, 1)
+
+	var wg sync.WaitGroup
+	results := make([][]*blob.JusticeKit, numBatches)
+	errResults := make([][]error, numBatches)
+	for i := 0; i < numBatches; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			results[i], errResults[i] = blob.DecryptMany(
+				pairs, blobType, 8,
+			)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < numBatches; i++ {
+		require.Equal(t, expKits, results[i])
+		require.Equal(t, expErrs, errResults[i])
+	}
+}
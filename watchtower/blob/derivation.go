@@ -31,14 +31,26 @@ func (h BreachHint) String() string {
 }
 
 // BreachKey is computed as SHA256(txid || txid), which produces the key for
-// decrypting a client's encrypted blobs.
+// decrypting a client's encrypted blobs. If a salt is used, the key is instead
+// computed as SHA256(txid || txid || salt).
 type BreachKey [KeySize]byte
 
-// NewBreachKeyFromHash creates a breach key from a transaction ID.
-func NewBreachKeyFromHash(hash *chainhash.Hash) BreachKey {
+// NewBreachKeyFromHash creates a breach key from a transaction ID. This is the
+// canonical derivation agreed upon by clients and towers: clients encrypt each
+// blob under the key derived from the txid of the revoked commitment, and
+// towers derive the same key once that commitment is seen on chain. Any other
+// derivation produces blobs that towers are unable to decrypt.
+//
+// An optional salt may be mixed into the derivation, such that towers storing
+// blobs for the same client under different salts derive different keys for
+// the same breach, and are unable to correlate their ciphertexts even if one
+// learns the txid. The client and tower must agree upon the salt out of band.
+// A nil or empty salt yields the canonical, unsalted key.
+func NewBreachKeyFromHash(hash *chainhash.Hash, salt []byte) BreachKey {
 	h := sha256.New()
 	h.Write(hash[:])
 	h.Write(hash[:])
+	h.Write(salt)
 
 	var key BreachKey
 	copy(key[:], h.Sum(nil))
@@ -50,12 +62,22 @@ func (k BreachKey) String() string {
 	return hex.EncodeToString(k[:])
 }
 
+// IsZero returns true if the breach key is all zeros, as is the case for an
+// uninitialized BreachKey. Such a key is never the output of a derivation in
+// practice, and encrypting under it likely means the real key wasn't set.
+func (k BreachKey) IsZero() bool {
+	return k == BreachKey{}
+}
+
 // NewBreachHintAndKeyFromHash derives a BreachHint and BreachKey from a given
-// txid in a single pass. The hint and key are computed as:
+// txid in a single pass, mixing the optional salt into the key as done by
+// NewBreachKeyFromHash. The hint and key are computed as:
 //
 //	hint = SHA256(txid)
-//	key = SHA256(txid || txid)
-func NewBreachHintAndKeyFromHash(hash *chainhash.Hash) (BreachHint, BreachKey) {
+//	key = SHA256(txid || txid || salt)
+func NewBreachHintAndKeyFromHash(hash *chainhash.Hash,
+	salt []byte) (BreachHint, BreachKey) {
+
 	var (
 		hint BreachHint
 		key  BreachKey
@@ -65,6 +87,7 @@ func NewBreachHintAndKeyFromHash(hash *chainhash.Hash) (BreachHint, BreachKey) {
 	h.Write(hash[:])
 	copy(hint[:], h.Sum(nil))
 	h.Write(hash[:])
+	h.Write(salt)
 	copy(key[:], h.Sum(nil))
 
 	return hint, key
@@ -0,0 +1,111 @@
+package blob_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/lightningnetwork/lnd/watchtower/blob"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBreachKeyDerivation pins the breach hint and breach key derived from a
+// known txid, ensuring that clients and towers remain compatible.
+func TestBreachKeyDerivation(t *testing.T) {
+	txid, err := chainhash.NewHashFromStr(
+		"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+	)
+	require.NoError(t, err)
+
+	const (
+		expHint = "337e2a4de220e4518f2a8a4bd8bc2c82"
+		expKey  = "a316ecd8f3e6a3676668954e45ab5f676efac56d902c1eae4ab1" +
+			"46a5c613504a"
+	)
+
+	require.Equal(t, expHint, blob.NewBreachHintFromHash(txid).String())
+	require.Equal(
+		t, expKey, blob.NewBreachKeyFromHash(txid, nil).String(),
+	)
+
+	// Deriving both in a single pass should match the individual
+	// derivations.
+	hint, key := blob.NewBreachHintAndKeyFromHash(txid, nil)
+	require.Equal(t, expHint, hint.String())
+	require.Equal(t, expKey, key.String())
+}
+
+// TestBreachKeySalt asserts that mixing a salt into the breach key derivation
+// yields a distinct key per salt, such that a blob encrypted for one tower can
+// only be decrypted by a tower deriving the key with the same salt.
+func TestBreachKeySalt(t *testing.T) {
+	txid, err := chainhash.NewHashFromStr(
+		"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+	)
+	require.NoError(t, err)
+
+	// An empty salt is equivalent to the unsalted derivation.
+	require.Equal(
+		t, blob.NewBreachKeyFromHash(txid, nil),
+		blob.NewBreachKeyFromHash(txid, []byte{}),
+	)
+
+	var (
+		saltA = []byte("tower a")
+		saltB = []byte("tower b")
+		keyA  = blob.NewBreachKeyFromHash(txid, saltA)
+		keyB  = blob.NewBreachKeyFromHash(txid, saltB)
+	)
+	require.NotEqual(t, keyA, keyB)
+	require.NotEqual(t, blob.NewBreachKeyFromHash(txid, nil), keyA)
+
+	// The single pass derivation should agree with the salted key, while
+	// leaving the hint unsalted so that towers can still detect breaches.
+	hint, key := blob.NewBreachHintAndKeyFromHash(txid, saltA)
+	require.Equal(t, blob.NewBreachHintFromHash(txid), hint)
+	require.Equal(t, keyA, key)
+
+	kit := &blob.JusticeKit{
+		BlobType:         blob.TypeAltruistCommit,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	ctxtA, err := kit.Encrypt(keyA)
+	require.NoError(t, err)
+	ctxtB, err := kit.Encrypt(keyB)
+	require.NoError(t, err)
+	require.NotEqual(t, ctxtA, ctxtB)
+
+	// Each tower is able to decrypt the blob encrypted under its own
+	// salt, but not the one encrypted for the other tower.
+	for _, test := range []struct {
+		key     blob.BreachKey
+		ctxt    []byte
+		foreign []byte
+	}{
+		{key: keyA, ctxt: ctxtA, foreign: ctxtB},
+		{key: keyB, ctxt: ctxtB, foreign: ctxtA},
+	} {
+		kit2, err := blob.Decrypt(test.key, test.ctxt, kit.BlobType)
+		require.NoError(t, err)
+		require.Equal(t, kit, kit2)
+
+		_, err = blob.Decrypt(test.key, test.foreign, kit.BlobType)
+		require.Error(t, err)
+	}
+}
+
+// TestBreachKeyIsZero asserts that IsZero only reports the all-zero key.
+func TestBreachKeyIsZero(t *testing.T) {
+	var key blob.BreachKey
+	require.True(t, key.IsZero())
+
+	key[blob.KeySize-1] = 0x01
+	require.False(t, key.IsZero())
+
+	key = blob.NewBreachKeyFromHash(&chainhash.Hash{}, nil)
+	require.False(t, key.IsZero())
+}
@@ -0,0 +1,330 @@
+package blob
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+// ExportVersion is the version of the encoding produced by Export.
+const ExportVersion uint8 = 0
+
+const (
+	// exportHeaderSize is the size of the header preceding the TLV stream
+	// of an export, consisting of the version and the stream length.
+	exportHeaderSize = 1 + 4
+
+	// sigSize is the size of a raw signature.
+	sigSize = 64
+)
+
+// The TLV types of the records in an export. All of them are even, as a tool
+// must understand every field to make use of a kit, while unknown odd records
+// are ignored, allowing future versions to append optional fields.
+const (
+	exportTypeBlobType         tlv.Type = 0
+	exportTypeSweepAddress     tlv.Type = 2
+	exportTypeRevocationPubKey tlv.Type = 4
+	exportTypeLocalDelayPubKey tlv.Type = 6
+	exportTypeCSVDelay         tlv.Type = 8
+	exportTypeToLocalSig       tlv.Type = 10
+	exportTypeToRemotePubKey   tlv.Type = 12
+	exportTypeToRemoteSig      tlv.Type = 14
+	exportTypeHtlcSigs         tlv.Type = 16
+	exportTypeDataCommitment   tlv.Type = 18
+	exportTypeLeaseExpiry      tlv.Type = 20
+	exportTypeTrailerRecords   tlv.Type = 22
+)
+
+// requiredExportTypes are the records that must be present in every export.
+var requiredExportTypes = []tlv.Type{
+	exportTypeBlobType,
+	exportTypeSweepAddress,
+	exportTypeRevocationPubKey,
+	exportTypeLocalDelayPubKey,
+	exportTypeCSVDelay,
+	exportTypeToLocalSig,
+}
+
+var (
+	// ErrUnknownExportVersion is returned when importing a kit exported
+	// using a version of the encoding that isn't understood.
+	ErrUnknownExportVersion = errors.New(
+		"unknown justice kit export version",
+	)
+
+	// ErrInvalidExport is returned when importing a kit whose export is
+	// truncated, has trailing bytes, or is otherwise malformed.
+	ErrInvalidExport = errors.New("invalid justice kit export")
+)
+
+// Export encodes the decrypted JusticeKit using a stable, self-describing
+// encoding meant for exchanging kits with external tools, such as monitoring
+// software, which can then read the kit without knowledge of the breach key
+// or the padded plaintext. The export carries every field of the kit, and is
+// read back losslessly by Import. Empty optional fields are omitted, and are
+// imported as nil. Like the compact encoding, the export must never be sent
+// to a tower, since its length leaks the contents of the kit.
+//
+// export encoding:
+//
+//	version:                         1 byte
+//	tlv stream length:               4 bytes
+//	tlv stream:                      n bytes
+//
+// tlv records:
+//
+//	0:  blob type                    2 bytes
+//	2:  sweep address                n bytes
+//	4:  revocation pubkey           33 bytes
+//	6:  local delay pubkey          33 bytes
+//	8:  csv delay                    4 bytes
+//	10: commit to-local sig         64 bytes
+//	12: commit to-remote pubkey     33 bytes, if present
+//	14: commit to-remote sig        64 bytes, if present
+//	16: second-level htlc sigs      64 bytes each, if present
+//	18: data commitment              n bytes, if present
+//	20: lease expiry                 4 bytes, if non-zero
+//	22: tlv trailer                  n bytes, if present
+func (b *JusticeKit) Export() ([]byte, error) {
+	var (
+		blobType   = uint16(b.BlobType)
+		toLocalSig = rawSig(b.CommitToLocalSig)
+	)
+
+	records := []tlv.Record{
+		tlv.MakePrimitiveRecord(exportTypeBlobType, &blobType),
+		tlv.MakePrimitiveRecord(
+			exportTypeSweepAddress, &b.SweepAddress,
+		),
+		tlv.MakePrimitiveRecord(
+			exportTypeRevocationPubKey,
+			(*[33]byte)(&b.RevocationPubKey),
+		),
+		tlv.MakePrimitiveRecord(
+			exportTypeLocalDelayPubKey,
+			(*[33]byte)(&b.LocalDelayPubKey),
+		),
+		tlv.MakePrimitiveRecord(exportTypeCSVDelay, &b.CSVDelay),
+		tlv.MakePrimitiveRecord(exportTypeToLocalSig, &toLocalSig),
+	}
+
+	if b.HasCommitToRemoteOutput() {
+		toRemoteSig := rawSig(b.CommitToRemoteSig)
+
+		records = append(records,
+			tlv.MakePrimitiveRecord(
+				exportTypeToRemotePubKey,
+				(*[33]byte)(&b.CommitToRemotePubKey),
+			),
+			tlv.MakePrimitiveRecord(
+				exportTypeToRemoteSig, &toRemoteSig,
+			),
+		)
+	}
+
+	if len(b.SecondLevelHtlcSigs) > 0 {
+		htlcSigs := make([]byte, 0, len(b.SecondLevelHtlcSigs)*sigSize)
+		for _, sig := range b.SecondLevelHtlcSigs {
+			htlcSigs = append(htlcSigs, sig.RawBytes()...)
+		}
+
+		records = append(records, tlv.MakePrimitiveRecord(
+			exportTypeHtlcSigs, &htlcSigs,
+		))
+	}
+
+	if len(b.DataCommitment) > 0 {
+		records = append(records, tlv.MakePrimitiveRecord(
+			exportTypeDataCommitment, &b.DataCommitment,
+		))
+	}
+
+	if b.LeaseExpiry != 0 {
+		records = append(records, tlv.MakePrimitiveRecord(
+			exportTypeLeaseExpiry, &b.LeaseExpiry,
+		))
+	}
+
+	trailer, err := b.serializeTLVTrailer()
+	if err != nil {
+		return nil, err
+	}
+	if len(trailer) > 0 {
+		records = append(records, tlv.MakePrimitiveRecord(
+			exportTypeTrailerRecords, &trailer,
+		))
+	}
+
+	stream, err := tlv.NewStream(records...)
+	if err != nil {
+		return nil, err
+	}
+
+	var streamBuf bytes.Buffer
+	if err := stream.Encode(&streamBuf); err != nil {
+		return nil, err
+	}
+
+	streamLen := streamBuf.Len()
+
+	export := make([]byte, exportHeaderSize, exportHeaderSize+streamLen)
+	export[0] = ExportVersion
+	byteOrder.PutUint32(export[1:exportHeaderSize], uint32(streamLen))
+
+	return append(export, streamBuf.Bytes()...), nil
+}
+
+// Import reconstructs a JusticeKit from the encoding produced by Export,
+// returning it along with its blob type. The export is rejected with
+// ErrUnknownExportVersion if it was produced by an unknown version of the
+// encoding, and with ErrInvalidExport if it's truncated, has trailing bytes,
+// or lacks any of the required fields.
+func Import(data []byte) (*JusticeKit, Type, error) {
+	if len(data) < exportHeaderSize {
+		return nil, 0, fmt.Errorf("%w: missing header",
+			ErrInvalidExport)
+	}
+
+	if data[0] != ExportVersion {
+		return nil, 0, fmt.Errorf("%w: %d", ErrUnknownExportVersion,
+			data[0])
+	}
+
+	streamLen := byteOrder.Uint32(data[1:exportHeaderSize])
+	if uint64(streamLen) != uint64(len(data)-exportHeaderSize) {
+		return nil, 0, fmt.Errorf("%w: expected %d byte stream, got %d",
+			ErrInvalidExport, streamLen, len(data)-exportHeaderSize)
+	}
+
+	var (
+		kit         JusticeKit
+		blobType    uint16
+		toLocalSig  [sigSize]byte
+		toRemoteSig [sigSize]byte
+		htlcSigs    []byte
+		trailer     []byte
+	)
+
+	stream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(exportTypeBlobType, &blobType),
+		tlv.MakePrimitiveRecord(
+			exportTypeSweepAddress, &kit.SweepAddress,
+		),
+		tlv.MakePrimitiveRecord(
+			exportTypeRevocationPubKey,
+			(*[33]byte)(&kit.RevocationPubKey),
+		),
+		tlv.MakePrimitiveRecord(
+			exportTypeLocalDelayPubKey,
+			(*[33]byte)(&kit.LocalDelayPubKey),
+		),
+		tlv.MakePrimitiveRecord(exportTypeCSVDelay, &kit.CSVDelay),
+		tlv.MakePrimitiveRecord(exportTypeToLocalSig, &toLocalSig),
+		tlv.MakePrimitiveRecord(
+			exportTypeToRemotePubKey,
+			(*[33]byte)(&kit.CommitToRemotePubKey),
+		),
+		tlv.MakePrimitiveRecord(exportTypeToRemoteSig, &toRemoteSig),
+		tlv.MakePrimitiveRecord(exportTypeHtlcSigs, &htlcSigs),
+		tlv.MakePrimitiveRecord(
+			exportTypeDataCommitment, &kit.DataCommitment,
+		),
+		tlv.MakePrimitiveRecord(
+			exportTypeLeaseExpiry, &kit.LeaseExpiry,
+		),
+		tlv.MakePrimitiveRecord(exportTypeTrailerRecords, &trailer),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	parsedTypes, err := stream.DecodeWithParsedTypesP2P(
+		bytes.NewReader(data[exportHeaderSize:]),
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+
+	for _, typ := range requiredExportTypes {
+		if _, ok := parsedTypes[typ]; !ok {
+			return nil, 0, fmt.Errorf("%w: missing record %d",
+				ErrInvalidExport, typ)
+		}
+	}
+
+	// The commit to-remote pubkey and signature are only meaningful
+	// together.
+	_, hasToRemotePubKey := parsedTypes[exportTypeToRemotePubKey]
+	_, hasToRemoteSig := parsedTypes[exportTypeToRemoteSig]
+	if hasToRemotePubKey != hasToRemoteSig {
+		return nil, 0, fmt.Errorf("%w: incomplete commit to-remote",
+			ErrInvalidExport)
+	}
+
+	kit.BlobType = Type(blobType)
+
+	// Taproot channels use schnorr signatures to spend the commitment
+	// outputs.
+	kit.CommitToLocalSig, err = importSig(toLocalSig[:], kit.BlobType)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if hasToRemoteSig {
+		kit.CommitToRemoteSig, err = importSig(
+			toRemoteSig[:], kit.BlobType,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if len(htlcSigs)%sigSize != 0 {
+		return nil, 0, fmt.Errorf("%w: invalid second-level htlc "+
+			"sigs length %d", ErrInvalidExport, len(htlcSigs))
+	}
+
+	for i := 0; i < len(htlcSigs); i += sigSize {
+		sig, err := lnwire.NewSigFromWireECDSA(htlcSigs[i : i+sigSize])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		kit.SecondLevelHtlcSigs = append(kit.SecondLevelHtlcSigs, sig)
+	}
+
+	if len(trailer) > 0 {
+		if err := kit.deserializeTLVTrailer(trailer); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return &kit, kit.BlobType, nil
+}
+
+// rawSig returns the raw 64-byte encoding of sig.
+func rawSig(sig lnwire.Sig) [sigSize]byte {
+	var raw [sigSize]byte
+	copy(raw[:], sig.RawBytes())
+
+	return raw
+}
+
+// importSig parses a raw 64-byte commitment signature, which is a schnorr
+// signature for taproot blob types.
+func importSig(raw []byte, blobType Type) (lnwire.Sig, error) {
+	sig, err := lnwire.NewSigFromWireECDSA(raw)
+	if err != nil {
+		return lnwire.Sig{}, err
+	}
+
+	if blobType.IsTaprootChannel() {
+		sig.ForceSchnorr()
+	}
+
+	return sig, nil
+}
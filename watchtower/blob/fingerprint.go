@@ -0,0 +1,25 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// BlobsEqual reports whether the two encrypted blobs are byte-for-byte
+// identical, allowing a tower to detect duplicate blobs without decrypting
+// them. The comparison is performed in constant time with respect to the
+// contents of the blobs, such that the time taken doesn't reveal the position
+// of the first differing byte. Since blobs of the same type have a constant
+// size, comparing their lengths reveals nothing beyond their types.
+func BlobsEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// BlobFingerprint returns the SHA-256 digest of an encrypted blob, which is
+// suitable as a key for detecting duplicate blobs, e.g. in a map or database
+// index. As blobs are encrypted under a random nonce, only resubmissions of
+// the same ciphertext share a fingerprint, and the fingerprint reveals nothing
+// about the plaintext.
+func BlobFingerprint(ctxt []byte) [32]byte {
+	return sha256.Sum256(ctxt)
+}
@@ -0,0 +1,58 @@
+package blob_test
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/watchtower/blob"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBlobFingerprint asserts that identical blobs compare equal and share a
+// fingerprint, while blobs differing in a single byte, or in their length, do
+// not.
+func TestBlobFingerprint(t *testing.T) {
+	kit := &blob.JusticeKit{
+		BlobType:         blob.TypeAltruistCommit,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	dup := append([]byte(nil), ctxt...)
+	require.True(t, blob.BlobsEqual(ctxt, dup))
+	require.Equal(t, blob.BlobFingerprint(ctxt), blob.BlobFingerprint(dup))
+
+	// Flipping any single byte should be detected, wherever it is.
+	for _, i := range []int{0, len(ctxt) / 2, len(ctxt) - 1} {
+		mutated := append([]byte(nil), ctxt...)
+		mutated[i] ^= 0x01
+
+		require.False(t, blob.BlobsEqual(ctxt, mutated), "byte %d", i)
+		require.NotEqual(
+			t, blob.BlobFingerprint(ctxt),
+			blob.BlobFingerprint(mutated), "byte %d", i,
+		)
+	}
+
+	// A truncated blob is never equal to the original.
+	require.False(t, blob.BlobsEqual(ctxt, ctxt[:len(ctxt)-1]))
+
+	// Encrypting the same kit again uses a fresh nonce, so the blobs are
+	// distinct.
+	ctxt2, err := kit.Encrypt(key)
+	require.NoError(t, err)
+	require.False(t, blob.BlobsEqual(ctxt, ctxt2))
+	require.NotEqual(
+		t, blob.BlobFingerprint(ctxt), blob.BlobFingerprint(ctxt2),
+	)
+}
@@ -7,10 +7,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"sync"
 
 	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/internal/aead"
+	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"golang.org/x/crypto/chacha20poly1305"
 )
@@ -22,9 +29,20 @@ const (
 	// KeySize is the length of a chacha20poly1305 key, 32 bytes.
 	KeySize = chacha20poly1305.KeySize
 
+	// MACSize is the length of the poly1305 authentication tag appended to
+	// each ciphertext, 16 bytes.
+	MACSize = aead.MACSize
+
 	// CiphertextExpansion is the number of bytes padded to a plaintext
 	// encrypted with chacha20poly1305, which comes from a 16-byte MAC.
-	CiphertextExpansion = 16
+	CiphertextExpansion = MACSize
+
+	// Overhead is the fixed number of bytes an encrypted blob adds on top
+	// of its plaintext, the prepended nonce plus the MAC. The size of any
+	// encrypted blob is thus:
+	//
+	//	CiphertextSize = PlaintextSize + Overhead
+	Overhead = NonceSize + MACSize
 
 	// V0PlaintextSize is the plaintext size of a version 0 encoded blob.
 	//    sweep address length:            1 byte
@@ -37,9 +55,52 @@ const (
 	//    commit to-remote sig:           64 bytes, maybe blank
 	V0PlaintextSize = 274
 
+	// ToRemotePlaintextSize is the plaintext size of a blob with
+	// FlagToRemoteOnly.
+	//    sweep address length:            1 byte
+	//    padded sweep address:           42 bytes
+	//    commit to-remote pubkey:        33 bytes
+	//    commit to-remote sig:           64 bytes
+	ToRemotePlaintextSize = 140
+
 	// MaxSweepAddrSize defines the maximum sweep address size that can be
 	// encoded in a blob.
 	MaxSweepAddrSize = 42
+
+	// MaxSecondLevelHtlcs is the maximum number of second-level HTLC
+	// outputs whose revocation signatures can be carried by a blob.
+	MaxSecondLevelHtlcs = 8
+
+	// SecondLevelHtlcsSize is the size of the section appended to the
+	// plaintext of blobs with FlagSecondLevelHtlcs.
+	//    number of signatures:            1 byte
+	//    padded revocation sigs:        512 bytes
+	SecondLevelHtlcsSize = 1 + MaxSecondLevelHtlcs*64
+
+	// MaxDataCommitmentSize is the maximum size of the data commitment
+	// that can be carried by a blob with FlagDataCommitment.
+	MaxDataCommitmentSize = 32
+
+	// DataCommitmentSize is the size of the section appended to the
+	// plaintext of blobs with FlagDataCommitment.
+	//    data commitment length:          1 byte
+	//    padded data commitment:         32 bytes
+	DataCommitmentSize = 1 + MaxDataCommitmentSize
+
+	// LeaseExpirySize is the size of the section appended to the
+	// plaintext of blobs with FlagLeaseChannel.
+	//    lease expiry:                    4 bytes
+	LeaseExpirySize = 4
+
+	// ChannelPointHeaderSize is the length of the optional plaintext header
+	// carrying the channel point of a blob, a 32-byte txid followed by a
+	// 4-byte output index.
+	ChannelPointHeaderSize = 36
+
+	// CommitmentHeaderSize is the length of the optional plaintext header
+	// carrying the channel point of a blob followed by the 8-byte number of
+	// the breached commitment.
+	CommitmentHeaderSize = ChannelPointHeaderSize + 8
 )
 
 // Size returns the size of the encoded-and-encrypted blob in bytes.
@@ -48,19 +109,88 @@ const (
 //	enciphered plaintext:  n bytes
 //	MAC:                  16 bytes
 func Size(blobType Type) int {
-	return NonceSize + PlaintextSize(blobType) + CiphertextExpansion
+	return PlaintextSize(blobType) + Overhead
+}
+
+// CiphertextSize is an alias for Size, returning the number of bytes needed
+// to store an encrypted blob of the given type, excluding the optional channel
+// point header. It returns Overhead for unsupported blob types, whose plaintext
+// size is zero.
+func CiphertextSize(blobType Type) int {
+	return Size(blobType)
+}
+
+// StorageFootprint returns the number of bytes a tower needs to store an
+// encrypted blob of the given type, including the given fixed overhead of the
+// row indexing it, for the purpose of capacity planning. As blobs are padded
+// to a constant size, the footprint doesn't depend on hasCommitToRemote: a
+// blob without a commit to-remote output still carries its blank fields.
+// ErrUnknownBlobType is returned for unsupported blob types.
+func StorageFootprint(blobType Type, hasCommitToRemote bool,
+	indexOverhead int) (int, error) {
+
+	if PlaintextSize(blobType) == 0 {
+		return 0, ErrUnknownBlobType
+	}
+
+	if indexOverhead < 0 {
+		return 0, ErrNegativeIndexOverhead
+	}
+
+	return CiphertextSize(blobType) + indexOverhead, nil
+}
+
+// MaxNumHTLCs returns the maximum number of HTLC signatures that can be
+// carried by a blob of the given type. Decoding a blob that claims to carry
+// more fails with ErrTooManyHTLCs, before anything is allocated for them.
+func MaxNumHTLCs(blobType Type) int {
+	if blobType.Has(FlagSecondLevelHtlcs) {
+		return MaxSecondLevelHtlcs
+	}
+
+	return 0
 }
 
 // PlaintextSize returns the size of the encoded-but-unencrypted blob in bytes.
 func PlaintextSize(blobType Type) int {
 	switch {
+	// The to-remote only encoding has no room for the to-local and HTLC
+	// outputs, nor for any of the optional sections.
+	case blobType.Has(FlagToRemoteOnly):
+		if Flag(blobType)&toRemoteOnlyExcluded != 0 {
+			return 0
+		}
+
+		return ToRemotePlaintextSize
+
 	case blobType.Has(FlagCommitOutputs):
-		return V0PlaintextSize
+		size := V0PlaintextSize
+		if blobType.Has(FlagSecondLevelHtlcs) {
+			size += SecondLevelHtlcsSize
+		}
+		if blobType.Has(FlagDataCommitment) {
+			size += DataCommitmentSize
+		}
+		if blobType.Has(FlagLeaseChannel) {
+			size += LeaseExpirySize
+		}
+		if blobType.Has(FlagTLVTrailer) {
+			size += TLVTrailerSize
+		}
+
+		return size
+
 	default:
 		return 0
 	}
 }
 
+// toRemoteOnlyExcluded is the set of flags that can't be combined with
+// FlagToRemoteOnly.
+const toRemoteOnlyExcluded = FlagCommitOutputs | FlagSecondLevelHtlcs |
+	FlagDataCommitment | FlagTLVTrailer | FlagLeaseChannel |
+	FlagTaprootKeySpend
+
 var (
 	// byteOrder specifies a big-endian encoding of all integer values.
 	byteOrder = binary.BigEndian
@@ -69,6 +199,20 @@ var (
 	// blob encoding scheme.
 	ErrUnknownBlobType = errors.New("unknown blob type")
 
+	// ErrPlaintextSizeMismatch signals that an encoded blob did not have
+	// the constant plaintext size required by its blob type.
+	ErrPlaintextSizeMismatch = errors.New("plaintext size mismatch")
+
+	// ErrNoChannelPoint signals that a ciphertext was not created with a
+	// channel point header.
+	ErrNoChannelPoint = errors.New("ciphertext has no channel point header")
+
+	// ErrNoCommitmentNumber signals that a ciphertext was not created with
+	// a commitment header.
+	ErrNoCommitmentNumber = errors.New(
+		"ciphertext has no commitment number header",
+	)
+
 	// ErrCiphertextTooSmall is a decryption error signaling that the
 	// ciphertext is smaller than the ciphertext expansion factor.
 	ErrCiphertextTooSmall = errors.New(
@@ -81,6 +225,13 @@ var (
 		"cannot obtain commit to-remote p2wkh output script from blob",
 	)
 
+	// ErrNoCommitToLocalOutput is returned when trying to spend the commit
+	// to-local output of a blob with FlagToRemoteOnly, which doesn't carry
+	// it.
+	ErrNoCommitToLocalOutput = errors.New(
+		"blob does not carry the commit to-local output",
+	)
+
 	// ErrSweepAddressToLong is returned when trying to encode or decode a
 	// sweep address with length greater than the maximum length of 42
 	// bytes, which supports p2wkh and p2sh addresses.
@@ -88,6 +239,154 @@ var (
 		"sweep address must be less than or equal to %d bytes long",
 		MaxSweepAddrSize,
 	)
+
+	// ErrNoSweepAddress is returned when trying to decode the sweep
+	// address of a blob that doesn't contain one.
+	ErrNoSweepAddress = errors.New("blob does not contain a sweep address")
+
+	// ErrUnknownSweepAddrType is returned when the sweep address of a blob
+	// is not a standard script paying to a single address.
+	ErrUnknownSweepAddrType = errors.New(
+		"sweep address is not a standard single address script",
+	)
+
+	// ErrSecondLevelHtlcsUnsupported is returned when attempting to add a
+	// second-level HTLC signature to a blob whose type doesn't have
+	// FlagSecondLevelHtlcs.
+	ErrSecondLevelHtlcsUnsupported = errors.New(
+		"blob type does not support second-level htlcs",
+	)
+
+	// ErrTaprootSecondLevelHtlcs is returned when creating a justice kit,
+	// adding a second-level HTLC signature, or building a second-level
+	// HTLC spend for a blob type combining FlagSecondLevelHtlcs with
+	// FlagTaprootChannel, as only the p2wsh second-level HTLC outputs of
+	// non-taproot channels can be swept.
+	ErrTaprootSecondLevelHtlcs = errors.New(
+		"second-level htlcs are not supported for taproot channels",
+	)
+
+	// ErrTooManyHTLCs is returned when a blob would carry, or claims to
+	// carry, more HTLC signatures than permitted by MaxNumHTLCs for its
+	// type.
+	ErrTooManyHTLCs = errors.New("blob exceeds max number of htlcs")
+
+	// ErrUnknownSecondLevelHtlc is returned when requesting the spend info
+	// of a second-level HTLC that isn't present in the blob.
+	ErrUnknownSecondLevelHtlc = errors.New("unknown second-level htlc")
+
+	// ErrSigAlreadySet is returned when attempting to add a signature to a
+	// JusticeKit that already holds a non-zero signature for that output.
+	ErrSigAlreadySet = errors.New("signature already set")
+
+	// ErrUnsupportedSigHash is returned when recording a sighash type for
+	// a commitment signature that the blob type can't carry.
+	ErrUnsupportedSigHash = errors.New("unsupported sighash type")
+
+	// ErrNotTaprootChannel is returned when attempting to build a taproot
+	// spend from a blob that isn't for a taproot channel.
+	ErrNotTaprootChannel = errors.New("blob is not for a taproot channel")
+
+	// ErrNotTaprootKeySpend is returned when requesting the key path
+	// tweak of a to-local output from a blob whose type doesn't have
+	// FlagTaprootKeySpend.
+	ErrNotTaprootKeySpend = errors.New(
+		"blob is not for a taproot key spend",
+	)
+
+	// ErrTaprootOutputMismatch is returned when the taproot output key
+	// reconstructed from a blob doesn't match the breached output.
+	ErrTaprootOutputMismatch = errors.New(
+		"taproot output key does not match breached output",
+	)
+
+	// ErrJusticeInputMismatch is returned when verifying the signatures of
+	// a blob against a justice transaction whose inputs are not exactly the
+	// breached outputs.
+	ErrJusticeInputMismatch = errors.New(
+		"justice transaction inputs do not match breached outputs",
+	)
+
+	// ErrOutputScriptMismatch is returned when the pkScript derived from a
+	// blob's witness script doesn't match that of the breached output it
+	// is meant to spend.
+	ErrOutputScriptMismatch = errors.New(
+		"blob script does not match breached output",
+	)
+
+	// ErrInvalidSignature is returned when a signature in the blob is not
+	// valid for the breached output it is meant to spend.
+	ErrInvalidSignature = errors.New("signature is invalid")
+
+	// ErrMissingPubKey is returned when constructing a JusticeKit without
+	// one of the pubkeys required by its outputs.
+	ErrMissingPubKey = errors.New("justice kit pubkey missing")
+
+	// ErrDegenerateKeys is returned when constructing a JusticeKit whose
+	// revocation and local delay pubkeys are identical, which would result
+	// in a degenerate to-local script that the holder of the delay key
+	// could sweep via the revocation path.
+	ErrDegenerateKeys = errors.New(
+		"revocation and local delay pubkeys must differ",
+	)
+
+	// ErrInvalidCSVDelay is returned when validating a blob whose to-local
+	// CSV delay is zero or doesn't fit in the 16-bit block-based relative
+	// locktime used by commitment transactions.
+	ErrInvalidCSVDelay = fmt.Errorf(
+		"csv delay must be between 1 and %d blocks", math.MaxUint16,
+	)
+
+	// ErrDataCommitmentUnsupported is returned when attempting to set a
+	// data commitment on a blob whose type doesn't have
+	// FlagDataCommitment.
+	ErrDataCommitmentUnsupported = errors.New(
+		"blob type does not support data commitments",
+	)
+
+	// ErrDataCommitmentTooLong is returned when trying to encode or decode
+	// a data commitment with length greater than MaxDataCommitmentSize.
+	ErrDataCommitmentTooLong = fmt.Errorf(
+		"data commitment must be less than or equal to %d bytes long",
+		MaxDataCommitmentSize,
+	)
+
+	// ErrMissingSignature signals that a kit is being encrypted without one
+	// of the signatures required to sweep its outputs, which would produce
+	// an unspendable justice transaction.
+	ErrMissingSignature = errors.New("justice kit signature missing")
+
+	// ErrZeroBreachKey is returned by EncryptStrict when asked to encrypt
+	// under an all-zero breach key, which most likely stems from a key
+	// that was never set.
+	ErrZeroBreachKey = errors.New("breach key is all zeros")
+
+	// ErrNonZeroPadding signals that a decrypted blob has a non-zero byte
+	// in one of its padding regions. As the encoder always zeroes padding,
+	// this indicates a corrupt blob or a mismatch in the encoding format.
+	ErrNonZeroPadding = errors.New("blob has non-zero padding")
+
+	// ErrMalformedBlob signals that a length prefix of a decoded blob is
+	// inconsistent with the bytes available for the field it describes.
+	// The error returned by the decoder also matches the more specific
+	// cause, such as ErrSweepAddressToLong or ErrTooManyHTLCs.
+	ErrMalformedBlob = errors.New("malformed blob")
+
+	// ErrUnspendableSweepAddr is returned when creating a justice kit
+	// whose sweep address can provably never be spent, such as an
+	// OP_RETURN output, which would burn the swept funds.
+	ErrUnspendableSweepAddr = errors.New("sweep address is unspendable")
+
+	// ErrVersionLengthMismatch is returned by DecryptChecked when the
+	// length of a ciphertext can't be that of a blob of the expected type,
+	// signaling that the blob was stored under a different type.
+	ErrVersionLengthMismatch = errors.New(
+		"ciphertext length doesn't match blob type",
+	)
+
+	// ErrNegativeIndexOverhead is returned by StorageFootprint when given
+	// a negative per-row index overhead.
+	ErrNegativeIndexOverhead = errors.New("index overhead is negative")
 )
 
 // PubKey is a 33-byte, serialized compressed public key.
@@ -148,263 +447,2564 @@ type JusticeKit struct {
 	// NOTE: This value is only used if CommitToRemotePubKey contains a valid
 	// compressed public key.
 	CommitToRemoteSig lnwire.Sig
-}
 
-// CommitToLocalWitnessScript returns the serialized witness script for the
-// commitment to-local output.
-func (b *JusticeKit) CommitToLocalWitnessScript() ([]byte, error) {
-	revocationPubKey, err := btcec.ParsePubKey(
-		b.RevocationPubKey[:],
-	)
-	if err != nil {
-		return nil, err
-	}
+	// SecondLevelHtlcSigs are signatures under RevocationPubKey using
+	// SIGHASH_ALL, each spending the revocation path of a CSV-delayed
+	// second-level HTLC output.
+	//
+	// The blob carries no other information about the HTLCs, so a
+	// signature is identified by its position alone, which pairs it with
+	// the output at the same position of JusticeInputs.SecondLevelHtlcs.
+	// The order is thus chosen by the client, and preserved exactly by the
+	// encoding, such that a kit re-encrypted with the same signatures
+	// produces the same plaintext.
+	//
+	// NOTE: This value is only encoded if BlobType has
+	// FlagSecondLevelHtlcs.
+	SecondLevelHtlcSigs []lnwire.Sig
 
-	localDelayedPubKey, err := btcec.ParsePubKey(
-		b.LocalDelayPubKey[:],
-	)
-	if err != nil {
-		return nil, err
-	}
+	// DataCommitment is an optional tag that the justice transaction
+	// commits to in an additional zero-value OP_RETURN output.
+	//
+	// NOTE: This value is only encoded if BlobType has
+	// FlagDataCommitment.
+	DataCommitment []byte
 
-	return input.CommitScriptToSelf(
-		b.CSVDelay, localDelayedPubKey, revocationPubKey,
-	)
+	// LeaseExpiry is the absolute block height at which the lease of a
+	// script enforced lease channel expires, which the to-local and
+	// second-level HTLC scripts of the breached commitment commit to.
+	//
+	// NOTE: This value is only encoded if BlobType has FlagLeaseChannel.
+	LeaseExpiry uint32
+
+	// TrailerRecords holds the records of the TLV trailer, keyed by their
+	// type. Records of types unknown to this version are retained when
+	// decoding, such that re-encrypting the kit preserves them.
+	//
+	// NOTE: This value is only encoded if BlobType has FlagTLVTrailer.
+	TrailerRecords map[uint64][]byte
+
+	// commitmentNumber is the number of the breached commitment, read from
+	// the commitment header of the ciphertext the kit was decrypted from.
+	// It is only valid if hasCommitmentNumber is true.
+	commitmentNumber    uint64
+	hasCommitmentNumber bool
+
+	// toLocalSigHash and toRemoteSigHash are the sighash types of the
+	// commit to-local and to-remote signatures, or zero if they use the
+	// default for the blob type. Non-default types are carried by the TLV
+	// trailer.
+	toLocalSigHash  txscript.SigHashType
+	toRemoteSigHash txscript.SigHashType
 }
 
-// CommitToLocalRevokeWitnessStack constructs a witness stack spending the
-// revocation clause of the commitment to-local output.
-//
-//	<revocation-sig> 1
-func (b *JusticeKit) CommitToLocalRevokeWitnessStack() ([][]byte, error) {
-	toLocalSig, err := b.CommitToLocalSig.ToSignature()
-	if err != nil {
-		return nil, err
-	}
+// JusticeKitParams holds the raw parameters of a breached commitment from which
+// a JusticeKit is constructed, independent of how the caller tracks its
+// channel state.
+type JusticeKitParams struct {
+	// SweepAddress is the pkScript of the output the justice transaction
+	// pays the swept funds to.
+	SweepAddress []byte
 
-	witnessStack := make([][]byte, 2)
-	witnessStack[0] = append(toLocalSig.Serialize(),
-		byte(txscript.SigHashAll))
-	witnessStack[1] = []byte{1}
+	// RevocationPubKey is the pubkey guarding the revocation clause of the
+	// breached to-local output.
+	RevocationPubKey *btcec.PublicKey
 
-	return witnessStack, nil
-}
+	// LocalDelayPubKey is the pubkey guarding the delayed clause of the
+	// breached to-local output.
+	LocalDelayPubKey *btcec.PublicKey
 
-// HasCommitToRemoteOutput returns true if the blob contains a to-remote p2wkh
-// pubkey.
-func (b *JusticeKit) HasCommitToRemoteOutput() bool {
-	return btcec.IsCompressedPubKey(b.CommitToRemotePubKey[:])
+	// CSVDelay is the relative timelock of the breached to-local output.
+	CSVDelay uint32
+
+	// HasToRemote signals that the breached commitment has a to-remote
+	// output that should be swept.
+	HasToRemote bool
+
+	// ToRemotePubKey is the pubkey of the breached to-remote output. It
+	// is only used, and required, if HasToRemote is true.
+	ToRemotePubKey *btcec.PublicKey
+
+	// LeaseExpiry is the lease expiry of a script enforced lease channel.
+	// It is only used if the blob type has FlagLeaseChannel.
+	LeaseExpiry uint32
+
+	// AllowUnspendableSweep, if true, permits a sweep address that can
+	// never be spent, such as an OP_RETURN output, for callers that
+	// deliberately burn the swept funds.
+	AllowUnspendableSweep bool
 }
 
-// CommitToRemoteWitnessScript returns the witness script for the commitment
-// to-remote output given the blob type. The script returned will either be for
-// a p2wpkh to-remote output or an p2wsh anchor to-remote output which includes
-// a CSV delay.
-func (b *JusticeKit) CommitToRemoteWitnessScript() ([]byte, error) {
-	if !btcec.IsCompressedPubKey(b.CommitToRemotePubKey[:]) {
-		return nil, ErrNoCommitToRemoteOutput
+// NewJusticeKitFromScripts constructs a JusticeKit of the given type from the
+// raw parameters of a breached commitment, leaving all signatures blank. An
+// error is returned if the sweep address is too long or, unless
+// AllowUnspendableSweep is set, unspendable, if a required pubkey is missing,
+// or if the revocation and local delay pubkeys are identical, which would make
+// the to-local script degenerate. Types with FlagToRemoteOnly only take
+// the to-remote pubkey, which is then required, and leave the to-local
+// parameters out of the kit.
+func NewJusticeKitFromScripts(t Type, params JusticeKitParams) (*JusticeKit,
+	error) {
+
+	if len(params.SweepAddress) > MaxSweepAddrSize {
+		log.Debugf("Unable to create %v justice kit: sweep address "+
+			"of %d bytes exceeds %d", t, len(params.SweepAddress),
+			MaxSweepAddrSize)
+
+		return nil, ErrSweepAddressToLong
 	}
 
-	// If this is a blob for an anchor channel, we'll return the p2wsh
-	// output containing a CSV delay of 1.
-	if b.BlobType.IsAnchorChannel() {
-		pk, err := btcec.ParsePubKey(b.CommitToRemotePubKey[:])
-		if err != nil {
-			return nil, err
+	if !params.AllowUnspendableSweep &&
+		txscript.IsUnspendable(params.SweepAddress) {
+
+		log.Debugf("Unable to create %v justice kit: sweep address "+
+			"%x is unspendable", t, params.SweepAddress)
+
+		return nil, ErrUnspendableSweepAddr
+	}
+
+	if t.Has(FlagToRemoteOnly) {
+		if !params.HasToRemote || params.ToRemotePubKey == nil {
+			log.Debugf("Unable to create %v justice kit: missing "+
+				"to-remote pubkey", t)
+
+			return nil, ErrMissingPubKey
 		}
 
-		return input.CommitScriptToRemoteConfirmed(pk)
+		return &JusticeKit{
+			BlobType:     t,
+			SweepAddress: params.SweepAddress,
+			CommitToRemotePubKey: toPubKey(
+				params.ToRemotePubKey,
+			),
+		}, nil
 	}
 
-	return b.CommitToRemotePubKey[:], nil
-}
+	if params.RevocationPubKey == nil || params.LocalDelayPubKey == nil {
+		log.Debugf("Unable to create %v justice kit: missing "+
+			"revocation or local delay pubkey", t)
 
-// CommitToRemoteWitnessStack returns a witness stack spending the commitment
-// to-remote output, which consists of a single signature satisfying either the
-// legacy or anchor witness scripts.
-//
-//	<to-remote-sig>
-func (b *JusticeKit) CommitToRemoteWitnessStack() ([][]byte, error) {
-	toRemoteSig, err := b.CommitToRemoteSig.ToSignature()
-	if err != nil {
-		return nil, err
+		return nil, ErrMissingPubKey
 	}
 
-	witnessStack := make([][]byte, 1)
-	witnessStack[0] = append(toRemoteSig.Serialize(),
-		byte(txscript.SigHashAll))
+	if t.Has(FlagTaprootKeySpend) && !t.IsTaprootChannel() {
+		log.Debugf("Unable to create %v justice kit: key spend "+
+			"requires a taproot channel", t)
 
-	return witnessStack, nil
-}
+		return nil, fmt.Errorf("%v: %w", FlagTaprootKeySpend,
+			ErrNotTaprootChannel)
+	}
 
-// Encrypt encodes the blob of justice using encoding version, and then
-// creates a ciphertext using chacha20poly1305 under the chosen (nonce, key)
-// pair.
-//
-// NOTE: It is the caller's responsibility to ensure that this method is only
-// called once for a given (nonce, key) pair.
-func (b *JusticeKit) Encrypt(key BreachKey) ([]byte, error) {
-	// Encode the plaintext using the provided version, to obtain the
-	// plaintext bytes.
-	var ptxtBuf bytes.Buffer
-	err := b.encode(&ptxtBuf, b.BlobType)
-	if err != nil {
-		return nil, err
+	if t.Has(FlagSecondLevelHtlcs) && t.IsTaprootChannel() {
+		log.Debugf("Unable to create %v justice kit: second-level "+
+			"htlcs require a non-taproot channel", t)
+
+		return nil, ErrTaprootSecondLevelHtlcs
 	}
 
-	// Create a new chacha20poly1305 cipher, using a 32-byte key.
-	cipher, err := chacha20poly1305.NewX(key[:])
-	if err != nil {
-		return nil, err
+	if params.RevocationPubKey.IsEqual(params.LocalDelayPubKey) {
+		log.Debugf("Unable to create %v justice kit: revocation "+
+			"and local delay pubkeys are identical", t)
+
+		return nil, ErrDegenerateKeys
 	}
 
-	// Allocate the ciphertext, which will contain the nonce, encrypted
-	// plaintext and MAC.
-	plaintext := ptxtBuf.Bytes()
-	ciphertext := make([]byte, Size(b.BlobType))
+	kit := &JusticeKit{
+		BlobType:         t,
+		SweepAddress:     params.SweepAddress,
+		RevocationPubKey: toPubKey(params.RevocationPubKey),
+		LocalDelayPubKey: toPubKey(params.LocalDelayPubKey),
+		CSVDelay:         params.CSVDelay,
+	}
 
-	// Generate a random  24-byte nonce in the ciphertext's prefix.
-	nonce := ciphertext[:NonceSize]
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, err
+	if t.Has(FlagLeaseChannel) {
+		kit.LeaseExpiry = params.LeaseExpiry
 	}
 
-	// Finally, encrypt the plaintext using the given nonce, storing the
-	// result in the ciphertext buffer.
-	cipher.Seal(ciphertext[NonceSize:NonceSize], nonce, plaintext, nil)
+	// Setting the to-remote pubkey serves as the indicator to the tower
+	// that the breaching transaction has a to-remote output to sweep.
+	if params.HasToRemote {
+		if params.ToRemotePubKey == nil {
+			log.Debugf("Unable to create %v justice kit: missing "+
+				"to-remote pubkey", t)
 
-	return ciphertext, nil
+			return nil, ErrMissingPubKey
+		}
+
+		kit.CommitToRemotePubKey = toPubKey(params.ToRemotePubKey)
+	}
+
+	return kit, nil
 }
 
-// Decrypt unenciphers a blob of justice by decrypting the ciphertext using
-// chacha20poly1305 with the chosen (nonce, key) pair. The internal plaintext is
-// then deserialized using the given encoding version.
-func Decrypt(key BreachKey, ciphertext []byte,
-	blobType Type) (*JusticeKit, error) {
+// NewJusticeKit constructs a JusticeKit of the given type for the breached
+// commitment described by breachInfo, sweeping to sweepAddr, and sweeping the
+// to-remote output if hasToRemote is true. It is a convenience wrapper around
+// NewJusticeKitFromScripts for callers holding a BreachRetribution, and as such
+// rejects an unspendable sweepAddr with ErrUnspendableSweepAddr. Callers that
+// deliberately burn the swept funds must use NewJusticeKitFromScripts with
+// AllowUnspendableSweep instead.
+func NewJusticeKit(t Type, sweepAddr []byte,
+	breachInfo *lnwallet.BreachRetribution,
+	hasToRemote bool) (*JusticeKit, error) {
+
+	keyRing := breachInfo.KeyRing
+
+	return NewJusticeKitFromScripts(t, JusticeKitParams{
+		SweepAddress:     sweepAddr,
+		RevocationPubKey: keyRing.RevocationKey,
+		LocalDelayPubKey: keyRing.ToLocalKey,
+		CSVDelay:         breachInfo.RemoteDelay,
+		HasToRemote:      hasToRemote,
+		ToRemotePubKey:   keyRing.ToRemoteKey,
+	})
+}
 
-	// Fail if the blob's overall length is less than required for the nonce
-	// and expansion factor.
-	if len(ciphertext) < NonceSize+CiphertextExpansion {
-		return nil, ErrCiphertextTooSmall
+// ToBreachInfo reconstructs the parts of the BreachRetribution the kit was
+// created from, such that a decrypted kit can be handed to tooling built
+// around lnwallet's breach handling. Only the RemoteDelay and the revocation,
+// to-local and to-remote keys of the KeyRing can be recovered, the latter only
+// if the kit sweeps a to-remote output. All other fields are left zero, as the
+// kit doesn't carry them, namely the breach transaction and height, chain
+// hash, revoked state number, outpoints, sign descriptors, LocalDelay and HTLC
+// retributions, as well as the commitment point, key tweaks and HTLC keys of
+// the KeyRing. Kits with FlagToRemoteOnly only recover the to-remote key.
+func (b *JusticeKit) ToBreachInfo() (*lnwallet.BreachRetribution, error) {
+	if b.BlobType.Has(FlagToRemoteOnly) {
+		toRemoteKey, err := b.CommitToRemoteKey()
+		if err != nil {
+			return nil, err
+		}
+
+		return &lnwallet.BreachRetribution{
+			KeyRing: &lnwallet.CommitmentKeyRing{
+				ToRemoteKey: toRemoteKey,
+			},
+		}, nil
 	}
 
-	// Create a new chacha20poly1305 cipher, using a 32-byte key.
-	cipher, err := chacha20poly1305.NewX(key[:])
+	revocationKey, err := b.RevocationKey()
 	if err != nil {
 		return nil, err
 	}
 
-	// Allocate the final buffer that will contain the blob's plaintext
-	// bytes, which is computed by subtracting the ciphertext expansion
-	// factor from the blob's length.
-	plaintext := make([]byte, len(ciphertext)-CiphertextExpansion)
-
-	// Decrypt the ciphertext, placing the resulting plaintext in our
-	// plaintext buffer.
-	nonce := ciphertext[:NonceSize]
-	_, err = cipher.Open(plaintext[:0], nonce, ciphertext[NonceSize:], nil)
+	toLocalKey, err := b.LocalDelayKey()
 	if err != nil {
 		return nil, err
 	}
 
-	// If decryption succeeded, we will then decode the plaintext bytes
-	// using the specified blob version.
-	boj := &JusticeKit{
-		BlobType: blobType,
+	keyRing := &lnwallet.CommitmentKeyRing{
+		RevocationKey: revocationKey,
+		ToLocalKey:    toLocalKey,
 	}
-	err = boj.decode(bytes.NewReader(plaintext), blobType)
-	if err != nil {
-		return nil, err
+
+	if b.HasCommitToRemoteOutput() {
+		keyRing.ToRemoteKey, err = b.CommitToRemoteKey()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return boj, nil
+	return &lnwallet.BreachRetribution{
+		RemoteDelay: b.CSVDelay,
+		KeyRing:     keyRing,
+	}, nil
 }
 
-// encode serializes the JusticeKit according to the version, returning an
-// error if the version is unknown.
-func (b *JusticeKit) encode(w io.Writer, blobType Type) error {
-	switch {
-	case blobType.Has(FlagCommitOutputs):
-		return b.encodeV0(w)
-	default:
-		return ErrUnknownBlobType
-	}
+// toPubKey serializes the given pubkey into a compressed PubKey.
+func toPubKey(pubKey *btcec.PublicKey) PubKey {
+	var blobPubKey PubKey
+	copy(blobPubKey[:], pubKey.SerializeCompressed())
+
+	return blobPubKey
 }
 
-// decode deserializes the JusticeKit according to the version, returning an
-// error if the version is unknown.
-func (b *JusticeKit) decode(r io.Reader, blobType Type) error {
-	switch {
-	case blobType.Has(FlagCommitOutputs):
-		return b.decodeV0(r)
-	default:
-		return ErrUnknownBlobType
+// AddToLocalSig stores the signature for the commitment to-local output. If a
+// non-zero signature is already present, ErrSigAlreadySet is returned and the
+// existing signature is retained, use ReplaceToLocalSig to overwrite it.
+func (b *JusticeKit) AddToLocalSig(sig lnwire.Sig) error {
+	if !isZeroSig(b.CommitToLocalSig) {
+		return fmt.Errorf("commit to-local: %w", ErrSigAlreadySet)
 	}
+
+	b.CommitToLocalSig = sig
+	b.toLocalSigHash = 0
+
+	return nil
 }
 
-// encodeV0 encodes the JusticeKit using the version 0 encoding scheme to the
-// provided io.Writer. The encoding supports sweeping of the commit to-local
-// output, and  optionally the  commit to-remote output. The encoding produces a
-// constant-size plaintext size of 274 bytes.
-//
-// blob version 0 plaintext encoding:
-//
-//	sweep address length:            1 byte
-//	padded sweep address:           42 bytes
-//	revocation pubkey:              33 bytes
-//	local delay pubkey:             33 bytes
-//	csv delay:                       4 bytes
-//	commit to-local revocation sig: 64 bytes
-//	commit to-remote pubkey:        33 bytes, maybe blank
-//	commit to-remote sig:           64 bytes, maybe blank
-func (b *JusticeKit) encodeV0(w io.Writer) error {
-	// Assert the sweep address length is sane.
-	if len(b.SweepAddress) > MaxSweepAddrSize {
-		return ErrSweepAddressToLong
+// AddToLocalSigWithSighash stores the signature for the commitment to-local
+// output as done by AddToLocalSig, recording the sighash type it was made with
+// such that it's appended to the signature in the witness. Besides the default
+// for the blob type, SIGHASH_SINGLE|SIGHASH_ANYONECANPAY is supported by
+// non-taproot blob types with FlagTLVTrailer, which carry the sighash type in
+// the trailer. ErrUnsupportedSigHash is returned for any other sighash type.
+func (b *JusticeKit) AddToLocalSigWithSighash(sig lnwire.Sig,
+	sigHash txscript.SigHashType) error {
+
+	if err := b.checkSigHash(sigHash); err != nil {
+		return fmt.Errorf("commit to-local: %w", err)
 	}
 
-	// Write the actual length of the sweep address as a single byte.
-	err := binary.Write(w, byteOrder, uint8(len(b.SweepAddress)))
-	if err != nil {
+	if err := b.AddToLocalSig(sig); err != nil {
 		return err
 	}
 
-	// Pad the sweep address to our maximum length of 42 bytes.
-	var sweepAddressBuf [MaxSweepAddrSize]byte
-	copy(sweepAddressBuf[:], b.SweepAddress)
+	return b.setSigHash(&b.toLocalSigHash, sigHash)
+}
 
-	// Write padded 42-byte sweep address.
-	_, err = w.Write(sweepAddressBuf[:])
-	if err != nil {
-		return err
-	}
+// ReplaceToLocalSig stores the signature for the commitment to-local output,
+// overwriting any signature that is already present. The signature is assumed
+// to use the default sighash type for the blob type.
+func (b *JusticeKit) ReplaceToLocalSig(sig lnwire.Sig) {
+	b.CommitToLocalSig = sig
+	b.toLocalSigHash = 0
+}
 
-	// Write 33-byte revocation public key.
-	_, err = w.Write(b.RevocationPubKey[:])
-	if err != nil {
-		return err
+// ToLocalSigHash returns the sighash type of the commitment to-local
+// signature.
+func (b *JusticeKit) ToLocalSigHash() txscript.SigHashType {
+	return b.sigHashOrDefault(b.toLocalSigHash)
+}
+
+// AddToRemoteSig stores the signature for the commitment to-remote output. If
+// a non-zero signature is already present, ErrSigAlreadySet is returned and
+// the existing signature is retained, use ReplaceToRemoteSig to overwrite it.
+func (b *JusticeKit) AddToRemoteSig(sig lnwire.Sig) error {
+	if !isZeroSig(b.CommitToRemoteSig) {
+		return fmt.Errorf("commit to-remote: %w", ErrSigAlreadySet)
 	}
 
-	// Write 33-byte local delay public key.
-	_, err = w.Write(b.LocalDelayPubKey[:])
-	if err != nil {
-		return err
+	b.CommitToRemoteSig = sig
+	b.toRemoteSigHash = 0
+
+	return nil
+}
+
+// AddToRemoteSigWithSighash stores the signature for the commitment to-remote
+// output as done by AddToRemoteSig, recording the sighash type it was made
+// with. The supported sighash types are those of AddToLocalSigWithSighash.
+func (b *JusticeKit) AddToRemoteSigWithSighash(sig lnwire.Sig,
+	sigHash txscript.SigHashType) error {
+
+	if err := b.checkSigHash(sigHash); err != nil {
+		return fmt.Errorf("commit to-remote: %w", err)
 	}
 
-	// Write 4-byte CSV delay.
-	err = binary.Write(w, byteOrder, b.CSVDelay)
-	if err != nil {
+	if err := b.AddToRemoteSig(sig); err != nil {
 		return err
 	}
 
-	// Write 64-byte revocation signature for commit to-local output.
-	_, err = w.Write(b.CommitToLocalSig.RawBytes())
+	return b.setSigHash(&b.toRemoteSigHash, sigHash)
+}
+
+// ReplaceToRemoteSig stores the signature for the commitment to-remote output,
+// overwriting any signature that is already present. The signature is assumed
+// to use the default sighash type for the blob type.
+func (b *JusticeKit) ReplaceToRemoteSig(sig lnwire.Sig) {
+	b.CommitToRemoteSig = sig
+	b.toRemoteSigHash = 0
+}
+
+// ToRemoteSigHash returns the sighash type of the commitment to-remote
+// signature.
+func (b *JusticeKit) ToRemoteSigHash() txscript.SigHashType {
+	return b.sigHashOrDefault(b.toRemoteSigHash)
+}
+
+// defaultSigHash returns the sighash type of commitment signatures for which
+// none was recorded, which is SIGHASH_DEFAULT for taproot channels and
+// SIGHASH_ALL otherwise.
+func (b *JusticeKit) defaultSigHash() txscript.SigHashType {
+	if b.BlobType.IsTaprootChannel() {
+		return txscript.SigHashDefault
+	}
+
+	return txscript.SigHashAll
+}
+
+// sigHashOrDefault returns the recorded sighash type, or the default for the
+// blob type if none was recorded.
+func (b *JusticeKit) sigHashOrDefault(
+	sigHash txscript.SigHashType) txscript.SigHashType {
+
+	if sigHash == 0 {
+		return b.defaultSigHash()
+	}
+
+	return sigHash
+}
+
+// checkSigHash returns ErrUnsupportedSigHash if the sighash type can't be used
+// for a commitment signature of the kit.
+func (b *JusticeKit) checkSigHash(sigHash txscript.SigHashType) error {
+	const singleAnyoneCanPay = txscript.SigHashSingle |
+		txscript.SigHashAnyOneCanPay
+
+	switch {
+	case sigHash == b.defaultSigHash():
+		return nil
+
+	case sigHash == singleAnyoneCanPay &&
+		!b.BlobType.IsTaprootChannel() &&
+		b.BlobType.Has(FlagTLVTrailer):
+
+		return nil
+
+	default:
+		return fmt.Errorf("%w: %v for %v blob", ErrUnsupportedSigHash,
+			sigHash, b.BlobType)
+	}
+}
+
+// setSigHash validates the sighash type and records it in dst, storing the
+// default for the blob type as zero such that it isn't encoded.
+func (b *JusticeKit) setSigHash(dst *txscript.SigHashType,
+	sigHash txscript.SigHashType) error {
+
+	if err := b.checkSigHash(sigHash); err != nil {
+		return err
+	}
+
+	*dst = 0
+	if sigHash != b.defaultSigHash() {
+		*dst = sigHash
+	}
+
+	return nil
+}
+
+// isZeroSig returns true if the signature is blank.
+func isZeroSig(sig lnwire.Sig) bool {
+	var zeroSig [64]byte
+	return bytes.Equal(sig.RawBytes(), zeroSig[:])
+}
+
+// validateSigs returns ErrMissingSignature, naming the offending output, if any
+// of the signatures required to sweep the kit's outputs is blank. The to-local
+// signature may only be omitted if the kit sweeps a signed to-remote output,
+// as is the case for a breached commitment whose to-local output is dust, and
+// is never required for blob types with FlagToRemoteOnly.
+func (b *JusticeKit) validateSigs() error {
+	if b.BlobType.Has(FlagToRemoteOnly) {
+		if !b.HasCommitToRemoteOutput() {
+			return ErrNoCommitToRemoteOutput
+		}
+
+		if isZeroSig(b.CommitToRemoteSig) {
+			return fmt.Errorf("commit to-remote: %w",
+				ErrMissingSignature)
+		}
+
+		return nil
+	}
+
+	requiresToRemoteSig := b.BlobType.RequiresToRemoteSig(
+		b.HasCommitToRemoteOutput(),
+	)
+	toRemoteOnly := requiresToRemoteSig && !isZeroSig(b.CommitToRemoteSig)
+
+	if isZeroSig(b.CommitToLocalSig) && !toRemoteOnly {
+		return fmt.Errorf("commit to-local: %w", ErrMissingSignature)
+	}
+
+	if requiresToRemoteSig && isZeroSig(b.CommitToRemoteSig) {
+		return fmt.Errorf("commit to-remote: %w", ErrMissingSignature)
+	}
+
+	for i, sig := range b.SecondLevelHtlcSigs {
+		if isZeroSig(sig) {
+			return fmt.Errorf("second-level htlc %d: %w", i,
+				ErrMissingSignature)
+		}
+	}
+
+	return nil
+}
+
+// SpendableOutputs reports which of the breached outputs described by the kit
+// can actually be swept, as the kit holds a non-zero signature for them along
+// with well-formed keys to reconstruct their witness. The htlcs returned are
+// the indices of the usable second-level HTLC signatures, as passed to
+// SecondLevelHtlcSpendInfo. Kits produced by a faulty encoder may, for
+// instance, commit to a to-remote output without signing it, in which case
+// only the remaining inputs should be added to the justice transaction.
+func (b *JusticeKit) SpendableOutputs() (toLocal, toRemote bool,
+	htlcs []int) {
+
+	if !isZeroSig(b.CommitToLocalSig) {
+		_, err := b.ToLocalOutputSpendInfo()
+		toLocal = err == nil
+	}
+
+	if b.HasCommitToRemoteOutput() && !isZeroSig(b.CommitToRemoteSig) {
+		_, err := b.ToRemoteOutputSpendInfo()
+		toRemote = err == nil
+	}
+
+	for i, sig := range b.SecondLevelHtlcSigs {
+		if isZeroSig(sig) {
+			continue
+		}
+
+		if _, _, err := b.SecondLevelHtlcSpendInfo(i); err == nil {
+			htlcs = append(htlcs, i)
+		}
+	}
+
+	return toLocal, toRemote, htlcs
+}
+
+// AddSecondLevelHtlcSig appends a signature spending the revocation path of a
+// second-level HTLC output. The blob type must have FlagSecondLevelHtlcs, but
+// not FlagTaprootChannel, and at most MaxSecondLevelHtlcs signatures can be
+// added, so that the encoding remains constant-size.
+func (b *JusticeKit) AddSecondLevelHtlcSig(sig lnwire.Sig) error {
+	if !b.BlobType.Has(FlagSecondLevelHtlcs) {
+		return ErrSecondLevelHtlcsUnsupported
+	}
+
+	if b.BlobType.IsTaprootChannel() {
+		return ErrTaprootSecondLevelHtlcs
+	}
+
+	if len(b.SecondLevelHtlcSigs) >= MaxNumHTLCs(b.BlobType) {
+		return ErrTooManyHTLCs
+	}
+
+	b.SecondLevelHtlcSigs = append(b.SecondLevelHtlcSigs, sig)
+
+	return nil
+}
+
+// SetDataCommitment sets the data the justice transaction should commit to in
+// an OP_RETURN output. The blob type must have FlagDataCommitment, and the data
+// can be at most MaxDataCommitmentSize bytes, so that the encoding remains
+// constant-size.
+func (b *JusticeKit) SetDataCommitment(data []byte) error {
+	if !b.BlobType.Has(FlagDataCommitment) {
+		return ErrDataCommitmentUnsupported
+	}
+
+	if len(data) > MaxDataCommitmentSize {
+		return ErrDataCommitmentTooLong
+	}
+
+	b.DataCommitment = data
+
+	return nil
+}
+
+// DataCommitmentOutput returns the zero-value OP_RETURN output committing to
+// the blob's data commitment, or nil if the blob doesn't carry one.
+func (b *JusticeKit) DataCommitmentOutput() (*wire.TxOut, error) {
+	if len(b.DataCommitment) == 0 {
+		return nil, nil
+	}
+
+	if len(b.DataCommitment) > MaxDataCommitmentSize {
+		return nil, ErrDataCommitmentTooLong
+	}
+
+	pkScript, err := txscript.NullDataScript(b.DataCommitment)
+	if err != nil {
+		return nil, err
+	}
+
+	return wire.NewTxOut(0, pkScript), nil
+}
+
+// SecondLevelHtlcSpendInfo returns the witness script and the witness stack
+// spending the revocation path of the i-th second-level HTLC output. The
+// second-level output is locked to the same revocation key, delay key and CSV
+// delay as the commitment to-local output, as well as the same lease expiry for
+// blob types with FlagLeaseChannel. Taproot channels are unsupported, and
+// return ErrTaprootSecondLevelHtlcs.
+//
+//	<revocation-sig> 1
+func (b *JusticeKit) SecondLevelHtlcSpendInfo(i int) ([]byte, [][]byte,
+	error) {
+
+	if b.BlobType.IsTaprootChannel() {
+		return nil, nil, ErrTaprootSecondLevelHtlcs
+	}
+
+	if i < 0 || i >= len(b.SecondLevelHtlcSigs) {
+		return nil, nil, ErrUnknownSecondLevelHtlc
+	}
+
+	revocationPubKey, err := b.RevocationKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	localDelayedPubKey, err := b.LocalDelayKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var witnessScript []byte
+	if b.BlobType.Has(FlagLeaseChannel) {
+		witnessScript, err = input.LeaseSecondLevelHtlcScript(
+			revocationPubKey, localDelayedPubKey, b.CSVDelay,
+			b.LeaseExpiry,
+		)
+	} else {
+		witnessScript, err = input.SecondLevelHtlcScript(
+			revocationPubKey, localDelayedPubKey, b.CSVDelay,
+		)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	revocationSig, err := b.SecondLevelHtlcSigs[i].ToSignature()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	witnessStack := make([][]byte, 2)
+	witnessStack[0] = append(revocationSig.Serialize(),
+		byte(txscript.SigHashAll))
+	witnessStack[1] = []byte{1}
+
+	return witnessScript, witnessStack, nil
+}
+
+// CommitToLocalWitnessScript returns the serialized witness script for the
+// commitment to-local output, which carries an additional CLTV clause on its
+// delayed path for blob types with FlagLeaseChannel.
+func (b *JusticeKit) CommitToLocalWitnessScript() ([]byte, error) {
+	if b.BlobType.Has(FlagToRemoteOnly) {
+		return nil, ErrNoCommitToLocalOutput
+	}
+
+	revocationPubKey, err := b.RevocationKey()
+	if err != nil {
+		return nil, err
+	}
+
+	localDelayedPubKey, err := b.LocalDelayKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if b.BlobType.Has(FlagLeaseChannel) {
+		return input.LeaseCommitScriptToSelf(
+			localDelayedPubKey, revocationPubKey, b.CSVDelay,
+			b.LeaseExpiry,
+		)
+	}
+
+	return input.CommitScriptToSelf(
+		b.CSVDelay, localDelayedPubKey, revocationPubKey,
+	)
+}
+
+// ToLocalOutputSpendInfo describes how a justice transaction spends the
+// revocation path of the breached commitment to-local output.
+type ToLocalOutputSpendInfo struct {
+	// WitnessScript is the witness script of the to-local output. For
+	// taproot channels, this is the script of the revocation leaf, and it
+	// is nil if the revocation path is the key path.
+	WitnessScript []byte
+
+	// WitnessStack is the witness stack satisfying the revocation path of
+	// WitnessScript, which is followed by the script itself in the final
+	// witness.
+	WitnessStack [][]byte
+
+	// ControlBlock is the serialized control block proving the inclusion
+	// of the revocation leaf in the output key, which terminates the final
+	// witness. It is only set for taproot channels spending the revocation
+	// leaf.
+	ControlBlock []byte
+
+	// WitnessSize is the estimated size of the final witness, including
+	// the witness script and control block.
+	WitnessSize int
+}
+
+// Witness assembles the final witness spending the to-local output, consisting
+// of the witness stack followed by the witness script and control block, if
+// any.
+func (s *ToLocalOutputSpendInfo) Witness() wire.TxWitness {
+	witness := make(wire.TxWitness, 0, len(s.WitnessStack)+2)
+	witness = append(witness, s.WitnessStack...)
+	if s.WitnessScript != nil {
+		witness = append(witness, s.WitnessScript)
+	}
+	if s.ControlBlock != nil {
+		witness = append(witness, s.ControlBlock)
+	}
+
+	return witness
+}
+
+// ToLocalOutputSpendInfo returns the witness script and witness stack for
+// spending the revocation path of the breached commitment to-local output,
+// accounting for the lease expiry of blob types with FlagLeaseChannel. The
+// CLTV clause of a leased to-local script only guards its delayed path, so the
+// revocation path can be spent immediately, and the justice transaction
+// needn't set a locktime. It must not be delayed until the lease expires, as
+// the breaching party could otherwise sweep the output first once its CSV
+// delay has elapsed. For taproot channels, the revocation leaf is spent
+// via the script path, unless the blob type has FlagTaprootKeySpend, in which
+// case the witness is a single schnorr signature for the key path. Blob types
+// with FlagToRemoteOnly return ErrNoCommitToLocalOutput.
+func (b *JusticeKit) ToLocalOutputSpendInfo() (*ToLocalOutputSpendInfo,
+	error) {
+
+	if b.BlobType.Has(FlagToRemoteOnly) {
+		return nil, ErrNoCommitToLocalOutput
+	}
+
+	if b.BlobType.IsTaprootChannel() {
+		return b.taprootToLocalSpendInfo()
+	}
+
+	witnessScript, err := b.CommitToLocalWitnessScript()
+	if err != nil {
+		return nil, err
+	}
+
+	witnessStack, err := b.CommitToLocalRevokeWitnessStack()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ToLocalOutputSpendInfo{
+		WitnessScript: witnessScript,
+		WitnessStack:  witnessStack,
+		WitnessSize:   b.toLocalPenaltyWitnessSize(),
+	}, nil
+}
+
+// ToLocalWitnessScript returns the witness script revealed by the witness
+// spending the revocation path of the breached commitment to-local output, as
+// in ToLocalOutputSpendInfo, without requiring the kit's signatures. This
+// allows a watcher to learn the scripts of the breached outputs before the kit
+// is signed. For taproot channels, this is the script of the revocation leaf,
+// and it is nil for blob types with FlagTaprootKeySpend, whose witness reveals
+// no script.
+func (b *JusticeKit) ToLocalWitnessScript() ([]byte, error) {
+	if b.BlobType.Has(FlagToRemoteOnly) {
+		return nil, ErrNoCommitToLocalOutput
+	}
+
+	if !b.BlobType.IsTaprootChannel() {
+		return b.CommitToLocalWitnessScript()
+	}
+
+	if b.BlobType.IsTaprootKeySpend() {
+		return nil, nil
+	}
+
+	scriptTree, err := b.commitToLocalScriptTree()
+	if err != nil {
+		return nil, err
+	}
+
+	return scriptTree.RevocationLeaf.Script, nil
+}
+
+// taprootToLocalSpendInfo returns the spend info for the revocation path of a
+// taproot to-local output, which is either its key path or its revocation
+// leaf depending on the blob type. Both are satisfied by a single signature
+// under the revocation pubkey.
+func (b *JusticeKit) taprootToLocalSpendInfo() (*ToLocalOutputSpendInfo,
+	error) {
+
+	toLocalSig, err := b.witnessSig(
+		b.CommitToLocalSig, b.ToLocalSigHash(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.BlobType.IsTaprootKeySpend() {
+		return &ToLocalOutputSpendInfo{
+			WitnessStack: [][]byte{toLocalSig},
+			WitnessSize:  input.TaprootKeyPathWitnessSize,
+		}, nil
+	}
+
+	scriptTree, err := b.commitToLocalScriptTree()
+	if err != nil {
+		return nil, err
+	}
+
+	ctrlBlock := input.MakeTaprootCtrlBlock(
+		scriptTree.RevocationLeaf.Script, &input.TaprootNUMSKey,
+		scriptTree.TapscriptTree,
+	)
+	ctrlBlockBytes, err := ctrlBlock.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ToLocalOutputSpendInfo{
+		WitnessScript: scriptTree.RevocationLeaf.Script,
+		WitnessStack:  [][]byte{toLocalSig},
+		ControlBlock:  ctrlBlockBytes,
+		WitnessSize:   input.TaprootToLocalRevokeWitnessSize,
+	}, nil
+}
+
+// toLocalPenaltyWitnessSize returns the estimated size of the witness spending
+// the revocation path of the to-local output, or a second-level HTLC output,
+// which is larger for leased channels due to the additional CLTV clause.
+func (b *JusticeKit) toLocalPenaltyWitnessSize() int {
+	if b.BlobType.Has(FlagLeaseChannel) {
+		return input.ToLocalPenaltyWitnessSize +
+			input.LeaseWitnessScriptSizeOverhead
+	}
+
+	return input.ToLocalPenaltyWitnessSize
+}
+
+// CommitToLocalRevokeWitnessStack constructs a witness stack spending the
+// revocation clause of the commitment to-local output. Taproot signatures use
+// SIGHASH_DEFAULT, and so carry no sighash flag.
+//
+//	<revocation-sig> 1
+func (b *JusticeKit) CommitToLocalRevokeWitnessStack() ([][]byte, error) {
+	toLocalSig, err := b.witnessSig(
+		b.CommitToLocalSig, b.ToLocalSigHash(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	witnessStack := make([][]byte, 2)
+	witnessStack[0] = toLocalSig
+	witnessStack[1] = []byte{1}
+
+	return witnessStack, nil
+}
+
+// witnessSig serializes a commitment signature made with the given sighash
+// type for inclusion in a witness stack. Signatures in taproot kits are schnorr
+// signatures using SIGHASH_DEFAULT, which is encoded by omitting the sighash
+// flag entirely, leaving exactly 64 bytes. All other signatures are suffixed
+// with an explicit sighash flag.
+func (b *JusticeKit) witnessSig(sig lnwire.Sig,
+	sigHash txscript.SigHashType) ([]byte, error) {
+
+	parsedSig, err := sig.ToSignature()
+	if err != nil {
+		return nil, err
+	}
+
+	sigBytes := parsedSig.Serialize()
+	if sigHash == txscript.SigHashDefault {
+		return sigBytes, nil
+	}
+
+	return append(sigBytes, byte(sigHash)), nil
+}
+
+// DecodeSweepAddress extracts the address paid to by the sweep pkScript for
+// the given network. An error is returned if the blob has no sweep address, or
+// if the pkScript doesn't pay to exactly one standard address.
+func (b *JusticeKit) DecodeSweepAddress(
+	params *chaincfg.Params) (btcutil.Address, error) {
+
+	if len(b.SweepAddress) == 0 {
+		return nil, ErrNoSweepAddress
+	}
+
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+		b.SweepAddress, params,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addrs) != 1 {
+		return nil, ErrUnknownSweepAddrType
+	}
+
+	return addrs[0], nil
+}
+
+// Copy returns a deep copy of the kit, such that modifying the copy, including
+// its sweep address, signatures, data commitment or trailer records, leaves
+// the original untouched.
+func (b *JusticeKit) Copy() *JusticeKit {
+	kit := *b
+	kit.SweepAddress = cloneBytes(b.SweepAddress)
+	kit.DataCommitment = cloneBytes(b.DataCommitment)
+
+	if b.SecondLevelHtlcSigs != nil {
+		kit.SecondLevelHtlcSigs = make(
+			[]lnwire.Sig, len(b.SecondLevelHtlcSigs),
+		)
+		copy(kit.SecondLevelHtlcSigs, b.SecondLevelHtlcSigs)
+	}
+
+	if b.TrailerRecords != nil {
+		kit.TrailerRecords = make(
+			map[uint64][]byte, len(b.TrailerRecords),
+		)
+		for typ, value := range b.TrailerRecords {
+			kit.TrailerRecords[typ] = cloneBytes(value)
+		}
+	}
+
+	return &kit
+}
+
+// cloneBytes returns a copy of b, preserving whether it is nil.
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append([]byte{}, b...)
+}
+
+// WithSweepAddress returns a copy of the kit paying to the given sweep
+// pkScript, which must be a P2WPKH, P2WSH or P2TR script, as these are the
+// outputs a tower is able to weigh when assembling the justice transaction.
+// The witness scripts and stacks of the copy are identical to those of the
+// original kit, since none of the breached outputs' scripts depend on the
+// sweep address.
+//
+// NOTE: All blob types supported by this package carry signatures made with
+// SIGHASH_ALL, or SIGHASH_DEFAULT for taproot channels, both of which commit
+// to every output of the justice transaction. No commitment type is therefore
+// safe to redirect without re-signing: the signatures of the copy remain valid
+// only for a justice transaction paying the original address, and must be
+// replaced via ReplaceToLocalSig and ReplaceToRemoteSig before the copy is
+// used.
+func (b *JusticeKit) WithSweepAddress(pkScript []byte) (*JusticeKit, error) {
+	if err := validateSweepAddress(pkScript); err != nil {
+		return nil, err
+	}
+
+	kit := b.Copy()
+	kit.SweepAddress = append([]byte(nil), pkScript...)
+
+	return kit, nil
+}
+
+// validateSweepAddress checks that pkScript is a non-empty P2WPKH, P2WSH or
+// P2TR script that fits in the sweep address field of a blob.
+func validateSweepAddress(pkScript []byte) error {
+	switch {
+	case len(pkScript) == 0:
+		return ErrNoSweepAddress
+
+	case len(pkScript) > MaxSweepAddrSize:
+		return ErrSweepAddressToLong
+	}
+
+	switch txscript.GetScriptClass(pkScript) {
+	case txscript.WitnessV0PubKeyHashTy, txscript.WitnessV0ScriptHashTy,
+		txscript.WitnessV1TaprootTy:
+
+		return nil
+
+	default:
+		return ErrUnknownSweepAddrType
+	}
+}
+
+// SweepPkScript returns a copy of the pkScript paying to the client's sweep
+// address, or ErrNoSweepAddress if the blob has none.
+func (b *JusticeKit) SweepPkScript() ([]byte, error) {
+	if len(b.SweepAddress) == 0 {
+		return nil, ErrNoSweepAddress
+	}
+
+	return append([]byte(nil), b.SweepAddress...), nil
+}
+
+// RevocationKey returns the parsed revocation pubkey guarding the revocation
+// clause of the remote party's to-local output.
+func (b *JusticeKit) RevocationKey() (*btcec.PublicKey, error) {
+	return btcec.ParsePubKey(b.RevocationPubKey[:])
+}
+
+// LocalDelayKey returns the parsed pubkey guarding the delayed path of the
+// remote party's to-local output.
+func (b *JusticeKit) LocalDelayKey() (*btcec.PublicKey, error) {
+	return btcec.ParsePubKey(b.LocalDelayPubKey[:])
+}
+
+// CommitToRemoteKey returns the parsed pubkey of the to-remote output of the
+// revoked commitment, or ErrNoCommitToRemoteOutput if the blob has none.
+func (b *JusticeKit) CommitToRemoteKey() (*btcec.PublicKey, error) {
+	if !b.HasCommitToRemoteOutput() {
+		return nil, ErrNoCommitToRemoteOutput
+	}
+
+	return btcec.ParsePubKey(b.CommitToRemotePubKey[:])
+}
+
+// HasCommitToRemoteOutput returns true if the blob contains a to-remote p2wkh
+// pubkey.
+func (b *JusticeKit) HasCommitToRemoteOutput() bool {
+	return btcec.IsCompressedPubKey(b.CommitToRemotePubKey[:])
+}
+
+// CommitToRemoteWitnessScript returns the witness script for the commitment
+// to-remote output given the blob type. The script returned will either be for
+// a p2wpkh to-remote output, an p2wsh anchor to-remote output which includes
+// a CSV delay, or the tapscript leaf of a taproot to-remote output.
+//
+// Legacy channels may either tweak the to-remote key with the per-commitment
+// point, or use a static to-remote key with option_static_remotekey. Both
+// variants pay to a p2wkh output of the to-remote key, and the client copies
+// the key exactly as it appears on the breached commitment, tweaked or not.
+// As such, the same script and witness spend either variant and no flag is
+// needed to tell them apart.
+func (b *JusticeKit) CommitToRemoteWitnessScript() ([]byte, error) {
+	if !btcec.IsCompressedPubKey(b.CommitToRemotePubKey[:]) {
+		return nil, ErrNoCommitToRemoteOutput
+	}
+
+	// If this is a blob for a taproot channel, we'll return the tapscript
+	// leaf which contains a CSV delay of 1.
+	if b.BlobType.IsTaprootChannel() {
+		scriptTree, err := b.commitToRemoteScriptTree()
+		if err != nil {
+			return nil, err
+		}
+
+		return scriptTree.SettleLeaf.Script, nil
+	}
+
+	// If this is a blob for an anchor channel, we'll return the p2wsh
+	// output containing a CSV delay of 1.
+	if b.BlobType.IsAnchorChannel() {
+		pk, err := btcec.ParsePubKey(b.CommitToRemotePubKey[:])
+		if err != nil {
+			return nil, err
+		}
+
+		return input.CommitScriptToRemoteConfirmed(pk)
+	}
+
+	return b.CommitToRemotePubKey[:], nil
+}
+
+// CommitToRemoteWitnessStack returns a witness stack spending the commitment
+// to-remote output, which consists of a single signature satisfying either the
+// legacy, anchor or taproot witness scripts. Taproot signatures use
+// SIGHASH_DEFAULT, and so carry no sighash flag.
+//
+//	<to-remote-sig>
+func (b *JusticeKit) CommitToRemoteWitnessStack() ([][]byte, error) {
+	toRemoteSig, err := b.witnessSig(
+		b.CommitToRemoteSig, b.ToRemoteSigHash(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	witnessStack := make([][]byte, 1)
+	witnessStack[0] = toRemoteSig
+
+	return witnessStack, nil
+}
+
+// ToRemoteOutputSpendInfo describes how a justice transaction spends the
+// breached commitment to-remote output.
+type ToRemoteOutputSpendInfo struct {
+	// WitnessScript is the witness script of the to-remote output, as
+	// returned by CommitToRemoteWitnessScript.
+	WitnessScript []byte
+
+	// WitnessStack is the witness stack spending the to-remote output,
+	// which is followed by the script itself in the final witness.
+	WitnessStack [][]byte
+
+	// ControlBlock is the serialized control block proving the inclusion
+	// of the to-remote leaf in the output key, which terminates the final
+	// witness. It is only set for taproot channels.
+	ControlBlock []byte
+
+	// WitnessSize is the estimated size of the final witness.
+	WitnessSize int
+
+	// Sequence is the nSequence that the justice transaction input
+	// spending the output must set. Anchor and taproot to-remote outputs
+	// are encumbered by a CSV delay of 1, and so require a sequence of 1,
+	// while legacy p2wkh to-remote outputs require none.
+	Sequence uint32
+}
+
+// Witness assembles the final witness spending the to-remote output,
+// consisting of the witness stack followed by the witness script and control
+// block, if any.
+func (s *ToRemoteOutputSpendInfo) Witness() wire.TxWitness {
+	witness := make(wire.TxWitness, 0, len(s.WitnessStack)+2)
+	witness = append(witness, s.WitnessStack...)
+	witness = append(witness, s.WitnessScript)
+	if s.ControlBlock != nil {
+		witness = append(witness, s.ControlBlock)
+	}
+
+	return witness
+}
+
+// ToRemoteOutputSpendInfo returns the witness script, witness stack and
+// required input sequence for spending the breached commitment to-remote
+// output, such that callers needn't special case the CSV delay of anchor
+// to-remote outputs. For taproot channels, the to-remote leaf is spent via the
+// script path, and the control block is included.
+func (b *JusticeKit) ToRemoteOutputSpendInfo() (*ToRemoteOutputSpendInfo,
+	error) {
+
+	witnessScript, err := b.CommitToRemoteWitnessScript()
+	if err != nil {
+		return nil, err
+	}
+
+	witnessStack, err := b.CommitToRemoteWitnessStack()
+	if err != nil {
+		return nil, err
+	}
+
+	var ctrlBlockBytes []byte
+	if b.BlobType.IsTaprootChannel() {
+		scriptTree, err := b.commitToRemoteScriptTree()
+		if err != nil {
+			return nil, err
+		}
+
+		ctrlBlock := input.MakeTaprootCtrlBlock(
+			scriptTree.SettleLeaf.Script, &input.TaprootNUMSKey,
+			scriptTree.TapscriptTree,
+		)
+		ctrlBlockBytes, err = ctrlBlock.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ToRemoteOutputSpendInfo{
+		WitnessScript: witnessScript,
+		WitnessStack:  witnessStack,
+		ControlBlock:  ctrlBlockBytes,
+		WitnessSize:   b.toRemoteWitnessSize(),
+		Sequence:      b.toRemoteSequence(),
+	}, nil
+}
+
+// ToRemoteWitnessScript returns the witness script revealed by the witness
+// spending the breached commitment to-remote output, as in
+// ToRemoteOutputSpendInfo, without requiring the kit's signatures. It is the
+// counterpart of ToLocalWitnessScript, and an alias for
+// CommitToRemoteWitnessScript, which already doesn't depend on the signature.
+func (b *JusticeKit) ToRemoteWitnessScript() ([]byte, error) {
+	return b.CommitToRemoteWitnessScript()
+}
+
+// InputSequences returns the nSequence that the justice transaction inputs
+// spending the breached commitment to-local and to-remote outputs must set,
+// such that callers needn't know which outputs carry a relative locktime. The
+// revocation path of the to-local output isn't subject to its CSV delay, so
+// its input never requires a sequence. Anchor and taproot to-remote outputs
+// are encumbered by a CSV delay of 1, while legacy p2wkh to-remote outputs
+// require none.
+func (b *JusticeKit) InputSequences() (toLocal, toRemote uint32) {
+	return 0, b.toRemoteSequence()
+}
+
+// toRemoteSequence returns the nSequence required by an input spending the
+// breached commitment to-remote output.
+func (b *JusticeKit) toRemoteSequence() uint32 {
+	if b.BlobType.IsAnchorChannel() || b.BlobType.IsTaprootChannel() {
+		return 1
+	}
+
+	return 0
+}
+
+// CommitToRemoteControlBlock returns the serialized control block required to
+// spend the tapscript leaf of a taproot to-remote output. The output key is
+// reconstructed from the to-remote pubkey, and checked against the pkScript of
+// the breached output, such that an invalid witness is never produced.
+//
+// NOTE: This is only valid for taproot channels.
+func (b *JusticeKit) CommitToRemoteControlBlock(pkScript []byte) ([]byte,
+	error) {
+
+	if !b.BlobType.IsTaprootChannel() {
+		return nil, ErrNotTaprootChannel
+	}
+
+	if !btcec.IsCompressedPubKey(b.CommitToRemotePubKey[:]) {
+		return nil, ErrNoCommitToRemoteOutput
+	}
+
+	scriptTree, err := b.commitToRemoteScriptTree()
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure that the output key derived from the blob matches the one
+	// committed to by the breached output.
+	expPkScript, err := input.PayToTaprootScript(scriptTree.TaprootKey)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(expPkScript, pkScript) {
+		return nil, ErrTaprootOutputMismatch
+	}
+
+	ctrlBlock := input.MakeTaprootCtrlBlock(
+		scriptTree.SettleLeaf.Script, &input.TaprootNUMSKey,
+		scriptTree.TapscriptTree,
+	)
+
+	return ctrlBlock.ToBytes()
+}
+
+// commitToRemoteScriptTree reconstructs the script tree of a taproot to-remote
+// output from the to-remote pubkey.
+func (b *JusticeKit) commitToRemoteScriptTree() (*input.CommitScriptTree,
+	error) {
+
+	toRemotePubKey, err := btcec.ParsePubKey(b.CommitToRemotePubKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return input.NewRemoteCommitScriptTree(toRemotePubKey)
+}
+
+// VerifySignatures checks that the commit to-local and commit to-remote
+// signatures contained in the JusticeKit are valid spends of the corresponding
+// outputs of the breached commitment described by breachInfo, when included in
+// the given justice transaction. This allows a client to catch signing bugs
+// before a blob is handed to a tower. The returned error names the signature
+// that failed to verify.
+func (b *JusticeKit) VerifySignatures(breachInfo *lnwallet.BreachRetribution,
+	justiceTx *wire.MsgTx) error {
+
+	toLocalDesc := breachInfo.RemoteOutputSignDesc
+	toRemoteDesc := breachInfo.LocalOutputSignDesc
+
+	// Gather the breached outputs spent by the justice transaction, which
+	// are needed to compute the sighashes.
+	var (
+		prevOutFetcher = txscript.NewMultiPrevOutFetcher(nil)
+		numBreached    int
+	)
+	if toLocalDesc != nil {
+		prevOutFetcher.AddPrevOut(
+			breachInfo.RemoteOutpoint, toLocalDesc.Output,
+		)
+		numBreached++
+	}
+	if toRemoteDesc != nil {
+		prevOutFetcher.AddPrevOut(
+			breachInfo.LocalOutpoint, toRemoteDesc.Output,
+		)
+		numBreached++
+	}
+
+	// Ensure that the justice transaction spends exactly the breached
+	// outputs, as we can't compute the sighashes otherwise.
+	if len(justiceTx.TxIn) != numBreached {
+		return ErrJusticeInputMismatch
+	}
+	for _, txIn := range justiceTx.TxIn {
+		prevOut := prevOutFetcher.FetchPrevOutput(
+			txIn.PreviousOutPoint,
+		)
+		if prevOut == nil {
+			return ErrJusticeInputMismatch
+		}
+	}
+
+	hashCache := txscript.NewTxSigHashes(justiceTx, prevOutFetcher)
+
+	// The to-local output is spent via the revocation path, so its
+	// signature must be valid under the revocation key. Taproot channels
+	// spend the revocation leaf, unless the revocation path is the key
+	// path, in which case the revocation key is tweaked with the tapscript
+	// root, while other channels spend a p2wsh output.
+	if toLocalDesc != nil {
+		var (
+			verifyKey   = breachInfo.KeyRing.RevocationKey
+			calcSigHash func(idx int) ([]byte, error)
+		)
+		switch {
+		case b.BlobType.IsTaprootKeySpend():
+			tapscriptRoot, err := b.ToLocalKeySpendTapscriptRoot()
+			if err != nil {
+				return err
+			}
+
+			verifyKey = txscript.ComputeTaprootOutputKey(
+				verifyKey, tapscriptRoot,
+			)
+			calcSigHash = func(idx int) ([]byte, error) {
+				return txscript.CalcTaprootSignatureHash(
+					hashCache, b.ToLocalSigHash(),
+					justiceTx, idx, prevOutFetcher,
+				)
+			}
+
+		case b.BlobType.IsTaprootChannel():
+			scriptTree, err := b.commitToLocalScriptTree()
+			if err != nil {
+				return err
+			}
+
+			calcSigHash = func(idx int) ([]byte, error) {
+				return txscript.CalcTapscriptSignaturehash(
+					hashCache, b.ToLocalSigHash(),
+					justiceTx, idx, prevOutFetcher,
+					scriptTree.RevocationLeaf,
+				)
+			}
+
+		default:
+			toLocalScript, err := b.CommitToLocalWitnessScript()
+			if err != nil {
+				return err
+			}
+
+			calcSigHash = func(idx int) ([]byte, error) {
+				return txscript.CalcWitnessSigHash(
+					toLocalScript, hashCache,
+					b.ToLocalSigHash(), justiceTx, idx,
+					toLocalDesc.Output.Value,
+				)
+			}
+		}
+
+		err := verifyJusticeSig(
+			justiceTx, breachInfo.RemoteOutpoint,
+			b.CommitToLocalSig, verifyKey, calcSigHash,
+		)
+		if err != nil {
+			return fmt.Errorf("commit to-local signature: %w", err)
+		}
+	}
+
+	if toRemoteDesc == nil {
+		return nil
+	}
+
+	// Taproot channels spend the to-remote tapscript leaf, anchor channels
+	// spend a p2wsh to-remote output, while legacy channels spend a p2wkh
+	// output whose script code is derived from the pkscript itself.
+	var calcSigHash func(idx int) ([]byte, error)
+	switch {
+	case b.BlobType.IsTaprootChannel():
+		leafScript, err := b.CommitToRemoteWitnessScript()
+		if err != nil {
+			return err
+		}
+
+		calcSigHash = func(idx int) ([]byte, error) {
+			return txscript.CalcTapscriptSignaturehash(
+				hashCache, b.ToRemoteSigHash(), justiceTx,
+				idx, prevOutFetcher,
+				txscript.NewBaseTapLeaf(leafScript),
+			)
+		}
+
+	default:
+		scriptCode := toRemoteDesc.Output.PkScript
+		if b.BlobType.IsAnchorChannel() {
+			var err error
+			scriptCode, err = b.CommitToRemoteWitnessScript()
+			if err != nil {
+				return err
+			}
+		}
+
+		calcSigHash = func(idx int) ([]byte, error) {
+			return txscript.CalcWitnessSigHash(
+				scriptCode, hashCache, b.ToRemoteSigHash(),
+				justiceTx, idx, toRemoteDesc.Output.Value,
+			)
+		}
+	}
+
+	err := verifyJusticeSig(
+		justiceTx, breachInfo.LocalOutpoint, b.CommitToRemoteSig,
+		breachInfo.KeyRing.ToRemoteKey, calcSigHash,
+	)
+	if err != nil {
+		return fmt.Errorf("commit to-remote signature: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyAgainstOutputs checks that the kit's witness scripts commit to the
+// given pkScripts of the breached to-local and to-remote outputs, which catches
+// mismatched blob types or keys before a justice transaction is broadcast. The
+// to-local check is skipped if toLocalPkScript is nil, as is the case for a
+// breached commitment whose to-local output is dust. A toRemotePkScript must be
+// given if and only if the kit has a commit to-remote output, otherwise
+// ErrNoCommitToRemoteOutput is returned. The returned ErrOutputScriptMismatch
+// names the output whose script didn't match.
+func (b *JusticeKit) VerifyAgainstOutputs(toLocalPkScript,
+	toRemotePkScript []byte) error {
+
+	if toLocalPkScript != nil {
+		expPkScript, err := b.CommitToLocalPkScript()
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(expPkScript, toLocalPkScript) {
+			return fmt.Errorf("commit to-local: %w",
+				ErrOutputScriptMismatch)
+		}
+	}
+
+	if (toRemotePkScript != nil) != b.HasCommitToRemoteOutput() {
+		return ErrNoCommitToRemoteOutput
+	}
+
+	if toRemotePkScript == nil {
+		return nil
+	}
+
+	expPkScript, err := b.CommitToRemotePkScript()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(expPkScript, toRemotePkScript) {
+		return fmt.Errorf("commit to-remote: %w",
+			ErrOutputScriptMismatch)
+	}
+
+	return nil
+}
+
+// CommitToLocalPkScript returns the pkScript of the breached commitment
+// to-local output, which is a P2TR output for taproot channels, and a P2WSH
+// output otherwise. This allows a watcher to locate the output on the
+// breaching commitment. Blob types with FlagToRemoteOnly return
+// ErrNoCommitToLocalOutput.
+func (b *JusticeKit) CommitToLocalPkScript() ([]byte, error) {
+	if b.BlobType.Has(FlagToRemoteOnly) {
+		return nil, ErrNoCommitToLocalOutput
+	}
+
+	if !b.BlobType.IsTaprootChannel() {
+		toLocalScript, err := b.CommitToLocalWitnessScript()
+		if err != nil {
+			return nil, err
+		}
+
+		return input.WitnessScriptHash(toLocalScript)
+	}
+
+	if b.BlobType.IsTaprootKeySpend() {
+		revocationPubKey, err := b.RevocationKey()
+		if err != nil {
+			return nil, err
+		}
+
+		tapscriptRoot, err := b.ToLocalKeySpendTapscriptRoot()
+		if err != nil {
+			return nil, err
+		}
+
+		outputKey := txscript.ComputeTaprootOutputKey(
+			revocationPubKey, tapscriptRoot,
+		)
+
+		return input.PayToTaprootScript(outputKey)
+	}
+
+	scriptTree, err := b.commitToLocalScriptTree()
+	if err != nil {
+		return nil, err
+	}
+
+	return input.PayToTaprootScript(scriptTree.TaprootKey)
+}
+
+// commitToLocalScriptTree reconstructs the script tree of a taproot to-local
+// output from the revocation and local delay pubkeys.
+func (b *JusticeKit) commitToLocalScriptTree() (*input.CommitScriptTree,
+	error) {
+
+	revocationPubKey, err := b.RevocationKey()
+	if err != nil {
+		return nil, err
+	}
+
+	localDelayedPubKey, err := b.LocalDelayKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return input.NewLocalCommitScriptTree(
+		b.CSVDelay, localDelayedPubKey, revocationPubKey,
+	)
+}
+
+// ToLocalKeySpendTapscriptRoot returns the root of the tapscript tree committed
+// to by a to-local output revoked via its key path, which consists solely of
+// the delayed leaf. Signers of the key path must tweak the revocation key, or
+// its MuSig2 aggregate, with this root.
+//
+// NOTE: This is only valid for blob types with FlagTaprootKeySpend.
+func (b *JusticeKit) ToLocalKeySpendTapscriptRoot() ([]byte, error) {
+	if !b.BlobType.IsTaprootKeySpend() {
+		return nil, ErrNotTaprootKeySpend
+	}
+
+	scriptTree, err := b.commitToLocalScriptTree()
+	if err != nil {
+		return nil, err
+	}
+
+	tapscriptTree := txscript.AssembleTaprootScriptTree(
+		scriptTree.SettleLeaf,
+	)
+	tapscriptRoot := tapscriptTree.RootNode.TapHash()
+
+	return tapscriptRoot[:], nil
+}
+
+// CommitToRemotePkScript returns the pkScript of the breached commitment
+// to-remote output, which is a P2TR output for taproot channels, a P2WSH
+// output for anchor channels, and a P2WKH output otherwise. This allows a
+// watcher to locate the output on the breaching commitment. Kits without a
+// to-remote output return ErrNoCommitToRemoteOutput.
+func (b *JusticeKit) CommitToRemotePkScript() ([]byte, error) {
+	if !b.HasCommitToRemoteOutput() {
+		return nil, ErrNoCommitToRemoteOutput
+	}
+
+	switch {
+	case b.BlobType.IsTaprootChannel():
+		scriptTree, err := b.commitToRemoteScriptTree()
+		if err != nil {
+			return nil, err
+		}
+
+		return input.PayToTaprootScript(scriptTree.TaprootKey)
+
+	case b.BlobType.IsAnchorChannel():
+		toRemoteScript, err := b.CommitToRemoteWitnessScript()
+		if err != nil {
+			return nil, err
+		}
+
+		return input.WitnessScriptHash(toRemoteScript)
+
+	default:
+		toRemotePubKey, err := btcec.ParsePubKey(
+			b.CommitToRemotePubKey[:],
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return input.CommitScriptUnencumbered(toRemotePubKey)
+	}
+}
+
+// verifyJusticeSig checks that sig is a valid signature under pubKey for the
+// input of the justice transaction spending prevOut, using calcSigHash to
+// compute the sighash of the input at the given index.
+func verifyJusticeSig(justiceTx *wire.MsgTx, prevOut wire.OutPoint,
+	sig lnwire.Sig, pubKey *btcec.PublicKey,
+	calcSigHash func(idx int) ([]byte, error)) error {
+
+	inputIndex := -1
+	for i, txIn := range justiceTx.TxIn {
+		if txIn.PreviousOutPoint == prevOut {
+			inputIndex = i
+			break
+		}
+	}
+	if inputIndex == -1 {
+		return ErrJusticeInputMismatch
+	}
+
+	sigHash, err := calcSigHash(inputIndex)
+	if err != nil {
+		return err
+	}
+
+	signature, err := sig.ToSignature()
+	if err != nil {
+		return err
+	}
+
+	if !signature.Verify(sigHash, pubKey) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Equal compares the JusticeKit against other, returning true if all fields
+// match. If the kits differ, a human-readable description of the first field
+// that does not match is returned, which is useful for diagnosing why a
+// decrypted kit does not match expectations.
+func (b *JusticeKit) Equal(other *JusticeKit) (bool, string) {
+	switch {
+	case b == nil && other == nil:
+		return true, ""
+
+	case b == nil || other == nil:
+		return false, fmt.Sprintf("JusticeKit mismatch: %v vs %v",
+			b, other)
+
+	case b.BlobType != other.BlobType:
+		return false, fmt.Sprintf("BlobType mismatch: %v vs %v",
+			b.BlobType, other.BlobType)
+
+	case !bytes.Equal(b.SweepAddress, other.SweepAddress):
+		return false, fmt.Sprintf("SweepAddress mismatch: %x vs %x",
+			b.SweepAddress, other.SweepAddress)
+
+	case b.RevocationPubKey != other.RevocationPubKey:
+		return false, fmt.Sprintf("RevocationPubKey mismatch: %x vs "+
+			"%x", b.RevocationPubKey, other.RevocationPubKey)
+
+	case b.LocalDelayPubKey != other.LocalDelayPubKey:
+		return false, fmt.Sprintf("LocalDelayPubKey mismatch: %x vs "+
+			"%x", b.LocalDelayPubKey, other.LocalDelayPubKey)
+
+	case b.CSVDelay != other.CSVDelay:
+		return false, fmt.Sprintf("CSVDelay mismatch: %d vs %d",
+			b.CSVDelay, other.CSVDelay)
+
+	case b.CommitToLocalSig != other.CommitToLocalSig:
+		return false, fmt.Sprintf("CommitToLocalSig mismatch: %x vs "+
+			"%x", b.CommitToLocalSig.RawBytes(),
+			other.CommitToLocalSig.RawBytes())
+
+	case b.HasCommitToRemoteOutput() != other.HasCommitToRemoteOutput():
+		return false, fmt.Sprintf("HasCommitToRemoteOutput mismatch: "+
+			"%v vs %v", b.HasCommitToRemoteOutput(),
+			other.HasCommitToRemoteOutput())
+
+	case b.CommitToRemotePubKey != other.CommitToRemotePubKey:
+		return false, fmt.Sprintf("CommitToRemotePubKey mismatch: %x "+
+			"vs %x", b.CommitToRemotePubKey,
+			other.CommitToRemotePubKey)
+
+	case b.CommitToRemoteSig != other.CommitToRemoteSig:
+		return false, fmt.Sprintf("CommitToRemoteSig mismatch: %x vs "+
+			"%x", b.CommitToRemoteSig.RawBytes(),
+			other.CommitToRemoteSig.RawBytes())
+
+	case !bytes.Equal(b.DataCommitment, other.DataCommitment):
+		return false, fmt.Sprintf("DataCommitment mismatch: %x vs %x",
+			b.DataCommitment, other.DataCommitment)
+
+	case b.LeaseExpiry != other.LeaseExpiry:
+		return false, fmt.Sprintf("LeaseExpiry mismatch: %d vs %d",
+			b.LeaseExpiry, other.LeaseExpiry)
+
+	case b.ToLocalSigHash() != other.ToLocalSigHash():
+		return false, fmt.Sprintf("ToLocalSigHash mismatch: %v vs %v",
+			b.ToLocalSigHash(), other.ToLocalSigHash())
+
+	case b.ToRemoteSigHash() != other.ToRemoteSigHash():
+		return false, fmt.Sprintf("ToRemoteSigHash mismatch: %v vs "+
+			"%v", b.ToRemoteSigHash(), other.ToRemoteSigHash())
+
+	case len(b.TrailerRecords) != len(other.TrailerRecords):
+		return false, fmt.Sprintf("TrailerRecords mismatch: %d vs %d "+
+			"records", len(b.TrailerRecords),
+			len(other.TrailerRecords))
+
+	case len(b.SecondLevelHtlcSigs) != len(other.SecondLevelHtlcSigs):
+		return false, fmt.Sprintf("SecondLevelHtlcSigs mismatch: %d "+
+			"vs %d sigs", len(b.SecondLevelHtlcSigs),
+			len(other.SecondLevelHtlcSigs))
+	}
+
+	for i, sig := range b.SecondLevelHtlcSigs {
+		if sig != other.SecondLevelHtlcSigs[i] {
+			return false, fmt.Sprintf("SecondLevelHtlcSigs mismatch "+
+				"at index %d: %x vs %x", i, sig.RawBytes(),
+				other.SecondLevelHtlcSigs[i].RawBytes())
+		}
+	}
+
+	for typ, value := range b.TrailerRecords {
+		otherValue, ok := other.TrailerRecords[typ]
+		if !ok || !bytes.Equal(value, otherValue) {
+			return false, fmt.Sprintf("TrailerRecords mismatch "+
+				"for type %d: %x vs %x", typ, value, otherValue)
+		}
+	}
+
+	return true, ""
+}
+
+// Encrypt encodes the blob of justice using encoding version, and then
+// creates a ciphertext using chacha20poly1305 under the chosen (nonce, key)
+// pair. ErrMissingSignature is returned if any signature required to sweep the
+// kit's outputs has not been added.
+//
+// NOTE: It is the caller's responsibility to ensure that this method is only
+// called once for a given (nonce, key) pair.
+func (b *JusticeKit) Encrypt(key BreachKey) ([]byte, error) {
+	// Allocate the ciphertext, which will contain the nonce, encrypted
+	// plaintext and MAC.
+	return sealKit(make([]byte, 0, Size(b.BlobType)), b, key, nil)
+}
+
+// EncryptStrict behaves like Encrypt, but fails with ErrZeroBreachKey if the
+// breach key is all zeros, guarding against encrypting under a key that was
+// never set. Encrypt accepts such keys for backwards compatibility.
+//
+// NOTE: It is the caller's responsibility to ensure that this method is only
+// called once for a given (nonce, key) pair.
+func (b *JusticeKit) EncryptStrict(key BreachKey) ([]byte, error) {
+	if key.IsZero() {
+		return nil, ErrZeroBreachKey
+	}
+
+	return b.Encrypt(key)
+}
+
+// EncryptWithChannelPoint behaves like Encrypt, but prefixes the ciphertext
+// with a plaintext header containing the given channel point. The header is
+// authenticated as associated data of the AEAD, such that any tampering with it
+// is detected by Decrypt. The channel point can be read back without the key
+// using ReadBlobChannelPoint, which allows operators to audit stored blobs.
+//
+// NOTE: It is the caller's responsibility to ensure that this method is only
+// called once for a given (nonce, key) pair.
+func (b *JusticeKit) EncryptWithChannelPoint(key BreachKey,
+	chanPoint wire.OutPoint) ([]byte, error) {
+
+	header := encodeChannelPoint(chanPoint)
+
+	ciphertext := make(
+		[]byte, 0, ChannelPointHeaderSize+Size(b.BlobType),
+	)
+	ciphertext = append(ciphertext, header[:]...)
+
+	return sealKit(ciphertext, b, key, header[:])
+}
+
+// EncryptWithCommitment behaves like EncryptWithChannelPoint, but additionally
+// includes the number of the breached commitment in the plaintext header. The
+// commitment number is stored as authenticated associated data rather than in
+// the encrypted payload, as a tower needs it without holding the breach key,
+// in order to drop the blobs of a channel superseded by a newer state. This
+// reveals the channel's state numbers to the tower, and should only be used
+// with towers trusted with them. The commitment number can be read back using
+// ReadBlobCommitmentNumber, and is exposed by the kit returned from Decrypt
+// through CommitmentNumber.
+//
+// NOTE: It is the caller's responsibility to ensure that this method is only
+// called once for a given (nonce, key) pair.
+func (b *JusticeKit) EncryptWithCommitment(key BreachKey,
+	chanPoint wire.OutPoint, commitNum uint64) ([]byte, error) {
+
+	var header [CommitmentHeaderSize]byte
+	chanPointHeader := encodeChannelPoint(chanPoint)
+	copy(header[:], chanPointHeader[:])
+	byteOrder.PutUint64(header[ChannelPointHeaderSize:], commitNum)
+
+	ciphertext := make([]byte, 0, CommitmentHeaderSize+Size(b.BlobType))
+	ciphertext = append(ciphertext, header[:]...)
+
+	return sealKit(ciphertext, b, key, header[:])
+}
+
+// CommitmentNumber returns the number of the breached commitment, and true, if
+// the kit was decrypted from a ciphertext created by EncryptWithCommitment.
+// Otherwise, false is returned.
+func (b *JusticeKit) CommitmentNumber() (uint64, bool) {
+	return b.commitmentNumber, b.hasCommitmentNumber
+}
+
+// EncryptWithAAD behaves like Encrypt, but additionally authenticates the
+// given associated data, such as a session ID and sequence number the tower
+// should be able to see in the clear. The associated data is not included in
+// the returned ciphertext, and must be stored or transmitted alongside it, such
+// that it can be passed to DecryptWithAAD.
+//
+// NOTE: It is the caller's responsibility to ensure that this method is only
+// called once for a given (nonce, key) pair.
+func (b *JusticeKit) EncryptWithAAD(key BreachKey, aad []byte) ([]byte,
+	error) {
+
+	return sealKit(make([]byte, 0, Size(b.BlobType)), b, key, aad)
+}
+
+// ReadBlobChannelPoint returns the channel point stored in the plaintext header
+// of a ciphertext created by EncryptWithChannelPoint or EncryptWithCommitment.
+// No key is required, though the channel point is only authenticated once the
+// blob is decrypted. ErrNoChannelPoint is returned if the ciphertext does not
+// carry a header.
+func ReadBlobChannelPoint(ctxt []byte) (wire.OutPoint, error) {
+	if headerSize(ctxt) == 0 {
+		return wire.OutPoint{}, ErrNoChannelPoint
+	}
+
+	var chanPoint wire.OutPoint
+	copy(chanPoint.Hash[:], ctxt[:32])
+	chanPoint.Index = byteOrder.Uint32(ctxt[32:ChannelPointHeaderSize])
+
+	return chanPoint, nil
+}
+
+// ReadBlobCommitmentNumber returns the commitment number stored in the
+// plaintext header of a ciphertext created by EncryptWithCommitment. No key is
+// required, though the commitment number is only authenticated once the blob
+// is decrypted. ErrNoCommitmentNumber is returned if the ciphertext does not
+// carry a commitment header.
+func ReadBlobCommitmentNumber(ctxt []byte) (uint64, error) {
+	if headerSize(ctxt) != CommitmentHeaderSize {
+		return 0, ErrNoCommitmentNumber
+	}
+
+	return byteOrder.Uint64(
+		ctxt[ChannelPointHeaderSize:CommitmentHeaderSize],
+	), nil
+}
+
+// BlobMatchesChannel reports whether a ciphertext created by
+// EncryptWithChannelPoint or EncryptWithCommitment under the given key was
+// encrypted for the given channel point. The channel point is authenticated by
+// opening the AEAD with it as associated data, which is cheaper than decrypting
+// the blob with Decrypt, as the plaintext is never decoded. A false result
+// doesn't distinguish a blob of another channel from one encrypted under a
+// different key, or one that was tampered with. ErrNoChannelPoint is returned
+// if the ciphertext doesn't carry a header for the given blob type.
+func BlobMatchesChannel(key BreachKey, ctxt []byte, version Type,
+	chanPoint wire.OutPoint) (bool, error) {
+
+	if PlaintextSize(version) == 0 {
+		return false, ErrUnknownBlobType
+	}
+
+	headerLen := len(ctxt) - Size(version)
+	if headerLen != ChannelPointHeaderSize &&
+		headerLen != CommitmentHeaderSize {
+
+		return false, ErrNoChannelPoint
+	}
+
+	// The header must carry the channel point for the AEAD to be opened
+	// with it, so we can bail out early if it doesn't. Only the header
+	// is used as associated data below, which authenticates it.
+	header := encodeChannelPoint(chanPoint)
+	if !bytes.Equal(ctxt[:ChannelPointHeaderSize], header[:]) {
+		return false, nil
+	}
+
+	cipher, err := newCipher(key)
+	if err != nil {
+		return false, err
+	}
+
+	ptxtBuf := getPlaintextBuf(PlaintextSize(version))
+	plaintext, err := cipher.OpenPrefixed(
+		ptxtBuf.Bytes(), ctxt[headerLen:], ctxt[:headerLen],
+	)
+	putPlaintextBuf(ptxtBuf, plaintext)
+
+	return err == nil, nil
+}
+
+// headerSize returns the size of the plaintext header of the ciphertext, either
+// ChannelPointHeaderSize or CommitmentHeaderSize, if its length matches that of
+// a supported blob type prefixed by such a header. Otherwise, zero is returned.
+func headerSize(ctxt []byte) int {
+	for blobType := range supportedTypes {
+		switch len(ctxt) {
+		case ChannelPointHeaderSize + Size(blobType):
+			return ChannelPointHeaderSize
+
+		case CommitmentHeaderSize + Size(blobType):
+			return CommitmentHeaderSize
+		}
+	}
+
+	return 0
+}
+
+// encodeChannelPoint serializes the channel point as a 32-byte txid followed by
+// a 4-byte big-endian output index.
+func encodeChannelPoint(chanPoint wire.OutPoint) [ChannelPointHeaderSize]byte {
+	var header [ChannelPointHeaderSize]byte
+	copy(header[:32], chanPoint.Hash[:])
+	byteOrder.PutUint32(header[32:], chanPoint.Index)
+
+	return header
+}
+
+// EncryptTo encodes the blob of justice using encoding version, and streams
+// the resulting ciphertext to the provided io.Writer. The bytes written are
+// identical in layout to those returned by Encrypt, the nonce followed by the
+// encrypted plaintext and MAC, and the total number of bytes written is
+// returned. The plaintext is encrypted in place, such that no buffer larger
+// than the padded plaintext and its MAC is allocated.
+//
+// NOTE: It is the caller's responsibility to ensure that this method is only
+// called once for a given (nonce, key) pair.
+func EncryptTo(w io.Writer, kit *JusticeKit, key BreachKey) (int, error) {
+	return encryptTo(w, kit, key, nil)
+}
+
+// encryptTo encrypts the kit as described in EncryptTo, authenticating the
+// given associated data alongside the ciphertext.
+func encryptTo(w io.Writer, kit *JusticeKit, key BreachKey,
+	ad []byte) (int, error) {
+
+	ciphertext, err := sealKit(
+		make([]byte, 0, Size(kit.BlobType)), kit, key, ad,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	// Finally, write out the nonce followed by the ciphertext.
+	return w.Write(ciphertext)
+}
+
+// plaintextPool recycles the scratch buffers holding the plaintext of the
+// blobs being encrypted or decrypted, sparing an allocation per blob.
+var plaintextPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// getPlaintextBuf returns an empty scratch buffer from plaintextPool, with
+// capacity for at least size bytes.
+func getPlaintextBuf(size int) *bytes.Buffer {
+	buf := plaintextPool.Get().(*bytes.Buffer)
+	if size > 0 {
+		buf.Grow(size)
+	}
+
+	return buf
+}
+
+// putPlaintextBuf wipes the plaintext held by the scratch buffer, such that it
+// doesn't linger in memory, before returning the buffer to plaintextPool.
+func putPlaintextBuf(buf *bytes.Buffer, plaintext []byte) {
+	for i := range plaintext {
+		plaintext[i] = 0
+	}
+
+	buf.Reset()
+	plaintextPool.Put(buf)
+}
+
+// EncryptWithProvider behaves like EncryptWithAAD, but delegates the
+// encryption of the padded plaintext to the given AEADProvider, such that the
+// breach key never needs to be held in process. A nil aad authenticates no
+// associated data, as done by Encrypt. ErrIncompatibleAEAD is returned if the
+// provider's ciphertext doesn't have the constant size of the blob type.
+func (b *JusticeKit) EncryptWithProvider(provider AEADProvider,
+	aad []byte) ([]byte, error) {
+
+	ptxtBuf, err := encodeKitPlaintext(b)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		putPlaintextBuf(ptxtBuf, ptxtBuf.Bytes())
+	}()
+	plaintext := ptxtBuf.Bytes()
+
+	ciphertext, err := provider.Seal(plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) != Size(b.BlobType) {
+		return nil, fmt.Errorf("%w: provider returned %d byte "+
+			"ciphertext, expected %d", ErrIncompatibleAEAD,
+			len(ciphertext), Size(b.BlobType))
+	}
+
+	log.Tracef("Encrypted %v blob with provider: plaintext=%d bytes, "+
+		"ciphertext=%d bytes", b.BlobType, len(plaintext),
+		len(ciphertext))
+
+	return ciphertext, nil
+}
+
+// encodeKitPlaintext validates the kit's signatures and encodes its padded
+// plaintext into a scratch buffer, which must be wiped and released using
+// putPlaintextBuf once the plaintext has been encrypted.
+func encodeKitPlaintext(kit *JusticeKit) (*bytes.Buffer, error) {
+	// Refuse to encrypt a kit that the tower would be unable to use to
+	// sweep the breached outputs.
+	if err := kit.validateSigs(); err != nil {
+		log.Debugf("Refusing to encrypt %v blob: %v", kit.BlobType,
+			err)
+
+		return nil, err
+	}
+
+	ptxtBuf := getPlaintextBuf(PlaintextSize(kit.BlobType))
+	if err := kit.serializePaddedTo(ptxtBuf); err != nil {
+		log.Debugf("Unable to encode %v blob: %v", kit.BlobType, err)
+
+		putPlaintextBuf(ptxtBuf, ptxtBuf.Bytes())
+
+		return nil, err
+	}
+
+	return ptxtBuf, nil
+}
+
+// sealKit encrypts the kit under a random nonce, authenticating the given
+// associated data alongside the ciphertext, and appends the nonce followed by
+// the ciphertext to dst.
+func sealKit(dst []byte, kit *JusticeKit, key BreachKey,
+	ad []byte) ([]byte, error) {
+
+	// Encode the plaintext into a scratch buffer, which is wiped once the
+	// plaintext has been encrypted.
+	ptxtBuf, err := encodeKitPlaintext(kit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		putPlaintextBuf(ptxtBuf, ptxtBuf.Bytes())
+	}()
+	plaintext := ptxtBuf.Bytes()
+
+	// Create the cipher using the configured AEAD factory, which is
+	// xchacha20poly1305 under a 32-byte key by default.
+	cipher, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Encrypt the plaintext under a random 24-byte nonce, which prefixes
+	// the resulting ciphertext.
+	ciphertext, err := cipher.SealRandom(dst, rand.Reader, plaintext, ad)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Tracef("Encrypted %v blob: plaintext=%d bytes, ciphertext=%d "+
+		"bytes", kit.BlobType, len(plaintext), len(ciphertext)-len(dst))
+
+	return ciphertext, nil
+}
+
+// SerializePadded returns the constant-size plaintext encoding of the
+// JusticeKit for its blob type, exactly as it is encrypted by Encrypt. All
+// padding is zeroed, such that the result is deterministic and can be used to
+// validate alternative encoders and decoders.
+func (b *JusticeKit) SerializePadded() ([]byte, error) {
+	ptxtBuf := bytes.NewBuffer(
+		make([]byte, 0, PlaintextSize(b.BlobType)),
+	)
+	if err := b.serializePaddedTo(ptxtBuf); err != nil {
+		return nil, err
+	}
+
+	return ptxtBuf.Bytes(), nil
+}
+
+// serializePaddedTo writes the constant-size plaintext encoding of the
+// JusticeKit to the empty buffer, as described in SerializePadded.
+func (b *JusticeKit) serializePaddedTo(ptxtBuf *bytes.Buffer) error {
+	err := b.encode(ptxtBuf, b.BlobType)
+	if err != nil {
+		return err
+	}
+
+	// All blobs of the same type must have a constant size, otherwise the
+	// length of the ciphertext would leak information about its contents.
+	if ptxtBuf.Len() != PlaintextSize(b.BlobType) {
+		return fmt.Errorf("%w: got %d bytes, expected %d",
+			ErrPlaintextSizeMismatch, ptxtBuf.Len(),
+			PlaintextSize(b.BlobType))
+	}
+
+	return nil
+}
+
+// Decrypt unenciphers a blob of justice by decrypting the ciphertext using
+// chacha20poly1305 with the chosen (nonce, key) pair. The internal plaintext is
+// then deserialized using the given encoding version. If the ciphertext carries
+// a channel point or commitment header, the header is authenticated as
+// associated data, and the commitment number of the latter is exposed through
+// the kit's CommitmentNumber.
+func Decrypt(key BreachKey, ciphertext []byte,
+	blobType Type) (*JusticeKit, error) {
+
+	ptxtBuf := getPlaintextBuf(len(ciphertext) - Overhead)
+	plaintext, err := decryptPlaintext(
+		ptxtBuf.Bytes(), key, ciphertext, blobType,
+	)
+	defer putPlaintextBuf(ptxtBuf, plaintext)
+	if err != nil {
+		log.Debugf("Unable to decrypt %v blob of %d bytes: %v",
+			blobType, len(ciphertext), err)
+
+		return nil, err
+	}
+
+	kit, err := decodeKit(plaintext, blobType)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext)-Size(blobType) == CommitmentHeaderSize {
+		kit.commitmentNumber = byteOrder.Uint64(
+			ciphertext[ChannelPointHeaderSize:CommitmentHeaderSize],
+		)
+		kit.hasCommitmentNumber = true
+	}
+
+	return kit, nil
+}
+
+// DecryptChecked decrypts a blob as in Decrypt, after checking that the length
+// of the ciphertext matches that of a blob of the expected type, with or
+// without a channel point or commitment header. This allows a tower storing
+// the blob type alongside the ciphertext to detect a mismatch between the two
+// with ErrVersionLengthMismatch, rather than with an opaque authentication
+// failure.
+func DecryptChecked(key BreachKey, ctxt []byte,
+	expectedVersion Type) (*JusticeKit, error) {
+
+	if PlaintextSize(expectedVersion) == 0 {
+		return nil, ErrUnknownBlobType
+	}
+
+	switch len(ctxt) - CiphertextSize(expectedVersion) {
+	case 0, ChannelPointHeaderSize, CommitmentHeaderSize:
+
+	default:
+		log.Debugf("Unable to decrypt %v blob: ciphertext of %d "+
+			"bytes doesn't match blob type", expectedVersion,
+			len(ctxt))
+
+		return nil, fmt.Errorf("%w: got %d bytes, expected %d for %v",
+			ErrVersionLengthMismatch, len(ctxt),
+			CiphertextSize(expectedVersion), expectedVersion)
+	}
+
+	return Decrypt(key, ctxt, expectedVersion)
+}
+
+// ValidateBlob checks that ciphertext is a blob of the given type that can be
+// used by a tower to sweep a breach. It returns nil only if the blob decrypts
+// under key to a well-formed justice kit whose pubkeys are valid and distinct,
+// whose CSV delay is within bounds, whose sweep address is a standard script,
+// and which carries every signature required to sweep its outputs. Otherwise,
+// the returned error describes the first check that failed. This allows tools
+// to sanity check stored blobs without constructing a justice transaction.
+func ValidateBlob(key BreachKey, ciphertext []byte, blobType Type) error {
+	kit, err := Decrypt(key, ciphertext, blobType)
+	if err != nil {
+		return fmt.Errorf("unable to decrypt blob: %w", err)
+	}
+
+	return kit.validate()
+}
+
+// validate performs the internal consistency checks of ValidateBlob on a
+// decoded justice kit.
+func (b *JusticeKit) validate() error {
+	if b.BlobType.Has(FlagToRemoteOnly) {
+		return b.validateToRemoteOnly()
+	}
+
+	revocationPubKey, err := b.RevocationKey()
+	if err != nil {
+		return fmt.Errorf("invalid revocation pubkey: %w", err)
+	}
+
+	localDelayPubKey, err := b.LocalDelayKey()
+	if err != nil {
+		return fmt.Errorf("invalid local delay pubkey: %w", err)
+	}
+
+	if revocationPubKey.IsEqual(localDelayPubKey) {
+		return ErrDegenerateKeys
+	}
+
+	if b.HasCommitToRemoteOutput() {
+		if _, err := b.CommitToRemoteKey(); err != nil {
+			return fmt.Errorf("invalid to-remote pubkey: %w", err)
+		}
+	}
+
+	if b.CSVDelay == 0 || b.CSVDelay > math.MaxUint16 {
+		return fmt.Errorf("%w: got %d", ErrInvalidCSVDelay, b.CSVDelay)
+	}
+
+	if err := validateSweepAddress(b.SweepAddress); err != nil {
+		return fmt.Errorf("invalid sweep address: %w", err)
+	}
+
+	return b.validateSigs()
+}
+
+// validateToRemoteOnly performs the checks of validate for a kit with
+// FlagToRemoteOnly, which only carries the to-remote output.
+func (b *JusticeKit) validateToRemoteOnly() error {
+	if _, err := b.CommitToRemoteKey(); err != nil {
+		return fmt.Errorf("invalid to-remote pubkey: %w", err)
+	}
+
+	if err := validateSweepAddress(b.SweepAddress); err != nil {
+		return fmt.Errorf("invalid sweep address: %w", err)
+	}
+
+	return b.validateSigs()
+}
+
+// DecryptWithAAD decrypts a ciphertext created by EncryptWithAAD, verifying
+// that it was encrypted with the given associated data. Decryption fails if
+// either the ciphertext or the associated data was tampered with.
+func DecryptWithAAD(key BreachKey, ciphertext, aad []byte,
+	blobType Type) (*JusticeKit, error) {
+
+	ptxtBuf := getPlaintextBuf(len(ciphertext) - Overhead)
+	plaintext, err := openPlaintext(ptxtBuf.Bytes(), key, ciphertext, aad)
+	defer putPlaintextBuf(ptxtBuf, plaintext)
+	if err != nil {
+		log.Debugf("Unable to decrypt %v blob of %d bytes with %d "+
+			"bytes of associated data: %v", blobType,
+			len(ciphertext), len(aad), err)
+
+		return nil, err
+	}
+
+	return decodeKit(plaintext, blobType)
+}
+
+// DecryptWithProvider decrypts a ciphertext created by EncryptWithProvider,
+// or by Encrypt or EncryptWithAAD under the key held by the provider,
+// delegating the decryption to the given AEADProvider. The aad must match that
+// the blob was encrypted with, and is nil for blobs created by Encrypt.
+// ErrIncompatibleAEAD is returned if the plaintext returned by the provider
+// isn't exactly Overhead bytes shorter than the ciphertext.
+func DecryptWithProvider(provider AEADProvider, ciphertext, aad []byte,
+	blobType Type) (*JusticeKit, error) {
+
+	// Fail if the blob's overall length is less than required for the
+	// nonce and expansion factor.
+	if len(ciphertext) < Overhead {
+		return nil, ErrCiphertextTooSmall
+	}
+
+	plaintext, err := provider.Open(ciphertext, aad)
+	if err != nil {
+		log.Debugf("Unable to decrypt %v blob of %d bytes with "+
+			"provider: %v", blobType, len(ciphertext), err)
+
+		return nil, err
+	}
+
+	// Wipe the plaintext once it has been decoded, as done for the
+	// scratch buffers used by Decrypt.
+	defer func() {
+		for i := range plaintext {
+			plaintext[i] = 0
+		}
+	}()
+
+	if len(plaintext) != len(ciphertext)-Overhead {
+		return nil, fmt.Errorf("%w: provider returned %d byte "+
+			"plaintext, expected %d", ErrIncompatibleAEAD,
+			len(plaintext), len(ciphertext)-Overhead)
+	}
+
+	return decodeKit(plaintext, blobType)
+}
+
+// decodeKit decodes a decrypted plaintext using the given encoding version.
+func decodeKit(plaintext []byte, blobType Type) (*JusticeKit, error) {
+	boj := &JusticeKit{
+		BlobType: blobType,
+	}
+	err := boj.decode(bytes.NewReader(plaintext), blobType)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Tracef("Decoded %v blob from %d byte plaintext", blobType,
+		len(plaintext))
+
+	return boj, nil
+}
+
+// decryptPlaintext authenticates and decrypts the ciphertext of a blob of the
+// given type, returning the encoded plaintext, which is written to dst as in
+// openPlaintext.
+func decryptPlaintext(dst []byte, key BreachKey, ciphertext []byte,
+	blobType Type) ([]byte, error) {
+
+	// Strip the channel point or commitment header if one is present, it
+	// must then match the associated data the blob was encrypted with.
+	var (
+		ad        []byte
+		headerLen = len(ciphertext) - Size(blobType)
+	)
+	if headerLen == ChannelPointHeaderSize ||
+		headerLen == CommitmentHeaderSize {
+
+		ad = ciphertext[:headerLen]
+		ciphertext = ciphertext[headerLen:]
+	}
+
+	return openPlaintext(dst, key, ciphertext, ad)
+}
+
+// openPlaintext authenticates and decrypts a ciphertext consisting of the
+// nonce followed by the encrypted plaintext and MAC, along with the given
+// associated data. The encoded plaintext is written to the start of dst's
+// backing array if it has sufficient capacity, and is otherwise allocated.
+func openPlaintext(dst []byte, key BreachKey, ciphertext,
+	ad []byte) ([]byte, error) {
+
+	// Fail if the blob's overall length is less than required for the nonce
+	// and expansion factor.
+	if len(ciphertext) < Overhead {
+		return nil, ErrCiphertextTooSmall
+	}
+
+	// Create the cipher using the configured AEAD factory, which is
+	// xchacha20poly1305 under a 32-byte key by default.
+	cipher, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decrypt the ciphertext, whose nonce is stored in its prefix,
+	// appending the resulting plaintext to the given buffer.
+	return cipher.OpenPrefixed(dst[:0], ciphertext, ad)
+}
+
+// DecryptFrom reads exactly the number of ciphertext bytes expected for the
+// given blob type from the io.Reader, and then decrypts them as in Decrypt. If
+// the reader is exhausted before the full ciphertext is read,
+// io.ErrUnexpectedEOF is returned.
+func DecryptFrom(r io.Reader, key BreachKey,
+	blobType Type) (*JusticeKit, error) {
+
+	// Refuse to read from the stream if we don't know how large the
+	// ciphertext for this blob type is.
+	if PlaintextSize(blobType) == 0 {
+		return nil, ErrUnknownBlobType
+	}
+
+	ciphertext := make([]byte, Size(blobType))
+	_, err := io.ReadFull(r, ciphertext)
+	switch {
+	// A stream that ends before any bytes are read is still a truncated
+	// blob from the perspective of the caller.
+	case err == io.EOF:
+		return nil, io.ErrUnexpectedEOF
+
+	case err != nil:
+		return nil, err
+	}
+
+	return Decrypt(key, ciphertext, blobType)
+}
+
+// encode serializes the JusticeKit according to the version, returning an
+// error if the version is unknown.
+func (b *JusticeKit) encode(w io.Writer, blobType Type) error {
+	switch {
+	case PlaintextSize(blobType) == 0:
+		return ErrUnknownBlobType
+
+	case blobType.Has(FlagToRemoteOnly):
+		return b.encodeToRemote(w)
+
+	case blobType.Has(FlagCommitOutputs):
+		if err := b.encodeV0(w); err != nil {
+			return err
+		}
+
+		if blobType.Has(FlagSecondLevelHtlcs) {
+			if err := b.encodeSecondLevelHtlcs(w); err != nil {
+				return err
+			}
+		}
+
+		if blobType.Has(FlagDataCommitment) {
+			if err := b.encodeDataCommitment(w); err != nil {
+				return err
+			}
+		}
+
+		if blobType.Has(FlagLeaseChannel) {
+			err := binary.Write(w, byteOrder, b.LeaseExpiry)
+			if err != nil {
+				return err
+			}
+		}
+
+		if blobType.Has(FlagTLVTrailer) {
+			return b.encodeTLVTrailer(w)
+		}
+
+		return nil
+
+	default:
+		return ErrUnknownBlobType
+	}
+}
+
+// decode deserializes the JusticeKit according to the version, returning an
+// error if the version is unknown.
+func (b *JusticeKit) decode(r io.Reader, blobType Type) error {
+	switch {
+	case PlaintextSize(blobType) == 0:
+		return ErrUnknownBlobType
+
+	case blobType.Has(FlagToRemoteOnly):
+		return b.decodeToRemote(r)
+
+	case blobType.Has(FlagCommitOutputs):
+		if err := b.decodeV0(r); err != nil {
+			return err
+		}
+
+		if blobType.Has(FlagSecondLevelHtlcs) {
+			if err := b.decodeSecondLevelHtlcs(r); err != nil {
+				return b.decodeFailed("second-level htlcs", err)
+			}
+		}
+
+		if blobType.Has(FlagDataCommitment) {
+			if err := b.decodeDataCommitment(r); err != nil {
+				return b.decodeFailed("data commitment", err)
+			}
+		}
+
+		if blobType.Has(FlagLeaseChannel) {
+			err := binary.Read(r, byteOrder, &b.LeaseExpiry)
+			if err != nil {
+				return b.decodeFailed("lease expiry", err)
+			}
+		}
+
+		if blobType.Has(FlagTLVTrailer) {
+			if err := b.decodeTLVTrailer(r); err != nil {
+				return b.decodeFailed("tlv trailer", err)
+			}
+		}
+
+		return nil
+
+	default:
+		return ErrUnknownBlobType
+	}
+}
+
+// encodeV0 encodes the JusticeKit using the version 0 encoding scheme to the
+// provided io.Writer. The encoding supports sweeping of the commit to-local
+// output, and  optionally the  commit to-remote output. The encoding produces a
+// constant-size plaintext size of 274 bytes.
+//
+// blob version 0 plaintext encoding:
+//
+//	sweep address length:            1 byte
+//	padded sweep address:           42 bytes
+//	revocation pubkey:              33 bytes
+//	local delay pubkey:             33 bytes
+//	csv delay:                       4 bytes
+//	commit to-local revocation sig: 64 bytes
+//	commit to-remote pubkey:        33 bytes, maybe blank
+//	commit to-remote sig:           64 bytes, maybe blank
+func (b *JusticeKit) encodeV0(w io.Writer) error {
+	err := b.encodeSweepAddress(w)
+	if err != nil {
+		return err
+	}
+
+	// Write 33-byte revocation public key.
+	_, err = w.Write(b.RevocationPubKey[:])
+	if err != nil {
+		return err
+	}
+
+	// Write 33-byte local delay public key.
+	_, err = w.Write(b.LocalDelayPubKey[:])
+	if err != nil {
+		return err
+	}
+
+	// Write 4-byte CSV delay.
+	err = binary.Write(w, byteOrder, b.CSVDelay)
+	if err != nil {
+		return err
+	}
+
+	// Write 64-byte revocation signature for commit to-local output.
+	_, err = w.Write(b.CommitToLocalSig.RawBytes())
+	if err != nil {
+		return err
+	}
+
+	return b.encodeCommitToRemote(w)
+}
+
+// encodeToRemote encodes the JusticeKit of a blob with FlagToRemoteOnly to the
+// provided io.Writer, which only supports sweeping the commit to-remote
+// output. The encoding produces a constant-size plaintext of 140 bytes.
+//
+// to-remote only plaintext encoding:
+//
+//	sweep address length:            1 byte
+//	padded sweep address:           42 bytes
+//	commit to-remote pubkey:        33 bytes
+//	commit to-remote sig:           64 bytes
+func (b *JusticeKit) encodeToRemote(w io.Writer) error {
+	if err := b.encodeSweepAddress(w); err != nil {
+		return err
+	}
+
+	return b.encodeCommitToRemote(w)
+}
+
+// encodeSweepAddress writes the length of the sweep address as a single byte,
+// followed by the sweep address padded to MaxSweepAddrSize.
+func (b *JusticeKit) encodeSweepAddress(w io.Writer) error {
+	// Assert the sweep address length is sane.
+	if len(b.SweepAddress) > MaxSweepAddrSize {
+		return ErrSweepAddressToLong
+	}
+
+	// Write the actual length of the sweep address as a single byte.
+	err := binary.Write(w, byteOrder, uint8(len(b.SweepAddress)))
 	if err != nil {
 		return err
 	}
 
+	// Pad the sweep address to our maximum length of 42 bytes.
+	var sweepAddressBuf [MaxSweepAddrSize]byte
+	copy(sweepAddressBuf[:], b.SweepAddress)
+
+	// Write padded 42-byte sweep address.
+	_, err = w.Write(sweepAddressBuf[:])
+	return err
+}
+
+// encodeCommitToRemote writes the commit to-remote pubkey and signature, both
+// of which are blank if the kit has no to-remote output.
+func (b *JusticeKit) encodeCommitToRemote(w io.Writer) error {
 	// Write 33-byte commit to-remote public key, which may be blank.
-	_, err = w.Write(b.CommitToRemotePubKey[:])
+	_, err := w.Write(b.CommitToRemotePubKey[:])
 	if err != nil {
 		return err
 	}
@@ -414,6 +3014,56 @@ func (b *JusticeKit) encodeV0(w io.Writer) error {
 	return err
 }
 
+// decodeFailed logs the failure to decode the named field of the kit, such
+// that operators can tell which part of a blob was malformed, and returns err.
+func (b *JusticeKit) decodeFailed(field string, err error) error {
+	log.Debugf("Unable to decode %s of %v blob: %v", field, b.BlobType,
+		err)
+
+	return err
+}
+
+// checkPadding returns ErrNonZeroPadding if any byte of the given padding
+// region is set.
+func checkPadding(padding []byte) error {
+	for _, c := range padding {
+		if c != 0 {
+			return ErrNonZeroPadding
+		}
+	}
+
+	return nil
+}
+
+// malformedBlobError is returned when a length prefix of a blob doesn't fit the
+// bytes available for its field, such that it matches both ErrMalformedBlob and
+// the cause describing the offending field.
+type malformedBlobError struct {
+	cause error
+}
+
+// malformedBlob returns an error matching both ErrMalformedBlob and cause.
+func malformedBlob(cause error) error {
+	return &malformedBlobError{cause: cause}
+}
+
+// Error returns a human readable description of the malformed field.
+//
+// NOTE: Part of the error interface.
+func (e *malformedBlobError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrMalformedBlob, e.cause)
+}
+
+// Is returns true if target is ErrMalformedBlob.
+func (e *malformedBlobError) Is(target error) bool {
+	return target == ErrMalformedBlob
+}
+
+// Unwrap returns the cause describing the malformed field.
+func (e *malformedBlobError) Unwrap() error {
+	return e.cause
+}
+
 // decodeV0 reconstructs a JusticeKit from the io.Reader, using version 0
 // encoding scheme. This will parse a constant size input stream of 274 bytes to
 // recover information for the commit to-local output, and possibly the commit
@@ -430,74 +3080,124 @@ func (b *JusticeKit) encodeV0(w io.Writer) error {
 //	commit to-remote pubkey:        33 bytes, maybe blank
 //	commit to-remote sig:           64 bytes, maybe blank
 func (b *JusticeKit) decodeV0(r io.Reader) error {
-	// Read the sweep address length as a single byte.
-	var sweepAddrLen uint8
-	err := binary.Read(r, byteOrder, &sweepAddrLen)
-	if err != nil {
-		return err
-	}
-
-	// Assert the sweep address length is sane.
-	if sweepAddrLen > MaxSweepAddrSize {
-		return ErrSweepAddressToLong
-	}
-
-	// Read padded 42-byte sweep address.
-	var sweepAddressBuf [MaxSweepAddrSize]byte
-	_, err = io.ReadFull(r, sweepAddressBuf[:])
+	err := b.decodeSweepAddress(r)
 	if err != nil {
 		return err
 	}
 
-	// Parse sweep address from padded buffer.
-	b.SweepAddress = make([]byte, sweepAddrLen)
-	copy(b.SweepAddress, sweepAddressBuf[:])
-
 	// Read 33-byte revocation public key.
 	_, err = io.ReadFull(r, b.RevocationPubKey[:])
 	if err != nil {
-		return err
+		return b.decodeFailed("revocation pubkey", err)
 	}
 
 	// Read 33-byte local delay public key.
 	_, err = io.ReadFull(r, b.LocalDelayPubKey[:])
 	if err != nil {
-		return err
+		return b.decodeFailed("local delay pubkey", err)
 	}
 
 	// Read 4-byte CSV delay.
 	err = binary.Read(r, byteOrder, &b.CSVDelay)
 	if err != nil {
-		return err
+		return b.decodeFailed("csv delay", err)
 	}
 
 	// Read 64-byte revocation signature for commit to-local output.
 	var localSig [64]byte
 	_, err = io.ReadFull(r, localSig[:])
 	if err != nil {
-		return err
+		return b.decodeFailed("commit to-local sig", err)
 	}
 
 	b.CommitToLocalSig, err = lnwire.NewSigFromWireECDSA(localSig[:])
 	if err != nil {
+		return b.decodeFailed("commit to-local sig", err)
+	}
+
+	// Taproot channels use a schnorr signature to spend the revocation
+	// path of the to-local output.
+	if b.BlobType.IsTaprootChannel() {
+		b.CommitToLocalSig.ForceSchnorr()
+	}
+
+	return b.decodeCommitToRemote(r)
+}
+
+// decodeToRemote reconstructs the JusticeKit of a blob with FlagToRemoteOnly
+// from the io.Reader, parsing a constant size input stream of 140 bytes to
+// recover the information for the commit to-remote output.
+//
+// to-remote only plaintext encoding:
+//
+//	sweep address length:            1 byte
+//	padded sweep address:           42 bytes
+//	commit to-remote pubkey:        33 bytes
+//	commit to-remote sig:           64 bytes
+func (b *JusticeKit) decodeToRemote(r io.Reader) error {
+	if err := b.decodeSweepAddress(r); err != nil {
 		return err
 	}
 
+	return b.decodeCommitToRemote(r)
+}
+
+// decodeSweepAddress reads the length of the sweep address as a single byte,
+// followed by the sweep address padded to MaxSweepAddrSize.
+func (b *JusticeKit) decodeSweepAddress(r io.Reader) error {
+	// Read the sweep address length as a single byte.
+	var sweepAddrLen uint8
+	err := binary.Read(r, byteOrder, &sweepAddrLen)
+	if err != nil {
+		return b.decodeFailed("sweep address length", err)
+	}
+
+	// Assert the sweep address length is sane.
+	if sweepAddrLen > MaxSweepAddrSize {
+		return b.decodeFailed(
+			"sweep address length",
+			malformedBlob(ErrSweepAddressToLong),
+		)
+	}
+
+	// Read padded 42-byte sweep address.
+	var sweepAddressBuf [MaxSweepAddrSize]byte
+	_, err = io.ReadFull(r, sweepAddressBuf[:])
+	if err != nil {
+		return b.decodeFailed("sweep address", err)
+	}
+
+	// The sweep address is padded with zeros past its length.
+	err = checkPadding(sweepAddressBuf[sweepAddrLen:])
+	if err != nil {
+		return b.decodeFailed("sweep address", err)
+	}
+
+	// Parse sweep address from padded buffer.
+	b.SweepAddress = make([]byte, sweepAddrLen)
+	copy(b.SweepAddress, sweepAddressBuf[:])
+
+	return nil
+}
+
+// decodeCommitToRemote reads the commit to-remote pubkey and signature, which
+// are only populated in the kit if the pubkey is a valid compressed pubkey.
+func (b *JusticeKit) decodeCommitToRemote(r io.Reader) error {
 	var (
 		commitToRemotePubkey PubKey
 		commitToRemoteSig    [64]byte
 	)
 
 	// Read 33-byte commit to-remote public key, which may be discarded.
-	_, err = io.ReadFull(r, commitToRemotePubkey[:])
+	_, err := io.ReadFull(r, commitToRemotePubkey[:])
 	if err != nil {
-		return err
+		return b.decodeFailed("commit to-remote pubkey", err)
 	}
 
 	// Read 64-byte commit to-remote signature, which may be discarded.
 	_, err = io.ReadFull(r, commitToRemoteSig[:])
 	if err != nil {
-		return err
+		return b.decodeFailed("commit to-remote sig", err)
 	}
 
 	// Only populate the commit to-remote fields in the decoded blob if a
@@ -507,9 +3207,142 @@ func (b *JusticeKit) decodeV0(r io.Reader) error {
 		b.CommitToRemoteSig, err = lnwire.NewSigFromWireECDSA(
 			commitToRemoteSig[:],
 		)
+		if err != nil {
+			return b.decodeFailed("commit to-remote sig", err)
+		}
+
+		// Taproot channels use a schnorr signature to spend the
+		// to-remote output.
+		if b.BlobType.IsTaprootChannel() {
+			b.CommitToRemoteSig.ForceSchnorr()
+		}
+	}
+
+	return nil
+}
+
+// encodeSecondLevelHtlcs encodes the second-level HTLC signatures of the
+// JusticeKit to the provided io.Writer. The signatures are padded to
+// MaxSecondLevelHtlcs, such that the encoding has a constant size of 513
+// bytes.
+//
+// second-level htlc encoding:
+//
+//	number of signatures:            1 byte
+//	padded revocation sigs:        512 bytes
+func (b *JusticeKit) encodeSecondLevelHtlcs(w io.Writer) error {
+	if len(b.SecondLevelHtlcSigs) > MaxNumHTLCs(b.BlobType) {
+		return ErrTooManyHTLCs
+	}
+
+	err := binary.Write(w, byteOrder, uint8(len(b.SecondLevelHtlcSigs)))
+	if err != nil {
+		return err
+	}
+
+	var sigsBuf [MaxSecondLevelHtlcs * 64]byte
+	for i, sig := range b.SecondLevelHtlcSigs {
+		copy(sigsBuf[i*64:], sig.RawBytes())
+	}
+
+	_, err = w.Write(sigsBuf[:])
+
+	return err
+}
+
+// decodeSecondLevelHtlcs reconstructs the second-level HTLC signatures of the
+// JusticeKit from the io.Reader, using the encoding described in
+// encodeSecondLevelHtlcs.
+func (b *JusticeKit) decodeSecondLevelHtlcs(r io.Reader) error {
+	var numSigs uint8
+	err := binary.Read(r, byteOrder, &numSigs)
+	if err != nil {
+		return err
+	}
+
+	// Reject a count exceeding the maximum for this type before reading
+	// any signatures, such that a crafted blob can't cause us to allocate
+	// more than the type permits.
+	if int(numSigs) > MaxNumHTLCs(b.BlobType) {
+		return malformedBlob(ErrTooManyHTLCs)
+	}
+
+	var sigsBuf [MaxSecondLevelHtlcs * 64]byte
+	_, err = io.ReadFull(r, sigsBuf[:])
+	if err != nil {
+		return err
+	}
+
+	if err := checkPadding(sigsBuf[int(numSigs)*64:]); err != nil {
+		return err
+	}
+
+	for i := 0; i < int(numSigs); i++ {
+		sig, err := lnwire.NewSigFromWireECDSA(sigsBuf[i*64 : (i+1)*64])
 		if err != nil {
 			return err
 		}
+
+		b.SecondLevelHtlcSigs = append(b.SecondLevelHtlcSigs, sig)
+	}
+
+	return nil
+}
+
+// encodeDataCommitment encodes the data commitment of the JusticeKit to the
+// provided io.Writer. The data is padded to MaxDataCommitmentSize, such that
+// the encoding has a constant size of 33 bytes.
+//
+// data commitment encoding:
+//
+//	data commitment length:          1 byte
+//	padded data commitment:         32 bytes
+func (b *JusticeKit) encodeDataCommitment(w io.Writer) error {
+	if len(b.DataCommitment) > MaxDataCommitmentSize {
+		return ErrDataCommitmentTooLong
+	}
+
+	err := binary.Write(w, byteOrder, uint8(len(b.DataCommitment)))
+	if err != nil {
+		return err
+	}
+
+	var dataBuf [MaxDataCommitmentSize]byte
+	copy(dataBuf[:], b.DataCommitment)
+
+	_, err = w.Write(dataBuf[:])
+
+	return err
+}
+
+// decodeDataCommitment reconstructs the data commitment of the JusticeKit from
+// the io.Reader, using the encoding described in encodeDataCommitment.
+func (b *JusticeKit) decodeDataCommitment(r io.Reader) error {
+	var dataLen uint8
+	err := binary.Read(r, byteOrder, &dataLen)
+	if err != nil {
+		return err
+	}
+
+	if dataLen > MaxDataCommitmentSize {
+		return malformedBlob(ErrDataCommitmentTooLong)
+	}
+
+	var dataBuf [MaxDataCommitmentSize]byte
+	_, err = io.ReadFull(r, dataBuf[:])
+	if err != nil {
+		return err
+	}
+
+	if err := checkPadding(dataBuf[dataLen:]); err != nil {
+		return err
+	}
+
+	// Leave the data commitment nil if it is empty, mirroring a kit on
+	// which no data commitment was set.
+	if dataLen > 0 {
+		b.DataCommitment = make([]byte, dataLen)
+		copy(b.DataCommitment, dataBuf[:dataLen])
 	}
 
 	return nil
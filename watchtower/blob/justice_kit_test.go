@@ -2,19 +2,35 @@ package blob_test
 
 import (
 	"bytes"
+	"crypto/cipher"
 	"crypto/rand"
 	"encoding/binary"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"math"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"testing/iotest"
 
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btclog"
+	"github.com/lightningnetwork/lnd/channeldb"
 	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwire"
 	"github.com/lightningnetwork/lnd/watchtower/blob"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
 func makePubKey(i uint64) blob.PubKey {
@@ -44,6 +60,14 @@ func makeAddr(size int) []byte {
 	return addr
 }
 
+// makeSweepAddr returns a p2wkh pkScript paying to a random witness program,
+// which unlike the random bytes of makeAddr is always spendable.
+func makeSweepAddr() []byte {
+	return append(
+		[]byte{txscript.OP_0, txscript.OP_DATA_20}, makeAddr(20)...,
+	)
+}
+
 type descriptorTest struct {
 	name                 string
 	encVersion           blob.Type
@@ -56,6 +80,9 @@ type descriptorTest struct {
 	hasCommitToRemote    bool
 	commitToRemotePubKey blob.PubKey
 	commitToRemoteSig    lnwire.Sig
+	dataCommitment       []byte
+	trailerRecords       map[uint64][]byte
+	leaseExpiry          uint32
 	encErr               error
 	decErr               error
 }
@@ -137,8 +164,109 @@ var descriptorTests = []descriptorTest{
 		commitToLocalSig: makeSig(1),
 		encErr:           blob.ErrSweepAddressToLong,
 	},
+	{
+		name:             "data commitment",
+		encVersion:       dataCommitmentType,
+		decVersion:       dataCommitmentType,
+		sweepAddr:        makeAddr(22),
+		revPubKey:        makePubKey(0),
+		delayPubKey:      makePubKey(1),
+		csvDelay:         144,
+		commitToLocalSig: makeSig(1),
+		dataCommitment:   []byte("proof of burn"),
+	},
+	{
+		name:             "data commitment max size",
+		encVersion:       dataCommitmentType,
+		decVersion:       dataCommitmentType,
+		sweepAddr:        makeAddr(22),
+		revPubKey:        makePubKey(0),
+		delayPubKey:      makePubKey(1),
+		csvDelay:         144,
+		commitToLocalSig: makeSig(1),
+		dataCommitment:   makeAddr(blob.MaxDataCommitmentSize),
+	},
+	{
+		name:             "data commitment too long",
+		encVersion:       dataCommitmentType,
+		decVersion:       dataCommitmentType,
+		sweepAddr:        makeAddr(22),
+		revPubKey:        makePubKey(0),
+		delayPubKey:      makePubKey(1),
+		csvDelay:         144,
+		commitToLocalSig: makeSig(1),
+		dataCommitment:   makeAddr(blob.MaxDataCommitmentSize + 1),
+		encErr:           blob.ErrDataCommitmentTooLong,
+	},
+	{
+		name:             "tlv trailer empty",
+		encVersion:       tlvTrailerType,
+		decVersion:       tlvTrailerType,
+		sweepAddr:        makeAddr(22),
+		revPubKey:        makePubKey(0),
+		delayPubKey:      makePubKey(1),
+		csvDelay:         144,
+		commitToLocalSig: makeSig(1),
+	},
+	{
+		name:             "tlv trailer unknown odd records",
+		encVersion:       tlvTrailerType,
+		decVersion:       tlvTrailerType,
+		sweepAddr:        makeAddr(22),
+		revPubKey:        makePubKey(0),
+		delayPubKey:      makePubKey(1),
+		csvDelay:         144,
+		commitToLocalSig: makeSig(1),
+		trailerRecords: map[uint64][]byte{
+			1:   []byte("optional"),
+			301: {},
+		},
+	},
+	{
+		name:             "tlv trailer too long",
+		encVersion:       tlvTrailerType,
+		decVersion:       tlvTrailerType,
+		sweepAddr:        makeAddr(22),
+		revPubKey:        makePubKey(0),
+		delayPubKey:      makePubKey(1),
+		csvDelay:         144,
+		commitToLocalSig: makeSig(1),
+		trailerRecords: map[uint64][]byte{
+			1: makeAddr(blob.MaxTLVTrailerSize),
+		},
+		encErr: blob.ErrTLVTrailerTooLong,
+	},
+	{
+		name:                 "lease channel",
+		encVersion:           leaseType,
+		decVersion:           leaseType,
+		sweepAddr:            makeAddr(22),
+		revPubKey:            makePubKey(0),
+		delayPubKey:          makePubKey(1),
+		csvDelay:             144,
+		commitToLocalSig:     makeSig(1),
+		hasCommitToRemote:    true,
+		commitToRemotePubKey: makePubKey(2),
+		commitToRemoteSig:    makeSig(2),
+		leaseExpiry:          800_000,
+	},
 }
 
+// dataCommitmentType is an altruist blob type carrying a data commitment.
+var dataCommitmentType = blob.TypeFromFlags(
+	blob.FlagCommitOutputs, blob.FlagDataCommitment,
+)
+
+// tlvTrailerType is an altruist blob type carrying a TLV trailer.
+var tlvTrailerType = blob.TypeFromFlags(
+	blob.FlagCommitOutputs, blob.FlagTLVTrailer,
+)
+
+// leaseType is an altruist blob type for a script enforced lease channel.
+var leaseType = blob.TypeFromFlags(
+	blob.FlagCommitOutputs, blob.FlagAnchorChannel, blob.FlagLeaseChannel,
+)
+
 // TestBlobJusticeKitEncryptDecrypt asserts that encrypting and decrypting a
 // plaintext blob produces the original. The tests include negative assertions
 // when passed invalid combinations, and that all successfully encrypted blobs
@@ -161,6 +289,9 @@ func testBlobJusticeKitEncryptDecrypt(t *testing.T, test descriptorTest) {
 		CommitToLocalSig:     test.commitToLocalSig,
 		CommitToRemotePubKey: test.commitToRemotePubKey,
 		CommitToRemoteSig:    test.commitToRemoteSig,
+		DataCommitment:       test.dataCommitment,
+		TrailerRecords:       test.trailerRecords,
+		LeaseExpiry:          test.leaseExpiry,
 	}
 
 	// Generate a random encryption key for the blob. The key is
@@ -316,6 +447,116 @@ func testJusticeKitRemoteWitnessConstruction(
 	require.Error(t, blob.ErrNoCommitToRemoteOutput, err)
 }
 
+// TestJusticeKitLegacyToRemoteVariants asserts that the to-remote witness
+// produced by a legacy JusticeKit validly spends the p2wkh to-remote output of
+// both channels that tweak the to-remote key with the per-commitment point,
+// and channels using option_static_remotekey.
+func TestJusticeKitLegacyToRemoteVariants(t *testing.T) {
+	const toRemoteAmt = 100000
+
+	basePrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	commitSecret, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	commitPoint := commitSecret.PubKey()
+
+	tests := []struct {
+		name    string
+		privKey *btcec.PrivateKey
+	}{
+		{
+			name: "tweaked to-remote key",
+			privKey: input.TweakPrivKey(
+				basePrivKey, input.SingleTweakBytes(
+					commitPoint, basePrivKey.PubKey(),
+				),
+			),
+		},
+		{
+			name:    "static to-remote key",
+			privKey: basePrivKey,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			toRemotePubKey := test.privKey.PubKey()
+
+			// The tweaked key must be the one derived from the
+			// commitment point, as placed in the key ring.
+			if test.privKey != basePrivKey {
+				require.True(t, toRemotePubKey.IsEqual(
+					input.TweakPubKey(
+						basePrivKey.PubKey(),
+						commitPoint,
+					),
+				))
+			}
+
+			pkScript, err := input.CommitScriptUnencumbered(
+				toRemotePubKey,
+			)
+			require.NoError(t, err)
+
+			justiceTx := wire.NewMsgTx(2)
+			justiceTx.AddTxIn(&wire.TxIn{
+				PreviousOutPoint: wire.OutPoint{
+					Hash: chainhash.Hash{0x01},
+				},
+			})
+			justiceTx.AddTxOut(
+				wire.NewTxOut(toRemoteAmt-1000, makeAddr(22)),
+			)
+
+			prevOutFetcher := txscript.NewCannedPrevOutputFetcher(
+				pkScript, toRemoteAmt,
+			)
+			hashCache := txscript.NewTxSigHashes(
+				justiceTx, prevOutFetcher,
+			)
+
+			rawSig, err := txscript.RawTxInWitnessSignature(
+				justiceTx, hashCache, 0, toRemoteAmt, pkScript,
+				txscript.SigHashAll, test.privKey,
+			)
+			require.NoError(t, err)
+
+			toRemoteSig, err := lnwire.NewSigFromECDSARawSignature(
+				rawSig[:len(rawSig)-1],
+			)
+			require.NoError(t, err)
+
+			kit := &blob.JusticeKit{
+				BlobType: blob.TypeAltruistCommit,
+				CommitToRemotePubKey: toBlobPubKey(
+					toRemotePubKey,
+				),
+				CommitToRemoteSig: toRemoteSig,
+			}
+
+			// The witness script of a p2wkh output is the pubkey
+			// itself, which completes the witness stack.
+			witnessScript, err := kit.CommitToRemoteWitnessScript()
+			require.NoError(t, err)
+			witnessStack, err := kit.CommitToRemoteWitnessStack()
+			require.NoError(t, err)
+
+			justiceTx.TxIn[0].Witness = append(
+				witnessStack, witnessScript,
+			)
+
+			vm, err := txscript.NewEngine(
+				pkScript, justiceTx, 0,
+				txscript.StandardVerifyFlags, nil, hashCache,
+				toRemoteAmt, prevOutFetcher,
+			)
+			require.NoError(t, err)
+			require.NoError(t, vm.Execute())
+		})
+	}
+}
+
 // TestJusticeKitToLocalWitnessConstruction tests that a JusticeKit returns the
 // proper to-local witness script and to-local witness stack for spending the
 // revocation path.
@@ -385,3 +626,4187 @@ func TestJusticeKitToLocalWitnessConstruction(t *testing.T) {
 	}
 	require.Equal(t, expWitnessStack, toLocalWitnessStack)
 }
+
+// TestJusticeKitToLocalLargeCSVDelays asserts that the to-local script of a kit
+// matches input.CommitScriptToSelf for CSV delays requiring script numbers of
+// different sizes, and that the witness spends its revocation path. As the
+// revocation path isn't encumbered by the CSV delay, the input doesn't signal
+// a relative locktime regardless of the delay.
+func TestJusticeKitToLocalLargeCSVDelays(t *testing.T) {
+	revPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	for _, csvDelay := range []uint32{1, 144, 1000, 2016, math.MaxUint16} {
+		csvDelay := csvDelay
+		t.Run(fmt.Sprintf("csv=%d", csvDelay), func(t *testing.T) {
+			kit, err := blob.NewJusticeKitFromScripts(
+				blob.TypeAltruistAnchorCommit,
+				blob.JusticeKitParams{
+					SweepAddress:     makeSweepAddr(),
+					RevocationPubKey: revPriv.PubKey(),
+					LocalDelayPubKey: delayPriv.PubKey(),
+					CSVDelay:         csvDelay,
+				},
+			)
+			require.NoError(t, err)
+
+			expScript, err := input.CommitScriptToSelf(
+				csvDelay, delayPriv.PubKey(), revPriv.PubKey(),
+			)
+			require.NoError(t, err)
+
+			toLocalScript, err := kit.CommitToLocalWitnessScript()
+			require.NoError(t, err)
+			require.Equal(t, expScript, toLocalScript)
+
+			toLocalPkScript, err := input.WitnessScriptHash(
+				toLocalScript,
+			)
+			require.NoError(t, err)
+			prevOut := wire.NewTxOut(100_000, toLocalPkScript)
+
+			toLocalSeq, _ := kit.InputSequences()
+			require.Zero(t, toLocalSeq)
+
+			justiceTx := wire.NewMsgTx(2)
+			justiceTx.AddTxIn(&wire.TxIn{
+				PreviousOutPoint: wire.OutPoint{
+					Hash: chainhash.Hash{0x01},
+				},
+				Sequence: toLocalSeq,
+			})
+			justiceTx.AddTxOut(
+				wire.NewTxOut(90_000, kit.SweepAddress),
+			)
+
+			prevOutFetcher := txscript.NewCannedPrevOutputFetcher(
+				prevOut.PkScript, prevOut.Value,
+			)
+			hashCache := txscript.NewTxSigHashes(
+				justiceTx, prevOutFetcher,
+			)
+
+			rawSig, err := txscript.RawTxInWitnessSignature(
+				justiceTx, hashCache, 0, prevOut.Value,
+				toLocalScript, txscript.SigHashAll, revPriv,
+			)
+			require.NoError(t, err)
+
+			sig, err := lnwire.NewSigFromECDSARawSignature(
+				rawSig[:len(rawSig)-1],
+			)
+			require.NoError(t, err)
+			require.NoError(t, kit.AddToLocalSig(sig))
+
+			spendInfo, err := kit.ToLocalOutputSpendInfo()
+			require.NoError(t, err)
+			require.Equal(t, toLocalScript, spendInfo.WitnessScript)
+
+			justiceTx.TxIn[0].Witness = append(
+				spendInfo.WitnessStack, spendInfo.WitnessScript,
+			)
+			require.GreaterOrEqual(
+				t, spendInfo.WitnessSize,
+				justiceTx.TxIn[0].Witness.SerializeSize(),
+			)
+
+			vm, err := txscript.NewEngine(
+				prevOut.PkScript, justiceTx, 0,
+				txscript.StandardVerifyFlags, nil, hashCache,
+				prevOut.Value, prevOutFetcher,
+			)
+			require.NoError(t, err)
+			require.NoError(t, vm.Execute())
+		})
+	}
+}
+
+// TestJusticeKitEqual asserts that JusticeKit.Equal reports identical kits as
+// equal, and that mutating a single field produces a description naming
+// exactly that field.
+func TestJusticeKitEqual(t *testing.T) {
+	newKit := func() *blob.JusticeKit {
+		return &blob.JusticeKit{
+			BlobType:             blob.TypeAltruistCommit,
+			SweepAddress:         bytes.Repeat([]byte{0x01}, 22),
+			RevocationPubKey:     makePubKey(0),
+			LocalDelayPubKey:     makePubKey(1),
+			CSVDelay:             144,
+			CommitToLocalSig:     makeSig(1),
+			CommitToRemotePubKey: makePubKey(2),
+			CommitToRemoteSig:    makeSig(2),
+		}
+	}
+
+	equal, diff := newKit().Equal(newKit())
+	require.True(t, equal)
+	require.Empty(t, diff)
+
+	tests := []struct {
+		field  string
+		mutate func(*blob.JusticeKit)
+	}{
+		{
+			field: "BlobType",
+			mutate: func(k *blob.JusticeKit) {
+				k.BlobType = blob.TypeRewardCommit
+			},
+		},
+		{
+			field: "SweepAddress",
+			mutate: func(k *blob.JusticeKit) {
+				k.SweepAddress[0] ^= 0xff
+			},
+		},
+		{
+			field: "RevocationPubKey",
+			mutate: func(k *blob.JusticeKit) {
+				k.RevocationPubKey = makePubKey(3)
+			},
+		},
+		{
+			field: "LocalDelayPubKey",
+			mutate: func(k *blob.JusticeKit) {
+				k.LocalDelayPubKey = makePubKey(3)
+			},
+		},
+		{
+			field: "CSVDelay",
+			mutate: func(k *blob.JusticeKit) {
+				k.CSVDelay++
+			},
+		},
+		{
+			field: "CommitToLocalSig",
+			mutate: func(k *blob.JusticeKit) {
+				k.CommitToLocalSig = makeSig(3)
+			},
+		},
+		{
+			field: "HasCommitToRemoteOutput",
+			mutate: func(k *blob.JusticeKit) {
+				k.CommitToRemotePubKey = blob.PubKey{}
+			},
+		},
+		{
+			field: "CommitToRemotePubKey",
+			mutate: func(k *blob.JusticeKit) {
+				k.CommitToRemotePubKey = makePubKey(3)
+			},
+		},
+		{
+			field: "CommitToRemoteSig",
+			mutate: func(k *blob.JusticeKit) {
+				k.CommitToRemoteSig = makeSig(3)
+			},
+		},
+		{
+			field: "LeaseExpiry",
+			mutate: func(k *blob.JusticeKit) {
+				k.LeaseExpiry = 800_000
+			},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.field, func(t *testing.T) {
+			kit := newKit()
+			test.mutate(kit)
+
+			equal, diff := newKit().Equal(kit)
+			require.False(t, equal)
+			require.True(
+				t, strings.HasPrefix(diff, test.field+" "),
+				"unexpected diff: %v", diff,
+			)
+		})
+	}
+}
+
+// TestJusticeKitDecodeSweepAddress asserts that the sweep pkScript of a
+// JusticeKit is properly decoded into the address it pays to.
+func TestJusticeKitDecodeSweepAddress(t *testing.T) {
+	params := &chaincfg.MainNetParams
+
+	hash20 := bytes.Repeat([]byte{0x01}, 20)
+	hash32 := bytes.Repeat([]byte{0x02}, 32)
+
+	p2wkh, err := btcutil.NewAddressWitnessPubKeyHash(hash20, params)
+	require.NoError(t, err)
+
+	p2wsh, err := btcutil.NewAddressWitnessScriptHash(hash32, params)
+	require.NoError(t, err)
+
+	privKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	p2tr, err := btcutil.NewAddressTaproot(
+		schnorr.SerializePubKey(privKey.PubKey()), params,
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		addr   btcutil.Address
+		script []byte
+		expErr error
+	}{
+		{
+			name: "p2wkh",
+			addr: p2wkh,
+		},
+		{
+			name: "p2wsh",
+			addr: p2wsh,
+		},
+		{
+			name: "p2tr",
+			addr: p2tr,
+		},
+		{
+			name:   "empty",
+			expErr: blob.ErrNoSweepAddress,
+		},
+		{
+			name:   "non-standard",
+			script: []byte{txscript.OP_TRUE},
+			expErr: blob.ErrUnknownSweepAddrType,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			script := test.script
+			if test.addr != nil {
+				script, err = txscript.PayToAddrScript(test.addr)
+				require.NoError(t, err)
+			}
+
+			kit := &blob.JusticeKit{
+				SweepAddress: script,
+			}
+
+			addr, err := kit.DecodeSweepAddress(params)
+			require.ErrorIs(t, err, test.expErr)
+			if test.expErr != nil {
+				return
+			}
+
+			require.Equal(t, test.addr.String(), addr.String())
+		})
+	}
+}
+
+// TestJusticeKitAccessors asserts that the accessors of a decrypted
+// JusticeKit return the values used to build the original kit, and that the
+// to-remote key is reported as absent for kits without a to-remote output.
+func TestJusticeKitAccessors(t *testing.T) {
+	tests := []struct {
+		name        string
+		blobType    blob.Type
+		hasToRemote bool
+	}{
+		{
+			name:     "legacy no to-remote",
+			blobType: blob.TypeAltruistCommit,
+		},
+		{
+			name:        "legacy to-remote",
+			blobType:    blob.TypeAltruistCommit,
+			hasToRemote: true,
+		},
+		{
+			name:        "anchor to-remote",
+			blobType:    blob.TypeAltruistAnchorCommit,
+			hasToRemote: true,
+		},
+		{
+			name:        "taproot to-remote",
+			blobType:    blob.TypeAltruistTaprootCommit,
+			hasToRemote: true,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			newKey := func() *btcec.PublicKey {
+				priv, err := btcec.NewPrivateKey()
+				require.NoError(t, err)
+
+				return priv.PubKey()
+			}
+
+			var (
+				revKey      = newKey()
+				delayKey    = newKey()
+				toRemoteKey = newKey()
+				sweepAddr   = makeAddr(22)
+			)
+
+			kit := &blob.JusticeKit{
+				BlobType:         test.blobType,
+				SweepAddress:     sweepAddr,
+				RevocationPubKey: toBlobPubKey(revKey),
+				LocalDelayPubKey: toBlobPubKey(delayKey),
+				CSVDelay:         144,
+				CommitToLocalSig: makeSig(1),
+			}
+			if test.hasToRemote {
+				kit.CommitToRemotePubKey = toBlobPubKey(
+					toRemoteKey,
+				)
+				kit.CommitToRemoteSig = makeSig(2)
+			}
+
+			var key blob.BreachKey
+			_, err := rand.Read(key[:])
+			require.NoError(t, err)
+
+			ctxt, err := kit.Encrypt(key)
+			require.NoError(t, err)
+
+			kit, err = blob.Decrypt(key, ctxt, test.blobType)
+			require.NoError(t, err)
+
+			pkScript, err := kit.SweepPkScript()
+			require.NoError(t, err)
+			require.Equal(t, sweepAddr, pkScript)
+
+			gotRevKey, err := kit.RevocationKey()
+			require.NoError(t, err)
+			require.True(t, revKey.IsEqual(gotRevKey))
+
+			gotDelayKey, err := kit.LocalDelayKey()
+			require.NoError(t, err)
+			require.True(t, delayKey.IsEqual(gotDelayKey))
+
+			require.EqualValues(t, 144, kit.CSVDelay)
+
+			gotToRemoteKey, err := kit.CommitToRemoteKey()
+			if !test.hasToRemote {
+				require.ErrorIs(
+					t, err, blob.ErrNoCommitToRemoteOutput,
+				)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, toRemoteKey.IsEqual(gotToRemoteKey))
+		})
+	}
+
+	// A kit without a sweep address reports it as absent.
+	_, err := (&blob.JusticeKit{}).SweepPkScript()
+	require.ErrorIs(t, err, blob.ErrNoSweepAddress)
+}
+
+// TestJusticeKitInputSequences asserts that the nSequence required by the
+// justice transaction inputs is reported for each blob type, with only the
+// anchor and taproot to-remote outputs carrying a relative locktime.
+func TestJusticeKitInputSequences(t *testing.T) {
+	tests := []struct {
+		name        string
+		blobType    blob.Type
+		expToRemote uint32
+	}{
+		{
+			name:        "legacy",
+			blobType:    blob.TypeAltruistCommit,
+			expToRemote: 0,
+		},
+		{
+			name:        "anchor",
+			blobType:    blob.TypeAltruistAnchorCommit,
+			expToRemote: 1,
+		},
+		{
+			name:        "lease",
+			blobType:    leaseType,
+			expToRemote: 1,
+		},
+		{
+			name:        "taproot",
+			blobType:    blob.TypeAltruistTaprootCommit,
+			expToRemote: 1,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kit := &blob.JusticeKit{
+				BlobType: test.blobType,
+			}
+
+			toLocal, toRemote := kit.InputSequences()
+			require.Zero(t, toLocal)
+			require.Equal(t, test.expToRemote, toRemote)
+		})
+	}
+}
+
+// TestEncryptTo asserts that streaming a JusticeKit's ciphertext to an
+// io.Writer produces a blob of the same size as Encrypt, which decrypts to the
+// same kit. Since each encryption samples a fresh nonce, the ciphertexts
+// themselves are not compared directly.
+func TestEncryptTo(t *testing.T) {
+	for _, test := range descriptorTests {
+		if test.encErr != nil || test.decErr != nil {
+			continue
+		}
+
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kit := &blob.JusticeKit{
+				BlobType:             test.encVersion,
+				SweepAddress:         test.sweepAddr,
+				RevocationPubKey:     test.revPubKey,
+				LocalDelayPubKey:     test.delayPubKey,
+				CSVDelay:             test.csvDelay,
+				CommitToLocalSig:     test.commitToLocalSig,
+				CommitToRemotePubKey: test.commitToRemotePubKey,
+				CommitToRemoteSig:    test.commitToRemoteSig,
+			}
+
+			var key blob.BreachKey
+			_, err := rand.Read(key[:])
+			require.NoError(t, err)
+
+			ctxt, err := kit.Encrypt(key)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			n, err := blob.EncryptTo(&buf, kit, key)
+			require.NoError(t, err)
+			require.Equal(t, len(ctxt), n)
+			require.Equal(t, len(ctxt), buf.Len())
+			require.Equal(t, blob.Size(test.encVersion), n)
+
+			kit1, err := blob.Decrypt(key, ctxt, test.decVersion)
+			require.NoError(t, err)
+
+			kit2, err := blob.Decrypt(key, buf.Bytes(), test.decVersion)
+			require.NoError(t, err)
+
+			require.Equal(t, kit1, kit2)
+			require.Equal(t, kit, kit2)
+		})
+	}
+}
+
+// TestDecryptFrom asserts that a blob read from an io.Reader that delivers the
+// ciphertext in small chunks decrypts to the same kit as Decrypt, and that a
+// truncated stream results in io.ErrUnexpectedEOF.
+func TestDecryptFrom(t *testing.T) {
+	kit := &blob.JusticeKit{
+		BlobType:             blob.TypeAltruistAnchorCommit,
+		SweepAddress:         makeAddr(22),
+		RevocationPubKey:     makePubKey(0),
+		LocalDelayPubKey:     makePubKey(1),
+		CSVDelay:             144,
+		CommitToLocalSig:     makeSig(1),
+		CommitToRemotePubKey: makePubKey(2),
+		CommitToRemoteSig:    makeSig(2),
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	expKit, err := blob.Decrypt(key, ctxt, kit.BlobType)
+	require.NoError(t, err)
+
+	// Read the blob from a stream delivering one byte at a time, followed
+	// by some trailing data that should not be consumed.
+	stream := io.MultiReader(
+		bytes.NewReader(ctxt), bytes.NewReader([]byte("trailing")),
+	)
+	kit2, err := blob.DecryptFrom(
+		iotest.OneByteReader(stream), key, kit.BlobType,
+	)
+	require.NoError(t, err)
+	require.Equal(t, expKit, kit2)
+
+	trailing, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	require.Equal(t, []byte("trailing"), trailing)
+
+	// A truncated stream, including an empty one, should fail with
+	// io.ErrUnexpectedEOF.
+	_, err = blob.DecryptFrom(
+		iotest.HalfReader(bytes.NewReader(ctxt[:len(ctxt)-1])), key,
+		kit.BlobType,
+	)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	_, err = blob.DecryptFrom(bytes.NewReader(nil), key, kit.BlobType)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+// TestBlobChannelPoint asserts that the channel point header written by
+// EncryptWithChannelPoint can be read without the key, survives a round trip
+// through Decrypt, and that tampering with it causes decryption to fail.
+func TestBlobChannelPoint(t *testing.T) {
+	kit := &blob.JusticeKit{
+		BlobType:             blob.TypeAltruistCommit,
+		SweepAddress:         makeAddr(22),
+		RevocationPubKey:     makePubKey(0),
+		LocalDelayPubKey:     makePubKey(1),
+		CSVDelay:             144,
+		CommitToLocalSig:     makeSig(1),
+		CommitToRemotePubKey: makePubKey(2),
+		CommitToRemoteSig:    makeSig(2),
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	chanPoint := wire.OutPoint{
+		Hash:  chainhash.Hash{0x01, 0x02, 0x03},
+		Index: 7,
+	}
+
+	ctxt, err := kit.EncryptWithChannelPoint(key, chanPoint)
+	require.NoError(t, err)
+	require.Len(
+		t, ctxt, blob.ChannelPointHeaderSize+blob.Size(kit.BlobType),
+	)
+
+	// The channel point should be readable without the key.
+	readChanPoint, err := blob.ReadBlobChannelPoint(ctxt)
+	require.NoError(t, err)
+	require.Equal(t, chanPoint, readChanPoint)
+
+	// Decrypting the blob should authenticate the header and recover the
+	// original kit.
+	kit2, err := blob.Decrypt(key, ctxt, kit.BlobType)
+	require.NoError(t, err)
+	require.Equal(t, kit, kit2)
+
+	// A blob without a header should not report a channel point.
+	plainCtxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	_, err = blob.ReadBlobChannelPoint(plainCtxt)
+	require.ErrorIs(t, err, blob.ErrNoChannelPoint)
+
+	// Finally, corrupting the channel point in the header should cause
+	// decryption to fail, even though the header itself isn't encrypted.
+	corrupted := append([]byte(nil), ctxt...)
+	corrupted[blob.ChannelPointHeaderSize-1] ^= 0x01
+
+	_, err = blob.Decrypt(key, corrupted, kit.BlobType)
+	require.Error(t, err)
+}
+
+// TestBlobCommitmentNumber asserts that the commitment header written by
+// EncryptWithCommitment can be read without the key, is exposed by the kit
+// after a round trip through Decrypt, and that tampering with it causes
+// decryption to fail.
+func TestBlobCommitmentNumber(t *testing.T) {
+	kit := &blob.JusticeKit{
+		BlobType:         blob.TypeAltruistAnchorCommit,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	chanPoint := wire.OutPoint{
+		Hash:  chainhash.Hash{0x04, 0x05, 0x06},
+		Index: 3,
+	}
+	const commitNum = 1_000_001
+
+	ctxt, err := kit.EncryptWithCommitment(key, chanPoint, commitNum)
+	require.NoError(t, err)
+	require.Len(
+		t, ctxt, blob.CommitmentHeaderSize+blob.Size(kit.BlobType),
+	)
+
+	// Both the commitment number and the channel point should be readable
+	// without the key.
+	readCommitNum, err := blob.ReadBlobCommitmentNumber(ctxt)
+	require.NoError(t, err)
+	require.EqualValues(t, commitNum, readCommitNum)
+
+	readChanPoint, err := blob.ReadBlobChannelPoint(ctxt)
+	require.NoError(t, err)
+	require.Equal(t, chanPoint, readChanPoint)
+
+	// The decrypted kit should expose the commitment number.
+	kit2, err := blob.Decrypt(key, ctxt, kit.BlobType)
+	require.NoError(t, err)
+
+	decodedNum, ok := kit2.CommitmentNumber()
+	require.True(t, ok)
+	require.EqualValues(t, commitNum, decodedNum)
+
+	equal, diff := kit.Equal(kit2)
+	require.True(t, equal, diff)
+
+	// Blobs without a header, or with only a channel point header, carry
+	// no commitment number.
+	_, ok = kit.CommitmentNumber()
+	require.False(t, ok)
+
+	plainCtxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	_, err = blob.ReadBlobCommitmentNumber(plainCtxt)
+	require.ErrorIs(t, err, blob.ErrNoCommitmentNumber)
+
+	chanPointCtxt, err := kit.EncryptWithChannelPoint(key, chanPoint)
+	require.NoError(t, err)
+
+	_, err = blob.ReadBlobCommitmentNumber(chanPointCtxt)
+	require.ErrorIs(t, err, blob.ErrNoCommitmentNumber)
+
+	kit3, err := blob.Decrypt(key, chanPointCtxt, kit.BlobType)
+	require.NoError(t, err)
+
+	_, ok = kit3.CommitmentNumber()
+	require.False(t, ok)
+
+	// Finally, a tower can't be tricked into ordering blobs by a tampered
+	// commitment number, as decryption then fails.
+	corrupted := append([]byte(nil), ctxt...)
+	corrupted[blob.CommitmentHeaderSize-1] ^= 0x01
+
+	_, err = blob.Decrypt(key, corrupted, kit.BlobType)
+	require.Error(t, err)
+}
+
+// TestDecryptChecked asserts that DecryptChecked decrypts blobs of the
+// expected type, with or without a header, and rejects a ciphertext whose
+// length doesn't match the expected type with ErrVersionLengthMismatch.
+func TestDecryptChecked(t *testing.T) {
+	kit := &blob.JusticeKit{
+		BlobType:         blob.TypeAltruistAnchorCommit,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	chanPoint := wire.OutPoint{
+		Hash:  chainhash.Hash{0x07, 0x08, 0x09},
+		Index: 1,
+	}
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	chanPointCtxt, err := kit.EncryptWithChannelPoint(key, chanPoint)
+	require.NoError(t, err)
+
+	commitCtxt, err := kit.EncryptWithCommitment(key, chanPoint, 42)
+	require.NoError(t, err)
+
+	for _, c := range [][]byte{ctxt, chanPointCtxt, commitCtxt} {
+		kit2, err := blob.DecryptChecked(key, c, kit.BlobType)
+		require.NoError(t, err)
+		require.Equal(t, kit.SweepAddress, kit2.SweepAddress)
+	}
+
+	// A blob stored under a type of a different size is rejected before
+	// attempting to decrypt it.
+	require.NotEqual(
+		t, blob.CiphertextSize(kit.BlobType),
+		blob.CiphertextSize(dataCommitmentType),
+	)
+	_, err = blob.DecryptChecked(key, ctxt, dataCommitmentType)
+	require.ErrorIs(t, err, blob.ErrVersionLengthMismatch)
+
+	// So is a truncated blob of the expected type.
+	_, err = blob.DecryptChecked(key, ctxt[1:], kit.BlobType)
+	require.ErrorIs(t, err, blob.ErrVersionLengthMismatch)
+
+	_, err = blob.DecryptChecked(key, ctxt, blob.Type(0))
+	require.ErrorIs(t, err, blob.ErrUnknownBlobType)
+}
+
+// TestBlobMatchesChannel asserts that BlobMatchesChannel only reports a match
+// for the channel point a blob was encrypted for, under the key it was
+// encrypted with.
+func TestBlobMatchesChannel(t *testing.T) {
+	kit := &blob.JusticeKit{
+		BlobType:         blob.TypeAltruistAnchorCommit,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	var key, otherKey blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+	_, err = rand.Read(otherKey[:])
+	require.NoError(t, err)
+
+	chanPoint := wire.OutPoint{
+		Hash:  chainhash.Hash{0x07, 0x08, 0x09},
+		Index: 1,
+	}
+	otherChanPoint := wire.OutPoint{
+		Hash:  chainhash.Hash{0x07, 0x08, 0x09},
+		Index: 2,
+	}
+
+	chanPointCtxt, err := kit.EncryptWithChannelPoint(key, chanPoint)
+	require.NoError(t, err)
+
+	commitCtxt, err := kit.EncryptWithCommitment(key, chanPoint, 42)
+	require.NoError(t, err)
+
+	for _, ctxt := range [][]byte{chanPointCtxt, commitCtxt} {
+		// The blob should only match the channel point it was
+		// encrypted for.
+		match, err := blob.BlobMatchesChannel(
+			key, ctxt, kit.BlobType, chanPoint,
+		)
+		require.NoError(t, err)
+		require.True(t, match)
+
+		match, err = blob.BlobMatchesChannel(
+			key, ctxt, kit.BlobType, otherChanPoint,
+		)
+		require.NoError(t, err)
+		require.False(t, match)
+
+		// Under a different key, the channel point can't be
+		// authenticated.
+		match, err = blob.BlobMatchesChannel(
+			otherKey, ctxt, kit.BlobType, chanPoint,
+		)
+		require.NoError(t, err)
+		require.False(t, match)
+
+		// Rewriting the header to claim the other channel point
+		// shouldn't produce a match either.
+		forged := append([]byte(nil), ctxt...)
+		forged[blob.ChannelPointHeaderSize-1] ^= 0x03
+
+		match, err = blob.BlobMatchesChannel(
+			key, forged, kit.BlobType, otherChanPoint,
+		)
+		require.NoError(t, err)
+		require.False(t, match)
+	}
+
+	// A blob without a header isn't bound to any channel point.
+	plainCtxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	_, err = blob.BlobMatchesChannel(
+		key, plainCtxt, kit.BlobType, chanPoint,
+	)
+	require.ErrorIs(t, err, blob.ErrNoChannelPoint)
+
+	_, err = blob.BlobMatchesChannel(
+		key, chanPointCtxt, blob.Type(1<<15), chanPoint,
+	)
+	require.ErrorIs(t, err, blob.ErrUnknownBlobType)
+}
+
+// TestToRemoteOnlyRoundTrip asserts that a kit with FlagToRemoteOnly has its
+// own constant size, survives a round trip through encryption without leaking
+// any to-local fields, also when prefixed by a channel point or commitment
+// header, and can't be combined with flags concerning the to-local output.
+func TestToRemoteOnlyRoundTrip(t *testing.T) {
+	blobType := blob.TypeAltruistToRemote
+	require.Equal(
+		t, blob.ToRemotePlaintextSize, blob.PlaintextSize(blobType),
+	)
+
+	revPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	toRemotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	// The kit can't be constructed without the to-remote pubkey.
+
+	sweepAddr := make([]byte, 22)
+	sweepAddr[0], sweepAddr[1] = txscript.OP_0, txscript.OP_DATA_20
+
+	_, err = blob.NewJusticeKitFromScripts(blobType, blob.JusticeKitParams{
+		SweepAddress: sweepAddr,
+	})
+	require.ErrorIs(t, err, blob.ErrMissingPubKey)
+
+	kit, err := blob.NewJusticeKitFromScripts(
+		blobType, blob.JusticeKitParams{
+			SweepAddress:     sweepAddr,
+			RevocationPubKey: revPriv.PubKey(),
+			LocalDelayPubKey: delayPriv.PubKey(),
+			CSVDelay:         144,
+			HasToRemote:      true,
+			ToRemotePubKey:   toRemotePriv.PubKey(),
+		},
+	)
+	require.NoError(t, err)
+
+	// The to-local parameters shouldn't make it into the kit.
+	require.Equal(t, blob.PubKey{}, kit.RevocationPubKey)
+	require.Equal(t, blob.PubKey{}, kit.LocalDelayPubKey)
+	require.Zero(t, kit.CSVDelay)
+
+	var key blob.BreachKey
+	_, err = rand.Read(key[:])
+	require.NoError(t, err)
+
+	// Encrypting the kit requires the to-remote signature.
+	_, err = kit.Encrypt(key)
+	require.ErrorIs(t, err, blob.ErrMissingSignature)
+
+	require.NoError(t, kit.AddToRemoteSig(makeSig(2)))
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+	require.Len(t, ctxt, blob.ToRemotePlaintextSize+blob.Overhead)
+	require.Equal(t, blob.Size(blobType), len(ctxt))
+
+	kit2, err := blob.Decrypt(key, ctxt, blobType)
+	require.NoError(t, err)
+	require.Equal(t, kit, kit2)
+	require.NoError(t, blob.ValidateBlob(key, ctxt, blobType))
+
+	// The to-local output isn't available, while the to-remote output is.
+	_, err = kit2.ToLocalOutputSpendInfo()
+	require.ErrorIs(t, err, blob.ErrNoCommitToLocalOutput)
+
+	_, err = kit2.ToLocalWitnessScript()
+	require.ErrorIs(t, err, blob.ErrNoCommitToLocalOutput)
+
+	_, err = kit2.ToRemoteWitnessScript()
+	require.NoError(t, err)
+
+	// The channel point and commitment number headers should be readable
+	// without the key, and the headered blobs should decrypt.
+	chanPoint := wire.OutPoint{
+		Hash:  chainhash.Hash{0x07, 0x08, 0x09},
+		Index: 1,
+	}
+
+	chanPointCtxt, err := kit.EncryptWithChannelPoint(key, chanPoint)
+	require.NoError(t, err)
+
+	readChanPoint, err := blob.ReadBlobChannelPoint(chanPointCtxt)
+	require.NoError(t, err)
+	require.Equal(t, chanPoint, readChanPoint)
+
+	kit2, err = blob.Decrypt(key, chanPointCtxt, blobType)
+	require.NoError(t, err)
+	require.Equal(t, kit, kit2)
+
+	commitCtxt, err := kit.EncryptWithCommitment(key, chanPoint, 42)
+	require.NoError(t, err)
+
+	readChanPoint, err = blob.ReadBlobChannelPoint(commitCtxt)
+	require.NoError(t, err)
+	require.Equal(t, chanPoint, readChanPoint)
+
+	readCommitNum, err := blob.ReadBlobCommitmentNumber(commitCtxt)
+	require.NoError(t, err)
+	require.EqualValues(t, 42, readCommitNum)
+
+	// Flags concerning the to-local or HTLC outputs, or the optional
+	// sections, can't be combined with FlagToRemoteOnly.
+	for _, flag := range []blob.Flag{
+		blob.FlagCommitOutputs, blob.FlagSecondLevelHtlcs,
+		blob.FlagDataCommitment, blob.FlagTLVTrailer,
+		blob.FlagLeaseChannel, blob.FlagTaprootKeySpend,
+	} {
+		invalidType := blob.TypeFromFlags(blob.FlagToRemoteOnly, flag)
+		require.Zero(t, blob.PlaintextSize(invalidType), flag)
+
+		invalidKit := kit.Copy()
+		invalidKit.BlobType = invalidType
+
+		_, err = invalidKit.Encrypt(key)
+		require.ErrorIs(t, err, blob.ErrUnknownBlobType, flag)
+	}
+}
+
+// TestJusticeKitVerifySignatures asserts that VerifySignatures accepts a kit
+// whose signatures spend the breached outputs in the justice transaction, and
+// rejects one with a signature that doesn't.
+func TestJusticeKitVerifySignatures(t *testing.T) {
+	const csvDelay = 144
+
+	revPrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	toRemotePrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	// Construct the breached to-local and to-remote outputs.
+	toLocalScript, err := input.CommitScriptToSelf(
+		csvDelay, delayPrivKey.PubKey(), revPrivKey.PubKey(),
+	)
+	require.NoError(t, err)
+	toLocalPkScript, err := input.WitnessScriptHash(toLocalScript)
+	require.NoError(t, err)
+
+	toRemotePkScript, err := input.CommitScriptUnencumbered(
+		toRemotePrivKey.PubKey(),
+	)
+	require.NoError(t, err)
+
+	breachTxHash := chainhash.Hash{0x01}
+	breachInfo := &lnwallet.BreachRetribution{
+		BreachTxHash: breachTxHash,
+		RemoteOutputSignDesc: &input.SignDescriptor{
+			Output: wire.NewTxOut(100000, toLocalPkScript),
+		},
+		RemoteOutpoint: wire.OutPoint{Hash: breachTxHash, Index: 0},
+		RemoteDelay:    csvDelay,
+		LocalOutputSignDesc: &input.SignDescriptor{
+			Output: wire.NewTxOut(50000, toRemotePkScript),
+		},
+		LocalOutpoint: wire.OutPoint{Hash: breachTxHash, Index: 1},
+		KeyRing: &lnwallet.CommitmentKeyRing{
+			RevocationKey: revPrivKey.PubKey(),
+			ToLocalKey:    delayPrivKey.PubKey(),
+			ToRemoteKey:   toRemotePrivKey.PubKey(),
+		},
+	}
+
+	// Build the justice transaction sweeping both outputs.
+	justiceTx := wire.NewMsgTx(2)
+	justiceTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: breachInfo.RemoteOutpoint,
+	})
+	justiceTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: breachInfo.LocalOutpoint,
+	})
+	justiceTx.AddTxOut(wire.NewTxOut(149000, makeAddr(22)))
+
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	prevOutFetcher.AddPrevOut(
+		breachInfo.RemoteOutpoint, breachInfo.RemoteOutputSignDesc.Output,
+	)
+	prevOutFetcher.AddPrevOut(
+		breachInfo.LocalOutpoint, breachInfo.LocalOutputSignDesc.Output,
+	)
+	hashCache := txscript.NewTxSigHashes(justiceTx, prevOutFetcher)
+
+	sign := func(idx int, amt int64, script []byte,
+		privKey *btcec.PrivateKey) lnwire.Sig {
+
+		rawSig, err := txscript.RawTxInWitnessSignature(
+			justiceTx, hashCache, idx, amt, script,
+			txscript.SigHashAll, privKey,
+		)
+		require.NoError(t, err)
+
+		sig, err := lnwire.NewSigFromECDSARawSignature(
+			rawSig[:len(rawSig)-1],
+		)
+		require.NoError(t, err)
+
+		return sig
+	}
+
+	kit := &blob.JusticeKit{
+		BlobType:             blob.TypeAltruistCommit,
+		SweepAddress:         makeAddr(22),
+		RevocationPubKey:     toBlobPubKey(revPrivKey.PubKey()),
+		LocalDelayPubKey:     toBlobPubKey(delayPrivKey.PubKey()),
+		CSVDelay:             csvDelay,
+		CommitToRemotePubKey: toBlobPubKey(toRemotePrivKey.PubKey()),
+		CommitToLocalSig: sign(
+			0, 100000, toLocalScript, revPrivKey,
+		),
+		CommitToRemoteSig: sign(
+			1, 50000, toRemotePkScript, toRemotePrivKey,
+		),
+	}
+
+	// The correctly signed kit should pass verification.
+	require.NoError(t, kit.VerifySignatures(breachInfo, justiceTx))
+
+	// Signing the to-local output with the wrong key should cause the
+	// to-local signature to be rejected.
+	badKit := *kit
+	badKit.CommitToLocalSig = sign(0, 100000, toLocalScript, delayPrivKey)
+
+	err = badKit.VerifySignatures(breachInfo, justiceTx)
+	require.ErrorIs(t, err, blob.ErrInvalidSignature)
+	require.Contains(t, err.Error(), "to-local")
+
+	// Likewise, swapping in a signature for a different input should cause
+	// the to-remote signature to be rejected.
+	badKit = *kit
+	badKit.CommitToRemoteSig = sign(
+		0, 100000, toLocalScript, toRemotePrivKey,
+	)
+
+	err = badKit.VerifySignatures(breachInfo, justiceTx)
+	require.ErrorIs(t, err, blob.ErrInvalidSignature)
+	require.Contains(t, err.Error(), "to-remote")
+
+	// Finally, a justice transaction that doesn't spend the breached
+	// outputs can't be verified.
+	otherTx := justiceTx.Copy()
+	otherTx.TxIn[0].PreviousOutPoint.Index = 2
+
+	err = kit.VerifySignatures(breachInfo, otherTx)
+	require.ErrorIs(t, err, blob.ErrJusticeInputMismatch)
+}
+
+// TestJusticeKitVerifySignaturesTaproot asserts that VerifySignatures checks
+// the commit to-local signature of taproot kits against the sighash of the
+// revocation leaf, or of the key path for types with FlagTaprootKeySpend, and
+// the commit to-remote signature against the sighash of the to-remote leaf.
+func TestJusticeKitVerifySignaturesTaproot(t *testing.T) {
+	const (
+		csvDelay    = 144
+		toLocalAmt  = 100_000
+		toRemoteAmt = 50_000
+	)
+
+	revPrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	toRemotePrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	var (
+		revPubKey      = revPrivKey.PubKey()
+		delayPubKey    = delayPrivKey.PubKey()
+		toRemotePubKey = toRemotePrivKey.PubKey()
+	)
+
+	// Construct the breached outputs independently of the kit.
+	toLocalTree, err := input.NewLocalCommitScriptTree(
+		csvDelay, delayPubKey, revPubKey,
+	)
+	require.NoError(t, err)
+	scriptPathPkScript, err := input.PayToTaprootScript(
+		toLocalTree.TaprootKey,
+	)
+	require.NoError(t, err)
+
+	// The key path of a to-local output revoked via its key commits to
+	// the delayed leaf under the revocation key.
+	delayTree := txscript.AssembleTaprootScriptTree(toLocalTree.SettleLeaf)
+	delayRoot := delayTree.RootNode.TapHash()
+	keySpendPkScript, err := input.PayToTaprootScript(
+		txscript.ComputeTaprootOutputKey(revPubKey, delayRoot[:]),
+	)
+	require.NoError(t, err)
+
+	toRemoteTree, err := input.NewRemoteCommitScriptTree(toRemotePubKey)
+	require.NoError(t, err)
+	toRemotePkScript, err := input.PayToTaprootScript(
+		toRemoteTree.TaprootKey,
+	)
+	require.NoError(t, err)
+
+	toSchnorrSig := func(rawSig []byte, err error) lnwire.Sig {
+		require.NoError(t, err)
+
+		sig, err := lnwire.NewSigFromSchnorrRawSignature(rawSig)
+		require.NoError(t, err)
+
+		return sig
+	}
+
+	// signScriptPath signs the first input of tx, spending the revocation
+	// leaf of the to-local output.
+	signScriptPath := func(tx *wire.MsgTx, hashes *txscript.TxSigHashes,
+		privKey *btcec.PrivateKey) lnwire.Sig {
+
+		return toSchnorrSig(txscript.RawTxInTapscriptSignature(
+			tx, hashes, 0, toLocalAmt, scriptPathPkScript,
+			toLocalTree.RevocationLeaf, txscript.SigHashDefault,
+			privKey,
+		))
+	}
+
+	// signKeyPath signs the first input of tx, spending the key path of
+	// the to-local output.
+	signKeyPath := func(tx *wire.MsgTx, hashes *txscript.TxSigHashes,
+		privKey *btcec.PrivateKey) lnwire.Sig {
+
+		return toSchnorrSig(txscript.RawTxInTaprootSignature(
+			tx, hashes, 0, toLocalAmt, keySpendPkScript,
+			delayRoot[:], txscript.SigHashDefault, privKey,
+		))
+	}
+
+	tests := []struct {
+		name            string
+		blobType        blob.Type
+		toLocalPkScript []byte
+		signToLocal     func(*wire.MsgTx, *txscript.TxSigHashes,
+			*btcec.PrivateKey) lnwire.Sig
+	}{
+		{
+			name:            "script path",
+			blobType:        blob.TypeAltruistTaprootCommit,
+			toLocalPkScript: scriptPathPkScript,
+			signToLocal:     signScriptPath,
+		},
+		{
+			name: "key path",
+			blobType: blob.TypeFromFlags(
+				blob.FlagCommitOutputs,
+				blob.FlagTaprootChannel,
+				blob.FlagTaprootKeySpend,
+			),
+			toLocalPkScript: keySpendPkScript,
+			signToLocal:     signKeyPath,
+		},
+	}
+
+	breachTxHash := chainhash.Hash{0x01}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			breachInfo := &lnwallet.BreachRetribution{
+				BreachTxHash: breachTxHash,
+				RemoteOutputSignDesc: &input.SignDescriptor{
+					Output: wire.NewTxOut(
+						toLocalAmt,
+						test.toLocalPkScript,
+					),
+				},
+				RemoteOutpoint: wire.OutPoint{
+					Hash: breachTxHash, Index: 0,
+				},
+				RemoteDelay: csvDelay,
+				LocalOutputSignDesc: &input.SignDescriptor{
+					Output: wire.NewTxOut(
+						toRemoteAmt, toRemotePkScript,
+					),
+				},
+				LocalOutpoint: wire.OutPoint{
+					Hash: breachTxHash, Index: 1,
+				},
+				KeyRing: &lnwallet.CommitmentKeyRing{
+					RevocationKey: revPubKey,
+					ToLocalKey:    delayPubKey,
+					ToRemoteKey:   toRemotePubKey,
+				},
+			}
+
+			justiceTx := wire.NewMsgTx(2)
+			justiceTx.AddTxIn(&wire.TxIn{
+				PreviousOutPoint: breachInfo.RemoteOutpoint,
+			})
+			justiceTx.AddTxIn(&wire.TxIn{
+				PreviousOutPoint: breachInfo.LocalOutpoint,
+			})
+			justiceTx.AddTxOut(wire.NewTxOut(
+				toLocalAmt+toRemoteAmt-1_000, makeSweepAddr(),
+			))
+
+			fetcher := txscript.NewMultiPrevOutFetcher(nil)
+			fetcher.AddPrevOut(
+				breachInfo.RemoteOutpoint,
+				breachInfo.RemoteOutputSignDesc.Output,
+			)
+			fetcher.AddPrevOut(
+				breachInfo.LocalOutpoint,
+				breachInfo.LocalOutputSignDesc.Output,
+			)
+			hashes := txscript.NewTxSigHashes(justiceTx, fetcher)
+
+			kit, err := blob.NewJusticeKitFromScripts(
+				test.blobType, blob.JusticeKitParams{
+					SweepAddress:     makeSweepAddr(),
+					RevocationPubKey: revPubKey,
+					LocalDelayPubKey: delayPubKey,
+					CSVDelay:         csvDelay,
+					HasToRemote:      true,
+					ToRemotePubKey:   toRemotePubKey,
+				},
+			)
+			require.NoError(t, err)
+
+			require.NoError(t, kit.AddToLocalSig(
+				test.signToLocal(justiceTx, hashes, revPrivKey),
+			))
+			require.NoError(t, kit.AddToRemoteSig(toSchnorrSig(
+				txscript.RawTxInTapscriptSignature(
+					justiceTx, hashes, 1, toRemoteAmt,
+					toRemotePkScript,
+					toRemoteTree.SettleLeaf,
+					txscript.SigHashDefault,
+					toRemotePrivKey,
+				),
+			)))
+
+			// The correctly signed kit should pass verification.
+			require.NoError(t, kit.VerifySignatures(
+				breachInfo, justiceTx,
+			))
+
+			// Signing the to-local output with the wrong key
+			// should cause the to-local signature to be rejected.
+			kit.ReplaceToLocalSig(test.signToLocal(
+				justiceTx, hashes, delayPrivKey,
+			))
+
+			err = kit.VerifySignatures(breachInfo, justiceTx)
+			require.ErrorIs(t, err, blob.ErrInvalidSignature)
+			require.Contains(t, err.Error(), "to-local")
+		})
+	}
+}
+
+// toBlobPubKey serializes the given pubkey into a blob.PubKey.
+func toBlobPubKey(pubKey *btcec.PublicKey) blob.PubKey {
+	var blobPubKey blob.PubKey
+	copy(blobPubKey[:], pubKey.SerializeCompressed())
+
+	return blobPubKey
+}
+
+// TestJusticeKitVerifyAgainstOutputs asserts that the pkScripts derived from a
+// kit's witness scripts are checked against those of the breached outputs.
+func TestJusticeKitVerifyAgainstOutputs(t *testing.T) {
+	const csvDelay = 144
+
+	newPubKey := func() *btcec.PublicKey {
+		priv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return priv.PubKey()
+	}
+
+	var (
+		revPubKey      = newPubKey()
+		delayPubKey    = newPubKey()
+		toRemotePubKey = newPubKey()
+	)
+
+	// Compute the breached pkScripts independently of the kit for each
+	// channel type.
+	toLocalScript, err := input.CommitScriptToSelf(
+		csvDelay, delayPubKey, revPubKey,
+	)
+	require.NoError(t, err)
+	p2wshToLocal, err := input.WitnessScriptHash(toLocalScript)
+	require.NoError(t, err)
+
+	p2wkhToRemote, err := input.CommitScriptUnencumbered(toRemotePubKey)
+	require.NoError(t, err)
+
+	anchorToRemoteScript, err := input.CommitScriptToRemoteConfirmed(
+		toRemotePubKey,
+	)
+	require.NoError(t, err)
+	p2wshToRemote, err := input.WitnessScriptHash(anchorToRemoteScript)
+	require.NoError(t, err)
+
+	toLocalTree, err := input.NewLocalCommitScriptTree(
+		csvDelay, delayPubKey, revPubKey,
+	)
+	require.NoError(t, err)
+	p2trToLocal, err := input.PayToTaprootScript(toLocalTree.TaprootKey)
+	require.NoError(t, err)
+
+	toRemoteTree, err := input.NewRemoteCommitScriptTree(toRemotePubKey)
+	require.NoError(t, err)
+	p2trToRemote, err := input.PayToTaprootScript(toRemoteTree.TaprootKey)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		blobType         blob.Type
+		toLocalPkScript  []byte
+		toRemotePkScript []byte
+	}{
+		{
+			name:             "legacy",
+			blobType:         blob.TypeAltruistCommit,
+			toLocalPkScript:  p2wshToLocal,
+			toRemotePkScript: p2wkhToRemote,
+		},
+		{
+			name:             "anchor",
+			blobType:         blob.TypeAltruistAnchorCommit,
+			toLocalPkScript:  p2wshToLocal,
+			toRemotePkScript: p2wshToRemote,
+		},
+		{
+			name:             "taproot",
+			blobType:         blob.TypeAltruistTaprootCommit,
+			toLocalPkScript:  p2trToLocal,
+			toRemotePkScript: p2trToRemote,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kit, err := blob.NewJusticeKitFromScripts(
+				test.blobType, blob.JusticeKitParams{
+					SweepAddress:     makeSweepAddr(),
+					RevocationPubKey: revPubKey,
+					LocalDelayPubKey: delayPubKey,
+					CSVDelay:         csvDelay,
+					HasToRemote:      true,
+					ToRemotePubKey:   toRemotePubKey,
+				},
+			)
+			require.NoError(t, err)
+
+			// The matching pkScripts should be accepted, also
+			// when the to-local output is absent.
+			require.NoError(t, kit.VerifyAgainstOutputs(
+				test.toLocalPkScript, test.toRemotePkScript,
+			))
+			require.NoError(t, kit.VerifyAgainstOutputs(
+				nil, test.toRemotePkScript,
+			))
+
+			// Swapping the pkScripts should be caught, naming
+			// the mismatched output.
+			err = kit.VerifyAgainstOutputs(
+				test.toRemotePkScript, test.toRemotePkScript,
+			)
+			require.ErrorIs(t, err, blob.ErrOutputScriptMismatch)
+			require.ErrorContains(t, err, "to-local")
+
+			err = kit.VerifyAgainstOutputs(
+				test.toLocalPkScript, test.toLocalPkScript,
+			)
+			require.ErrorIs(t, err, blob.ErrOutputScriptMismatch)
+			require.ErrorContains(t, err, "to-remote")
+
+			// A breached to-remote output must be given if and
+			// only if the kit has one.
+			err = kit.VerifyAgainstOutputs(
+				test.toLocalPkScript, nil,
+			)
+			require.ErrorIs(t, err, blob.ErrNoCommitToRemoteOutput)
+		})
+	}
+
+	// A pkScript of a different channel type should also be rejected.
+	kit, err := blob.NewJusticeKitFromScripts(
+		blob.TypeAltruistAnchorCommit, blob.JusticeKitParams{
+			SweepAddress:     makeSweepAddr(),
+			RevocationPubKey: revPubKey,
+			LocalDelayPubKey: delayPubKey,
+			CSVDelay:         csvDelay,
+		},
+	)
+	require.NoError(t, err)
+
+	err = kit.VerifyAgainstOutputs(p2trToLocal, nil)
+	require.ErrorIs(t, err, blob.ErrOutputScriptMismatch)
+
+	err = kit.VerifyAgainstOutputs(p2wshToLocal, p2wshToRemote)
+	require.ErrorIs(t, err, blob.ErrNoCommitToRemoteOutput)
+}
+
+// TestJusticeKitWithSweepAddress asserts that redirecting a kit to a new sweep
+// address leaves its witnesses untouched, while its signatures remain bound to
+// a justice transaction paying the original address.
+func TestJusticeKitWithSweepAddress(t *testing.T) {
+	const csvDelay = 144
+
+	revPrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	toLocalScript, err := input.CommitScriptToSelf(
+		csvDelay, delayPrivKey.PubKey(), revPrivKey.PubKey(),
+	)
+	require.NoError(t, err)
+	toLocalPkScript, err := input.WitnessScriptHash(toLocalScript)
+	require.NoError(t, err)
+
+	breachTxHash := chainhash.Hash{0x01}
+	breachInfo := &lnwallet.BreachRetribution{
+		BreachTxHash: breachTxHash,
+		RemoteOutputSignDesc: &input.SignDescriptor{
+			Output: wire.NewTxOut(100000, toLocalPkScript),
+		},
+		RemoteOutpoint: wire.OutPoint{Hash: breachTxHash},
+		RemoteDelay:    csvDelay,
+		KeyRing: &lnwallet.CommitmentKeyRing{
+			RevocationKey: revPrivKey.PubKey(),
+			ToLocalKey:    delayPrivKey.PubKey(),
+		},
+	}
+
+	newP2WSH := func() []byte {
+		addr := make([]byte, 34)
+		addr[0], addr[1] = txscript.OP_0, txscript.OP_DATA_32
+		_, err := rand.Read(addr[2:])
+		require.NoError(t, err)
+
+		return addr
+	}
+	oldAddr, newAddr := newP2WSH(), newP2WSH()
+
+	justiceTx := func(sweepAddr []byte) *wire.MsgTx {
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: breachInfo.RemoteOutpoint,
+		})
+		tx.AddTxOut(wire.NewTxOut(99000, sweepAddr))
+
+		return tx
+	}
+	oldTx := justiceTx(oldAddr)
+
+	prevOutFetcher := txscript.NewCannedPrevOutputFetcher(
+		toLocalPkScript, 100000,
+	)
+	rawSig, err := txscript.RawTxInWitnessSignature(
+		oldTx, txscript.NewTxSigHashes(oldTx, prevOutFetcher), 0,
+		100000, toLocalScript, txscript.SigHashAll, revPrivKey,
+	)
+	require.NoError(t, err)
+	toLocalSig, err := lnwire.NewSigFromECDSARawSignature(
+		rawSig[:len(rawSig)-1],
+	)
+	require.NoError(t, err)
+
+	kit := &blob.JusticeKit{
+		BlobType:         blob.TypeAltruistCommit,
+		SweepAddress:     oldAddr,
+		RevocationPubKey: toBlobPubKey(revPrivKey.PubKey()),
+		LocalDelayPubKey: toBlobPubKey(delayPrivKey.PubKey()),
+		CSVDelay:         csvDelay,
+		CommitToLocalSig: toLocalSig,
+	}
+
+	// Scripts that the tower can't sweep to should be rejected.
+	_, err = kit.WithSweepAddress(nil)
+	require.ErrorIs(t, err, blob.ErrNoSweepAddress)
+
+	_, err = kit.WithSweepAddress(makeAddr(blob.MaxSweepAddrSize + 1))
+	require.ErrorIs(t, err, blob.ErrSweepAddressToLong)
+
+	p2pkh := append([]byte{txscript.OP_DUP, txscript.OP_HASH160,
+		txscript.OP_DATA_20}, make([]byte, 20)...)
+	p2pkh = append(p2pkh, txscript.OP_EQUALVERIFY, txscript.OP_CHECKSIG)
+	_, err = kit.WithSweepAddress(p2pkh)
+	require.ErrorIs(t, err, blob.ErrUnknownSweepAddrType)
+
+	// Redirecting the kit should only change the sweep address of the
+	// copy, leaving the original and all witnesses untouched.
+	redirected, err := kit.WithSweepAddress(newAddr)
+	require.NoError(t, err)
+	require.Equal(t, newAddr, redirected.SweepAddress)
+	require.Equal(t, oldAddr, kit.SweepAddress)
+
+	expReqs, err := kit.SpendRequests()
+	require.NoError(t, err)
+	reqs, err := redirected.SpendRequests()
+	require.NoError(t, err)
+	require.Equal(t, expReqs, reqs)
+
+	// The carried signature is still valid for the justice transaction
+	// it was made for, but as it commits to the outputs, it isn't valid
+	// for one paying the new address.
+	require.NoError(t, redirected.VerifySignatures(breachInfo, oldTx))
+
+	err = redirected.VerifySignatures(breachInfo, justiceTx(newAddr))
+	require.ErrorIs(t, err, blob.ErrInvalidSignature)
+}
+
+// TestJusticeKitTaprootToRemoteSpend asserts that the witness assembled from
+// a taproot JusticeKit satisfies the to-remote output of a taproot commitment
+// when executed by the script engine.
+func TestJusticeKitTaprootToRemoteSpend(t *testing.T) {
+	toRemotePrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	// Construct the taproot to-remote output of the breached commitment.
+	scriptTree, err := input.NewRemoteCommitScriptTree(
+		toRemotePrivKey.PubKey(),
+	)
+	require.NoError(t, err)
+
+	toRemotePkScript, err := input.PayToTaprootScript(scriptTree.TaprootKey)
+	require.NoError(t, err)
+
+	const toRemoteAmt = 50000
+	breachTxHash := chainhash.Hash{0x01}
+	toRemoteOutPoint := wire.OutPoint{Hash: breachTxHash, Index: 1}
+	toRemoteTxOut := wire.NewTxOut(toRemoteAmt, toRemotePkScript)
+
+	// Build the justice transaction sweeping the to-remote output, which
+	// must satisfy the 1-block CSV delay.
+	justiceTx := wire.NewMsgTx(2)
+	justiceTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: toRemoteOutPoint,
+		Sequence:         1,
+	})
+	justiceTx.AddTxOut(wire.NewTxOut(toRemoteAmt-1000, makeAddr(22)))
+
+	prevOutFetcher := txscript.NewCannedPrevOutputFetcher(
+		toRemotePkScript, toRemoteAmt,
+	)
+	hashCache := txscript.NewTxSigHashes(justiceTx, prevOutFetcher)
+
+	rawSig, err := txscript.RawTxInTapscriptSignature(
+		justiceTx, hashCache, 0, toRemoteAmt, toRemotePkScript,
+		scriptTree.SettleLeaf, txscript.SigHashDefault,
+		toRemotePrivKey,
+	)
+	require.NoError(t, err)
+
+	toRemoteSig, err := lnwire.NewSigFromSchnorrRawSignature(rawSig)
+	require.NoError(t, err)
+
+	kit := &blob.JusticeKit{
+		BlobType:             blob.TypeAltruistTaprootCommit,
+		SweepAddress:         makeAddr(22),
+		RevocationPubKey:     makePubKey(0),
+		LocalDelayPubKey:     makePubKey(1),
+		CSVDelay:             144,
+		CommitToLocalSig:     makeSig(1),
+		CommitToRemotePubKey: toBlobPubKey(toRemotePrivKey.PubKey()),
+		CommitToRemoteSig:    toRemoteSig,
+	}
+
+	// Round trip the kit through encryption, to ensure that the to-remote
+	// signature is decoded as a schnorr signature.
+	var key blob.BreachKey
+	_, err = rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	kit, err = blob.Decrypt(key, ctxt, blob.TypeAltruistTaprootCommit)
+	require.NoError(t, err)
+
+	// Assemble the full witness from the kit.
+	witnessStack, err := kit.CommitToRemoteWitnessStack()
+	require.NoError(t, err)
+
+	leafScript, err := kit.CommitToRemoteWitnessScript()
+	require.NoError(t, err)
+	require.Equal(t, scriptTree.SettleLeaf.Script, leafScript)
+
+	ctrlBlock, err := kit.CommitToRemoteControlBlock(toRemotePkScript)
+	require.NoError(t, err)
+
+	justiceTx.TxIn[0].Witness = append(witnessStack, leafScript, ctrlBlock)
+
+	// The resulting witness should satisfy the to-remote output.
+	vm, err := txscript.NewEngine(
+		toRemotePkScript, justiceTx, 0, txscript.StandardVerifyFlags,
+		nil, hashCache, toRemoteAmt, prevOutFetcher,
+	)
+	require.NoError(t, err)
+	require.NoError(t, vm.Execute())
+
+	// The signature should also pass verification against the breach.
+	breachInfo := &lnwallet.BreachRetribution{
+		BreachTxHash: breachTxHash,
+		LocalOutputSignDesc: &input.SignDescriptor{
+			Output: toRemoteTxOut,
+		},
+		LocalOutpoint: toRemoteOutPoint,
+		KeyRing: &lnwallet.CommitmentKeyRing{
+			ToRemoteKey: toRemotePrivKey.PubKey(),
+		},
+	}
+	require.NoError(t, kit.VerifySignatures(breachInfo, justiceTx))
+
+	// A breached output paying to a different key should be rejected when
+	// constructing the control block.
+	otherPkScript, err := input.PayToTaprootScript(
+		toRemotePrivKey.PubKey(),
+	)
+	require.NoError(t, err)
+
+	_, err = kit.CommitToRemoteControlBlock(otherPkScript)
+	require.ErrorIs(t, err, blob.ErrTaprootOutputMismatch)
+
+	// Finally, non-taproot blobs can't produce a control block.
+	kit.BlobType = blob.TypeAltruistAnchorCommit
+	_, err = kit.CommitToRemoteControlBlock(toRemotePkScript)
+	require.ErrorIs(t, err, blob.ErrNotTaprootChannel)
+}
+
+// TestJusticeKitTaprootWitnessSighash asserts that taproot kits produce
+// witness signatures using SIGHASH_DEFAULT, which are exactly 64 bytes with no
+// trailing sighash flag, while other kits append an explicit SIGHASH_ALL.
+func TestJusticeKitTaprootWitnessSighash(t *testing.T) {
+	privKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	var digest [32]byte
+	schnorrSig, err := schnorr.Sign(privKey, digest[:])
+	require.NoError(t, err)
+	taprootSig, err := lnwire.NewSigFromSignature(schnorrSig)
+	require.NoError(t, err)
+
+	ecdsaSig, err := lnwire.NewSigFromSignature(
+		ecdsa.Sign(privKey, digest[:]),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		blobType blob.Type
+		sig      lnwire.Sig
+	}{
+		{
+			name:     "taproot",
+			blobType: blob.TypeAltruistTaprootCommit,
+			sig:      taprootSig,
+		},
+		{
+			name:     "anchor",
+			blobType: blob.TypeAltruistAnchorCommit,
+			sig:      ecdsaSig,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kit := &blob.JusticeKit{
+				BlobType:         test.blobType,
+				SweepAddress:     makeAddr(22),
+				RevocationPubKey: toBlobPubKey(privKey.PubKey()),
+				LocalDelayPubKey: toBlobPubKey(privKey.PubKey()),
+				CSVDelay:         144,
+				CommitToLocalSig: test.sig,
+				CommitToRemotePubKey: toBlobPubKey(
+					privKey.PubKey(),
+				),
+				CommitToRemoteSig: test.sig,
+			}
+
+			// Round trip the kit through encryption, so that the
+			// signatures are decoded according to the blob type.
+			var key blob.BreachKey
+			_, err := rand.Read(key[:])
+			require.NoError(t, err)
+
+			ctxt, err := kit.Encrypt(key)
+			require.NoError(t, err)
+
+			kit, err = blob.Decrypt(key, ctxt, test.blobType)
+			require.NoError(t, err)
+
+			toLocalStack, err :=
+				kit.CommitToLocalRevokeWitnessStack()
+			require.NoError(t, err)
+
+			toRemoteStack, err := kit.CommitToRemoteWitnessStack()
+			require.NoError(t, err)
+
+			for _, witnessSig := range [][]byte{
+				toLocalStack[0], toRemoteStack[0],
+			} {
+				if test.blobType.IsTaprootChannel() {
+					require.Len(t, witnessSig, 64)
+					require.Equal(
+						t, schnorrSig.Serialize(),
+						witnessSig,
+					)
+
+					continue
+				}
+
+				sigHashFlag := witnessSig[len(witnessSig)-1]
+				require.Equal(
+					t, byte(txscript.SigHashAll),
+					sigHashFlag,
+				)
+			}
+		})
+	}
+}
+
+// TestJusticeKitTaprootKeySpend asserts that the blob type of a taproot kit
+// selects whether its to-local output is revoked via the key path or via the
+// revocation leaf, and that the resulting witness spends the breached output.
+func TestJusticeKitTaprootKeySpend(t *testing.T) {
+	const (
+		csvDelay = 144
+		value    = 100_000
+	)
+
+	revPrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	keySpendType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagTaprootChannel,
+		blob.FlagTaprootKeySpend,
+	)
+
+	newKit := func(blobType blob.Type) (*blob.JusticeKit, error) {
+		return blob.NewJusticeKitFromScripts(
+			blobType, blob.JusticeKitParams{
+				SweepAddress:     makeSweepAddr(),
+				RevocationPubKey: revPrivKey.PubKey(),
+				LocalDelayPubKey: delayPrivKey.PubKey(),
+				CSVDelay:         csvDelay,
+			},
+		)
+	}
+
+	// The key path can only be used for taproot channels.
+	_, err = newKit(blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagTaprootKeySpend,
+	))
+	require.ErrorIs(t, err, blob.ErrNotTaprootChannel)
+
+	// Compute the output keys independently of the kit. The script path
+	// output commits to both leaves under the NUMS key, while the key path
+	// output commits to the delayed leaf under the revocation key.
+	toLocalTree, err := input.NewLocalCommitScriptTree(
+		csvDelay, delayPrivKey.PubKey(), revPrivKey.PubKey(),
+	)
+	require.NoError(t, err)
+	scriptPathPkScript, err := input.PayToTaprootScript(
+		toLocalTree.TaprootKey,
+	)
+	require.NoError(t, err)
+
+	delayTree := txscript.AssembleTaprootScriptTree(toLocalTree.SettleLeaf)
+	delayRoot := delayTree.RootNode.TapHash()
+	keyPathPkScript, err := input.PayToTaprootScript(
+		txscript.ComputeTaprootOutputKey(
+			revPrivKey.PubKey(), delayRoot[:],
+		),
+	)
+	require.NoError(t, err)
+
+	// newJusticeTx returns a transaction spending the given output, along
+	// with the sighashes needed to sign and verify it.
+	newJusticeTx := func(pkScript []byte) (*wire.MsgTx,
+		txscript.PrevOutputFetcher, *txscript.TxSigHashes) {
+
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{Index: 1},
+		})
+		tx.AddTxOut(&wire.TxOut{
+			Value:    value - 1_000,
+			PkScript: makeAddr(22),
+		})
+
+		fetcher := txscript.NewCannedPrevOutputFetcher(pkScript, value)
+
+		return tx, fetcher, txscript.NewTxSigHashes(tx, fetcher)
+	}
+
+	// spend round trips the kit through encryption, such that the
+	// signature is decoded according to the blob type, and asserts that
+	// the resulting witness spends the output.
+	spend := func(kit *blob.JusticeKit, pkScript []byte, tx *wire.MsgTx,
+		fetcher txscript.PrevOutputFetcher,
+		hashes *txscript.TxSigHashes) *blob.ToLocalOutputSpendInfo {
+
+		var key blob.BreachKey
+		_, err := rand.Read(key[:])
+		require.NoError(t, err)
+
+		ctxt, err := kit.Encrypt(key)
+		require.NoError(t, err)
+		kit, err = blob.Decrypt(key, ctxt, kit.BlobType)
+		require.NoError(t, err)
+
+		spendInfo, err := kit.ToLocalOutputSpendInfo()
+		require.NoError(t, err)
+
+		tx.TxIn[0].Witness = spendInfo.Witness()
+		vm, err := txscript.NewEngine(
+			pkScript, tx, 0, txscript.StandardVerifyFlags, nil,
+			hashes, value, fetcher,
+		)
+		require.NoError(t, err)
+		require.NoError(t, vm.Execute())
+
+		return spendInfo
+	}
+
+	t.Run("key path", func(t *testing.T) {
+		kit, err := newKit(keySpendType)
+		require.NoError(t, err)
+
+		tapscriptRoot, err := kit.ToLocalKeySpendTapscriptRoot()
+		require.NoError(t, err)
+		require.Equal(t, delayRoot[:], tapscriptRoot)
+
+		require.NoError(t, kit.VerifyAgainstOutputs(
+			keyPathPkScript, nil,
+		))
+		err = kit.VerifyAgainstOutputs(scriptPathPkScript, nil)
+		require.ErrorIs(t, err, blob.ErrOutputScriptMismatch)
+
+		tx, fetcher, hashes := newJusticeTx(keyPathPkScript)
+		rawSig, err := txscript.RawTxInTaprootSignature(
+			tx, hashes, 0, value, keyPathPkScript, tapscriptRoot,
+			txscript.SigHashDefault, revPrivKey,
+		)
+		require.NoError(t, err)
+		sig, err := lnwire.NewSigFromSchnorrRawSignature(rawSig)
+		require.NoError(t, err)
+		require.NoError(t, kit.AddToLocalSig(sig))
+
+		spendInfo := spend(kit, keyPathPkScript, tx, fetcher, hashes)
+		require.Nil(t, spendInfo.WitnessScript)
+		require.Nil(t, spendInfo.ControlBlock)
+		require.Len(t, spendInfo.WitnessStack, 1)
+		require.Equal(t, rawSig, spendInfo.WitnessStack[0])
+		require.Equal(
+			t, input.TaprootKeyPathWitnessSize,
+			spendInfo.WitnessSize,
+		)
+	})
+
+	t.Run("script path", func(t *testing.T) {
+		kit, err := newKit(blob.TypeAltruistTaprootCommit)
+		require.NoError(t, err)
+
+		_, err = kit.ToLocalKeySpendTapscriptRoot()
+		require.ErrorIs(t, err, blob.ErrNotTaprootKeySpend)
+
+		require.NoError(t, kit.VerifyAgainstOutputs(
+			scriptPathPkScript, nil,
+		))
+
+		tx, fetcher, hashes := newJusticeTx(scriptPathPkScript)
+		rawSig, err := txscript.RawTxInTapscriptSignature(
+			tx, hashes, 0, value, scriptPathPkScript,
+			toLocalTree.RevocationLeaf, txscript.SigHashDefault,
+			revPrivKey,
+		)
+		require.NoError(t, err)
+		sig, err := lnwire.NewSigFromSchnorrRawSignature(rawSig)
+		require.NoError(t, err)
+		require.NoError(t, kit.AddToLocalSig(sig))
+
+		spendInfo := spend(kit, scriptPathPkScript, tx, fetcher, hashes)
+		require.Equal(
+			t, toLocalTree.RevocationLeaf.Script,
+			spendInfo.WitnessScript,
+		)
+		require.NotEmpty(t, spendInfo.ControlBlock)
+		require.Len(t, spendInfo.WitnessStack, 1)
+		require.Equal(
+			t, input.TaprootToLocalRevokeWitnessSize,
+			spendInfo.WitnessSize,
+		)
+	})
+}
+
+// TestAllTypesConstantSize asserts that, for every known blob type, the
+// ciphertext has the same size regardless of the sweep address length and the
+// presence of a to-remote output. This prevents blobs from being fingerprinted
+// by their size.
+func TestAllTypesConstantSize(t *testing.T) {
+	blobTypes := append(
+		blob.SupportedTypes(),
+		blob.TypeFromFlags(
+			blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+			blob.FlagSecondLevelHtlcs,
+		),
+		dataCommitmentType, tlvTrailerType, leaseType,
+	)
+
+	for _, blobType := range blobTypes {
+		for _, sweepAddrSize := range []int{0, blob.MaxSweepAddrSize} {
+			for _, hasToRemote := range []bool{false, true} {
+				if !hasToRemote &&
+					blobType.Has(blob.FlagToRemoteOnly) {
+
+					continue
+				}
+
+				kit := &blob.JusticeKit{
+					BlobType:         blobType,
+					SweepAddress:     makeAddr(sweepAddrSize),
+					RevocationPubKey: makePubKey(0),
+					LocalDelayPubKey: makePubKey(1),
+					CSVDelay:         144,
+					CommitToLocalSig: makeSig(1),
+				}
+				if hasToRemote {
+					kit.CommitToRemotePubKey = makePubKey(2)
+					kit.CommitToRemoteSig = makeSig(2)
+				}
+
+				var key blob.BreachKey
+				_, err := rand.Read(key[:])
+				require.NoError(t, err)
+
+				ctxt, err := kit.Encrypt(key)
+				require.NoError(t, err)
+				require.Lenf(t, ctxt, blob.Size(blobType),
+					"type=%v sweep_addr_size=%d "+
+						"to_remote=%v", blobType,
+					sweepAddrSize, hasToRemote)
+			}
+		}
+	}
+}
+
+// TestCiphertextOverhead asserts that the exported overhead plus a type's
+// plaintext size equals the length of the ciphertext produced by the encoder.
+func TestCiphertextOverhead(t *testing.T) {
+	require.Equal(t, blob.NonceSize+blob.MACSize, blob.Overhead)
+
+	blobTypes := append(
+		blob.SupportedTypes(),
+		blob.TypeFromFlags(
+			blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+			blob.FlagSecondLevelHtlcs,
+		),
+		dataCommitmentType,
+	)
+
+	for _, blobType := range blobTypes {
+		kit := &blob.JusticeKit{
+			BlobType:         blobType,
+			SweepAddress:     makeAddr(22),
+			RevocationPubKey: makePubKey(0),
+			LocalDelayPubKey: makePubKey(1),
+			CSVDelay:         144,
+			CommitToLocalSig: makeSig(1),
+		}
+
+		// Types sweeping only the to-remote output require one to be
+		// present.
+		if blobType.Has(blob.FlagToRemoteOnly) {
+			kit.CommitToRemotePubKey = makePubKey(2)
+			kit.CommitToRemoteSig = makeSig(2)
+		}
+
+		var key blob.BreachKey
+		_, err := rand.Read(key[:])
+		require.NoError(t, err)
+
+		ctxt, err := kit.Encrypt(key)
+		require.NoError(t, err)
+
+		expSize := blob.PlaintextSize(blobType) + blob.Overhead
+		require.Lenf(t, ctxt, expSize, "type=%v", blobType)
+		require.Equal(t, expSize, blob.CiphertextSize(blobType))
+	}
+}
+
+// TestStorageFootprint asserts that the storage footprint of a blob is its
+// ciphertext size plus the supplied index overhead across types, regardless
+// of whether it has a commit to-remote output.
+func TestStorageFootprint(t *testing.T) {
+	blobTypes := append(
+		blob.SupportedTypes(), dataCommitmentType,
+	)
+
+	for _, blobType := range blobTypes {
+		size := blob.CiphertextSize(blobType)
+
+		for _, overhead := range []int{0, 8, 73} {
+			for _, hasToRemote := range []bool{false, true} {
+				footprint, err := blob.StorageFootprint(
+					blobType, hasToRemote, overhead,
+				)
+				require.NoError(t, err)
+				require.Equalf(t, size+overhead, footprint,
+					"type=%v", blobType)
+			}
+		}
+	}
+
+	_, err := blob.StorageFootprint(blob.Type(0), true, 8)
+	require.ErrorIs(t, err, blob.ErrUnknownBlobType)
+
+	_, err = blob.StorageFootprint(blob.TypeAltruistCommit, true, -1)
+	require.ErrorIs(t, err, blob.ErrNegativeIndexOverhead)
+}
+
+// TestJusticeKitAddSigs asserts that signatures can only be added to a
+// JusticeKit once, unless explicitly replaced.
+func TestJusticeKitAddSigs(t *testing.T) {
+	kit := &blob.JusticeKit{}
+
+	// Adding signatures to a fresh kit should succeed.
+	require.NoError(t, kit.AddToLocalSig(makeSig(1)))
+	require.NoError(t, kit.AddToRemoteSig(makeSig(2)))
+	require.Equal(t, makeSig(1), kit.CommitToLocalSig)
+	require.Equal(t, makeSig(2), kit.CommitToRemoteSig)
+
+	// Adding them a second time should fail, leaving the original
+	// signatures in place.
+	err := kit.AddToLocalSig(makeSig(3))
+	require.ErrorIs(t, err, blob.ErrSigAlreadySet)
+	require.Equal(t, makeSig(1), kit.CommitToLocalSig)
+
+	err = kit.AddToRemoteSig(makeSig(4))
+	require.ErrorIs(t, err, blob.ErrSigAlreadySet)
+	require.Equal(t, makeSig(2), kit.CommitToRemoteSig)
+
+	// Explicitly replacing the signatures should overwrite them.
+	kit.ReplaceToLocalSig(makeSig(3))
+	kit.ReplaceToRemoteSig(makeSig(4))
+	require.Equal(t, makeSig(3), kit.CommitToLocalSig)
+	require.Equal(t, makeSig(4), kit.CommitToRemoteSig)
+}
+
+// TestJusticeKitEncryptMissingSigs asserts that a kit cannot be encrypted
+// while missing any of the signatures required to sweep its outputs.
+func TestJusticeKitEncryptMissingSigs(t *testing.T) {
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	newKit := func(hasToRemote bool) *blob.JusticeKit {
+		kit := &blob.JusticeKit{
+			BlobType:         blob.TypeAltruistCommit,
+			SweepAddress:     makeAddr(22),
+			RevocationPubKey: makePubKey(1),
+			LocalDelayPubKey: makePubKey(2),
+			CSVDelay:         144,
+		}
+		if hasToRemote {
+			kit.CommitToRemotePubKey = makePubKey(3)
+		}
+
+		return kit
+	}
+
+	// A kit without its to-local signature should be rejected.
+	kit := newKit(false)
+	_, err = kit.Encrypt(key)
+	require.ErrorIs(t, err, blob.ErrMissingSignature)
+	require.ErrorContains(t, err, "commit to-local")
+
+	require.NoError(t, kit.AddToLocalSig(makeSig(1)))
+	_, err = kit.Encrypt(key)
+	require.NoError(t, err)
+
+	// A kit with a to-remote output should also require its signature.
+	kit = newKit(true)
+	require.NoError(t, kit.AddToLocalSig(makeSig(1)))
+
+	_, err = kit.Encrypt(key)
+	require.ErrorIs(t, err, blob.ErrMissingSignature)
+	require.ErrorContains(t, err, "commit to-remote")
+
+	require.NoError(t, kit.AddToRemoteSig(makeSig(2)))
+	_, err = kit.Encrypt(key)
+	require.NoError(t, err)
+
+	// A kit sweeping only a signed to-remote output, as produced when the
+	// to-local output is dust, may omit the to-local signature.
+	kit = newKit(true)
+	require.NoError(t, kit.AddToRemoteSig(makeSig(2)))
+
+	_, err = kit.Encrypt(key)
+	require.NoError(t, err)
+}
+
+// TestJusticeKitSecondLevelHtlcSpend asserts that the witness returned for a
+// second-level HTLC output carried by a JusticeKit spends the revocation path
+// of the output, and that the signatures survive encryption.
+func TestJusticeKitSecondLevelHtlcSpend(t *testing.T) {
+	const (
+		csvDelay = 144
+		htlcAmt  = 20000
+	)
+
+	revPrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	// Construct the CSV-delayed output of a second-level HTLC transaction.
+	htlcScript, err := input.SecondLevelHtlcScript(
+		revPrivKey.PubKey(), delayPrivKey.PubKey(), csvDelay,
+	)
+	require.NoError(t, err)
+	htlcPkScript, err := input.WitnessScriptHash(htlcScript)
+	require.NoError(t, err)
+
+	// Build a justice transaction sweeping the second-level output.
+	justiceTx := wire.NewMsgTx(2)
+	justiceTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{0x02}},
+	})
+	justiceTx.AddTxOut(wire.NewTxOut(htlcAmt-1000, makeAddr(22)))
+
+	prevOutFetcher := txscript.NewCannedPrevOutputFetcher(
+		htlcPkScript, htlcAmt,
+	)
+	hashCache := txscript.NewTxSigHashes(justiceTx, prevOutFetcher)
+
+	rawSig, err := txscript.RawTxInWitnessSignature(
+		justiceTx, hashCache, 0, htlcAmt, htlcScript,
+		txscript.SigHashAll, revPrivKey,
+	)
+	require.NoError(t, err)
+
+	htlcSig, err := lnwire.NewSigFromECDSARawSignature(
+		rawSig[:len(rawSig)-1],
+	)
+	require.NoError(t, err)
+
+	blobType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagSecondLevelHtlcs,
+	)
+	kit := &blob.JusticeKit{
+		BlobType:         blobType,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: toBlobPubKey(revPrivKey.PubKey()),
+		LocalDelayPubKey: toBlobPubKey(delayPrivKey.PubKey()),
+		CSVDelay:         csvDelay,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	// Fill the kit with dummy signatures, placing the real one last.
+	for i := 0; i < blob.MaxSecondLevelHtlcs-1; i++ {
+		require.NoError(t, kit.AddSecondLevelHtlcSig(makeSig(i+2)))
+	}
+	require.NoError(t, kit.AddSecondLevelHtlcSig(htlcSig))
+
+	// No more signatures can be added without breaking the constant size
+	// of the encoding.
+	err = kit.AddSecondLevelHtlcSig(htlcSig)
+	require.ErrorIs(t, err, blob.ErrTooManyHTLCs)
+
+	// Round trip the kit through encryption.
+	var key blob.BreachKey
+	_, err = rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+	require.Len(t, ctxt, blob.Size(blobType))
+
+	kit2, err := blob.Decrypt(key, ctxt, blobType)
+	require.NoError(t, err)
+	require.Equal(t, kit, kit2)
+
+	// The spend info for the last second-level HTLC should satisfy its
+	// output.
+	witnessScript, witnessStack, err := kit2.SecondLevelHtlcSpendInfo(
+		blob.MaxSecondLevelHtlcs - 1,
+	)
+	require.NoError(t, err)
+	require.Equal(t, htlcScript, witnessScript)
+
+	justiceTx.TxIn[0].Witness = append(witnessStack, witnessScript)
+
+	vm, err := txscript.NewEngine(
+		htlcPkScript, justiceTx, 0, txscript.StandardVerifyFlags,
+		nil, hashCache, htlcAmt, prevOutFetcher,
+	)
+	require.NoError(t, err)
+	require.NoError(t, vm.Execute())
+
+	// Requesting a second-level HTLC that isn't present should fail.
+	_, _, err = kit2.SecondLevelHtlcSpendInfo(blob.MaxSecondLevelHtlcs)
+	require.ErrorIs(t, err, blob.ErrUnknownSecondLevelHtlc)
+
+	// Finally, blob types without the flag can't carry second-level HTLC
+	// signatures.
+	legacyKit := &blob.JusticeKit{BlobType: blob.TypeAltruistAnchorCommit}
+	err = legacyKit.AddSecondLevelHtlcSig(htlcSig)
+	require.ErrorIs(t, err, blob.ErrSecondLevelHtlcsUnsupported)
+}
+
+// TestTaprootSecondLevelHtlcsRejected asserts that FlagSecondLevelHtlcs can't
+// be combined with FlagTaprootChannel, as the kit can only build the p2wsh
+// witness of a non-taproot second-level HTLC output.
+func TestTaprootSecondLevelHtlcsRejected(t *testing.T) {
+	blobType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagTaprootChannel,
+		blob.FlagSecondLevelHtlcs,
+	)
+
+	revPrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPrivKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	// The combination should be rejected when creating the kit.
+	_, err = blob.NewJusticeKitFromScripts(blobType, blob.JusticeKitParams{
+		SweepAddress:     makeSweepAddr(),
+		RevocationPubKey: revPrivKey.PubKey(),
+		LocalDelayPubKey: delayPrivKey.PubKey(),
+		CSVDelay:         144,
+	})
+	require.ErrorIs(t, err, blob.ErrTaprootSecondLevelHtlcs)
+
+	// A kit assembled by hand can neither accept second-level HTLC
+	// signatures, nor produce a witness for one it already carries.
+	kit := &blob.JusticeKit{BlobType: blobType}
+	err = kit.AddSecondLevelHtlcSig(makeSig(1))
+	require.ErrorIs(t, err, blob.ErrTaprootSecondLevelHtlcs)
+
+	kit.SecondLevelHtlcSigs = []lnwire.Sig{makeSig(1)}
+	_, _, err = kit.SecondLevelHtlcSpendInfo(0)
+	require.ErrorIs(t, err, blob.ErrTaprootSecondLevelHtlcs)
+}
+
+// TestSecondLevelHtlcSigsOrder asserts that the second-level HTLC signatures
+// of a kit are encoded in the order they were added, whatever that order is,
+// such that the plaintext is byte-stable across encodings and the decoded
+// signatures remain paired with the same breached outputs.
+func TestSecondLevelHtlcSigsOrder(t *testing.T) {
+	blobType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagSecondLevelHtlcs,
+	)
+
+	sweepAddr := makeAddr(22)
+	newKit := func(sigIdxs ...int) *blob.JusticeKit {
+		kit := &blob.JusticeKit{
+			BlobType:         blobType,
+			SweepAddress:     sweepAddr,
+			RevocationPubKey: makePubKey(0),
+			LocalDelayPubKey: makePubKey(1),
+			CSVDelay:         144,
+			CommitToLocalSig: makeSig(1),
+		}
+		for _, i := range sigIdxs {
+			err := kit.AddSecondLevelHtlcSig(makeSig(i))
+			require.NoError(t, err)
+		}
+
+		return kit
+	}
+
+	// Add the signatures in an arbitrary order.
+	order := []int{7, 2, 9, 4, 3}
+	kit := newKit(order...)
+
+	ptxt, err := kit.SerializePadded()
+	require.NoError(t, err)
+
+	// The signatures follow the v0 fields and the signature count, in the
+	// order they were added.
+	const sigsOffset = blob.V0PlaintextSize + 1
+	require.EqualValues(t, len(order), ptxt[blob.V0PlaintextSize])
+	for i, idx := range order {
+		sig := makeSig(idx)
+		require.Equal(
+			t, sig.RawBytes(), ptxt[sigsOffset+i*64:][:64],
+			"sig %d", i,
+		)
+	}
+
+	// Encoding a kit with the same signatures yields the same plaintext,
+	// while a different order yields a different one.
+	ptxt2, err := newKit(order...).SerializePadded()
+	require.NoError(t, err)
+	require.Equal(t, ptxt, ptxt2)
+
+	reordered, err := newKit(2, 3, 4, 7, 9).SerializePadded()
+	require.NoError(t, err)
+	require.NotEqual(t, ptxt, reordered)
+
+	// The order survives a round trip through encryption.
+	var key blob.BreachKey
+	_, err = rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	decrypted, err := blob.Decrypt(key, ctxt, blobType)
+	require.NoError(t, err)
+	require.Equal(t, kit.SecondLevelHtlcSigs, decrypted.SecondLevelHtlcSigs)
+}
+
+// TestDecryptTooManyHTLCs asserts that Decrypt rejects a blob whose HTLC count
+// exceeds MaxNumHTLCs for its type, rather than trusting the count.
+func TestDecryptTooManyHTLCs(t *testing.T) {
+	blobType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagSecondLevelHtlcs,
+	)
+	require.Equal(t, blob.MaxSecondLevelHtlcs, blob.MaxNumHTLCs(blobType))
+	require.Zero(t, blob.MaxNumHTLCs(blob.TypeAltruistAnchorCommit))
+
+	kit := &blob.JusticeKit{
+		BlobType:         blobType,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	// Open the ciphertext, overwrite the HTLC count that directly follows
+	// the v0 fields with the largest possible value, and seal it again
+	// under the same nonce.
+	cipher, err := chacha20poly1305.NewX(key[:])
+	require.NoError(t, err)
+
+	nonce := ctxt[:blob.NonceSize]
+	ptxt, err := cipher.Open(nil, nonce, ctxt[blob.NonceSize:], nil)
+	require.NoError(t, err)
+
+	ptxt[blob.V0PlaintextSize] = 0xff
+	ctxt = cipher.Seal(append([]byte{}, nonce...), nonce, ptxt, nil)
+
+	_, err = blob.Decrypt(key, ctxt, blobType)
+	require.ErrorIs(t, err, blob.ErrTooManyHTLCs)
+}
+
+// TestDecryptNonZeroPadding asserts that Decrypt rejects a blob with a
+// non-zero byte in any of its padding regions with ErrNonZeroPadding, even if
+// the tampered plaintext was sealed under the right key, while a blob with all
+// padding zeroed decodes.
+func TestDecryptNonZeroPadding(t *testing.T) {
+	blobType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagSecondLevelHtlcs, blob.FlagDataCommitment,
+		blob.FlagTLVTrailer,
+	)
+
+	kit := &blob.JusticeKit{
+		BlobType:            blobType,
+		SweepAddress:        makeAddr(22),
+		RevocationPubKey:    makePubKey(0),
+		LocalDelayPubKey:    makePubKey(1),
+		CSVDelay:            144,
+		CommitToLocalSig:    makeSig(1),
+		SecondLevelHtlcSigs: []lnwire.Sig{makeSig(2)},
+		TrailerRecords:      map[uint64][]byte{65: {0x01}},
+	}
+	require.NoError(t, kit.SetDataCommitment([]byte("tag")))
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	cipher, err := chacha20poly1305.NewX(key[:])
+	require.NoError(t, err)
+
+	nonce := ctxt[:blob.NonceSize]
+	ptxt, err := cipher.Open(nil, nonce, ctxt[blob.NonceSize:], nil)
+	require.NoError(t, err)
+
+	const (
+		htlcsOffset = blob.V0PlaintextSize
+		dataOffset  = htlcsOffset + blob.SecondLevelHtlcsSize
+		tlvOffset   = dataOffset + blob.DataCommitmentSize
+	)
+
+	tests := []struct {
+		name   string
+		offset int
+	}{
+		{
+			name:   "sweep address",
+			offset: 1 + 22,
+		},
+		{
+			name:   "sweep address end",
+			offset: blob.MaxSweepAddrSize,
+		},
+		{
+			name:   "second-level htlc sigs",
+			offset: htlcsOffset + 1 + 64,
+		},
+		{
+			name:   "second-level htlc sigs end",
+			offset: dataOffset - 1,
+		},
+		{
+			name:   "data commitment",
+			offset: dataOffset + 1 + len("tag"),
+		},
+		{
+			name:   "tlv trailer end",
+			offset: len(ptxt) - 1,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Flip a single padding byte of the plaintext, and seal
+			// it again under the same nonce.
+			tampered := append([]byte{}, ptxt...)
+			require.Zero(t, tampered[test.offset])
+			tampered[test.offset] ^= 0x01
+
+			tamperedCtxt := cipher.Seal(
+				append([]byte{}, nonce...), nonce, tampered,
+				nil,
+			)
+
+			_, err := blob.Decrypt(key, tamperedCtxt, blobType)
+			require.ErrorIs(t, err, blob.ErrNonZeroPadding)
+		})
+	}
+
+	// The untampered blob should still decode.
+	_, err = blob.Decrypt(key, ctxt, blobType)
+	require.NoError(t, err)
+}
+
+// TestDecryptMalformedLengths asserts that a blob whose plaintext carries a
+// length prefix inconsistent with the bytes available for its field is
+// rejected with ErrMalformedBlob, along with the more specific cause, rather
+// than causing an out of range slice or a large allocation.
+func TestDecryptMalformedLengths(t *testing.T) {
+	blobType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagSecondLevelHtlcs, blob.FlagDataCommitment,
+		blob.FlagTLVTrailer,
+	)
+
+	kit := &blob.JusticeKit{
+		BlobType:         blobType,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	cipher, err := chacha20poly1305.NewX(key[:])
+	require.NoError(t, err)
+
+	nonce := ctxt[:blob.NonceSize]
+	ptxt, err := cipher.Open(nil, nonce, ctxt[blob.NonceSize:], nil)
+	require.NoError(t, err)
+
+	const (
+		htlcsOffset = blob.V0PlaintextSize
+		dataOffset  = htlcsOffset + blob.SecondLevelHtlcsSize
+		tlvOffset   = dataOffset + blob.DataCommitmentSize
+	)
+
+	// tlvStream returns a tampering function replacing the TLV trailer
+	// with the given stream.
+	tlvStream := func(stream ...byte) func([]byte) {
+		return func(ptxt []byte) {
+			binary.BigEndian.PutUint16(
+				ptxt[tlvOffset:], uint16(len(stream)),
+			)
+			copy(ptxt[tlvOffset+2:], stream)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		tamper func([]byte)
+		cause  error
+	}{
+		{
+			name: "sweep address length",
+			tamper: func(ptxt []byte) {
+				ptxt[0] = blob.MaxSweepAddrSize + 1
+			},
+			cause: blob.ErrSweepAddressToLong,
+		},
+		{
+			name: "max sweep address length",
+			tamper: func(ptxt []byte) {
+				ptxt[0] = 0xff
+			},
+			cause: blob.ErrSweepAddressToLong,
+		},
+		{
+			name: "htlc count",
+			tamper: func(ptxt []byte) {
+				ptxt[htlcsOffset] = blob.MaxSecondLevelHtlcs + 1
+			},
+			cause: blob.ErrTooManyHTLCs,
+		},
+		{
+			name: "max htlc count",
+			tamper: func(ptxt []byte) {
+				ptxt[htlcsOffset] = 0xff
+			},
+			cause: blob.ErrTooManyHTLCs,
+		},
+		{
+			name: "data commitment length",
+			tamper: func(ptxt []byte) {
+				const tooLong = blob.MaxDataCommitmentSize + 1
+				ptxt[dataOffset] = tooLong
+			},
+			cause: blob.ErrDataCommitmentTooLong,
+		},
+		{
+			name: "tlv stream length",
+			tamper: func(ptxt []byte) {
+				binary.BigEndian.PutUint16(
+					ptxt[tlvOffset:],
+					blob.MaxTLVTrailerSize+1,
+				)
+			},
+			cause: blob.ErrTLVTrailerTooLong,
+		},
+		{
+			name: "max tlv stream length",
+			tamper: func(ptxt []byte) {
+				binary.BigEndian.PutUint16(
+					ptxt[tlvOffset:], 0xffff,
+				)
+			},
+			cause: blob.ErrTLVTrailerTooLong,
+		},
+		{
+			name: "tlv record length",
+			tamper: tlvStream(
+				0x41, 0x05, 0x01,
+			),
+		},
+		{
+			name: "huge tlv record length",
+			tamper: tlvStream(
+				0x41, 0xff, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff,
+				0xff, 0xff,
+			),
+		},
+		{
+			name:   "truncated tlv record length",
+			tamper: tlvStream(0x41, 0xfd, 0x01),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Tamper with the plaintext, and seal it again under
+			// the same nonce, such that it still authenticates.
+			tampered := append([]byte{}, ptxt...)
+			test.tamper(tampered)
+
+			tamperedCtxt := cipher.Seal(
+				append([]byte{}, nonce...), nonce, tampered,
+				nil,
+			)
+
+			_, err := blob.Decrypt(key, tamperedCtxt, blobType)
+			require.ErrorIs(t, err, blob.ErrMalformedBlob)
+			if test.cause != nil {
+				require.ErrorIs(t, err, test.cause)
+			}
+		})
+	}
+
+	// A well-formed record of an unknown odd type should still decode.
+	wellFormed := append([]byte{}, ptxt...)
+	tlvStream(0x41, 0x01, 0x01)(wellFormed)
+	wellFormedCtxt := cipher.Seal(
+		append([]byte{}, nonce...), nonce, wellFormed, nil,
+	)
+
+	kit2, err := blob.Decrypt(key, wellFormedCtxt, blobType)
+	require.NoError(t, err)
+	require.Equal(t, map[uint64][]byte{65: {0x01}}, kit2.TrailerRecords)
+}
+
+// TestDecodeFailureLogged asserts that a blob failing to decode emits a log
+// line naming the field that could not be decoded.
+func TestDecodeFailureLogged(t *testing.T) {
+	var logBuf bytes.Buffer
+	logger := btclog.NewBackend(&logBuf).Logger("TEST")
+	logger.SetLevel(btclog.LevelTrace)
+
+	blob.UseLogger(logger)
+	t.Cleanup(blob.DisableLog)
+
+	kit := &blob.JusticeKit{
+		BlobType:         blob.TypeAltruistCommit,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+	require.Contains(t, logBuf.String(), "Encrypted")
+
+	// Reseal the plaintext with an oversized sweep address length, which
+	// can't be produced by Encrypt.
+	cipher, err := chacha20poly1305.NewX(key[:])
+	require.NoError(t, err)
+
+	nonce := ctxt[:blob.NonceSize]
+	ptxt, err := cipher.Open(nil, nonce, ctxt[blob.NonceSize:], nil)
+	require.NoError(t, err)
+
+	ptxt[0] = blob.MaxSweepAddrSize + 1
+	ctxt = cipher.Seal(append([]byte{}, nonce...), nonce, ptxt, nil)
+
+	logBuf.Reset()
+	_, err = blob.Decrypt(key, ctxt, blob.TypeAltruistCommit)
+	require.ErrorIs(t, err, blob.ErrSweepAddressToLong)
+	require.Contains(t, logBuf.String(), "Unable to decode sweep address "+
+		"length")
+}
+
+// TestJusticeKitPlaintextByteOrder pins the offsets and big-endian byte order
+// of the fixed-position fields in the decrypted plaintext of a blob, such that
+// a refactor can't silently change the encoding shared with other versions.
+func TestJusticeKitPlaintextByteOrder(t *testing.T) {
+	const (
+		// csvDelay is chosen such that every byte differs, which
+		// ensures that a flipped byte order is detected.
+		csvDelay = 0x01020304
+
+		sweepAddrOffset       = 0
+		csvDelayOffset        = 109
+		toRemotePubKeyOffset  = 177
+		secondLevelHtlcOffset = 274
+	)
+
+	blobType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagSecondLevelHtlcs,
+	)
+
+	sweepAddr := makeAddr(22)
+	kit := &blob.JusticeKit{
+		BlobType:             blobType,
+		SweepAddress:         sweepAddr,
+		RevocationPubKey:     makePubKey(0),
+		LocalDelayPubKey:     makePubKey(1),
+		CSVDelay:             csvDelay,
+		CommitToLocalSig:     makeSig(1),
+		CommitToRemotePubKey: makePubKey(3),
+		CommitToRemoteSig:    makeSig(2),
+	}
+	require.NoError(t, kit.AddSecondLevelHtlcSig(makeSig(3)))
+	require.NoError(t, kit.AddSecondLevelHtlcSig(makeSig(4)))
+
+	var key blob.BreachKey
+	copy(key[:], bytes.Repeat([]byte{0x42}, blob.KeySize))
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	cipher, err := chacha20poly1305.NewX(key[:])
+	require.NoError(t, err)
+
+	ptxt, err := cipher.Open(
+		nil, ctxt[:blob.NonceSize], ctxt[blob.NonceSize:], nil,
+	)
+	require.NoError(t, err)
+	require.Len(t, ptxt, blob.PlaintextSize(blobType))
+
+	// The sweep address is prefixed with its length as a single byte.
+	require.Equal(t, byte(len(sweepAddr)), ptxt[sweepAddrOffset])
+	require.Equal(
+		t, sweepAddr,
+		ptxt[sweepAddrOffset+1:sweepAddrOffset+1+len(sweepAddr)],
+	)
+
+	// The csv delay is encoded as a big-endian uint32.
+	require.Equal(
+		t, []byte{0x01, 0x02, 0x03, 0x04},
+		ptxt[csvDelayOffset:csvDelayOffset+4],
+	)
+
+	// The presence of the commit to-remote output is signaled by a
+	// compressed pubkey, whose first byte is 0x02 or 0x03, rather than a
+	// blank key.
+	require.Equal(t, byte(0x03), ptxt[toRemotePubKeyOffset])
+
+	// The second-level htlc signatures are prefixed with their count as a
+	// single byte.
+	require.Equal(t, byte(2), ptxt[secondLevelHtlcOffset])
+
+	// Blanking the to-remote pubkey should zero its flag byte.
+	kit.CommitToRemotePubKey = blob.PubKey{}
+	kit.CommitToRemoteSig = lnwire.Sig{}
+
+	ctxt, err = kit.Encrypt(key)
+	require.NoError(t, err)
+
+	ptxt, err = cipher.Open(
+		nil, ctxt[:blob.NonceSize], ctxt[blob.NonceSize:], nil,
+	)
+	require.NoError(t, err)
+	require.Zero(t, ptxt[toRemotePubKeyOffset])
+}
+
+// goldenBlob is a blob of type TypeAltruistCommit encrypted under a key of
+// repeated 0x42 bytes, prior to the encryption being moved into a shared AEAD
+// helper. It ensures blobs remain compatible across that refactor.
+const goldenBlob = "bef2eb988103064a48ab9af3e20c7fde49a98d73550cd5a16527a621a19ed24c6aa47fd2d4ecd27feaaddd96b029bf823c00b017f3e807fe543975a03e7f12848750e8adfa0912a588778ac1fc81aa35c5f76c9bbace46168a2ff319c7df903a01c48b222711be350978895a092431d65b4f0f32f19cb128057f881db48a271c0058c3021b2ee22475d17a92f0818e33d47ab84826f8dcd72f5f6f5408771d417ff8b96f97b78d16f10947156f825267b5102c7801cdd1bc238099337b7b454d9271f97360b1db6b41ce5a6c2b4c87d9f4573a12daee9366b4c46cbbe737637de4c5e60d5be760a8c024d0a8e4a32bce7526a81dd71570aa3bd8b9eda0db309d9af71ee8cf55e614460127f53641260f0f71aa02beceb126c894a007d350da7320a8e6682c5ccfc1a2855eb618d1df11ca9dc8e9cf004df8387d"
+
+// TestDecryptGoldenBlob asserts that a blob encrypted by an earlier version of
+// the package still decrypts to the expected JusticeKit.
+func TestDecryptGoldenBlob(t *testing.T) {
+	expKit := &blob.JusticeKit{
+		BlobType:             blob.TypeAltruistCommit,
+		SweepAddress:         bytes.Repeat([]byte{0x11}, 22),
+		RevocationPubKey:     makePubKey(0),
+		LocalDelayPubKey:     makePubKey(1),
+		CSVDelay:             144,
+		CommitToLocalSig:     makeSig(1),
+		CommitToRemotePubKey: makePubKey(2),
+		CommitToRemoteSig:    makeSig(2),
+	}
+
+	var key blob.BreachKey
+	copy(key[:], bytes.Repeat([]byte{0x42}, blob.KeySize))
+
+	ctxt, err := hex.DecodeString(goldenBlob)
+	require.NoError(t, err)
+
+	kit, err := blob.Decrypt(key, ctxt, blob.TypeAltruistCommit)
+	require.NoError(t, err)
+	require.Equal(t, expKit, kit)
+}
+
+// TestJusticeKitDataCommitment asserts that a data commitment can only be set
+// on blob types that support it, is bounded in length, and is rendered as a
+// zero-value OP_RETURN output.
+func TestJusticeKitDataCommitment(t *testing.T) {
+	kit := &blob.JusticeKit{BlobType: blob.TypeAltruistAnchorCommit}
+
+	err := kit.SetDataCommitment([]byte("tag"))
+	require.ErrorIs(t, err, blob.ErrDataCommitmentUnsupported)
+
+	// Without a data commitment, no output is returned.
+	kit.BlobType = dataCommitmentType
+	txOut, err := kit.DataCommitmentOutput()
+	require.NoError(t, err)
+	require.Nil(t, txOut)
+
+	err = kit.SetDataCommitment(makeAddr(blob.MaxDataCommitmentSize + 1))
+	require.ErrorIs(t, err, blob.ErrDataCommitmentTooLong)
+
+	require.NoError(t, kit.SetDataCommitment([]byte("tag")))
+
+	txOut, err = kit.DataCommitmentOutput()
+	require.NoError(t, err)
+	require.Zero(t, txOut.Value)
+	require.Equal(
+		t, txscript.NullDataTy, txscript.GetScriptClass(txOut.PkScript),
+	)
+
+	pushes, err := txscript.PushedData(txOut.PkScript)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("tag")}, pushes)
+}
+
+// TestEncryptDecryptWithAAD asserts that a kit encrypted with associated data
+// can only be decrypted when presented with the same associated data.
+func TestEncryptDecryptWithAAD(t *testing.T) {
+	kit := &blob.JusticeKit{
+		BlobType:         blob.TypeAltruistAnchorCommit,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	// The associated data is a session ID followed by a sequence number,
+	// which is kept outside the ciphertext.
+	aad := append(bytes.Repeat([]byte{0x01}, 33), 0x00, 0x00, 0x00, 0x05)
+
+	ctxt, err := kit.EncryptWithAAD(key, aad)
+	require.NoError(t, err)
+	require.Len(t, ctxt, blob.Size(kit.BlobType))
+
+	// Matching associated data decrypts to the original kit.
+	kit2, err := blob.DecryptWithAAD(key, ctxt, aad, kit.BlobType)
+	require.NoError(t, err)
+	require.Equal(t, kit, kit2)
+
+	// Mismatched or missing associated data fails authentication.
+	badAAD := append([]byte{}, aad...)
+	badAAD[len(badAAD)-1] ^= 0x01
+
+	_, err = blob.DecryptWithAAD(key, ctxt, badAAD, kit.BlobType)
+	require.Error(t, err)
+
+	_, err = blob.DecryptWithAAD(key, ctxt, nil, kit.BlobType)
+	require.Error(t, err)
+
+	_, err = blob.Decrypt(key, ctxt, kit.BlobType)
+	require.Error(t, err)
+}
+
+// TestJusticeKitSerializePadded asserts that the padded plaintext has the
+// constant size of its type, that all padding is zeroed, and that it matches
+// the plaintext sealed by Encrypt.
+func TestJusticeKitSerializePadded(t *testing.T) {
+	const (
+		sweepAddrLen    = 22
+		toRemoteOffset  = 177
+		htlcOffset      = blob.V0PlaintextSize
+		dataCommitLen   = 3
+		dataCommitStart = htlcOffset + blob.SecondLevelHtlcsSize
+	)
+
+	blobType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagSecondLevelHtlcs, blob.FlagDataCommitment,
+	)
+
+	kit := &blob.JusticeKit{
+		BlobType:         blobType,
+		SweepAddress:     makeAddr(sweepAddrLen),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+	require.NoError(t, kit.AddSecondLevelHtlcSig(makeSig(2)))
+	require.NoError(t, kit.SetDataCommitment(makeAddr(dataCommitLen)))
+
+	ptxt, err := kit.SerializePadded()
+	require.NoError(t, err)
+	require.Len(t, ptxt, blob.PlaintextSize(blobType))
+
+	requireZero := func(name string, b []byte) {
+		require.Equalf(t, make([]byte, len(b)), b, "%s not zeroed", name)
+	}
+
+	// The sweep address is padded to its maximum size.
+	requireZero(
+		"sweep address padding",
+		ptxt[1+sweepAddrLen:1+blob.MaxSweepAddrSize],
+	)
+
+	// The blank commit to-remote pubkey and signature are zeroed.
+	requireZero("commit to-remote", ptxt[toRemoteOffset:htlcOffset])
+
+	// The unused second-level htlc signatures are zeroed.
+	requireZero(
+		"second-level htlc padding",
+		ptxt[htlcOffset+1+64:dataCommitStart],
+	)
+
+	// The data commitment is padded to its maximum size, which ends the
+	// plaintext.
+	requireZero(
+		"data commitment padding",
+		ptxt[dataCommitStart+1+dataCommitLen:],
+	)
+
+	// The padded plaintext is exactly what Encrypt seals.
+	var key blob.BreachKey
+	_, err = rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	cipher, err := chacha20poly1305.NewX(key[:])
+	require.NoError(t, err)
+
+	sealed, err := cipher.Open(
+		nil, ctxt[:blob.NonceSize], ctxt[blob.NonceSize:], nil,
+	)
+	require.NoError(t, err)
+	require.Equal(t, ptxt, sealed)
+}
+
+// TestNewJusticeKitFromScripts asserts that NewJusticeKitFromScripts produces
+// the same kit as NewJusticeKit for the same inputs, and that it rejects
+// invalid parameters.
+func TestNewJusticeKitFromScripts(t *testing.T) {
+	newPubKey := func() *btcec.PublicKey {
+		priv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return priv.PubKey()
+	}
+
+	var (
+		sweepAddr   = makeSweepAddr()
+		revKey      = newPubKey()
+		toLocalKey  = newPubKey()
+		toRemoteKey = newPubKey()
+	)
+
+	breachInfo := &lnwallet.BreachRetribution{
+		RemoteDelay: 144,
+		KeyRing: &lnwallet.CommitmentKeyRing{
+			RevocationKey: revKey,
+			ToLocalKey:    toLocalKey,
+			ToRemoteKey:   toRemoteKey,
+		},
+	}
+
+	for _, hasToRemote := range []bool{false, true} {
+		kit, err := blob.NewJusticeKit(
+			blob.TypeAltruistAnchorCommit, sweepAddr, breachInfo,
+			hasToRemote,
+		)
+		require.NoError(t, err)
+
+		params := blob.JusticeKitParams{
+			SweepAddress:     sweepAddr,
+			RevocationPubKey: revKey,
+			LocalDelayPubKey: toLocalKey,
+			CSVDelay:         144,
+			HasToRemote:      hasToRemote,
+			ToRemotePubKey:   toRemoteKey,
+		}
+		kit2, err := blob.NewJusticeKitFromScripts(
+			blob.TypeAltruistAnchorCommit, params,
+		)
+		require.NoError(t, err)
+		require.Equal(t, kit, kit2)
+		require.Equal(t, hasToRemote, kit2.HasCommitToRemoteOutput())
+	}
+
+	tests := []struct {
+		name   string
+		params blob.JusticeKitParams
+		expErr error
+	}{
+		{
+			name: "sweep addr too long",
+			params: blob.JusticeKitParams{
+				SweepAddress: makeAddr(
+					blob.MaxSweepAddrSize + 1,
+				),
+				RevocationPubKey: revKey,
+				LocalDelayPubKey: toLocalKey,
+			},
+			expErr: blob.ErrSweepAddressToLong,
+		},
+		{
+			name: "missing revocation key",
+			params: blob.JusticeKitParams{
+				SweepAddress:     sweepAddr,
+				LocalDelayPubKey: toLocalKey,
+			},
+			expErr: blob.ErrMissingPubKey,
+		},
+		{
+			name: "missing to-remote key",
+			params: blob.JusticeKitParams{
+				SweepAddress:     sweepAddr,
+				RevocationPubKey: revKey,
+				LocalDelayPubKey: toLocalKey,
+				HasToRemote:      true,
+			},
+			expErr: blob.ErrMissingPubKey,
+		},
+		{
+			name: "degenerate keys",
+			params: blob.JusticeKitParams{
+				SweepAddress:     sweepAddr,
+				RevocationPubKey: revKey,
+				LocalDelayPubKey: revKey,
+			},
+			expErr: blob.ErrDegenerateKeys,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			_, err := blob.NewJusticeKitFromScripts(
+				blob.TypeAltruistCommit, test.params,
+			)
+			require.ErrorIs(t, err, test.expErr)
+		})
+	}
+}
+
+// TestNewJusticeKitUnspendableSweep asserts that a justice kit can't be
+// created with an unspendable sweep address, unless AllowUnspendableSweep is
+// set.
+func TestNewJusticeKitUnspendableSweep(t *testing.T) {
+	revPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	newParams := func(sweepAddr []byte) blob.JusticeKitParams {
+		return blob.JusticeKitParams{
+			SweepAddress:     sweepAddr,
+			RevocationPubKey: revPriv.PubKey(),
+			LocalDelayPubKey: delayPriv.PubKey(),
+			CSVDelay:         144,
+		}
+	}
+
+	// A regular p2wkh sweep address is accepted.
+	sweepAddr := makeSweepAddr()
+	kit, err := blob.NewJusticeKitFromScripts(
+		blob.TypeAltruistCommit, newParams(sweepAddr),
+	)
+	require.NoError(t, err)
+	require.Equal(t, sweepAddr, kit.SweepAddress)
+
+	// An OP_RETURN output would burn the swept funds, and is rejected by
+	// default.
+	burnAddr := []byte{txscript.OP_RETURN, txscript.OP_DATA_1, 0x01}
+	_, err = blob.NewJusticeKitFromScripts(
+		blob.TypeAltruistCommit, newParams(burnAddr),
+	)
+	require.ErrorIs(t, err, blob.ErrUnspendableSweepAddr)
+
+	// It is accepted if the caller explicitly allows it.
+	params := newParams(burnAddr)
+	params.AllowUnspendableSweep = true
+	kit, err = blob.NewJusticeKitFromScripts(
+		blob.TypeAltruistCommit, params,
+	)
+	require.NoError(t, err)
+	require.Equal(t, burnAddr, kit.SweepAddress)
+}
+
+// TestJusticeKitToBreachInfo asserts that the fields of a BreachRetribution
+// carried by a kit survive a round trip through encryption and ToBreachInfo.
+func TestJusticeKitToBreachInfo(t *testing.T) {
+	newPubKey := func() *btcec.PublicKey {
+		priv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return priv.PubKey()
+	}
+
+	breachInfo := &lnwallet.BreachRetribution{
+		BreachHeight: 800_000,
+		RemoteDelay:  144,
+		LocalDelay:   1,
+		KeyRing: &lnwallet.CommitmentKeyRing{
+			CommitPoint:   newPubKey(),
+			RevocationKey: newPubKey(),
+			ToLocalKey:    newPubKey(),
+			ToRemoteKey:   newPubKey(),
+			LocalHtlcKey:  newPubKey(),
+			RemoteHtlcKey: newPubKey(),
+		},
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	for _, hasToRemote := range []bool{false, true} {
+		kit, err := blob.NewJusticeKit(
+			blob.TypeAltruistAnchorCommit, makeSweepAddr(),
+			breachInfo, hasToRemote,
+		)
+		require.NoError(t, err)
+		require.NoError(t, kit.AddToLocalSig(makeSig(1)))
+		if hasToRemote {
+			require.NoError(t, kit.AddToRemoteSig(makeSig(2)))
+		}
+
+		ctxt, err := kit.Encrypt(key)
+		require.NoError(t, err)
+
+		kit2, err := blob.Decrypt(key, ctxt, kit.BlobType)
+		require.NoError(t, err)
+
+		info, err := kit2.ToBreachInfo()
+		require.NoError(t, err)
+
+		// Only the delay and the keys committed to by the kit are
+		// recovered.
+		expKeyRing := &lnwallet.CommitmentKeyRing{
+			RevocationKey: breachInfo.KeyRing.RevocationKey,
+			ToLocalKey:    breachInfo.KeyRing.ToLocalKey,
+		}
+		if hasToRemote {
+			expKeyRing.ToRemoteKey = breachInfo.KeyRing.ToRemoteKey
+		}
+
+		require.Equal(t, &lnwallet.BreachRetribution{
+			RemoteDelay: breachInfo.RemoteDelay,
+			KeyRing:     expKeyRing,
+		}, info)
+	}
+}
+
+// TestSetAEADFactory asserts that the AEAD factory installed via
+// SetAEADFactory is used to encrypt and decrypt blobs, that blobs remain
+// interoperable with the default implementation, and that factories producing
+// an AEAD with an incompatible nonce size are rejected.
+func TestSetAEADFactory(t *testing.T) {
+	t.Cleanup(func() {
+		blob.SetAEADFactory(nil)
+	})
+
+	var calls atomic.Int32
+	blob.SetAEADFactory(func(key []byte) (cipher.AEAD, error) {
+		calls.Add(1)
+		return chacha20poly1305.NewX(key)
+	})
+
+	kit := &blob.JusticeKit{
+		BlobType:         blob.TypeAltruistCommit,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, calls.Load())
+
+	kit2, err := blob.Decrypt(key, ctxt, kit.BlobType)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, calls.Load())
+	require.Equal(t, kit, kit2)
+
+	// The golden blob, produced by the default implementation, must also
+	// decrypt using the installed factory.
+	var goldenKey blob.BreachKey
+	copy(goldenKey[:], bytes.Repeat([]byte{0x42}, blob.KeySize))
+
+	goldenCtxt, err := hex.DecodeString(goldenBlob)
+	require.NoError(t, err)
+
+	_, err = blob.Decrypt(goldenKey, goldenCtxt, blob.TypeAltruistCommit)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, calls.Load())
+
+	// After restoring the default, the blob encrypted using the factory
+	// should still decrypt to the same kit.
+	blob.SetAEADFactory(nil)
+
+	kit2, err = blob.Decrypt(key, ctxt, kit.BlobType)
+	require.NoError(t, err)
+	require.Equal(t, kit, kit2)
+	require.EqualValues(t, 3, calls.Load())
+
+	// An AEAD whose nonce size doesn't match the blob encoding can't be
+	// used.
+	blob.SetAEADFactory(chacha20poly1305.New)
+
+	_, err = kit.Encrypt(key)
+	require.ErrorIs(t, err, blob.ErrIncompatibleAEAD)
+
+	_, err = blob.Decrypt(key, ctxt, kit.BlobType)
+	require.ErrorIs(t, err, blob.ErrIncompatibleAEAD)
+}
+
+// fakeKMS is an AEADProvider standing in for a key management service, which
+// holds the breach key on behalf of the caller. If pad is set, the plaintext
+// is padded by a byte before being sealed, breaking the blob encoding.
+type fakeKMS struct {
+	key   blob.BreachKey
+	pad   bool
+	seals int
+	opens int
+}
+
+// Seal encrypts the plaintext under a random nonce using xchacha20poly1305.
+func (k *fakeKMS) Seal(plaintext, aad []byte) ([]byte, error) {
+	k.seals++
+
+	aead, err := chacha20poly1305.NewX(k.key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	if k.pad {
+		plaintext = append(plaintext, 0x00)
+	}
+
+	nonce := make([]byte, blob.NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// Open decrypts a ciphertext prefixed by its nonce using xchacha20poly1305.
+func (k *fakeKMS) Open(ciphertext, aad []byte) ([]byte, error) {
+	k.opens++
+
+	aead, err := chacha20poly1305.NewX(k.key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := ciphertext[:blob.NonceSize]
+	plaintext, err := aead.Open(
+		nil, nonce, ciphertext[blob.NonceSize:], aad,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if k.pad {
+		plaintext = append(plaintext, 0x00)
+	}
+
+	return plaintext, nil
+}
+
+// TestEncryptDecryptWithProvider asserts that blobs can be encrypted and
+// decrypted by an external AEADProvider, that such blobs are interoperable
+// with those of Encrypt and Decrypt, and that providers breaking the constant
+// size of the blob encoding are rejected.
+func TestEncryptDecryptWithProvider(t *testing.T) {
+	kit := &blob.JusticeKit{
+		BlobType:         blob.TypeAltruistAnchorCommit,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	kms := &fakeKMS{key: key}
+	aad := []byte("session id and sequence number")
+
+	// A kit encrypted by the provider should have the constant size of its
+	// type, and decrypt to the original kit using the provider.
+	ctxt, err := kit.EncryptWithProvider(kms, aad)
+	require.NoError(t, err)
+	require.Len(t, ctxt, blob.Size(kit.BlobType))
+	require.Equal(t, 1, kms.seals)
+
+	kit2, err := blob.DecryptWithProvider(kms, ctxt, aad, kit.BlobType)
+	require.NoError(t, err)
+	require.Equal(t, 1, kms.opens)
+	require.Equal(t, kit, kit2)
+
+	// The associated data is authenticated by the provider.
+	_, err = blob.DecryptWithProvider(kms, ctxt, nil, kit.BlobType)
+	require.Error(t, err)
+
+	// Blobs of the provider and the default path are interchangeable.
+	kit2, err = blob.DecryptWithAAD(key, ctxt, aad, kit.BlobType)
+	require.NoError(t, err)
+	require.Equal(t, kit, kit2)
+
+	ctxt, err = kit.Encrypt(key)
+	require.NoError(t, err)
+	require.Len(t, ctxt, blob.Size(kit.BlobType))
+
+	kit2, err = blob.DecryptWithProvider(kms, ctxt, nil, kit.BlobType)
+	require.NoError(t, err)
+	require.Equal(t, kit, kit2)
+
+	// The default provider should behave like Encrypt and Decrypt.
+	keyProvider := blob.NewKeyProvider(key)
+
+	ctxt, err = kit.EncryptWithProvider(keyProvider, nil)
+	require.NoError(t, err)
+	require.Len(t, ctxt, blob.Size(kit.BlobType))
+
+	kit2, err = blob.Decrypt(key, ctxt, kit.BlobType)
+	require.NoError(t, err)
+	require.Equal(t, kit, kit2)
+
+	// Kits missing signatures are refused before reaching the provider.
+	_, err = (&blob.JusticeKit{
+		BlobType:     kit.BlobType,
+		SweepAddress: kit.SweepAddress,
+	}).EncryptWithProvider(kms, nil)
+	require.ErrorIs(t, err, blob.ErrMissingSignature)
+	require.Equal(t, 1, kms.seals)
+
+	// A provider that changes the size of the blob can't be used, as the
+	// ciphertext would leak the size of the kit.
+	badKMS := &fakeKMS{key: key, pad: true}
+
+	_, err = kit.EncryptWithProvider(badKMS, nil)
+	require.ErrorIs(t, err, blob.ErrIncompatibleAEAD)
+
+	_, err = blob.DecryptWithProvider(badKMS, ctxt, nil, kit.BlobType)
+	require.ErrorIs(t, err, blob.ErrIncompatibleAEAD)
+
+	_, err = blob.DecryptWithProvider(kms, ctxt[:10], nil, kit.BlobType)
+	require.ErrorIs(t, err, blob.ErrCiphertextTooSmall)
+}
+
+// TestValidateBlob asserts that ValidateBlob accepts a well-formed blob, and
+// rejects blobs that can't be decrypted or whose kit fails validation.
+func TestValidateBlob(t *testing.T) {
+	const blobType = blob.TypeAltruistAnchorCommit
+
+	breach := newTestBreach(t, blobType, chainhash.Hash{})
+	require.NoError(t, breach.kit.AddToLocalSig(makeSig(1)))
+	require.NoError(t, breach.kit.AddToRemoteSig(makeSig(2)))
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := breach.kit.Encrypt(key)
+	require.NoError(t, err)
+	require.NoError(t, blob.ValidateBlob(key, ctxt, blobType))
+
+	// Blobs that can't be decrypted under the key are rejected.
+	var wrongKey blob.BreachKey
+	_, err = rand.Read(wrongKey[:])
+	require.NoError(t, err)
+	require.Error(t, blob.ValidateBlob(wrongKey, ctxt, blobType))
+
+	flipped := append([]byte(nil), ctxt...)
+	flipped[len(flipped)/2] ^= 0x01
+	require.Error(t, blob.ValidateBlob(key, flipped, blobType))
+
+	require.ErrorIs(
+		t, blob.ValidateBlob(key, ctxt[:blob.Overhead-1], blobType),
+		blob.ErrCiphertextTooSmall,
+	)
+
+	require.Error(t, blob.ValidateBlob(
+		key, ctxt[:len(ctxt)-1], blobType,
+	))
+
+	// Blobs that decrypt to an invalid kit are rejected with an error
+	// naming the failed check.
+	tests := []struct {
+		name   string
+		mutate func(*blob.JusticeKit)
+		err    error
+	}{
+		{
+			name: "degenerate keys",
+			mutate: func(kit *blob.JusticeKit) {
+				kit.LocalDelayPubKey = kit.RevocationPubKey
+			},
+			err: blob.ErrDegenerateKeys,
+		},
+		{
+			name: "invalid revocation pubkey",
+			mutate: func(kit *blob.JusticeKit) {
+				kit.RevocationPubKey = blob.PubKey{}
+			},
+		},
+		{
+			name: "zero csv delay",
+			mutate: func(kit *blob.JusticeKit) {
+				kit.CSVDelay = 0
+			},
+			err: blob.ErrInvalidCSVDelay,
+		},
+		{
+			name: "csv delay too large",
+			mutate: func(kit *blob.JusticeKit) {
+				kit.CSVDelay = 1 << 16
+			},
+			err: blob.ErrInvalidCSVDelay,
+		},
+		{
+			name: "non-standard sweep address",
+			mutate: func(kit *blob.JusticeKit) {
+				kit.SweepAddress = bytes.Repeat(
+					[]byte{0xff}, 22,
+				)
+			},
+			err: blob.ErrUnknownSweepAddrType,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kit := *breach.kit
+			test.mutate(&kit)
+
+			ctxt, err := kit.Encrypt(key)
+			require.NoError(t, err)
+
+			err = blob.ValidateBlob(key, ctxt, blobType)
+			require.Error(t, err)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+			}
+		})
+	}
+}
+
+// TestJusticeKitTLVTrailerUnknownTypes asserts that a decoder skips records of
+// unknown odd types in the TLV trailer, while failing on records of unknown
+// even types, which signal fields that must be understood.
+func TestJusticeKitTLVTrailerUnknownTypes(t *testing.T) {
+	kit := &blob.JusticeKit{
+		BlobType:         tlvTrailerType,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+		TrailerRecords: map[uint64][]byte{
+			65537: []byte("optional"),
+		},
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	// An unknown optional field should be skipped, leaving the known
+	// fields intact.
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	kit2, err := blob.Decrypt(key, ctxt, kit.BlobType)
+	require.NoError(t, err)
+
+	equal, diff := kit.Equal(kit2)
+	require.True(t, equal, diff)
+
+	compact, err := kit.SerializeCompact()
+	require.NoError(t, err)
+
+	kit2, err = blob.DeserializeCompact(compact)
+	require.NoError(t, err)
+	require.Equal(t, kit, kit2)
+
+	// An unknown mandatory field should fail the decoding.
+	kit.TrailerRecords[65536] = []byte("mandatory")
+
+	ctxt, err = kit.Encrypt(key)
+	require.NoError(t, err)
+
+	_, err = blob.Decrypt(key, ctxt, kit.BlobType)
+	require.ErrorIs(t, err, blob.ErrUnknownRequiredTrailerType)
+
+	compact, err = kit.SerializeCompact()
+	require.NoError(t, err)
+
+	_, err = blob.DeserializeCompact(compact)
+	require.ErrorIs(t, err, blob.ErrUnknownRequiredTrailerType)
+}
+
+// TestJusticeKitLeaseToLocalSpend asserts that the to-local witness of a kit
+// for a script enforced lease channel spends the revocation path of the
+// script produced by input.LeaseCommitScriptToSelf, without requiring the
+// justice transaction to wait for the lease to expire.
+func TestJusticeKitLeaseToLocalSpend(t *testing.T) {
+	const (
+		csvDelay    = 144
+		leaseExpiry = 800_000
+	)
+
+	revPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	kit, err := blob.NewJusticeKitFromScripts(
+		leaseType, blob.JusticeKitParams{
+			SweepAddress:     makeSweepAddr(),
+			RevocationPubKey: revPriv.PubKey(),
+			LocalDelayPubKey: delayPriv.PubKey(),
+			CSVDelay:         csvDelay,
+			LeaseExpiry:      leaseExpiry,
+		},
+	)
+	require.NoError(t, err)
+	require.EqualValues(t, leaseExpiry, kit.LeaseExpiry)
+
+	expToLocalScript, err := input.LeaseCommitScriptToSelf(
+		delayPriv.PubKey(), revPriv.PubKey(), csvDelay, leaseExpiry,
+	)
+	require.NoError(t, err)
+
+	toLocalScript, err := kit.CommitToLocalWitnessScript()
+	require.NoError(t, err)
+	require.Equal(t, expToLocalScript, toLocalScript)
+
+	toLocalPkScript, err := input.WitnessScriptHash(toLocalScript)
+	require.NoError(t, err)
+	prevOut := wire.NewTxOut(100_000, toLocalPkScript)
+
+	// Sign a justice transaction spending the breached to-local output,
+	// whose locktime isn't bound to the lease expiry.
+	justiceTx := wire.NewMsgTx(2)
+	justiceTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{0x01}},
+	})
+	justiceTx.AddTxOut(wire.NewTxOut(90_000, kit.SweepAddress))
+
+	prevOutFetcher := txscript.NewCannedPrevOutputFetcher(
+		prevOut.PkScript, prevOut.Value,
+	)
+	hashCache := txscript.NewTxSigHashes(justiceTx, prevOutFetcher)
+
+	rawSig, err := txscript.RawTxInWitnessSignature(
+		justiceTx, hashCache, 0, prevOut.Value, toLocalScript,
+		txscript.SigHashAll, revPriv,
+	)
+	require.NoError(t, err)
+
+	sig, err := lnwire.NewSigFromECDSARawSignature(rawSig[:len(rawSig)-1])
+	require.NoError(t, err)
+	require.NoError(t, kit.AddToLocalSig(sig))
+
+	spendInfo, err := kit.ToLocalOutputSpendInfo()
+	require.NoError(t, err)
+	require.Equal(t, toLocalScript, spendInfo.WitnessScript)
+
+	justiceTx.TxIn[0].Witness = append(
+		spendInfo.WitnessStack, spendInfo.WitnessScript,
+	)
+	witnessSize := justiceTx.TxIn[0].Witness.SerializeSize()
+	require.GreaterOrEqual(t, spendInfo.WitnessSize, witnessSize)
+
+	vm, err := txscript.NewEngine(
+		prevOut.PkScript, justiceTx, 0, txscript.StandardVerifyFlags,
+		nil, hashCache, prevOut.Value, prevOutFetcher,
+	)
+	require.NoError(t, err)
+	require.NoError(t, vm.Execute())
+
+	// The second-level HTLC outputs of the breached commitment carry the
+	// same lease expiry.
+	kit.BlobType |= blob.Type(blob.FlagSecondLevelHtlcs)
+	require.NoError(t, kit.AddSecondLevelHtlcSig(sig))
+	expHtlcScript, err := input.LeaseSecondLevelHtlcScript(
+		revPriv.PubKey(), delayPriv.PubKey(), csvDelay, leaseExpiry,
+	)
+	require.NoError(t, err)
+
+	htlcScript, _, err := kit.SecondLevelHtlcSpendInfo(0)
+	require.NoError(t, err)
+	require.Equal(t, expHtlcScript, htlcScript)
+}
+
+// TestJusticeKitToRemoteSpendInfo asserts that the to-remote spend info
+// carries the sequence required by the CSV delay of anchor and taproot
+// to-remote outputs, and no sequence for legacy p2wkh outputs.
+func TestJusticeKitToRemoteSpendInfo(t *testing.T) {
+	privKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	revPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	var digest [32]byte
+	schnorrSig, err := schnorr.Sign(privKey, digest[:])
+	require.NoError(t, err)
+	taprootSig, err := lnwire.NewSigFromSignature(schnorrSig)
+	require.NoError(t, err)
+
+	ecdsaSig, err := lnwire.NewSigFromSignature(
+		ecdsa.Sign(privKey, digest[:]),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		blobType    blob.Type
+		sig         lnwire.Sig
+		expSequence uint32
+	}{
+		{
+			name:        "legacy",
+			blobType:    blob.TypeAltruistCommit,
+			sig:         ecdsaSig,
+			expSequence: 0,
+		},
+		{
+			name:        "anchor",
+			blobType:    blob.TypeAltruistAnchorCommit,
+			sig:         ecdsaSig,
+			expSequence: 1,
+		},
+		{
+			name:        "taproot",
+			blobType:    blob.TypeAltruistTaprootCommit,
+			sig:         taprootSig,
+			expSequence: 1,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kit, err := blob.NewJusticeKitFromScripts(
+				test.blobType, blob.JusticeKitParams{
+					SweepAddress:     makeSweepAddr(),
+					RevocationPubKey: revPriv.PubKey(),
+					LocalDelayPubKey: delayPriv.PubKey(),
+					CSVDelay:         144,
+					HasToRemote:      true,
+					ToRemotePubKey:   privKey.PubKey(),
+				},
+			)
+			require.NoError(t, err)
+			require.NoError(t, kit.AddToRemoteSig(test.sig))
+
+			spendInfo, err := kit.ToRemoteOutputSpendInfo()
+			require.NoError(t, err)
+			require.Equal(t, test.expSequence, spendInfo.Sequence)
+
+			expScript, err := kit.CommitToRemoteWitnessScript()
+			require.NoError(t, err)
+			require.Equal(t, expScript, spendInfo.WitnessScript)
+
+			expStack, err := kit.CommitToRemoteWitnessStack()
+			require.NoError(t, err)
+			require.Equal(t, expStack, spendInfo.WitnessStack)
+		})
+	}
+}
+
+// TestJusticeKitWitnessScripts asserts that the witness scripts returned by
+// ToLocalWitnessScript and ToRemoteWitnessScript are available before the kit
+// is signed, and match the script elements of the full witnesses spending the
+// breached outputs once it is.
+func TestJusticeKitWitnessScripts(t *testing.T) {
+	privKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	revPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	var digest [32]byte
+	schnorrSig, err := schnorr.Sign(privKey, digest[:])
+	require.NoError(t, err)
+	taprootSig, err := lnwire.NewSigFromSignature(schnorrSig)
+	require.NoError(t, err)
+
+	ecdsaSig, err := lnwire.NewSigFromSignature(
+		ecdsa.Sign(privKey, digest[:]),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		blobType blob.Type
+		sig      lnwire.Sig
+	}{
+		{
+			name:     "legacy",
+			blobType: blob.TypeAltruistCommit,
+			sig:      ecdsaSig,
+		},
+		{
+			name:     "anchor",
+			blobType: blob.TypeAltruistAnchorCommit,
+			sig:      ecdsaSig,
+		},
+		{
+			name:     "lease",
+			blobType: leaseType,
+			sig:      ecdsaSig,
+		},
+		{
+			name:     "taproot",
+			blobType: blob.TypeAltruistTaprootCommit,
+			sig:      taprootSig,
+		},
+		{
+			name: "taproot key spend",
+			blobType: blob.TypeFromFlags(
+				blob.FlagCommitOutputs, blob.FlagTaprootChannel,
+				blob.FlagTaprootKeySpend,
+			),
+			sig: taprootSig,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kit, err := blob.NewJusticeKitFromScripts(
+				test.blobType, blob.JusticeKitParams{
+					SweepAddress:     makeSweepAddr(),
+					RevocationPubKey: revPriv.PubKey(),
+					LocalDelayPubKey: delayPriv.PubKey(),
+					CSVDelay:         144,
+					HasToRemote:      true,
+					ToRemotePubKey:   privKey.PubKey(),
+				},
+			)
+			require.NoError(t, err)
+
+			// The scripts don't depend on the signatures.
+			toLocalScript, err := kit.ToLocalWitnessScript()
+			require.NoError(t, err)
+			toRemoteScript, err := kit.ToRemoteWitnessScript()
+			require.NoError(t, err)
+			require.NotEmpty(t, toRemoteScript)
+
+			require.NoError(t, kit.AddToLocalSig(test.sig))
+			require.NoError(t, kit.AddToRemoteSig(test.sig))
+
+			// The to-local script directly follows the witness
+			// stack in the full witness, unless the output is
+			// spent via its key path, which reveals no script.
+			toLocalInfo, err := kit.ToLocalOutputSpendInfo()
+			require.NoError(t, err)
+			require.Equal(
+				t, toLocalInfo.WitnessScript, toLocalScript,
+			)
+
+			toLocalWitness := toLocalInfo.Witness()
+			numElems := len(toLocalInfo.WitnessStack)
+			if test.blobType.IsTaprootKeySpend() {
+				require.Nil(t, toLocalScript)
+				require.Len(t, toLocalWitness, numElems)
+			} else {
+				require.NotEmpty(t, toLocalScript)
+				require.Equal(
+					t, toLocalScript,
+					toLocalWitness[numElems],
+				)
+			}
+
+			// The to-remote script follows the witness stack in
+			// the full witness attached by ApplyWitnesses.
+			toRemoteInfo, err := kit.ToRemoteOutputSpendInfo()
+			require.NoError(t, err)
+			require.Equal(
+				t, toRemoteInfo.WitnessScript, toRemoteScript,
+			)
+
+			reqs, err := kit.SpendRequests()
+			require.NoError(t, err)
+			require.Len(t, reqs, 2)
+			require.Equal(t, blob.SpendCommitToRemote, reqs[1].Kind)
+
+			tx := wire.NewMsgTx(2)
+			tx.AddTxIn(&wire.TxIn{})
+			err = blob.ApplyWitnesses(tx, reqs[1:], []int{0})
+			require.NoError(t, err)
+
+			toRemoteWitness := tx.TxIn[0].Witness
+			numElems = len(toRemoteInfo.WitnessStack)
+			require.Len(t, toRemoteWitness, numElems+1)
+			require.Equal(
+				t, toRemoteScript, toRemoteWitness[numElems],
+			)
+		})
+	}
+}
+
+// TestJusticeKitZeroFeeHtlcAnchorScripts asserts that anchor kits produce the
+// to-remote and second-level HTLC scripts of both anchor channel flavors, so
+// that zero-fee HTLC anchor channels can be backed up with the same blob types
+// as regular anchor channels.
+func TestJusticeKitZeroFeeHtlcAnchorScripts(t *testing.T) {
+	revPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	toRemotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	const csvDelay = 144
+
+	blobType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagSecondLevelHtlcs,
+	)
+	kit, err := blob.NewJusticeKitFromScripts(
+		blobType, blob.JusticeKitParams{
+			SweepAddress:     makeSweepAddr(),
+			RevocationPubKey: revPriv.PubKey(),
+			LocalDelayPubKey: delayPriv.PubKey(),
+			CSVDelay:         csvDelay,
+			HasToRemote:      true,
+			ToRemotePubKey:   toRemotePriv.PubKey(),
+		},
+	)
+	require.NoError(t, err)
+
+	var digest [32]byte
+	sig, err := lnwire.NewSigFromSignature(
+		ecdsa.Sign(toRemotePriv, digest[:]),
+	)
+	require.NoError(t, err)
+	require.NoError(t, kit.AddToRemoteSig(sig))
+	require.NoError(t, kit.AddSecondLevelHtlcSig(sig))
+
+	toRemoteInfo, err := kit.ToRemoteOutputSpendInfo()
+	require.NoError(t, err)
+
+	htlcScript, htlcStack, err := kit.SecondLevelHtlcSpendInfo(0)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		chanType channeldb.ChannelType
+	}{
+		{
+			name: "anchors",
+			chanType: channeldb.SingleFunderTweaklessBit |
+				channeldb.AnchorOutputsBit,
+		},
+		{
+			name: "zero-fee htlc anchors",
+			chanType: channeldb.SingleFunderTweaklessBit |
+				channeldb.AnchorOutputsBit |
+				channeldb.ZeroHtlcTxFeeBit,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			toRemote, delay, err := lnwallet.CommitScriptToRemote(
+				test.chanType, true, toRemotePriv.PubKey(), 0,
+			)
+			require.NoError(t, err)
+			require.Equal(
+				t, toRemote.WitnessScriptToSign(),
+				toRemoteInfo.WitnessScript,
+			)
+			require.Equal(t, delay, toRemoteInfo.Sequence)
+
+			htlcDesc, err := lnwallet.SecondLevelHtlcScript(
+				test.chanType, true, revPriv.PubKey(),
+				delayPriv.PubKey(), csvDelay, 0,
+			)
+			require.NoError(t, err)
+			require.Equal(
+				t, htlcDesc.WitnessScriptToSign(), htlcScript,
+			)
+		})
+	}
+
+	// Unlike the HTLC transactions of zero-fee HTLC channels, which are
+	// signed with SIGHASH_SINGLE|ANYONECANPAY, the justice transaction is
+	// signed by the client alone, so its signatures always commit to the
+	// whole transaction.
+	toRemoteSig := toRemoteInfo.WitnessStack[0]
+	sigHash := txscript.SigHashType(toRemoteSig[len(toRemoteSig)-1])
+	require.Equal(t, txscript.SigHashAll, sigHash)
+
+	sigHash = txscript.SigHashType(htlcStack[0][len(htlcStack[0])-1])
+	require.Equal(t, txscript.SigHashAll, sigHash)
+}
+
+// TestJusticeKitSpendableOutputs asserts that SpendableOutputs only reports the
+// outputs for which the kit holds a usable signature.
+func TestJusticeKitSpendableOutputs(t *testing.T) {
+	blobType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagSecondLevelHtlcs,
+	)
+	breach := newTestBreach(t, blobType, chainhash.Hash{})
+	kit := breach.kit
+
+	var digest [32]byte
+	sig, err := lnwire.NewSigFromSignature(
+		ecdsa.Sign(breach.revPriv, digest[:]),
+	)
+	require.NoError(t, err)
+
+	// Nothing can be swept by a kit that hasn't been signed.
+	toLocal, toRemote, htlcs := kit.SpendableOutputs()
+	require.False(t, toLocal)
+	require.False(t, toRemote)
+	require.Empty(t, htlcs)
+
+	// A kit committing to a to-remote output without signing it can only
+	// sweep its to-local output.
+	require.NoError(t, kit.AddToLocalSig(sig))
+
+	toLocal, toRemote, htlcs = kit.SpendableOutputs()
+	require.True(t, toLocal)
+	require.False(t, toRemote)
+	require.Empty(t, htlcs)
+
+	// Blank second-level HTLC signatures are skipped.
+	require.NoError(t, kit.AddToRemoteSig(sig))
+	require.NoError(t, kit.AddSecondLevelHtlcSig(lnwire.Sig{}))
+	require.NoError(t, kit.AddSecondLevelHtlcSig(sig))
+
+	toLocal, toRemote, htlcs = kit.SpendableOutputs()
+	require.True(t, toLocal)
+	require.True(t, toRemote)
+	require.Equal(t, []int{1}, htlcs)
+}
+
+// TestJusticeKitSigHash asserts that the sighash type recorded when adding a
+// commitment signature is appended to the signature in the witness, survives
+// encryption for blob types with a TLV trailer, and is rejected by blob types
+// that can't carry it.
+func TestJusticeKitSigHash(t *testing.T) {
+	const singleAnyoneCanPay = txscript.SigHashSingle |
+		txscript.SigHashAnyOneCanPay
+
+	priv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	digest := chainhash.HashB([]byte("justice"))
+	sig, err := lnwire.NewSigFromSignature(ecdsa.Sign(priv, digest))
+	require.NoError(t, err)
+
+	newKit := func(flags ...blob.Flag) *blob.JusticeKit {
+		return &blob.JusticeKit{
+			BlobType:             blob.TypeFromFlags(flags...),
+			SweepAddress:         makeAddr(22),
+			RevocationPubKey:     makePubKey(0),
+			LocalDelayPubKey:     makePubKey(1),
+			CSVDelay:             144,
+			CommitToRemotePubKey: makePubKey(2),
+		}
+	}
+
+	// requireWitnessSigHash asserts that the signatures in the to-local and
+	// to-remote witnesses end with the given sighash flags.
+	requireWitnessSigHash := func(kit *blob.JusticeKit, toLocal,
+		toRemote txscript.SigHashType) {
+
+		t.Helper()
+
+		require.Equal(t, toLocal, kit.ToLocalSigHash())
+		require.Equal(t, toRemote, kit.ToRemoteSigHash())
+
+		stack, err := kit.CommitToLocalRevokeWitnessStack()
+		require.NoError(t, err)
+		toLocalSig := stack[0]
+		require.EqualValues(t, toLocal, toLocalSig[len(toLocalSig)-1])
+
+		stack, err = kit.CommitToRemoteWitnessStack()
+		require.NoError(t, err)
+		toRemoteSig := stack[0]
+		require.EqualValues(
+			t, toRemote, toRemoteSig[len(toRemoteSig)-1],
+		)
+	}
+
+	t.Run("default", func(t *testing.T) {
+		kit := newKit(blob.FlagCommitOutputs, blob.FlagTLVTrailer)
+		require.NoError(t, kit.AddToLocalSig(sig))
+		require.NoError(t, kit.AddToRemoteSigWithSighash(
+			sig, txscript.SigHashAll,
+		))
+
+		requireWitnessSigHash(
+			kit, txscript.SigHashAll, txscript.SigHashAll,
+		)
+	})
+
+	t.Run("single anyonecanpay", func(t *testing.T) {
+		kit := newKit(blob.FlagCommitOutputs, blob.FlagTLVTrailer)
+		require.NoError(t, kit.AddToLocalSigWithSighash(
+			sig, singleAnyoneCanPay,
+		))
+		require.NoError(t, kit.AddToRemoteSig(sig))
+
+		requireWitnessSigHash(
+			kit, singleAnyoneCanPay, txscript.SigHashAll,
+		)
+
+		// The recorded sighash type is carried by the TLV trailer,
+		// such that the tower builds the same witness.
+		var key blob.BreachKey
+		_, err := rand.Read(key[:])
+		require.NoError(t, err)
+
+		ctxt, err := kit.Encrypt(key)
+		require.NoError(t, err)
+
+		decKit, err := blob.Decrypt(key, ctxt, kit.BlobType)
+		require.NoError(t, err)
+		requireWitnessSigHash(
+			decKit, singleAnyoneCanPay, txscript.SigHashAll,
+		)
+
+		// Replacing the signature reverts to the default.
+		decKit.ReplaceToLocalSig(sig)
+		requireWitnessSigHash(
+			decKit, txscript.SigHashAll, txscript.SigHashAll,
+		)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		// Blob types without a trailer can't carry a sighash type.
+		kit := newKit(blob.FlagCommitOutputs)
+		err := kit.AddToLocalSigWithSighash(sig, singleAnyoneCanPay)
+		require.ErrorIs(t, err, blob.ErrUnsupportedSigHash)
+		require.NoError(t, kit.AddToLocalSigWithSighash(
+			sig, txscript.SigHashAll,
+		))
+
+		// Only SIGHASH_SINGLE|SIGHASH_ANYONECANPAY is supported
+		// besides the default.
+		kit = newKit(blob.FlagCommitOutputs, blob.FlagTLVTrailer)
+		err = kit.AddToRemoteSigWithSighash(sig, txscript.SigHashNone)
+		require.ErrorIs(t, err, blob.ErrUnsupportedSigHash)
+
+		// Taproot signatures always use SIGHASH_DEFAULT.
+		kit = newKit(
+			blob.FlagCommitOutputs, blob.FlagTaprootChannel,
+			blob.FlagTLVTrailer,
+		)
+		err = kit.AddToLocalSigWithSighash(sig, singleAnyoneCanPay)
+		require.ErrorIs(t, err, blob.ErrUnsupportedSigHash)
+		require.NoError(t, kit.AddToLocalSigWithSighash(
+			sig, txscript.SigHashDefault,
+		))
+		require.Equal(t, txscript.SigHashDefault, kit.ToLocalSigHash())
+	})
+}
+
+// fixedNonceProvider is an AEADProvider sealing every plaintext under the same
+// nonce, such that encrypting identical plaintexts yields identical
+// ciphertexts.
+type fixedNonceProvider struct {
+	aead cipher.AEAD
+}
+
+func (p *fixedNonceProvider) Seal(plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, p.aead.NonceSize())
+	return p.aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func (p *fixedNonceProvider) Open(ciphertext, aad []byte) ([]byte, error) {
+	nonce := ciphertext[:p.aead.NonceSize()]
+	return p.aead.Open(nil, nonce, ciphertext[len(nonce):], aad)
+}
+
+// TestJusticeKitCopy asserts that mutating a copy of a kit leaves the original
+// untouched, such that it still encrypts to its original ciphertext.
+func TestJusticeKitCopy(t *testing.T) {
+	blobType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagSecondLevelHtlcs, blob.FlagDataCommitment,
+		blob.FlagTLVTrailer,
+	)
+
+	kit := &blob.JusticeKit{
+		BlobType:             blobType,
+		SweepAddress:         makeAddr(22),
+		RevocationPubKey:     makePubKey(0),
+		LocalDelayPubKey:     makePubKey(1),
+		CSVDelay:             144,
+		CommitToLocalSig:     makeSig(1),
+		CommitToRemotePubKey: makePubKey(2),
+		CommitToRemoteSig:    makeSig(2),
+		SecondLevelHtlcSigs:  []lnwire.Sig{makeSig(3)},
+		DataCommitment:       []byte{1, 2, 3},
+		TrailerRecords:       map[uint64][]byte{1: {4, 5}},
+	}
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	aead, err := chacha20poly1305.NewX(key[:])
+	require.NoError(t, err)
+	provider := &fixedNonceProvider{aead: aead}
+
+	origCtxt, err := kit.EncryptWithProvider(provider, nil)
+	require.NoError(t, err)
+
+	kitCopy := kit.Copy()
+	equal, diff := kit.Equal(kitCopy)
+	require.True(t, equal, diff)
+
+	// Mutate every field of the copy, both in place and by reassignment.
+	kitCopy.SweepAddress[0] ^= 0xff
+	kitCopy.SweepAddress = append(kitCopy.SweepAddress, 0x00)
+	kitCopy.RevocationPubKey[1] ^= 0xff
+	kitCopy.ReplaceToLocalSig(makeSig(4))
+	kitCopy.ReplaceToRemoteSig(makeSig(5))
+	kitCopy.SecondLevelHtlcSigs[0] = makeSig(6)
+	kitCopy.DataCommitment[0] ^= 0xff
+	kitCopy.TrailerRecords[1][0] ^= 0xff
+	kitCopy.TrailerRecords[3] = []byte{6}
+
+	equal, _ = kit.Equal(kitCopy)
+	require.False(t, equal)
+
+	// The original should be unaffected, and still encrypt to the same
+	// ciphertext.
+	ctxt, err := kit.EncryptWithProvider(provider, nil)
+	require.NoError(t, err)
+	require.Equal(t, origCtxt, ctxt)
+}
+
+// TestEncryptStrictZeroKey asserts that EncryptStrict rejects an all-zero
+// breach key, while Encrypt still accepts it for backwards compatibility.
+func TestEncryptStrictZeroKey(t *testing.T) {
+	kit := &blob.JusticeKit{
+		BlobType:         blob.TypeAltruistCommit,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	var zeroKey blob.BreachKey
+	_, err := kit.EncryptStrict(zeroKey)
+	require.ErrorIs(t, err, blob.ErrZeroBreachKey)
+
+	ctxt, err := kit.Encrypt(zeroKey)
+	require.NoError(t, err)
+
+	decKit, err := blob.Decrypt(zeroKey, ctxt, kit.BlobType)
+	require.NoError(t, err)
+	require.Equal(t, kit.CSVDelay, decKit.CSVDelay)
+
+	// Any other key is accepted by the strict path.
+	var key blob.BreachKey
+	_, err = rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err = kit.EncryptStrict(key)
+	require.NoError(t, err)
+
+	_, err = blob.Decrypt(key, ctxt, kit.BlobType)
+	require.NoError(t, err)
+}
@@ -0,0 +1,726 @@
+package blob
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/btcutil/txsort"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+)
+
+// rewardScale is the denominator of RewardParams.Rate, which is expressed in
+// millionths.
+const rewardScale = 1_000_000
+
+var (
+	// ErrJusticePSBTUnsupported is returned when building a justice PSBT,
+	// or estimating the justice transaction weight, for a taproot blob,
+	// whose to-local output can't yet be spent from the fields of a
+	// JusticeKit.
+	ErrJusticePSBTUnsupported = errors.New(
+		"justice psbt unsupported for taproot blobs",
+	)
+
+	// ErrNoJusticeInputs is returned when building a justice PSBT, or
+	// estimating the justice transaction weight, without any breached
+	// outputs to sweep.
+	ErrNoJusticeInputs = errors.New("no breached outputs to sweep")
+
+	// ErrJusticeFeeExceedsInputs is returned when the fee of a justice
+	// PSBT exceeds the total value of the breached outputs it sweeps.
+	ErrJusticeFeeExceedsInputs = errors.New(
+		"justice fee exceeds value of breached outputs",
+	)
+
+	// ErrJusticeRewardExceedsInputs is returned when previewing the
+	// recovery of a reward blob whose fee and reward to the tower exceed
+	// the total value of the breached outputs it sweeps.
+	ErrJusticeRewardExceedsInputs = errors.New(
+		"justice fee and reward exceed value of breached outputs",
+	)
+
+	// ErrBatchDataCommitment is returned when batching the justice
+	// transactions of several kits, one of which carries a data
+	// commitment.
+	ErrBatchDataCommitment = errors.New(
+		"kits with a data commitment can't be batched",
+	)
+
+	// ErrDuplicateJusticeInput is returned when batching the justice
+	// transactions of several kits that sweep the same breached output.
+	ErrDuplicateJusticeInput = errors.New("duplicate breached output")
+)
+
+// JusticeInput identifies a breached output swept by a justice transaction.
+type JusticeInput struct {
+	// OutPoint is the outpoint of the breached output.
+	OutPoint wire.OutPoint
+
+	// Output is the breached output itself, which is required to sign and
+	// validate the input spending it. A nil Output marks a breached output
+	// that is deliberately left unswept, as done by FilterEconomicalInputs,
+	// while keeping the remaining outputs paired with the kit's
+	// signatures.
+	Output *wire.TxOut
+}
+
+// unswept returns true if the breached output is left out of the justice
+// transaction.
+func (i *JusticeInput) unswept() bool {
+	return i.Output == nil
+}
+
+// JusticeInputs holds the breached outputs that a justice transaction built
+// from a JusticeKit sweeps. These are located on the breached commitment by
+// the caller, since the kit itself only carries their scripts.
+type JusticeInputs struct {
+	// CommitToLocal is the breached commitment to-local output. It may
+	// only be omitted if the kit sweeps a to-remote output, as is the case
+	// for a breached commitment whose to-local output is dust.
+	CommitToLocal *JusticeInput
+
+	// CommitToRemote is the breached commitment to-remote output. It must
+	// be set if and only if the kit has a commit to-remote output.
+	CommitToRemote *JusticeInput
+
+	// SecondLevelHtlcs are the breached second-level HTLC outputs, in the
+	// same order as the kit's SecondLevelHtlcSigs.
+	SecondLevelHtlcs []JusticeInput
+}
+
+// JusticeInputsFromBreach returns the JusticeInputs locating the commitment
+// outputs of a breach, as described by lnd's BreachRetribution. The breaching
+// party's to-local output is taken from the RemoteOutputSignDesc, and the
+// to-remote output paying us from the LocalOutputSignDesc, either of which is
+// left nil if it was trimmed as dust. An ErrNoJusticeInputs error is returned
+// if both were trimmed.
+//
+// NOTE: The HTLC outputs of the breached commitment aren't returned, as a kit
+// only sweeps second-level HTLC outputs. These are created once the breaching
+// party confirms a second-level HTLC transaction, and as such aren't part of
+// the BreachRetribution. They must be appended to SecondLevelHtlcs by the
+// caller once known.
+func JusticeInputsFromBreach(
+	breachInfo *lnwallet.BreachRetribution) (JusticeInputs, error) {
+
+	var (
+		inputs JusticeInputs
+		err    error
+	)
+
+	inputs.CommitToLocal, err = justiceInputFromSignDesc(
+		breachInfo.RemoteOutpoint, breachInfo.RemoteOutputSignDesc,
+	)
+	if err != nil {
+		return JusticeInputs{}, fmt.Errorf("to-local output: %w", err)
+	}
+
+	inputs.CommitToRemote, err = justiceInputFromSignDesc(
+		breachInfo.LocalOutpoint, breachInfo.LocalOutputSignDesc,
+	)
+	if err != nil {
+		return JusticeInputs{}, fmt.Errorf("to-remote output: %w", err)
+	}
+
+	if inputs.CommitToLocal == nil && inputs.CommitToRemote == nil {
+		return JusticeInputs{}, ErrNoJusticeInputs
+	}
+
+	return inputs, nil
+}
+
+// justiceInputFromSignDesc returns the JusticeInput for the breached output at
+// the given outpoint described by signDesc, or nil if signDesc is nil.
+func justiceInputFromSignDesc(outPoint wire.OutPoint,
+	signDesc *input.SignDescriptor) (*JusticeInput, error) {
+
+	if signDesc == nil {
+		return nil, nil
+	}
+
+	if signDesc.Output == nil {
+		return nil, errors.New("sign descriptor has no output")
+	}
+
+	return &JusticeInput{
+		OutPoint: outPoint,
+		Output: wire.NewTxOut(
+			signDesc.Output.Value, signDesc.Output.PkScript,
+		),
+	}, nil
+}
+
+// justicePSBTInput bundles a breached output with the information required to
+// populate the PSBT input spending it.
+type justicePSBTInput struct {
+	JusticeInput
+
+	// witnessScript is the witness script of a p2wsh output, or nil for a
+	// p2wkh output.
+	witnessScript []byte
+
+	// witness is the final witness of the input, or nil if its signature
+	// is not yet known.
+	witness wire.TxWitness
+
+	// witnessSize is the estimated size of the final witness, which is
+	// independent of the signature such that the transaction is the same
+	// before and after the kit is signed.
+	witnessSize int
+
+	sequence uint32
+}
+
+// JusticePSBT builds a PSBT for the justice transaction sweeping the given
+// breached outputs to the kit's sweep address, paying a fee at feeRate. The
+// inputs carry their WitnessUtxo and witness script, along with their
+// FinalScriptWitness if the kit holds the corresponding signature. The unsigned
+// transaction is deterministic, such that a PSBT built before the kit is signed
+// can be used to compute the sighashes of the signatures later added to it.
+// This allows the justice transaction to be inspected and combined with
+// external signing workflows.
+func (b *JusticeKit) JusticePSBT(inputs JusticeInputs,
+	feeRate chainfee.SatPerKWeight) (*psbt.Packet, error) {
+
+	psbtInputs, err := b.justicePSBTInputs(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	dataOutput, err := b.DataCommitmentOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	justiceTx, err := buildJusticeTx(
+		psbtInputs, b.SweepAddress, dataOutput, feeRate,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	packet, err := psbt.NewFromUnsignedTx(justiceTx)
+	if err != nil {
+		return nil, err
+	}
+
+	inputsByOutPoint := make(map[wire.OutPoint]*justicePSBTInput)
+	for i := range psbtInputs {
+		inputsByOutPoint[psbtInputs[i].OutPoint] = &psbtInputs[i]
+	}
+
+	for i, txIn := range justiceTx.TxIn {
+		inp := inputsByOutPoint[txIn.PreviousOutPoint]
+
+		pInput := &packet.Inputs[i]
+		pInput.WitnessUtxo = inp.Output
+		pInput.WitnessScript = inp.witnessScript
+
+		if inp.witness == nil {
+			continue
+		}
+
+		var witness bytes.Buffer
+		err := psbt.WriteTxWitness(&witness, inp.witness)
+		if err != nil {
+			return nil, err
+		}
+		pInput.FinalScriptWitness = witness.Bytes()
+	}
+
+	return packet, nil
+}
+
+// BuildBatchedJusticeTxn builds a single justice transaction sweeping the
+// breached outputs of several kits to sweepAddr, paying a fee at feeRate over
+// the combined weight of the transaction. The i-th entry of inputs holds the
+// breached outputs of the i-th kit, whose commitment outputs must be locked to
+// the kit's scripts, otherwise ErrOutputScriptMismatch is returned.
+// This allows a client recovering from downtime to sweep the breaches of
+// several channels at once, rather than paying for a transaction per breach.
+//
+// Like JusticePSBT, the unsigned transaction is deterministic, and inputs whose
+// signature is not yet held by their kit are left without a witness. As the
+// signatures commit to the whole transaction, the kits' signatures must have
+// been produced for the batched transaction itself, and those produced for
+// the justice transaction of an individual kit can't be reused. Kits carrying a
+// data commitment can't be batched, as a standard transaction can only carry a
+// single OP_RETURN output.
+func BuildBatchedJusticeTxn(kits []*JusticeKit, inputs []JusticeInputs,
+	sweepAddr []byte, feeRate chainfee.SatPerKWeight) (*wire.MsgTx, error) {
+
+	if len(kits) == 0 {
+		return nil, ErrNoJusticeInputs
+	}
+	if len(kits) != len(inputs) {
+		return nil, ErrJusticeInputMismatch
+	}
+
+	if err := validateSweepAddress(sweepAddr); err != nil {
+		return nil, err
+	}
+
+	var (
+		psbtInputs []justicePSBTInput
+		outPoints  = make(map[wire.OutPoint]struct{})
+	)
+	for i, kit := range kits {
+		if len(kit.DataCommitment) != 0 {
+			return nil, ErrBatchDataCommitment
+		}
+
+		kitInputs, err := kit.justicePSBTInputs(inputs[i])
+		if err != nil {
+			return nil, fmt.Errorf("kit %d: %w", i, err)
+		}
+
+		// Ensure the kit's scripts commit to the outputs it was
+		// paired with, so that a mixup between the kits surfaces here
+		// rather than as an invalid transaction.
+		if err := kit.verifyJusticeInputs(inputs[i]); err != nil {
+			return nil, fmt.Errorf("kit %d: %w", i, err)
+		}
+
+		for _, inp := range kitInputs {
+			if _, ok := outPoints[inp.OutPoint]; ok {
+				return nil, fmt.Errorf("%w: %v",
+					ErrDuplicateJusticeInput, inp.OutPoint)
+			}
+			outPoints[inp.OutPoint] = struct{}{}
+		}
+
+		psbtInputs = append(psbtInputs, kitInputs...)
+	}
+
+	justiceTx, err := buildJusticeTx(psbtInputs, sweepAddr, nil, feeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	witnesses := make(map[wire.OutPoint]wire.TxWitness, len(psbtInputs))
+	for _, inp := range psbtInputs {
+		witnesses[inp.OutPoint] = inp.witness
+	}
+	for _, txIn := range justiceTx.TxIn {
+		txIn.Witness = witnesses[txIn.PreviousOutPoint]
+	}
+
+	return justiceTx, nil
+}
+
+// buildJusticeTx assembles the unsigned justice transaction spending the given
+// inputs, with an optional data commitment output and a sweep output paying
+// the total input value less the fee at feeRate to sweepAddr. The transaction
+// is sorted according to BIP69, as is done for justice transactions assembled
+// by the tower.
+func buildJusticeTx(psbtInputs []justicePSBTInput, sweepAddr []byte,
+	dataOutput *wire.TxOut,
+	feeRate chainfee.SatPerKWeight) (*wire.MsgTx, error) {
+
+	var (
+		weightEstimate input.TxWeightEstimator
+		totalAmt       btcutil.Amount
+		justiceTx      = wire.NewMsgTx(2)
+	)
+	for _, inp := range psbtInputs {
+		weightEstimate.AddWitnessInput(inp.witnessSize)
+		totalAmt += btcutil.Amount(inp.Output.Value)
+
+		justiceTx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: inp.OutPoint,
+			Sequence:         inp.sequence,
+		})
+	}
+
+	// The sweep output's value is only known once the fee is, but its
+	// weight doesn't depend on the value.
+	sweepOutput := wire.NewTxOut(0, sweepAddr)
+	weightEstimate.AddTxOutput(sweepOutput)
+
+	if dataOutput != nil {
+		weightEstimate.AddTxOutput(dataOutput)
+		justiceTx.AddTxOut(dataOutput)
+	}
+
+	fee := feeRate.FeeForWeight(int64(weightEstimate.Weight()))
+	if fee >= totalAmt {
+		return nil, ErrJusticeFeeExceedsInputs
+	}
+
+	sweepOutput.Value = int64(totalAmt - fee)
+	justiceTx.AddTxOut(sweepOutput)
+
+	txsort.InPlaceSort(justiceTx)
+
+	return justiceTx, nil
+}
+
+// JusticeTxWeight estimates the weight of the justice transaction sweeping the
+// given breached outputs, including the sweep output, the data commitment
+// output if the kit carries one, and the tower's P2WKH reward output if the
+// blob type has FlagReward. The witness of each input is estimated at its
+// maximum size, such that the estimate is never below the weight of the final
+// transaction, and can be used to decide whether sweeping the outputs is
+// economical at a given fee rate.
+func (b *JusticeKit) JusticeTxWeight(inputs JusticeInputs) (int64, error) {
+	psbtInputs, err := b.justicePSBTInputs(inputs)
+	if err != nil {
+		return 0, err
+	}
+
+	var weightEstimate input.TxWeightEstimator
+	for _, inp := range psbtInputs {
+		weightEstimate.AddWitnessInput(inp.witnessSize)
+	}
+
+	weightEstimate.AddTxOutput(wire.NewTxOut(0, b.SweepAddress))
+
+	dataOutput, err := b.DataCommitmentOutput()
+	if err != nil {
+		return 0, err
+	}
+	if dataOutput != nil {
+		weightEstimate.AddTxOutput(dataOutput)
+	}
+
+	if b.BlobType.Has(FlagReward) {
+		weightEstimate.AddP2WKHOutput()
+	}
+
+	return int64(weightEstimate.Weight()), nil
+}
+
+// BreakEvenFeeRate returns the fee rate at which the fee of the justice
+// transaction sweeping the given breached outputs, whose weight is estimated
+// as done by JusticeTxWeight, consumes the total value of the swept outputs.
+// The rate is rounded down, such that sweeping at any higher fee rate costs
+// more than it recovers. Clients can compare it against the current fee
+// estimate to decide whether a breach is still worth sweeping. For reward blob
+// types, the weight of the tower's reward output is included, though its value
+// isn't deducted, as it depends on the session's policy.
+func (b *JusticeKit) BreakEvenFeeRate(
+	inputs JusticeInputs) (chainfee.SatPerKWeight, error) {
+
+	weight, err := b.JusticeTxWeight(inputs)
+	if err != nil {
+		return 0, err
+	}
+
+	breakEven := inputs.sweptValue() * 1000 / btcutil.Amount(weight)
+
+	return chainfee.SatPerKWeight(breakEven), nil
+}
+
+// RewardParams are the reward parameters of the session a reward blob is
+// backed up to, as negotiated in the session's policy.
+type RewardParams struct {
+	// Base is the fixed amount rewarded to the tower.
+	Base uint32
+
+	// Rate is the proportion of the swept value remaining after Base that
+	// is rewarded to the tower, expressed in millionths.
+	Rate uint32
+}
+
+// amount returns the reward owed to the tower out of the given total, rounded
+// up to the nearest whole satoshi, as computed by the tower's policy.
+func (r RewardParams) amount(total btcutil.Amount) btcutil.Amount {
+	base := btcutil.Amount(r.Base)
+	if base > total {
+		return base
+	}
+
+	proportional := ((total-base)*btcutil.Amount(r.Rate) +
+		rewardScale - 1) / rewardScale
+
+	return base + proportional
+}
+
+// PreviewRecovery splits the total value of the given breached outputs into
+// the amount swept back to the client, the fee paid at feeRate, and, for reward
+// blob types, the tower's reward under the given reward parameters, which are
+// otherwise ignored. The fee is computed over the weight estimated by
+// JusticeTxWeight, such that the components always add up to the total value
+// swept, allowing the outcome of a justice transaction to be previewed without
+// building it. ErrJusticeFeeExceedsInputs or ErrJusticeRewardExceedsInputs is
+// returned if the swept outputs can't pay for the fee, or the fee and reward,
+// respectively.
+func (b *JusticeKit) PreviewRecovery(inputs JusticeInputs,
+	feeRate chainfee.SatPerKWeight, reward RewardParams) (btcutil.Amount,
+	btcutil.Amount, btcutil.Amount, error) {
+
+	weight, err := b.JusticeTxWeight(inputs)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	totalAmt := inputs.sweptValue()
+
+	fee := feeRate.FeeForWeight(weight)
+	if fee >= totalAmt {
+		return 0, 0, 0, ErrJusticeFeeExceedsInputs
+	}
+
+	var rewardAmt btcutil.Amount
+	if b.BlobType.Has(FlagReward) {
+		rewardAmt = reward.amount(totalAmt)
+		if rewardAmt+fee >= totalAmt {
+			return 0, 0, 0, ErrJusticeRewardExceedsInputs
+		}
+	}
+
+	return totalAmt - fee - rewardAmt, fee, rewardAmt, nil
+}
+
+// sweptValue returns the total value of the breached outputs, skipping those
+// left unswept.
+func (i *JusticeInputs) sweptValue() btcutil.Amount {
+	var total btcutil.Amount
+	for _, inp := range []*JusticeInput{i.CommitToLocal, i.CommitToRemote} {
+		if inp != nil && !inp.unswept() {
+			total += btcutil.Amount(inp.Output.Value)
+		}
+	}
+	for _, htlc := range i.SecondLevelHtlcs {
+		if !htlc.unswept() {
+			total += btcutil.Amount(htlc.Output.Value)
+		}
+	}
+
+	return total
+}
+
+// verifyJusticeInputs checks that the kit's witness scripts commit to the
+// breached to-local and to-remote outputs it sweeps, skipping those left
+// unswept.
+func (b *JusticeKit) verifyJusticeInputs(inputs JusticeInputs) error {
+	toLocal := inputs.CommitToLocal
+	if toLocal != nil && !toLocal.unswept() {
+		expPkScript, err := b.CommitToLocalPkScript()
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(expPkScript, toLocal.Output.PkScript) {
+			return fmt.Errorf("commit to-local: %w",
+				ErrOutputScriptMismatch)
+		}
+	}
+
+	toRemote := inputs.CommitToRemote
+	if toRemote != nil && !toRemote.unswept() {
+		expPkScript, err := b.CommitToRemotePkScript()
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(expPkScript, toRemote.Output.PkScript) {
+			return fmt.Errorf("commit to-remote: %w",
+				ErrOutputScriptMismatch)
+		}
+	}
+
+	return nil
+}
+
+// FilterEconomicalInputs returns the given breached outputs with those that
+// aren't worth sweeping at feeRate left unswept, such that a justice
+// transaction built from the result recovers more value. A breached output is
+// left unswept if its value is below dustLimit, or doesn't exceed the fee paid
+// for the weight of the input spending it, which is estimated using the
+// maximum size of its witness. Unswept to-remote and second-level HTLC outputs
+// have their Output cleared rather than being removed, as they must remain
+// paired with the kit's signatures, while an unswept to-local output is
+// omitted. ErrNoJusticeInputs is returned if none of the breached outputs are
+// worth sweeping.
+func (b *JusticeKit) FilterEconomicalInputs(inputs JusticeInputs,
+	feeRate chainfee.SatPerKWeight,
+	dustLimit btcutil.Amount) (JusticeInputs, error) {
+
+	// Ensure the breached outputs match those of the kit before filtering
+	// them.
+	if _, err := b.justicePSBTInputs(inputs); err != nil {
+		return JusticeInputs{}, err
+	}
+
+	var (
+		filtered JusticeInputs
+		numSwept int
+	)
+	sweep := func(inp *JusticeInput, witnessSize int) bool {
+		if inp.unswept() {
+			return false
+		}
+
+		value := btcutil.Amount(inp.Output.Value)
+		fee := feeRate.FeeForWeight(justiceInputWeight(witnessSize))
+		if value < dustLimit || value <= fee {
+			return false
+		}
+
+		numSwept++
+
+		return true
+	}
+
+	toLocal := inputs.CommitToLocal
+	if toLocal != nil && sweep(toLocal, b.toLocalPenaltyWitnessSize()) {
+		filtered.CommitToLocal = toLocal
+	}
+
+	if toRemote := inputs.CommitToRemote; toRemote != nil {
+		filtered.CommitToRemote = &JusticeInput{
+			OutPoint: toRemote.OutPoint,
+		}
+		if sweep(toRemote, b.toRemoteWitnessSize()) {
+			filtered.CommitToRemote = toRemote
+		}
+	}
+
+	for _, htlc := range inputs.SecondLevelHtlcs {
+		if !sweep(&htlc, b.toLocalPenaltyWitnessSize()) {
+			htlc.Output = nil
+		}
+
+		filtered.SecondLevelHtlcs = append(
+			filtered.SecondLevelHtlcs, htlc,
+		)
+	}
+
+	if numSwept == 0 {
+		return JusticeInputs{}, ErrNoJusticeInputs
+	}
+
+	return filtered, nil
+}
+
+// justiceInputWeight returns the weight added to a justice transaction by an
+// input whose witness has the given size.
+func justiceInputWeight(witnessSize int) int64 {
+	return int64(input.InputSize*blockchain.WitnessScaleFactor +
+		witnessSize)
+}
+
+// toRemoteWitnessSize returns the estimated size of the witness spending the
+// breached commitment to-remote output, which is a tapscript leaf for taproot
+// channels, a p2wsh output with a CSV delay of 1 for anchor channels, and a
+// p2wkh output otherwise.
+func (b *JusticeKit) toRemoteWitnessSize() int {
+	switch {
+	case b.BlobType.IsTaprootChannel():
+		return input.TaprootToRemoteWitnessSize
+
+	case b.BlobType.IsAnchorChannel():
+		return input.ToRemoteConfirmedWitnessSize
+	}
+
+	return input.P2WKHWitnessSize
+}
+
+// justicePSBTInputs pairs each of the breached outputs with its witness script
+// and, if signed, its final witness. Unswept outputs are skipped. An error is
+// returned if the breached outputs don't match those of the kit.
+func (b *JusticeKit) justicePSBTInputs(
+	inputs JusticeInputs) ([]justicePSBTInput, error) {
+
+	if b.BlobType.IsTaprootChannel() {
+		return nil, ErrJusticePSBTUnsupported
+	}
+
+	if (inputs.CommitToRemote != nil) != b.HasCommitToRemoteOutput() ||
+		len(inputs.SecondLevelHtlcs) != len(b.SecondLevelHtlcSigs) {
+
+		return nil, ErrJusticeInputMismatch
+	}
+
+	if inputs.CommitToLocal == nil && inputs.CommitToRemote == nil &&
+		len(inputs.SecondLevelHtlcs) == 0 {
+
+		return nil, ErrNoJusticeInputs
+	}
+
+	var psbtInputs []justicePSBTInput
+
+	if inputs.CommitToLocal != nil && !inputs.CommitToLocal.unswept() {
+		script, err := b.CommitToLocalWitnessScript()
+		if err != nil {
+			return nil, err
+		}
+
+		inp := justicePSBTInput{
+			JusticeInput:  *inputs.CommitToLocal,
+			witnessScript: script,
+			witnessSize:   b.toLocalPenaltyWitnessSize(),
+		}
+		if !isZeroSig(b.CommitToLocalSig) {
+			stack, err := b.CommitToLocalRevokeWitnessStack()
+			if err != nil {
+				return nil, err
+			}
+			inp.witness = append(stack, script)
+		}
+
+		psbtInputs = append(psbtInputs, inp)
+	}
+
+	if inputs.CommitToRemote != nil && !inputs.CommitToRemote.unswept() {
+		script, err := b.CommitToRemoteWitnessScript()
+		if err != nil {
+			return nil, err
+		}
+
+		// Anchor to-remote outputs are p2wsh outputs with a CSV delay
+		// of 1, while legacy to-remote outputs are p2wkh outputs, for
+		// which the "script" is the pubkey terminating the witness.
+		inp := justicePSBTInput{
+			JusticeInput: *inputs.CommitToRemote,
+			witnessSize:  b.toRemoteWitnessSize(),
+			sequence:     b.toRemoteSequence(),
+		}
+		if b.BlobType.IsAnchorChannel() {
+			inp.witnessScript = script
+		}
+		if !isZeroSig(b.CommitToRemoteSig) {
+			stack, err := b.CommitToRemoteWitnessStack()
+			if err != nil {
+				return nil, err
+			}
+			inp.witness = append(stack, script)
+		}
+
+		psbtInputs = append(psbtInputs, inp)
+	}
+
+	// Second-level HTLC outputs share the script of the to-local output,
+	// and so also the size of its revocation witness.
+	for i, htlc := range inputs.SecondLevelHtlcs {
+		if htlc.unswept() {
+			continue
+		}
+
+		script, stack, err := b.SecondLevelHtlcSpendInfo(i)
+		if err != nil {
+			return nil, err
+		}
+
+		psbtInputs = append(psbtInputs, justicePSBTInput{
+			JusticeInput:  htlc,
+			witnessScript: script,
+			witness:       append(stack, script),
+			witnessSize:   b.toLocalPenaltyWitnessSize(),
+		})
+	}
+
+	if len(psbtInputs) == 0 {
+		return nil, ErrNoJusticeInputs
+	}
+
+	return psbtInputs, nil
+}
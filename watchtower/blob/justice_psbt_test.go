@@ -0,0 +1,761 @@
+package blob_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/psbt"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwallet"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/watchtower/blob"
+	"github.com/lightningnetwork/lnd/watchtower/wtpolicy"
+	"github.com/stretchr/testify/require"
+)
+
+// TestJusticePSBT asserts that a justice PSBT built from a JusticeKit can be
+// signed, finalized and extracted into a transaction that passes script
+// validation.
+func TestJusticePSBT(t *testing.T) {
+	tests := []struct {
+		name     string
+		blobType blob.Type
+	}{
+		{
+			name:     "legacy",
+			blobType: blob.TypeAltruistCommit,
+		},
+		{
+			name:     "anchor",
+			blobType: blob.TypeAltruistAnchorCommit,
+		},
+		{
+			name: "lease",
+			blobType: blob.TypeFromFlags(
+				blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+				blob.FlagLeaseChannel,
+			),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			testJusticePSBT(t, test.blobType)
+		})
+	}
+}
+
+func testJusticePSBT(t *testing.T, blobType blob.Type) {
+	newPrivKey := func() *btcec.PrivateKey {
+		priv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return priv
+	}
+
+	var (
+		revPriv      = newPrivKey()
+		toLocalPriv  = newPrivKey()
+		toRemotePriv = newPrivKey()
+		csvDelay     = uint32(144)
+		sweepAddr    = make([]byte, 22)
+	)
+	sweepAddr[0], sweepAddr[1] = txscript.OP_0, txscript.OP_DATA_20
+
+	kit, err := blob.NewJusticeKitFromScripts(
+		blobType, blob.JusticeKitParams{
+			SweepAddress:     sweepAddr,
+			RevocationPubKey: revPriv.PubKey(),
+			LocalDelayPubKey: toLocalPriv.PubKey(),
+			CSVDelay:         csvDelay,
+			HasToRemote:      true,
+			ToRemotePubKey:   toRemotePriv.PubKey(),
+			LeaseExpiry:      800_000,
+		},
+	)
+	require.NoError(t, err)
+
+	// Construct the breached outputs locked to the kit's scripts.
+	toLocalScript, err := kit.CommitToLocalWitnessScript()
+	require.NoError(t, err)
+	toLocalPkScript, err := input.WitnessScriptHash(toLocalScript)
+	require.NoError(t, err)
+
+	toRemoteScript, err := kit.CommitToRemoteWitnessScript()
+	require.NoError(t, err)
+
+	var toRemotePkScript []byte
+	if blobType.IsAnchorChannel() {
+		toRemotePkScript, err = input.WitnessScriptHash(toRemoteScript)
+	} else {
+		toRemotePkScript, err = input.CommitScriptUnencumbered(
+			toRemotePriv.PubKey(),
+		)
+	}
+	require.NoError(t, err)
+
+	breachTxID := chainhash.Hash{0x01}
+	inputs := blob.JusticeInputs{
+		CommitToLocal: &blob.JusticeInput{
+			OutPoint: wire.OutPoint{Hash: breachTxID, Index: 0},
+			Output:   wire.NewTxOut(200_000, toLocalPkScript),
+		},
+		CommitToRemote: &blob.JusticeInput{
+			OutPoint: wire.OutPoint{Hash: breachTxID, Index: 1},
+			Output:   wire.NewTxOut(100_000, toRemotePkScript),
+		},
+	}
+
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	prevOutFetcher.AddPrevOut(
+		inputs.CommitToLocal.OutPoint, inputs.CommitToLocal.Output,
+	)
+	prevOutFetcher.AddPrevOut(
+		inputs.CommitToRemote.OutPoint, inputs.CommitToRemote.Output,
+	)
+
+	const feeRate = chainfee.SatPerKWeight(2500)
+
+	// Build the PSBT before the kit is signed, which can't be finalized
+	// yet, but fixes the transaction to be signed.
+	packet, err := kit.JusticePSBT(inputs, feeRate)
+	require.NoError(t, err)
+	require.Len(t, packet.Inputs, 2)
+	for _, pInput := range packet.Inputs {
+		require.NotNil(t, pInput.WitnessUtxo)
+		require.Nil(t, pInput.FinalScriptWitness)
+	}
+
+	unsignedTx := packet.UnsignedTx
+	hashCache := txscript.NewTxSigHashes(unsignedTx, prevOutFetcher)
+
+	sign := func(prevOut *blob.JusticeInput, script []byte,
+		priv *btcec.PrivateKey) lnwire.Sig {
+
+		idx := -1
+		for i, txIn := range unsignedTx.TxIn {
+			if txIn.PreviousOutPoint == prevOut.OutPoint {
+				idx = i
+			}
+		}
+		require.NotEqual(t, -1, idx)
+
+		rawSig, err := txscript.RawTxInWitnessSignature(
+			unsignedTx, hashCache, idx, prevOut.Output.Value,
+			script, txscript.SigHashAll, priv,
+		)
+		require.NoError(t, err)
+
+		sig, err := lnwire.NewSigFromECDSARawSignature(
+			rawSig[:len(rawSig)-1],
+		)
+		require.NoError(t, err)
+
+		return sig
+	}
+
+	toRemoteScriptCode := toRemotePkScript
+	if blobType.IsAnchorChannel() {
+		toRemoteScriptCode = toRemoteScript
+	}
+
+	require.NoError(t, kit.AddToLocalSig(
+		sign(inputs.CommitToLocal, toLocalScript, revPriv),
+	))
+	require.NoError(t, kit.AddToRemoteSig(
+		sign(inputs.CommitToRemote, toRemoteScriptCode, toRemotePriv),
+	))
+
+	// Rebuilding the PSBT from the signed kit should yield the same
+	// transaction, now with all inputs finalized.
+	packet, err = kit.JusticePSBT(inputs, feeRate)
+	require.NoError(t, err)
+	require.Equal(t, unsignedTx.TxHash(), packet.UnsignedTx.TxHash())
+
+	require.NoError(t, psbt.MaybeFinalizeAll(packet))
+	require.True(t, packet.IsComplete())
+
+	justiceTx, err := psbt.Extract(packet)
+	require.NoError(t, err)
+	require.Len(t, justiceTx.TxOut, 1)
+	require.Equal(t, sweepAddr, justiceTx.TxOut[0].PkScript)
+
+	// The estimated weight should cover the final transaction, exceeding
+	// it by at most a few bytes per input, as the estimate assumes
+	// maximally sized signatures.
+	estWeight, err := kit.JusticeTxWeight(inputs)
+	require.NoError(t, err)
+
+	txWeight := blockchain.GetTransactionWeight(btcutil.NewTx(justiceTx))
+	require.GreaterOrEqual(t, estWeight, txWeight)
+	require.InDelta(t, txWeight, estWeight, float64(4*len(justiceTx.TxIn)))
+
+	hashCache = txscript.NewTxSigHashes(justiceTx, prevOutFetcher)
+	for i, txIn := range justiceTx.TxIn {
+		prevOut := prevOutFetcher.FetchPrevOutput(
+			txIn.PreviousOutPoint,
+		)
+
+		vm, err := txscript.NewEngine(
+			prevOut.PkScript, justiceTx, i,
+			txscript.StandardVerifyFlags, nil, hashCache,
+			prevOut.Value, prevOutFetcher,
+		)
+		require.NoError(t, err)
+		require.NoErrorf(t, vm.Execute(), "input %d", i)
+	}
+
+	// A mismatch between the kit's outputs and the given inputs should be
+	// rejected.
+	_, err = kit.JusticePSBT(blob.JusticeInputs{
+		CommitToLocal: inputs.CommitToLocal,
+	}, feeRate)
+	require.ErrorIs(t, err, blob.ErrJusticeInputMismatch)
+
+	_, err = kit.JusticeTxWeight(blob.JusticeInputs{
+		CommitToLocal: inputs.CommitToLocal,
+	})
+	require.ErrorIs(t, err, blob.ErrJusticeInputMismatch)
+
+	// A reward blob type additionally accounts for the tower's P2WKH
+	// reward output.
+	rewardKit := *kit
+	rewardKit.BlobType |= blob.Type(blob.FlagReward)
+
+	rewardWeight, err := rewardKit.JusticeTxWeight(inputs)
+	require.NoError(t, err)
+	require.Equal(
+		t, estWeight+4*input.P2WKHOutputSize, rewardWeight,
+	)
+}
+
+// testBreach bundles a kit with the breached outputs it sweeps and the keys
+// required to sign for them.
+type testBreach struct {
+	kit                *blob.JusticeKit
+	inputs             blob.JusticeInputs
+	revPriv            *btcec.PrivateKey
+	toRemotePriv       *btcec.PrivateKey
+	toLocalScript      []byte
+	toRemoteScriptCode []byte
+}
+
+// newTestBreach creates an unsigned kit of the given type along with its
+// breached to-local and to-remote outputs on the commitment with the given
+// txid.
+func newTestBreach(t *testing.T, blobType blob.Type,
+	breachTxID chainhash.Hash) *testBreach {
+
+	newPrivKey := func() *btcec.PrivateKey {
+		priv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return priv
+	}
+
+	var (
+		revPriv      = newPrivKey()
+		toLocalPriv  = newPrivKey()
+		toRemotePriv = newPrivKey()
+		sweepAddr    = make([]byte, 22)
+	)
+	sweepAddr[0], sweepAddr[1] = txscript.OP_0, txscript.OP_DATA_20
+
+	kit, err := blob.NewJusticeKitFromScripts(
+		blobType, blob.JusticeKitParams{
+			SweepAddress:     sweepAddr,
+			RevocationPubKey: revPriv.PubKey(),
+			LocalDelayPubKey: toLocalPriv.PubKey(),
+			CSVDelay:         144,
+			HasToRemote:      true,
+			ToRemotePubKey:   toRemotePriv.PubKey(),
+		},
+	)
+	require.NoError(t, err)
+
+	toLocalScript, err := kit.CommitToLocalWitnessScript()
+	require.NoError(t, err)
+	toLocalPkScript, err := input.WitnessScriptHash(toLocalScript)
+	require.NoError(t, err)
+
+	toRemoteScript, err := kit.CommitToRemoteWitnessScript()
+	require.NoError(t, err)
+
+	toRemotePkScript, err := input.CommitScriptUnencumbered(
+		toRemotePriv.PubKey(),
+	)
+	require.NoError(t, err)
+	toRemoteScriptCode := toRemotePkScript
+
+	if blobType.IsAnchorChannel() {
+		toRemotePkScript, err = input.WitnessScriptHash(toRemoteScript)
+		require.NoError(t, err)
+		toRemoteScriptCode = toRemoteScript
+	}
+
+	return &testBreach{
+		kit: kit,
+		inputs: blob.JusticeInputs{
+			CommitToLocal: &blob.JusticeInput{
+				OutPoint: wire.OutPoint{Hash: breachTxID},
+				Output: wire.NewTxOut(
+					200_000, toLocalPkScript,
+				),
+			},
+			CommitToRemote: &blob.JusticeInput{
+				OutPoint: wire.OutPoint{
+					Hash: breachTxID, Index: 1,
+				},
+				Output: wire.NewTxOut(
+					100_000, toRemotePkScript,
+				),
+			},
+		},
+		revPriv:            revPriv,
+		toRemotePriv:       toRemotePriv,
+		toLocalScript:      toLocalScript,
+		toRemoteScriptCode: toRemoteScriptCode,
+	}
+}
+
+// TestJusticeInputsFromBreach asserts that the JusticeInputs returned for a
+// BreachRetribution locate its commitment outputs, omitting those trimmed as
+// dust, and can be used to build the justice PSBT of the breach's kit.
+func TestJusticeInputsFromBreach(t *testing.T) {
+	breachTxID := chainhash.Hash{0x01}
+	tb := newTestBreach(t, blob.TypeAltruistAnchorCommit, breachTxID)
+
+	// Our output is the to-remote output of the breached commitment, while
+	// that of the breaching party is its to-local output.
+	breachInfo := &lnwallet.BreachRetribution{
+		BreachTxHash:   breachTxID,
+		LocalOutpoint:  tb.inputs.CommitToRemote.OutPoint,
+		RemoteOutpoint: tb.inputs.CommitToLocal.OutPoint,
+		LocalOutputSignDesc: &input.SignDescriptor{
+			Output: tb.inputs.CommitToRemote.Output,
+		},
+		RemoteOutputSignDesc: &input.SignDescriptor{
+			Output: tb.inputs.CommitToLocal.Output,
+		},
+		HtlcRetributions: []lnwallet.HtlcRetribution{{
+			OutPoint: wire.OutPoint{Hash: breachTxID, Index: 2},
+			SignDesc: input.SignDescriptor{
+				Output: wire.NewTxOut(50_000, []byte{0x00}),
+			},
+		}},
+	}
+
+	inputs, err := blob.JusticeInputsFromBreach(breachInfo)
+	require.NoError(t, err)
+	require.Equal(t, tb.inputs, inputs)
+
+	// The breached outputs shouldn't alias those of the breach info.
+	require.NotSame(t, tb.inputs.CommitToLocal.Output,
+		inputs.CommitToLocal.Output)
+
+	// The inputs can be used to build the justice PSBT as is.
+	_, err = tb.kit.JusticePSBT(inputs, chainfee.FeePerKwFloor)
+	require.NoError(t, err)
+
+	// An output trimmed as dust is left out.
+	remoteSignDesc := breachInfo.RemoteOutputSignDesc
+	breachInfo.RemoteOutputSignDesc = nil
+
+	inputs, err = blob.JusticeInputsFromBreach(breachInfo)
+	require.NoError(t, err)
+	require.Nil(t, inputs.CommitToLocal)
+	require.Equal(t, tb.inputs.CommitToRemote, inputs.CommitToRemote)
+
+	breachInfo.RemoteOutputSignDesc = remoteSignDesc
+	breachInfo.LocalOutputSignDesc = nil
+
+	inputs, err = blob.JusticeInputsFromBreach(breachInfo)
+	require.NoError(t, err)
+	require.Equal(t, tb.inputs.CommitToLocal, inputs.CommitToLocal)
+	require.Nil(t, inputs.CommitToRemote)
+
+	// Without any commitment outputs, there is nothing to sweep.
+	breachInfo.RemoteOutputSignDesc = nil
+
+	_, err = blob.JusticeInputsFromBreach(breachInfo)
+	require.ErrorIs(t, err, blob.ErrNoJusticeInputs)
+
+	// A sign descriptor without an output is rejected.
+	breachInfo.RemoteOutputSignDesc = &input.SignDescriptor{}
+
+	_, err = blob.JusticeInputsFromBreach(breachInfo)
+	require.Error(t, err)
+}
+
+// TestBuildBatchedJusticeTxn asserts that the breached outputs of several kits
+// can be swept by a single justice transaction, whose inputs all pass script
+// validation once the kits are signed for it.
+func TestBuildBatchedJusticeTxn(t *testing.T) {
+	breaches := []*testBreach{
+		newTestBreach(t, blob.TypeAltruistCommit, chainhash.Hash{0x01}),
+		newTestBreach(
+			t, blob.TypeAltruistAnchorCommit, chainhash.Hash{0x02},
+		),
+	}
+
+	var (
+		kits           []*blob.JusticeKit
+		inputs         []blob.JusticeInputs
+		prevOutFetcher = txscript.NewMultiPrevOutFetcher(nil)
+		totalAmt       int64
+	)
+	for _, breach := range breaches {
+		kits = append(kits, breach.kit)
+		inputs = append(inputs, breach.inputs)
+
+		for _, inp := range []*blob.JusticeInput{
+			breach.inputs.CommitToLocal,
+			breach.inputs.CommitToRemote,
+		} {
+			prevOutFetcher.AddPrevOut(inp.OutPoint, inp.Output)
+			totalAmt += inp.Output.Value
+		}
+	}
+
+	sweepAddr := make([]byte, 34)
+	sweepAddr[0], sweepAddr[1] = txscript.OP_1, txscript.OP_DATA_32
+
+	const feeRate = chainfee.SatPerKWeight(2500)
+
+	// Build the batched transaction before the kits are signed, leaving
+	// all inputs without a witness.
+	unsignedTx, err := blob.BuildBatchedJusticeTxn(
+		kits, inputs, sweepAddr, feeRate,
+	)
+	require.NoError(t, err)
+	require.Len(t, unsignedTx.TxIn, 4)
+	require.Len(t, unsignedTx.TxOut, 1)
+	require.Equal(t, sweepAddr, unsignedTx.TxOut[0].PkScript)
+	for _, txIn := range unsignedTx.TxIn {
+		require.Nil(t, txIn.Witness)
+	}
+
+	// The fee should be paid over the combined weight, which must be
+	// larger than that of the justice transaction of any single kit.
+	var combinedWeight int64
+	for i, kit := range kits {
+		weight, err := kit.JusticeTxWeight(inputs[i])
+		require.NoError(t, err)
+		combinedWeight += weight
+	}
+	fee := totalAmt - unsignedTx.TxOut[0].Value
+	require.Greater(t, fee, int64(0))
+	require.Less(t, fee, int64(feeRate.FeeForWeight(combinedWeight)))
+
+	hashCache := txscript.NewTxSigHashes(unsignedTx, prevOutFetcher)
+	sign := func(prevOut *blob.JusticeInput, script []byte,
+		priv *btcec.PrivateKey) lnwire.Sig {
+
+		idx := -1
+		for i, txIn := range unsignedTx.TxIn {
+			if txIn.PreviousOutPoint == prevOut.OutPoint {
+				idx = i
+			}
+		}
+		require.NotEqual(t, -1, idx)
+
+		rawSig, err := txscript.RawTxInWitnessSignature(
+			unsignedTx, hashCache, idx, prevOut.Output.Value,
+			script, txscript.SigHashAll, priv,
+		)
+		require.NoError(t, err)
+
+		sig, err := lnwire.NewSigFromECDSARawSignature(
+			rawSig[:len(rawSig)-1],
+		)
+		require.NoError(t, err)
+
+		return sig
+	}
+
+	for _, breach := range breaches {
+		require.NoError(t, breach.kit.AddToLocalSig(sign(
+			breach.inputs.CommitToLocal, breach.toLocalScript,
+			breach.revPriv,
+		)))
+		require.NoError(t, breach.kit.AddToRemoteSig(sign(
+			breach.inputs.CommitToRemote, breach.toRemoteScriptCode,
+			breach.toRemotePriv,
+		)))
+	}
+
+	// Rebuilding the transaction from the signed kits should yield the
+	// same transaction, now with every input carrying a witness.
+	justiceTx, err := blob.BuildBatchedJusticeTxn(
+		kits, inputs, sweepAddr, feeRate,
+	)
+	require.NoError(t, err)
+	require.Equal(t, unsignedTx.TxHash(), justiceTx.TxHash())
+
+	hashCache = txscript.NewTxSigHashes(justiceTx, prevOutFetcher)
+	for i, txIn := range justiceTx.TxIn {
+		prevOut := prevOutFetcher.FetchPrevOutput(
+			txIn.PreviousOutPoint,
+		)
+
+		vm, err := txscript.NewEngine(
+			prevOut.PkScript, justiceTx, i,
+			txscript.StandardVerifyFlags, nil, hashCache,
+			prevOut.Value, prevOutFetcher,
+		)
+		require.NoError(t, err)
+		require.NoErrorf(t, vm.Execute(), "input %d", i)
+	}
+
+	// Kits sweeping the same breached output can't be batched.
+	_, err = blob.BuildBatchedJusticeTxn(
+		[]*blob.JusticeKit{kits[0], kits[0]},
+		[]blob.JusticeInputs{inputs[0], inputs[0]}, sweepAddr, feeRate,
+	)
+	require.ErrorIs(t, err, blob.ErrDuplicateJusticeInput)
+
+	// Each kit must be given its breached outputs.
+	_, err = blob.BuildBatchedJusticeTxn(
+		kits, inputs[:1], sweepAddr, feeRate,
+	)
+	require.ErrorIs(t, err, blob.ErrJusticeInputMismatch)
+
+	_, err = blob.BuildBatchedJusticeTxn(
+		kits, []blob.JusticeInputs{inputs[1], inputs[0]}, sweepAddr,
+		feeRate,
+	)
+	require.ErrorIs(t, err, blob.ErrOutputScriptMismatch)
+
+	_, err = blob.BuildBatchedJusticeTxn(
+		kits, inputs, []byte{txscript.OP_RETURN}, feeRate,
+	)
+	require.ErrorIs(t, err, blob.ErrUnknownSweepAddrType)
+}
+
+// TestFilterEconomicalInputs asserts that breached outputs below the dust
+// limit, or worth less than the fee of sweeping them, are left unswept, while
+// the justice transaction can still be built from the remaining ones.
+func TestFilterEconomicalInputs(t *testing.T) {
+	const dustLimit = btcutil.Amount(330)
+
+	tests := []struct {
+		name          string
+		toRemoteValue int64
+		feeRate       chainfee.SatPerKWeight
+		expToRemote   bool
+	}{
+		{
+			name:          "economical",
+			toRemoteValue: 2_000,
+			feeRate:       1_000,
+			expToRemote:   true,
+		},
+		{
+			name:          "dust",
+			toRemoteValue: 300,
+			feeRate:       253,
+			expToRemote:   false,
+		},
+		{
+			name:          "below fee",
+			toRemoteValue: 2_000,
+			feeRate:       10_000,
+			expToRemote:   false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			breach := newTestBreach(
+				t, blob.TypeAltruistCommit,
+				chainhash.Hash{0x01},
+			)
+			inputs := breach.inputs
+			inputs.CommitToRemote.Output.Value = test.toRemoteValue
+
+			filtered, err := breach.kit.FilterEconomicalInputs(
+				inputs, test.feeRate, dustLimit,
+			)
+			require.NoError(t, err)
+
+			// The to-local output is always worth sweeping.
+			require.Equal(
+				t, inputs.CommitToLocal, filtered.CommitToLocal,
+			)
+
+			// The to-remote output is kept paired with the kit,
+			// even when left unswept.
+			require.NotNil(t, filtered.CommitToRemote)
+			require.Equal(
+				t, inputs.CommitToRemote.OutPoint,
+				filtered.CommitToRemote.OutPoint,
+			)
+
+			expNumInputs := 1
+			if test.expToRemote {
+				require.Equal(
+					t, inputs.CommitToRemote,
+					filtered.CommitToRemote,
+				)
+				expNumInputs++
+			} else {
+				require.Nil(t, filtered.CommitToRemote.Output)
+			}
+
+			packet, err := breach.kit.JusticePSBT(
+				filtered, test.feeRate,
+			)
+			require.NoError(t, err)
+			require.Len(t, packet.UnsignedTx.TxIn, expNumInputs)
+		})
+	}
+
+	// If none of the breached outputs are worth sweeping, the filter
+	// should fail.
+	breach := newTestBreach(t, blob.TypeAltruistCommit, chainhash.Hash{})
+	_, err := breach.kit.FilterEconomicalInputs(
+		breach.inputs, chainfee.SatPerKWeight(1_000_000), dustLimit,
+	)
+	require.ErrorIs(t, err, blob.ErrNoJusticeInputs)
+}
+
+// TestBreakEvenFeeRate asserts that the break-even fee rate of a justice
+// transaction matches a hand calculation, and that a justice transaction can
+// be built at, but not above, the break-even fee rate.
+func TestBreakEvenFeeRate(t *testing.T) {
+	breach := newTestBreach(
+		t, blob.TypeAltruistCommit, chainhash.Hash{0x01},
+	)
+
+	// The justice transaction spends the 200,000 sat to-local and 100,000
+	// sat to-remote outputs into a single p2wkh sweep output:
+	//
+	//	stripped size: 8 (version, locktime) + 1 (input count) +
+	//	               2 * 41 (inputs) + 1 (output count) +
+	//	               31 (sweep output) = 123 bytes
+	//	witness:       2 (marker, flag) + 157 (to-local penalty) +
+	//	               109 (to-remote p2wkh) = 268 bytes
+	//	weight:        4 * 123 + 268 = 760 wu
+	//
+	// At the break-even rate, the fee consumes all 300,000 sats:
+	// 300,000 * 1000 / 760 = 394,736.8 sat/kw, rounded down.
+	weight, err := breach.kit.JusticeTxWeight(breach.inputs)
+	require.NoError(t, err)
+	require.EqualValues(t, 760, weight)
+
+	feeRate, err := breach.kit.BreakEvenFeeRate(breach.inputs)
+	require.NoError(t, err)
+	require.Equal(t, chainfee.SatPerKWeight(394_736), feeRate)
+
+	// A justice transaction can still be built at the break-even fee
+	// rate, though it recovers next to nothing, while any higher rate
+	// costs more than it recovers.
+	_, err = breach.kit.JusticePSBT(breach.inputs, feeRate)
+	require.NoError(t, err)
+
+	_, err = breach.kit.JusticePSBT(breach.inputs, feeRate+1)
+	require.ErrorIs(t, err, blob.ErrJusticeFeeExceedsInputs)
+
+	// A reward blob type additionally pays for the tower's 31-byte p2wkh
+	// reward output: 300,000 * 1000 / (760 + 4 * 31) = 339,366.5 sat/kw.
+	rewardKit := *breach.kit
+	rewardKit.BlobType |= blob.Type(blob.FlagReward)
+
+	feeRate, err = rewardKit.BreakEvenFeeRate(breach.inputs)
+	require.NoError(t, err)
+	require.Equal(t, chainfee.SatPerKWeight(339_366), feeRate)
+
+	// Unswept outputs don't contribute to the value recovered, nor to the
+	// weight of the transaction: 200,000 * 1000 / (760 - 4 * 41 - 109) =
+	// 410,677.6 sat/kw.
+	inputs := breach.inputs
+	inputs.CommitToRemote = &blob.JusticeInput{
+		OutPoint: inputs.CommitToRemote.OutPoint,
+	}
+
+	feeRate, err = breach.kit.BreakEvenFeeRate(inputs)
+	require.NoError(t, err)
+	require.Equal(t, chainfee.SatPerKWeight(410_677), feeRate)
+}
+
+// TestPreviewRecovery asserts that the swept amount, fee and reward previewed
+// for a justice transaction match a hand calculation, and always add up to the
+// total value of the breached outputs.
+func TestPreviewRecovery(t *testing.T) {
+	breach := newTestBreach(
+		t, blob.TypeAltruistCommit, chainhash.Hash{0x01},
+	)
+
+	const (
+		totalAmt = btcutil.Amount(300_000)
+		feeRate  = chainfee.SatPerKWeight(1000)
+	)
+	reward := blob.RewardParams{Base: 1000, Rate: 10_000}
+
+	// As computed in TestBreakEvenFeeRate, the altruist justice
+	// transaction weighs 760 wu, and pays no reward regardless of the
+	// reward parameters.
+	swept, fee, rewardAmt, err := breach.kit.PreviewRecovery(
+		breach.inputs, feeRate, reward,
+	)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(760), fee)
+	require.Zero(t, rewardAmt)
+	require.Equal(t, totalAmt, swept+fee+rewardAmt)
+
+	// The swept amount should match the sweep output of the justice
+	// transaction built at the same fee rate.
+	packet, err := breach.kit.JusticePSBT(breach.inputs, feeRate)
+	require.NoError(t, err)
+	require.Len(t, packet.UnsignedTx.TxOut, 1)
+	require.EqualValues(t, swept, packet.UnsignedTx.TxOut[0].Value)
+
+	// A reward blob type additionally pays for the tower's 31-byte p2wkh
+	// reward output, and rewards the tower 1,000 sats plus 1% of the
+	// remaining 299,000 sats, matching the tower's policy.
+	rewardKit := *breach.kit
+	rewardKit.BlobType |= blob.Type(blob.FlagReward)
+
+	swept, fee, rewardAmt, err = rewardKit.PreviewRecovery(
+		breach.inputs, feeRate, reward,
+	)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(760+4*31), fee)
+	require.Equal(t, btcutil.Amount(3990), rewardAmt)
+	require.Equal(t, wtpolicy.ComputeRewardAmount(
+		totalAmt, reward.Base, reward.Rate,
+	), rewardAmt)
+	require.Equal(t, totalAmt, swept+fee+rewardAmt)
+
+	// Unswept outputs don't contribute to the value recovered.
+	inputs := breach.inputs
+	inputs.CommitToRemote = &blob.JusticeInput{
+		OutPoint: inputs.CommitToRemote.OutPoint,
+	}
+
+	swept, fee, rewardAmt, err = breach.kit.PreviewRecovery(
+		inputs, feeRate, reward,
+	)
+	require.NoError(t, err)
+	require.Equal(t, btcutil.Amount(200_000), swept+fee+rewardAmt)
+
+	// Recovery fails if the outputs can't pay for the fee, or for the fee
+	// and the tower's reward.
+	_, _, _, err = breach.kit.PreviewRecovery(
+		breach.inputs, 400_000, reward,
+	)
+	require.ErrorIs(t, err, blob.ErrJusticeFeeExceedsInputs)
+
+	_, _, _, err = rewardKit.PreviewRecovery(
+		breach.inputs, feeRate, blob.RewardParams{Base: 300_000},
+	)
+	require.ErrorIs(t, err, blob.ErrJusticeRewardExceedsInputs)
+}
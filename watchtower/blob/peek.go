@@ -0,0 +1,72 @@
+package blob
+
+import (
+	"io"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+)
+
+const (
+	// v0CSVDelayOffset is the offset of the csv delay within a version 0
+	// plaintext, following the sweep address and the two pubkeys.
+	v0CSVDelayOffset = 1 + MaxSweepAddrSize + 33 + 33
+
+	// v0ToRemotePubKeyOffset is the offset of the commit to-remote pubkey
+	// within a version 0 plaintext, following the csv delay and the commit
+	// to-local signature.
+	v0ToRemotePubKeyOffset = v0CSVDelayOffset + 4 + 64
+)
+
+// BlobSummary holds the fields of a decrypted blob that can be read without
+// reconstructing the full JusticeKit.
+type BlobSummary struct {
+	// HasCommitToRemote is true if the blob carries a commit to-remote
+	// output.
+	HasCommitToRemote bool
+
+	// CSVDelay is the relative timelock of the commit to-local output.
+	CSVDelay uint32
+
+	// SweepAddrLen is the length of the sweep address in bytes.
+	SweepAddrLen int
+}
+
+// PeekBlob decrypts the ciphertext of a blob of the given type and returns a
+// summary of its header fields, without decoding the pubkeys and signatures
+// of the full JusticeKit. This is cheaper than Decrypt for callers that only
+// need to inspect the blob, but offers the same authentication guarantees,
+// and returns the same summary as would be derived from a successful Decrypt.
+func PeekBlob(key BreachKey, ctxt []byte, version Type) (BlobSummary, error) {
+	if !version.Has(FlagCommitOutputs) {
+		return BlobSummary{}, ErrUnknownBlobType
+	}
+
+	ptxtBuf := getPlaintextBuf(len(ctxt) - Overhead)
+	plaintext, err := decryptPlaintext(ptxtBuf.Bytes(), key, ctxt, version)
+	defer putPlaintextBuf(ptxtBuf, plaintext)
+	if err != nil {
+		return BlobSummary{}, err
+	}
+
+	if len(plaintext) < V0PlaintextSize {
+		return BlobSummary{}, io.ErrUnexpectedEOF
+	}
+
+	sweepAddrLen := plaintext[0]
+	if sweepAddrLen > MaxSweepAddrSize {
+		return BlobSummary{}, ErrSweepAddressToLong
+	}
+
+	var (
+		csvDelay       = plaintext[v0CSVDelayOffset:]
+		toRemotePubKey = plaintext[v0ToRemotePubKeyOffset:]
+	)
+
+	return BlobSummary{
+		HasCommitToRemote: btcec.IsCompressedPubKey(
+			toRemotePubKey[:33],
+		),
+		CSVDelay:     byteOrder.Uint32(csvDelay[:4]),
+		SweepAddrLen: int(sweepAddrLen),
+	}, nil
+}
@@ -0,0 +1,117 @@
+package blob_test
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/lightningnetwork/lnd/watchtower/blob"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPeekBlob asserts that the summary returned by PeekBlob matches the
+// corresponding fields of the fully decrypted JusticeKit.
+func TestPeekBlob(t *testing.T) {
+	for _, test := range descriptorTests {
+		if test.encErr != nil || test.decErr != nil {
+			continue
+		}
+
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kit := &blob.JusticeKit{
+				BlobType:             test.encVersion,
+				SweepAddress:         test.sweepAddr,
+				RevocationPubKey:     test.revPubKey,
+				LocalDelayPubKey:     test.delayPubKey,
+				CSVDelay:             test.csvDelay,
+				CommitToLocalSig:     test.commitToLocalSig,
+				CommitToRemotePubKey: test.commitToRemotePubKey,
+				CommitToRemoteSig:    test.commitToRemoteSig,
+			}
+
+			var key blob.BreachKey
+			_, err := rand.Read(key[:])
+			require.NoError(t, err)
+
+			ctxt, err := kit.Encrypt(key)
+			require.NoError(t, err)
+
+			summary, err := blob.PeekBlob(key, ctxt, test.decVersion)
+			require.NoError(t, err)
+
+			decKit, err := blob.Decrypt(key, ctxt, test.decVersion)
+			require.NoError(t, err)
+
+			require.Equal(t, blob.BlobSummary{
+				HasCommitToRemote: decKit.HasCommitToRemoteOutput(),
+				CSVDelay:          decKit.CSVDelay,
+				SweepAddrLen:      len(decKit.SweepAddress),
+			}, summary)
+		})
+	}
+
+	// Peeking with the wrong key should fail authentication, just as
+	// Decrypt would.
+	kit := &blob.JusticeKit{
+		BlobType:         blob.TypeAltruistCommit,
+		SweepAddress:     makeAddr(22),
+		RevocationPubKey: makePubKey(0),
+		LocalDelayPubKey: makePubKey(1),
+		CSVDelay:         144,
+		CommitToLocalSig: makeSig(1),
+	}
+
+	var key, wrongKey blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+	_, err = rand.Read(wrongKey[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+
+	_, err = blob.PeekBlob(wrongKey, ctxt, blob.TypeAltruistCommit)
+	require.Error(t, err)
+}
+
+// BenchmarkPeekBlob measures the cost of summarizing a blob with PeekBlob, for
+// which BenchmarkDecrypt/altruist-commit/to-remote provides a baseline.
+func BenchmarkPeekBlob(b *testing.B) {
+	key, ctxt := benchmarkBlob(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := blob.PeekBlob(key, ctxt, blob.TypeAltruistCommit)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkBlob returns an encrypted blob with both commitment outputs, along
+// with the key needed to decrypt it.
+func benchmarkBlob(b *testing.B) (blob.BreachKey, []byte) {
+	kit := &blob.JusticeKit{
+		BlobType:             blob.TypeAltruistCommit,
+		SweepAddress:         makeAddr(22),
+		RevocationPubKey:     makePubKey(0),
+		LocalDelayPubKey:     makePubKey(1),
+		CSVDelay:             144,
+		CommitToLocalSig:     makeSig(1),
+		CommitToRemotePubKey: makePubKey(2),
+		CommitToRemoteSig:    makeSig(2),
+	}
+
+	var key blob.BreachKey
+	if _, err := rand.Read(key[:]); err != nil {
+		b.Fatal(err)
+	}
+
+	ctxt, err := kit.Encrypt(key)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return key, ctxt
+}
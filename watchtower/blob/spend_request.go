@@ -0,0 +1,180 @@
+package blob
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ErrInvalidInputMapping is returned by ApplyWitnesses when the spend requests
+// can't be matched to the inputs of the justice transaction, as the number of
+// requests and input indices differ, or an index is out of range or repeated.
+var ErrInvalidInputMapping = errors.New("invalid spend request input mapping")
+
+// SpendKind identifies which breached output a SpendRequest sweeps.
+type SpendKind uint8
+
+const (
+	// SpendCommitToLocal denotes the commitment to-local output, spent via
+	// its revocation clause.
+	SpendCommitToLocal SpendKind = iota
+
+	// SpendCommitToRemote denotes the commitment to-remote output.
+	SpendCommitToRemote
+
+	// SpendSecondLevelHtlc denotes a second-level HTLC output, spent via
+	// its revocation clause.
+	SpendSecondLevelHtlc
+)
+
+// String returns a human-readable name for the SpendKind.
+func (k SpendKind) String() string {
+	switch k {
+	case SpendCommitToLocal:
+		return "commit to-local"
+	case SpendCommitToRemote:
+		return "commit to-remote"
+	case SpendSecondLevelHtlc:
+		return "second-level htlc"
+	default:
+		return "unknown"
+	}
+}
+
+// SpendRequest bundles everything the JusticeKit knows about spending one of
+// the breached outputs. Callers are expected to locate the corresponding
+// outpoint and attach the witness to the input spending it.
+type SpendRequest struct {
+	// Kind identifies the breached output being spent.
+	Kind SpendKind
+
+	// Index is the position of the signature within SecondLevelHtlcSigs
+	// for SpendSecondLevelHtlc requests, and zero otherwise.
+	Index int
+
+	// WitnessScript is the witness script of the breached output. For
+	// legacy to-remote outputs this is the serialized p2wkh pubkey, and
+	// for taproot to-remote outputs it is the tapscript leaf.
+	WitnessScript []byte
+
+	// WitnessStack is the witness stack satisfying WitnessScript, which
+	// excludes the witness script itself and, for taproot outputs, the
+	// control block.
+	WitnessStack [][]byte
+
+	// Sequence is the sequence number the spending input must carry. This
+	// is non-zero only for outputs encumbered by a relative locktime, such
+	// as the CSV delay of 1 on anchor and taproot to-remote outputs. The
+	// revocation clauses of the to-local and second-level HTLC outputs are
+	// not subject to their CSV delay.
+	Sequence uint32
+}
+
+// SpendRequests returns a SpendRequest for every output the JusticeKit is able
+// to sweep: the commitment to-local output, the commitment to-remote output if
+// present, followed by each second-level HTLC output in order. The requests
+// are equivalent to those assembled from the individual witness accessors.
+func (b *JusticeKit) SpendRequests() ([]SpendRequest, error) {
+	toLocalScript, err := b.CommitToLocalWitnessScript()
+	if err != nil {
+		return nil, err
+	}
+
+	toLocalStack, err := b.CommitToLocalRevokeWitnessStack()
+	if err != nil {
+		return nil, err
+	}
+
+	reqs := []SpendRequest{{
+		Kind:          SpendCommitToLocal,
+		WitnessScript: toLocalScript,
+		WitnessStack:  toLocalStack,
+	}}
+
+	if b.HasCommitToRemoteOutput() {
+		toRemoteScript, err := b.CommitToRemoteWitnessScript()
+		if err != nil {
+			return nil, err
+		}
+
+		toRemoteStack, err := b.CommitToRemoteWitnessStack()
+		if err != nil {
+			return nil, err
+		}
+
+		// Anchor and taproot to-remote outputs can only be spent
+		// after a CSV delay of 1.
+		_, sequence := b.InputSequences()
+
+		reqs = append(reqs, SpendRequest{
+			Kind:          SpendCommitToRemote,
+			WitnessScript: toRemoteScript,
+			WitnessStack:  toRemoteStack,
+			Sequence:      sequence,
+		})
+	}
+
+	for i := range b.SecondLevelHtlcSigs {
+		script, stack, err := b.SecondLevelHtlcSpendInfo(i)
+		if err != nil {
+			return nil, err
+		}
+
+		reqs = append(reqs, SpendRequest{
+			Kind:          SpendSecondLevelHtlc,
+			Index:         i,
+			WitnessScript: script,
+			WitnessStack:  stack,
+		})
+	}
+
+	return reqs, nil
+}
+
+// ApplyWitnesses attaches the witness and sequence number of each spend
+// request to the input of tx spending the corresponding breached output, where
+// the i-th request is applied to the input at inputIndices[i]. The final
+// witness of each input consists of the request's witness stack followed by
+// its witness script. The mapping is validated in full before any input is
+// modified, such that tx is left untouched if ErrInvalidInputMapping is
+// returned.
+//
+// NOTE: As the sequence numbers are part of the signed transaction, they
+// should already match those of the requests when the kit is signed.
+func ApplyWitnesses(tx *wire.MsgTx, requests []SpendRequest,
+	inputIndices []int) error {
+
+	if len(requests) != len(inputIndices) {
+		return fmt.Errorf("%w: %d spend requests for %d inputs",
+			ErrInvalidInputMapping, len(requests),
+			len(inputIndices))
+	}
+
+	seen := make(map[int]struct{}, len(inputIndices))
+	for _, idx := range inputIndices {
+		if idx < 0 || idx >= len(tx.TxIn) {
+			return fmt.Errorf("%w: input index %d out of range "+
+				"for %d inputs", ErrInvalidInputMapping, idx,
+				len(tx.TxIn))
+		}
+
+		if _, ok := seen[idx]; ok {
+			return fmt.Errorf("%w: duplicate input index %d",
+				ErrInvalidInputMapping, idx)
+		}
+		seen[idx] = struct{}{}
+	}
+
+	for i, req := range requests {
+		witness := make(wire.TxWitness, 0, len(req.WitnessStack)+1)
+		witness = append(witness, req.WitnessStack...)
+		witness = append(witness, req.WitnessScript)
+
+		txIn := tx.TxIn[inputIndices[i]]
+		txIn.Witness = witness
+		txIn.Sequence = req.Sequence
+	}
+
+	return nil
+}
@@ -0,0 +1,261 @@
+package blob_test
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/watchtower/blob"
+	"github.com/stretchr/testify/require"
+)
+
+// TestJusticeKitSpendRequests asserts that the spend requests returned by a
+// JusticeKit match the output of the individual witness accessors.
+func TestJusticeKitSpendRequests(t *testing.T) {
+	newPubKey := func() blob.PubKey {
+		priv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		return toBlobPubKey(priv.PubKey())
+	}
+
+	newSig := func() lnwire.Sig {
+		priv, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+
+		sig, err := lnwire.NewSigFromSignature(
+			ecdsa.Sign(priv, make([]byte, 32)),
+		)
+		require.NoError(t, err)
+
+		return sig
+	}
+
+	tests := []struct {
+		name        string
+		blobType    blob.Type
+		hasToRemote bool
+		numHtlcs    int
+		expSequence uint32
+	}{
+		{
+			name:     "legacy to-local only",
+			blobType: blob.TypeAltruistCommit,
+		},
+		{
+			name:        "legacy to-local and to-remote",
+			blobType:    blob.TypeAltruistCommit,
+			hasToRemote: true,
+		},
+		{
+			name:        "anchor to-local and to-remote",
+			blobType:    blob.TypeAltruistAnchorCommit,
+			hasToRemote: true,
+			expSequence: 1,
+		},
+		{
+			name: "anchor with second-level htlcs",
+			blobType: blob.TypeFromFlags(
+				blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+				blob.FlagSecondLevelHtlcs,
+			),
+			hasToRemote: true,
+			numHtlcs:    3,
+			expSequence: 1,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kit := &blob.JusticeKit{
+				BlobType:         test.blobType,
+				SweepAddress:     makeAddr(22),
+				RevocationPubKey: newPubKey(),
+				LocalDelayPubKey: newPubKey(),
+				CSVDelay:         144,
+				CommitToLocalSig: newSig(),
+			}
+			if test.hasToRemote {
+				kit.CommitToRemotePubKey = newPubKey()
+				kit.CommitToRemoteSig = newSig()
+			}
+			for i := 0; i < test.numHtlcs; i++ {
+				err := kit.AddSecondLevelHtlcSig(newSig())
+				require.NoError(t, err)
+			}
+
+			reqs, err := kit.SpendRequests()
+			require.NoError(t, err)
+
+			expNumReqs := 1 + test.numHtlcs
+			if test.hasToRemote {
+				expNumReqs++
+			}
+			require.Len(t, reqs, expNumReqs)
+
+			// The first request always spends the to-local output.
+			toLocalScript, err := kit.CommitToLocalWitnessScript()
+			require.NoError(t, err)
+			toLocalStack, err := kit.CommitToLocalRevokeWitnessStack()
+			require.NoError(t, err)
+
+			require.Equal(t, blob.SpendRequest{
+				Kind:          blob.SpendCommitToLocal,
+				WitnessScript: toLocalScript,
+				WitnessStack:  toLocalStack,
+			}, reqs[0])
+			reqs = reqs[1:]
+
+			if test.hasToRemote {
+				toRemoteScript, err :=
+					kit.CommitToRemoteWitnessScript()
+				require.NoError(t, err)
+				toRemoteStack, err :=
+					kit.CommitToRemoteWitnessStack()
+				require.NoError(t, err)
+
+				require.Equal(t, blob.SpendRequest{
+					Kind:          blob.SpendCommitToRemote,
+					WitnessScript: toRemoteScript,
+					WitnessStack:  toRemoteStack,
+					Sequence:      test.expSequence,
+				}, reqs[0])
+				reqs = reqs[1:]
+			}
+
+			for i, req := range reqs {
+				script, stack, err :=
+					kit.SecondLevelHtlcSpendInfo(i)
+				require.NoError(t, err)
+
+				require.Equal(t, blob.SpendRequest{
+					Kind:          blob.SpendSecondLevelHtlc,
+					Index:         i,
+					WitnessScript: script,
+					WitnessStack:  stack,
+				}, req)
+			}
+		})
+	}
+}
+
+// TestApplyWitnesses asserts that the witnesses of a signed kit's spend
+// requests can be attached to a two-input justice transaction, whose inputs
+// then pass script validation, and that invalid input mappings are rejected
+// without modifying the transaction.
+func TestApplyWitnesses(t *testing.T) {
+	breach := newTestBreach(
+		t, blob.TypeAltruistAnchorCommit, chainhash.Hash{0x01},
+	)
+	kit, inputs := breach.kit, breach.inputs
+
+	packet, err := kit.JusticePSBT(inputs, chainfee.SatPerKWeight(2500))
+	require.NoError(t, err)
+	justiceTx := packet.UnsignedTx
+	require.Len(t, justiceTx.TxIn, 2)
+
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	inputIndex := make(map[blob.SpendKind]int)
+	for i, txIn := range justiceTx.TxIn {
+		switch txIn.PreviousOutPoint {
+		case inputs.CommitToLocal.OutPoint:
+			inputIndex[blob.SpendCommitToLocal] = i
+			prevOutFetcher.AddPrevOut(
+				txIn.PreviousOutPoint,
+				inputs.CommitToLocal.Output,
+			)
+
+		case inputs.CommitToRemote.OutPoint:
+			inputIndex[blob.SpendCommitToRemote] = i
+			prevOutFetcher.AddPrevOut(
+				txIn.PreviousOutPoint,
+				inputs.CommitToRemote.Output,
+			)
+		}
+	}
+	require.Len(t, inputIndex, 2)
+
+	hashCache := txscript.NewTxSigHashes(justiceTx, prevOutFetcher)
+	sign := func(kind blob.SpendKind, script []byte,
+		priv *btcec.PrivateKey) lnwire.Sig {
+
+		idx := inputIndex[kind]
+		prevOut := prevOutFetcher.FetchPrevOutput(
+			justiceTx.TxIn[idx].PreviousOutPoint,
+		)
+
+		rawSig, err := txscript.RawTxInWitnessSignature(
+			justiceTx, hashCache, idx, prevOut.Value, script,
+			txscript.SigHashAll, priv,
+		)
+		require.NoError(t, err)
+
+		sig, err := lnwire.NewSigFromECDSARawSignature(
+			rawSig[:len(rawSig)-1],
+		)
+		require.NoError(t, err)
+
+		return sig
+	}
+
+	require.NoError(t, kit.AddToLocalSig(sign(
+		blob.SpendCommitToLocal, breach.toLocalScript, breach.revPriv,
+	)))
+	require.NoError(t, kit.AddToRemoteSig(sign(
+		blob.SpendCommitToRemote, breach.toRemoteScriptCode,
+		breach.toRemotePriv,
+	)))
+
+	reqs, err := kit.SpendRequests()
+	require.NoError(t, err)
+	require.Len(t, reqs, 2)
+
+	indices := make([]int, 0, len(reqs))
+	for _, req := range reqs {
+		indices = append(indices, inputIndex[req.Kind])
+	}
+
+	// Invalid mappings should be rejected before any input is modified.
+	invalidMappings := [][]int{
+		indices[:1],
+		{indices[0], indices[0]},
+		{indices[0], len(justiceTx.TxIn)},
+		{indices[0], -1},
+	}
+	for _, mapping := range invalidMappings {
+		err := blob.ApplyWitnesses(justiceTx, reqs, mapping)
+		require.ErrorIs(t, err, blob.ErrInvalidInputMapping)
+
+		for _, txIn := range justiceTx.TxIn {
+			require.Nil(t, txIn.Witness)
+		}
+	}
+
+	require.NoError(t, blob.ApplyWitnesses(justiceTx, reqs, indices))
+
+	// Each input should now carry its request's sequence number and a
+	// witness that satisfies the script of the breached output.
+	for i, req := range reqs {
+		txIn := justiceTx.TxIn[indices[i]]
+		require.Equal(t, req.Sequence, txIn.Sequence)
+	}
+
+	for i, txIn := range justiceTx.TxIn {
+		prevOut := prevOutFetcher.FetchPrevOutput(
+			txIn.PreviousOutPoint,
+		)
+
+		vm, err := txscript.NewEngine(
+			prevOut.PkScript, justiceTx, i,
+			txscript.StandardVerifyFlags, nil, hashCache,
+			prevOut.Value, prevOutFetcher,
+		)
+		require.NoError(t, err)
+		require.NoErrorf(t, vm.Execute(), "input %d", i)
+	}
+}
@@ -0,0 +1,273 @@
+package blob
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/lightningnetwork/lnd/tlv"
+)
+
+const (
+	// MaxTLVTrailerSize is the maximum size of the TLV stream that can be
+	// carried by a blob with FlagTLVTrailer.
+	MaxTLVTrailerSize = 62
+
+	// TLVTrailerSize is the size of the section appended to the plaintext
+	// of blobs with FlagTLVTrailer.
+	//    tlv stream length:               2 bytes
+	//    padded tlv stream:              62 bytes
+	TLVTrailerSize = 2 + MaxTLVTrailerSize
+)
+
+// The TLV types of the trailer fields known to this version. Both are even, as
+// a tower that can't honor the recorded sighash types would otherwise sweep
+// using witnesses that fail to validate.
+const (
+	// trailerTypeToLocalSigHash is the 1-byte sighash type of the commit
+	// to-local signature, present if it isn't the default for the type.
+	trailerTypeToLocalSigHash tlv.Type = 0
+
+	// trailerTypeToRemoteSigHash is the 1-byte sighash type of the commit
+	// to-remote signature, present if it isn't the default for the type.
+	trailerTypeToRemoteSigHash tlv.Type = 2
+)
+
+var (
+	// ErrTLVTrailerTooLong is returned when trying to encode or decode a
+	// TLV trailer with length greater than MaxTLVTrailerSize.
+	ErrTLVTrailerTooLong = fmt.Errorf(
+		"tlv trailer must be less than or equal to %d bytes",
+		MaxTLVTrailerSize,
+	)
+
+	// ErrUnknownRequiredTrailerType is returned when decoding a TLV trailer
+	// containing a record of an unknown even type, which signals a field
+	// that the decoder must understand to make use of the blob.
+	ErrUnknownRequiredTrailerType = errors.New(
+		"unknown required tlv trailer type",
+	)
+
+	// ErrReservedTrailerType is returned when encoding a TLV trailer whose
+	// TrailerRecords hold a record of a type reserved for a field known
+	// to this version.
+	ErrReservedTrailerType = errors.New("reserved tlv trailer type")
+)
+
+// encodeTLVTrailer encodes the TrailerRecords of the JusticeKit as a TLV stream
+// to the provided io.Writer. The stream is padded to MaxTLVTrailerSize, such
+// that the encoding has a constant size of 64 bytes.
+//
+// tlv trailer encoding:
+//
+//	tlv stream length:               2 bytes
+//	padded tlv stream:              62 bytes
+func (b *JusticeKit) encodeTLVTrailer(w io.Writer) error {
+	stream, err := b.serializeTLVTrailer()
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(w, byteOrder, uint16(len(stream)))
+	if err != nil {
+		return err
+	}
+
+	var trailerBuf [MaxTLVTrailerSize]byte
+	copy(trailerBuf[:], stream)
+
+	_, err = w.Write(trailerBuf[:])
+
+	return err
+}
+
+// decodeTLVTrailer reconstructs the TrailerRecords of the JusticeKit from the
+// io.Reader, using the encoding described in encodeTLVTrailer.
+func (b *JusticeKit) decodeTLVTrailer(r io.Reader) error {
+	var streamLen uint16
+	err := binary.Read(r, byteOrder, &streamLen)
+	if err != nil {
+		return err
+	}
+
+	if streamLen > MaxTLVTrailerSize {
+		return malformedBlob(ErrTLVTrailerTooLong)
+	}
+
+	var trailerBuf [MaxTLVTrailerSize]byte
+	_, err = io.ReadFull(r, trailerBuf[:])
+	if err != nil {
+		return err
+	}
+
+	if err := checkPadding(trailerBuf[streamLen:]); err != nil {
+		return err
+	}
+
+	return b.deserializeTLVTrailer(trailerBuf[:streamLen])
+}
+
+// serializeTLVTrailer returns the unpadded TLV stream of the kit's known
+// trailer fields and TrailerRecords, failing if it exceeds MaxTLVTrailerSize.
+func (b *JusticeKit) serializeTLVTrailer() ([]byte, error) {
+	var (
+		records       []tlv.Record
+		toLocalHash   = uint8(b.toLocalSigHash)
+		toRemoteHash  = uint8(b.toRemoteSigHash)
+		reservedTypes = []tlv.Type{
+			trailerTypeToLocalSigHash, trailerTypeToRemoteSigHash,
+		}
+	)
+	if b.toLocalSigHash != 0 {
+		records = append(records, tlv.MakePrimitiveRecord(
+			trailerTypeToLocalSigHash, &toLocalHash,
+		))
+	}
+	if b.toRemoteSigHash != 0 {
+		records = append(records, tlv.MakePrimitiveRecord(
+			trailerTypeToRemoteSigHash, &toRemoteHash,
+		))
+	}
+
+	for _, typ := range reservedTypes {
+		if _, ok := b.TrailerRecords[uint64(typ)]; ok {
+			return nil, fmt.Errorf("%w: %d", ErrReservedTrailerType,
+				typ)
+		}
+	}
+
+	records = append(records, tlv.MapToRecords(b.TrailerRecords)...)
+	tlv.SortRecords(records)
+
+	stream, err := tlv.NewStream(records...)
+	if err != nil {
+		return nil, err
+	}
+
+	var streamBuf bytes.Buffer
+	if err := stream.Encode(&streamBuf); err != nil {
+		return nil, err
+	}
+
+	if streamBuf.Len() > MaxTLVTrailerSize {
+		return nil, ErrTLVTrailerTooLong
+	}
+
+	return streamBuf.Bytes(), nil
+}
+
+// checkTLVRecordLengths walks the records of an unpadded TLV stream, returning
+// an error matching ErrMalformedBlob if a record is truncated or claims a
+// length exceeding the bytes left in the stream. This must be done before
+// decoding the stream, as the decoder allocates a buffer of the claimed length
+// for each unknown record before reading it, which a crafted trailer could
+// otherwise use to trigger an arbitrarily large allocation.
+func checkTLVRecordLengths(stream []byte) error {
+	var (
+		buf [8]byte
+		r   = bytes.NewReader(stream)
+	)
+	for r.Len() > 0 {
+		if _, err := tlv.ReadVarInt(r, &buf); err != nil {
+			return malformedBlob(fmt.Errorf("tlv record type: %w",
+				err))
+		}
+
+		length, err := tlv.ReadVarInt(r, &buf)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return malformedBlob(fmt.Errorf("tlv record length: %w",
+				err))
+		}
+
+		if length > uint64(r.Len()) {
+			return malformedBlob(fmt.Errorf("tlv record length %d "+
+				"exceeds %d remaining bytes", length, r.Len()))
+		}
+
+		if _, err := r.Seek(int64(length), io.SeekCurrent); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deserializeTLVTrailer parses the unpadded TLV stream of a trailer into the
+// kit. The trailer follows the "it's ok to be odd" rule, allowing newer
+// encoders to append fields without breaking older decoders: records of
+// unknown odd types are skipped, and retained in TrailerRecords such that
+// re-encrypting the kit preserves them, while records of unknown even types
+// fail the decoding with ErrUnknownRequiredTrailerType.
+func (b *JusticeKit) deserializeTLVTrailer(trailer []byte) error {
+	if err := checkTLVRecordLengths(trailer); err != nil {
+		return err
+	}
+
+	// Fields understood by this version are records of the stream, which
+	// populate the kit directly, while any other record is parsed as an
+	// unknown type.
+	var toLocalHash, toRemoteHash uint8
+	stream, err := tlv.NewStream(
+		tlv.MakePrimitiveRecord(
+			trailerTypeToLocalSigHash, &toLocalHash,
+		),
+		tlv.MakePrimitiveRecord(
+			trailerTypeToRemoteSigHash, &toRemoteHash,
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	parsedTypes, err := stream.DecodeWithParsedTypes(
+		bytes.NewReader(trailer),
+	)
+	if err != nil {
+		return err
+	}
+
+	for typ, value := range parsedTypes {
+		switch {
+		// Known fields have already been decoded into the kit.
+		case typ == trailerTypeToLocalSigHash,
+			typ == trailerTypeToRemoteSigHash:
+
+			continue
+
+		case typ%2 == 0:
+			return fmt.Errorf("%w: %d",
+				ErrUnknownRequiredTrailerType, typ)
+		}
+
+		if b.TrailerRecords == nil {
+			b.TrailerRecords = make(map[uint64][]byte)
+		}
+		b.TrailerRecords[uint64(typ)] = value
+	}
+
+	if _, ok := parsedTypes[trailerTypeToLocalSigHash]; ok {
+		err := b.setSigHash(
+			&b.toLocalSigHash, txscript.SigHashType(toLocalHash),
+		)
+		if err != nil {
+			return fmt.Errorf("commit to-local: %w", err)
+		}
+	}
+
+	if _, ok := parsedTypes[trailerTypeToRemoteSigHash]; ok {
+		err := b.setSigHash(
+			&b.toRemoteSigHash, txscript.SigHashType(toRemoteHash),
+		)
+		if err != nil {
+			return fmt.Errorf("commit to-remote: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -1,10 +1,16 @@
 package blob
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 )
 
+// ErrNoCommonType is returned by NegotiateType when the client and tower don't
+// support any blob type in common.
+var ErrNoCommonType = errors.New("no mutually supported blob type")
+
 // Flag represents a specify option that can be present in a Type.
 type Flag uint16
 
@@ -22,8 +28,56 @@ const (
 
 	// FlagAnchorChannel signals that this blob is meant to spend an anchor
 	// channel, and therefore must expect a P2WSH-style to-remote output if
-	// one exists.
+	// one exists. This covers both option_anchor_outputs and
+	// option_anchors_zero_fee_htlc_tx channels, as their commitment and
+	// second-level HTLC output scripts are identical. The zero-fee variant
+	// only differs in the fees and SIGHASH_SINGLE|ANYONECANPAY signatures
+	// of the HTLC transactions themselves, which the tower never creates,
+	// while all justice signatures use SIGHASH_ALL.
 	FlagAnchorChannel Flag = 1 << 2
+
+	// FlagTaprootChannel signals that this blob is meant to spend a
+	// taproot channel, and therefore must expect a P2TR-style to-remote
+	// output, spent via a tapscript with a 1-block CSV delay.
+	FlagTaprootChannel Flag = 1 << 3
+
+	// FlagSecondLevelHtlcs signals that the blob carries signatures for
+	// the revocation paths of second-level HTLC outputs, in addition to
+	// the commitment outputs.
+	FlagSecondLevelHtlcs Flag = 1 << 4
+
+	// FlagDataCommitment signals that the blob carries an optional data
+	// commitment, which the justice transaction commits to in an
+	// additional zero-value OP_RETURN output.
+	FlagDataCommitment Flag = 1 << 5
+
+	// FlagTLVTrailer signals that the blob carries a trailing TLV stream,
+	// allowing optional fields to be added to the blob without requiring
+	// a new blob type to be understood by every decoder.
+	FlagTLVTrailer Flag = 1 << 6
+
+	// FlagLeaseChannel signals that this blob is meant to spend a script
+	// enforced lease channel whose breaching party is the channel
+	// initiator, such that its to-local and second-level HTLC outputs
+	// carry an additional CLTV clause bound to the lease expiry, which the
+	// blob carries.
+	FlagLeaseChannel Flag = 1 << 7
+
+	// FlagTaprootKeySpend signals that the revocation path of a taproot
+	// to-local output is its key path, whose internal key is the
+	// revocation pubkey, e.g. a MuSig2 aggregate of the revocation key
+	// shares. The output only commits to the delayed path as a tapscript
+	// leaf, and is swept with a single schnorr signature. This flag is
+	// only valid in combination with FlagTaprootChannel.
+	FlagTaprootKeySpend Flag = 1 << 8
+
+	// FlagToRemoteOnly signals that the blob only carries the information
+	// required to sweep the commitment to-remote output, for clients that
+	// handle the to-local output themselves and don't want to share its
+	// keys with the tower. The channel flags still select the to-remote
+	// script, but this flag is mutually exclusive with FlagCommitOutputs
+	// and all other flags concerning the to-local or HTLC outputs.
+	FlagToRemoteOnly Flag = 1 << 9
 )
 
 // Type returns a Type consisting solely of this flag enabled.
@@ -40,6 +94,20 @@ func (f Flag) String() string {
 		return "FlagCommitOutputs"
 	case FlagAnchorChannel:
 		return "FlagAnchorChannel"
+	case FlagTaprootChannel:
+		return "FlagTaprootChannel"
+	case FlagSecondLevelHtlcs:
+		return "FlagSecondLevelHtlcs"
+	case FlagDataCommitment:
+		return "FlagDataCommitment"
+	case FlagTLVTrailer:
+		return "FlagTLVTrailer"
+	case FlagLeaseChannel:
+		return "FlagLeaseChannel"
+	case FlagTaprootKeySpend:
+		return "FlagTaprootKeySpend"
+	case FlagToRemoteOnly:
+		return "FlagToRemoteOnly"
 	default:
 		return "FlagUnknown"
 	}
@@ -65,6 +133,16 @@ const (
 	// TypeRewardCommit sweeps only commitment outputs to a sweep address
 	// controlled by the user, and pays a negotiated reward to the tower.
 	TypeRewardCommit = Type(FlagCommitOutputs | FlagReward)
+
+	// TypeAltruistTaprootCommit sweeps only commitment outputs from a
+	// taproot commitment to a sweep address controlled by the user, and
+	// does not give the tower a reward.
+	TypeAltruistTaprootCommit = Type(FlagCommitOutputs | FlagTaprootChannel)
+
+	// TypeAltruistToRemote sweeps only the commitment to-remote output of
+	// a legacy channel to a sweep address controlled by the user, and does
+	// not give the tower a reward.
+	TypeAltruistToRemote = Type(FlagToRemoteOnly)
 )
 
 // Identifier returns a unique, stable string identifier for the blob Type.
@@ -76,6 +154,10 @@ func (t Type) Identifier() (string, error) {
 		return "anchor", nil
 	case TypeRewardCommit:
 		return "reward", nil
+	case TypeAltruistTaprootCommit:
+		return "taproot", nil
+	case TypeAltruistToRemote:
+		return "to-remote", nil
 	default:
 		return "", fmt.Errorf("unknown blob type: %v", t)
 	}
@@ -101,11 +183,39 @@ func (t Type) IsAnchorChannel() bool {
 	return t.Has(FlagAnchorChannel)
 }
 
+// IsTaprootChannel returns true if the blob type is for a taproot channel.
+func (t Type) IsTaprootChannel() bool {
+	return t.Has(FlagTaprootChannel)
+}
+
+// IsTaprootKeySpend returns true if the blob type is for a taproot channel
+// whose to-local output is revoked via its key path.
+func (t Type) IsTaprootKeySpend() bool {
+	return t.IsTaprootChannel() && t.Has(FlagTaprootKeySpend)
+}
+
+// RequiresToRemoteSig returns true if a justice kit of this type must carry a
+// signature for the commitment to-remote output, given whether the breached
+// commitment has one. Only types sweeping the commitment outputs, or only its
+// to-remote output, sweep the to-remote output, which is then signed
+// regardless of the channel type.
+func (t Type) RequiresToRemoteSig(hasCommitToRemote bool) bool {
+	return hasCommitToRemote &&
+		(t.Has(FlagCommitOutputs) || t.Has(FlagToRemoteOnly))
+}
+
 // knownFlags maps the supported flags to their name.
 var knownFlags = map[Flag]struct{}{
-	FlagReward:        {},
-	FlagCommitOutputs: {},
-	FlagAnchorChannel: {},
+	FlagReward:           {},
+	FlagCommitOutputs:    {},
+	FlagAnchorChannel:    {},
+	FlagTaprootChannel:   {},
+	FlagSecondLevelHtlcs: {},
+	FlagDataCommitment:   {},
+	FlagTLVTrailer:       {},
+	FlagLeaseChannel:     {},
+	FlagTaprootKeySpend:  {},
+	FlagToRemoteOnly:     {},
 }
 
 // String returns a human readable description of a Type.
@@ -152,9 +262,11 @@ func (t Type) String() string {
 // supportedTypes is the set of all configurations known to be supported by the
 // package.
 var supportedTypes = map[Type]struct{}{
-	TypeAltruistCommit:       {},
-	TypeRewardCommit:         {},
-	TypeAltruistAnchorCommit: {},
+	TypeAltruistCommit:        {},
+	TypeRewardCommit:          {},
+	TypeAltruistAnchorCommit:  {},
+	TypeAltruistTaprootCommit: {},
+	TypeAltruistToRemote:      {},
 }
 
 // IsSupportedType returns true if the given type is supported by the package.
@@ -171,3 +283,113 @@ func SupportedTypes() []Type {
 	}
 	return supported
 }
+
+// TypeInfo describes a supported blob type, as needed by tooling that
+// negotiates or displays session types.
+type TypeInfo struct {
+	// Type is the blob type being described.
+	Type Type
+
+	// Name is the human readable description of the type, as returned by
+	// its String method.
+	Name string
+
+	// Flags is the list of known flags enabled for the type, in ascending
+	// order.
+	Flags []Flag
+
+	// CiphertextSize is the size of an encrypted blob of this type. Since
+	// blobs are padded to a constant size, this is the same whether or not
+	// the blob carries a commit to-remote output.
+	CiphertextSize int
+}
+
+// RegisteredTypes returns a TypeInfo for each supported blob type, sorted by
+// type.
+func RegisteredTypes() []TypeInfo {
+	types := SupportedTypes()
+	sort.Slice(types, func(i, j int) bool {
+		return types[i] < types[j]
+	})
+
+	infos := make([]TypeInfo, 0, len(types))
+	for _, t := range types {
+		var flags []Flag
+		for f := Flag(1); f != 0; f <<= 1 {
+			if _, ok := knownFlags[f]; ok && t.Has(f) {
+				flags = append(flags, f)
+			}
+		}
+
+		infos = append(infos, TypeInfo{
+			Type:           t,
+			Name:           t.String(),
+			Flags:          flags,
+			CiphertextSize: CiphertextSize(t),
+		})
+	}
+
+	return infos
+}
+
+// typePreference ranks blob types by capability for NegotiateType. The channel
+// type takes precedence, preferring taproot over anchor over legacy channels,
+// followed by preferring a reward for the tower over an altruist sweep.
+func typePreference(t Type) int {
+	var rank int
+	switch {
+	case t.IsTaprootChannel():
+		rank = 4
+	case t.IsAnchorChannel():
+		rank = 2
+	}
+
+	if t.Has(FlagReward) {
+		rank++
+	}
+
+	return rank
+}
+
+// preferredType returns true if a should be selected over b during
+// negotiation.
+func preferredType(a, b Type) bool {
+	if typePreference(a) != typePreference(b) {
+		return typePreference(a) > typePreference(b)
+	}
+
+	return a < b
+}
+
+// NegotiateType selects the most capable blob type supported by both the
+// client and the tower, as ranked by typePreference. Ties are broken in favor
+// of the lower type value, such that both sides arrive at the same result
+// regardless of the order of their lists. ErrNoCommonType is returned if the
+// lists share no type.
+func NegotiateType(clientSupported, towerSupported []Type) (Type, error) {
+	towerTypes := make(map[Type]struct{}, len(towerSupported))
+	for _, t := range towerSupported {
+		towerTypes[t] = struct{}{}
+	}
+
+	var (
+		best  Type
+		found bool
+	)
+	for _, t := range clientSupported {
+		if _, ok := towerTypes[t]; !ok {
+			continue
+		}
+
+		if !found || preferredType(t, best) {
+			best = t
+			found = true
+		}
+	}
+
+	if !found {
+		return 0, ErrNoCommonType
+	}
+
+	return best, nil
+}
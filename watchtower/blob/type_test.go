@@ -1,12 +1,14 @@
 package blob_test
 
 import (
+	"crypto/rand"
 	"testing"
 
 	"github.com/lightningnetwork/lnd/watchtower/blob"
+	"github.com/stretchr/testify/require"
 )
 
-var unknownFlag = blob.Flag(16)
+var unknownFlag = blob.Flag(1 << 15)
 
 type typeStringTest struct {
 	name   string
@@ -16,19 +18,50 @@ type typeStringTest struct {
 
 var typeStringTests = []typeStringTest{
 	{
-		name:   "commit no-reward",
-		typ:    blob.TypeAltruistCommit,
-		expStr: "[No-FlagAnchorChannel|FlagCommitOutputs|No-FlagReward]",
+		name: "commit no-reward",
+		typ:  blob.TypeAltruistCommit,
+		expStr: "[No-FlagToRemoteOnly|No-FlagTaprootKeySpend|" +
+			"No-FlagLeaseChannel|No-FlagTLVTrailer|" +
+			"No-FlagDataCommitment|No-FlagSecondLevelHtlcs|" +
+			"No-FlagTaprootChannel|No-FlagAnchorChannel|" +
+			"FlagCommitOutputs|No-FlagReward]",
 	},
 	{
-		name:   "commit reward",
-		typ:    blob.TypeRewardCommit,
-		expStr: "[No-FlagAnchorChannel|FlagCommitOutputs|FlagReward]",
+		name: "commit reward",
+		typ:  blob.TypeRewardCommit,
+		expStr: "[No-FlagToRemoteOnly|No-FlagTaprootKeySpend|" +
+			"No-FlagLeaseChannel|No-FlagTLVTrailer|" +
+			"No-FlagDataCommitment|No-FlagSecondLevelHtlcs|" +
+			"No-FlagTaprootChannel|No-FlagAnchorChannel|" +
+			"FlagCommitOutputs|FlagReward]",
 	},
 	{
-		name:   "unknown flag",
-		typ:    unknownFlag.Type(),
-		expStr: "0000000000010000[No-FlagAnchorChannel|No-FlagCommitOutputs|No-FlagReward]",
+		name: "taproot commit",
+		typ:  blob.TypeAltruistTaprootCommit,
+		expStr: "[No-FlagToRemoteOnly|No-FlagTaprootKeySpend|" +
+			"No-FlagLeaseChannel|No-FlagTLVTrailer|" +
+			"No-FlagDataCommitment|No-FlagSecondLevelHtlcs|" +
+			"FlagTaprootChannel|No-FlagAnchorChannel|" +
+			"FlagCommitOutputs|No-FlagReward]",
+	},
+	{
+		name: "to-remote only",
+		typ:  blob.TypeAltruistToRemote,
+		expStr: "[FlagToRemoteOnly|No-FlagTaprootKeySpend|" +
+			"No-FlagLeaseChannel|No-FlagTLVTrailer|" +
+			"No-FlagDataCommitment|No-FlagSecondLevelHtlcs|" +
+			"No-FlagTaprootChannel|No-FlagAnchorChannel|" +
+			"No-FlagCommitOutputs|No-FlagReward]",
+	},
+	{
+		name: "unknown flag",
+		typ:  unknownFlag.Type(),
+		expStr: "1000000000000000[No-FlagToRemoteOnly|" +
+			"No-FlagTaprootKeySpend|No-FlagLeaseChannel|" +
+			"No-FlagTLVTrailer|No-FlagDataCommitment|" +
+			"No-FlagSecondLevelHtlcs|No-FlagTaprootChannel|" +
+			"No-FlagAnchorChannel|No-FlagCommitOutputs|" +
+			"No-FlagReward]",
 	},
 }
 
@@ -129,6 +162,18 @@ func TestSupportedTypes(t *testing.T) {
 			blob.TypeAltruistAnchorCommit)
 	}
 
+	// Assert that the altruist taproot commit types are supported.
+	if !blob.IsSupportedType(blob.TypeAltruistTaprootCommit) {
+		t.Fatalf("default type %s is not supported",
+			blob.TypeAltruistTaprootCommit)
+	}
+
+	// Assert that the altruist to-remote types are supported.
+	if !blob.IsSupportedType(blob.TypeAltruistToRemote) {
+		t.Fatalf("default type %s is not supported",
+			blob.TypeAltruistToRemote)
+	}
+
 	// Assert that all claimed supported types are actually supported.
 	for _, supType := range blob.SupportedTypes() {
 		if blob.IsSupportedType(supType) {
@@ -139,3 +184,222 @@ func TestSupportedTypes(t *testing.T) {
 			supType)
 	}
 }
+
+// TestTypeRequiresToRemoteSig asserts that a to-remote signature is required
+// by every type sweeping the commitment outputs, or only the to-remote output,
+// if and only if the breached commitment has a to-remote output, and never by
+// types that don't sweep the commitment outputs.
+func TestTypeRequiresToRemoteSig(t *testing.T) {
+	commitTypes := append(
+		blob.SupportedTypes(),
+		blob.TypeFromFlags(
+			blob.FlagCommitOutputs, blob.FlagTaprootChannel,
+			blob.FlagTaprootKeySpend,
+		),
+	)
+	for _, blobType := range commitTypes {
+		require.True(t, blobType.RequiresToRemoteSig(true),
+			"type %v", blobType)
+		require.False(t, blobType.RequiresToRemoteSig(false),
+			"type %v", blobType)
+	}
+
+	nonCommitTypes := []blob.Type{
+		blob.FlagReward.Type(),
+		blob.FlagAnchorChannel.Type(),
+		unknownFlag.Type(),
+	}
+	for _, blobType := range nonCommitTypes {
+		require.False(t, blobType.RequiresToRemoteSig(true),
+			"type %v", blobType)
+		require.False(t, blobType.RequiresToRemoteSig(false),
+			"type %v", blobType)
+	}
+}
+
+// TestRegisteredTypes asserts that RegisteredTypes describes exactly the
+// built-in supported types, and that the reported ciphertext sizes match the
+// encrypted blobs both with and without a commit to-remote output.
+func TestRegisteredTypes(t *testing.T) {
+	expInfos := []blob.TypeInfo{
+		{
+			Type:           blob.TypeAltruistCommit,
+			Name:           blob.TypeAltruistCommit.String(),
+			Flags:          []blob.Flag{blob.FlagCommitOutputs},
+			CiphertextSize: 314,
+		},
+		{
+			Type: blob.TypeRewardCommit,
+			Name: blob.TypeRewardCommit.String(),
+			Flags: []blob.Flag{
+				blob.FlagReward, blob.FlagCommitOutputs,
+			},
+			CiphertextSize: 314,
+		},
+		{
+			Type: blob.TypeAltruistAnchorCommit,
+			Name: blob.TypeAltruistAnchorCommit.String(),
+			Flags: []blob.Flag{
+				blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+			},
+			CiphertextSize: 314,
+		},
+		{
+			Type: blob.TypeAltruistTaprootCommit,
+			Name: blob.TypeAltruistTaprootCommit.String(),
+			Flags: []blob.Flag{
+				blob.FlagCommitOutputs, blob.FlagTaprootChannel,
+			},
+			CiphertextSize: 314,
+		},
+		{
+			Type:           blob.TypeAltruistToRemote,
+			Name:           blob.TypeAltruistToRemote.String(),
+			Flags:          []blob.Flag{blob.FlagToRemoteOnly},
+			CiphertextSize: 180,
+		},
+	}
+
+	infos := blob.RegisteredTypes()
+	require.Equal(t, expInfos, infos)
+
+	for _, info := range infos {
+		for _, hasToRemote := range []bool{false, true} {
+			// Types sweeping only the to-remote output require
+			// one to be present.
+			if !hasToRemote &&
+				info.Type.Has(blob.FlagToRemoteOnly) {
+
+				continue
+			}
+
+			kit := &blob.JusticeKit{
+				BlobType:         info.Type,
+				SweepAddress:     makeAddr(22),
+				RevocationPubKey: makePubKey(0),
+				LocalDelayPubKey: makePubKey(1),
+				CSVDelay:         144,
+				CommitToLocalSig: makeSig(1),
+			}
+			if hasToRemote {
+				kit.CommitToRemotePubKey = makePubKey(2)
+				kit.CommitToRemoteSig = makeSig(2)
+			}
+
+			var key blob.BreachKey
+			_, err := rand.Read(key[:])
+			require.NoError(t, err)
+
+			ctxt, err := kit.Encrypt(key)
+			require.NoError(t, err)
+			require.Lenf(t, ctxt, info.CiphertextSize,
+				"type=%v to_remote=%v", info.Type, hasToRemote)
+		}
+	}
+}
+
+// TestNegotiateType asserts that NegotiateType picks the most capable type
+// supported by both sides, independent of the order of their lists.
+func TestNegotiateType(t *testing.T) {
+	rewardAnchorType := blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagReward,
+	)
+
+	tests := []struct {
+		name    string
+		client  []blob.Type
+		tower   []blob.Type
+		expType blob.Type
+		expErr  error
+	}{
+		{
+			name: "single overlap",
+			client: []blob.Type{
+				blob.TypeAltruistCommit,
+				blob.TypeAltruistAnchorCommit,
+			},
+			tower: []blob.Type{
+				blob.TypeAltruistCommit,
+				blob.TypeRewardCommit,
+			},
+			expType: blob.TypeAltruistCommit,
+		},
+		{
+			name: "no overlap",
+			client: []blob.Type{
+				blob.TypeAltruistAnchorCommit,
+			},
+			tower: []blob.Type{
+				blob.TypeAltruistCommit,
+				blob.TypeRewardCommit,
+			},
+			expErr: blob.ErrNoCommonType,
+		},
+		{
+			name:   "empty lists",
+			expErr: blob.ErrNoCommonType,
+		},
+		{
+			name: "anchor over legacy reward",
+			client: []blob.Type{
+				blob.TypeRewardCommit,
+				blob.TypeAltruistAnchorCommit,
+			},
+			tower: []blob.Type{
+				blob.TypeAltruistAnchorCommit,
+				blob.TypeRewardCommit,
+			},
+			expType: blob.TypeAltruistAnchorCommit,
+		},
+		{
+			name: "taproot over anchor",
+			client: []blob.Type{
+				blob.TypeAltruistAnchorCommit,
+				blob.TypeAltruistTaprootCommit,
+				rewardAnchorType,
+			},
+			tower: []blob.Type{
+				rewardAnchorType,
+				blob.TypeAltruistTaprootCommit,
+				blob.TypeAltruistAnchorCommit,
+			},
+			expType: blob.TypeAltruistTaprootCommit,
+		},
+		{
+			name: "reward over altruist",
+			client: []blob.Type{
+				blob.TypeAltruistCommit,
+				blob.TypeRewardCommit,
+				blob.TypeAltruistAnchorCommit,
+				rewardAnchorType,
+			},
+			tower: []blob.Type{
+				blob.TypeAltruistAnchorCommit,
+				rewardAnchorType,
+				blob.TypeAltruistCommit,
+				blob.TypeRewardCommit,
+			},
+			expType: rewardAnchorType,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			blobType, err := blob.NegotiateType(
+				test.client, test.tower,
+			)
+			require.ErrorIs(t, err, test.expErr)
+			require.Equal(t, test.expType, blobType)
+
+			// Negotiation must be symmetric, such that both the
+			// client and the tower agree on the result.
+			blobType, err = blob.NegotiateType(
+				test.tower, test.client,
+			)
+			require.ErrorIs(t, err, test.expErr)
+			require.Equal(t, test.expType, blobType)
+		})
+	}
+}
@@ -0,0 +1,542 @@
+package blob_test
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/lightningnetwork/lnd/input"
+	"github.com/lightningnetwork/lnd/lnwire"
+	"github.com/lightningnetwork/lnd/watchtower/blob"
+	"github.com/stretchr/testify/require"
+)
+
+// breachedOutput is a breached commitment output whose script was derived
+// independently of the JusticeKit, along with the means to sign for it.
+type breachedOutput struct {
+	txOut *wire.TxOut
+
+	// sign produces a signature for the given input of the justice
+	// transaction, using the sighash type expected by the kit.
+	sign func(tx *wire.MsgTx, hashes *txscript.TxSigHashes,
+		idx int) lnwire.Sig
+}
+
+// segwitV0Output returns a breached output paying to pkScript, which is signed
+// with SIGHASH_ALL under priv using the given script code.
+func segwitV0Output(t *testing.T, value int64, pkScript, scriptCode []byte,
+	priv *btcec.PrivateKey) *breachedOutput {
+
+	return &breachedOutput{
+		txOut: wire.NewTxOut(value, pkScript),
+		sign: func(tx *wire.MsgTx, hashes *txscript.TxSigHashes,
+			idx int) lnwire.Sig {
+
+			rawSig, err := txscript.RawTxInWitnessSignature(
+				tx, hashes, idx, value, scriptCode,
+				txscript.SigHashAll, priv,
+			)
+			require.NoError(t, err)
+
+			// The sighash flag is appended by the kit.
+			sig, err := lnwire.NewSigFromECDSARawSignature(
+				rawSig[:len(rawSig)-1],
+			)
+			require.NoError(t, err)
+
+			return sig
+		},
+	}
+}
+
+// tapscriptOutput returns a breached output paying to the given taproot output
+// key, which is spent via the given leaf signed with SIGHASH_DEFAULT under
+// priv.
+func tapscriptOutput(t *testing.T, value int64, outputKey *btcec.PublicKey,
+	leaf txscript.TapLeaf, priv *btcec.PrivateKey) *breachedOutput {
+
+	pkScript, err := input.PayToTaprootScript(outputKey)
+	require.NoError(t, err)
+
+	return &breachedOutput{
+		txOut: wire.NewTxOut(value, pkScript),
+		sign: func(tx *wire.MsgTx, hashes *txscript.TxSigHashes,
+			idx int) lnwire.Sig {
+
+			rawSig, err := txscript.RawTxInTapscriptSignature(
+				tx, hashes, idx, value, pkScript, leaf,
+				txscript.SigHashDefault, priv,
+			)
+			require.NoError(t, err)
+
+			sig, err := lnwire.NewSigFromSchnorrRawSignature(rawSig)
+			require.NoError(t, err)
+
+			return sig
+		},
+	}
+}
+
+// executeInput runs the given input of tx, spending prevOuts, through the
+// script engine, returning the error of the execution.
+func executeInput(t *testing.T, tx *wire.MsgTx, prevOuts []*wire.TxOut,
+	idx int) error {
+
+	t.Helper()
+
+	fetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for i, txIn := range tx.TxIn {
+		fetcher.AddPrevOut(txIn.PreviousOutPoint, prevOuts[i])
+	}
+	hashes := txscript.NewTxSigHashes(tx, fetcher)
+
+	vm, err := txscript.NewEngine(
+		prevOuts[idx].PkScript, tx, idx, txscript.StandardVerifyFlags,
+		nil, hashes, prevOuts[idx].Value, fetcher,
+	)
+	require.NoError(t, err)
+
+	return vm.Execute()
+}
+
+// executeWitnesses runs every input of tx, spending the given outputs, through
+// the script engine, asserting that each witness satisfies its output.
+func executeWitnesses(t *testing.T, tx *wire.MsgTx,
+	prevOuts []*wire.TxOut) {
+
+	t.Helper()
+
+	for i := range tx.TxIn {
+		require.NoErrorf(t, executeInput(t, tx, prevOuts, i),
+			"input %d", i)
+	}
+}
+
+// requireExportedWitnesses asserts that a kit reconstructed by Import from the
+// Export of the given kit produces byte-identical spend info, and thus
+// witnesses, for each output the kit sweeps, such that no field needed to
+// assemble them is encoded lossily.
+func requireExportedWitnesses(t *testing.T, kit *blob.JusticeKit) {
+	t.Helper()
+
+	export, err := kit.Export()
+	require.NoError(t, err)
+	imported, _, err := blob.Import(export)
+	require.NoError(t, err)
+
+	if !kit.BlobType.Has(blob.FlagToRemoteOnly) {
+		toLocalInfo, err := kit.ToLocalOutputSpendInfo()
+		require.NoError(t, err)
+		importedInfo, err := imported.ToLocalOutputSpendInfo()
+		require.NoError(t, err)
+
+		require.Equal(t, toLocalInfo, importedInfo)
+		require.Equal(t, toLocalInfo.Witness(), importedInfo.Witness())
+	}
+
+	if kit.HasCommitToRemoteOutput() {
+		toRemoteInfo, err := kit.ToRemoteOutputSpendInfo()
+		require.NoError(t, err)
+		importedInfo, err := imported.ToRemoteOutputSpendInfo()
+		require.NoError(t, err)
+
+		require.Equal(t, toRemoteInfo, importedInfo)
+		require.Equal(t, toRemoteInfo.Witness(), importedInfo.Witness())
+	}
+}
+
+// TestJusticeKitWitnessesExecute asserts that, for every commitment type, the
+// witnesses assembled from a kit holding real signatures satisfy the breached
+// outputs under the script engine. The outputs' scripts are derived
+// independently of the kit, and the kit is round tripped through encryption,
+// such that the signatures are decoded as done by a tower. The witnesses are
+// also asserted to survive a round trip through Export and Import.
+func TestJusticeKitWitnessesExecute(t *testing.T) {
+	const (
+		csvDelay    = 144
+		leaseExpiry = 800_000
+		toLocalAmt  = 200_000
+		toRemoteAmt = 100_000
+	)
+
+	revPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	delayPriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	toRemotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+
+	revPub, delayPub := revPriv.PubKey(), delayPriv.PubKey()
+	toRemotePub := toRemotePriv.PubKey()
+
+	p2wshOutput := func(value int64, script []byte,
+		priv *btcec.PrivateKey) *breachedOutput {
+
+		pkScript, err := input.WitnessScriptHash(script)
+		require.NoError(t, err)
+
+		return segwitV0Output(t, value, pkScript, script, priv)
+	}
+
+	// The to-local outputs of each commitment type.
+	toLocalScript, err := input.CommitScriptToSelf(
+		csvDelay, delayPub, revPub,
+	)
+	require.NoError(t, err)
+	toLocal := p2wshOutput(toLocalAmt, toLocalScript, revPriv)
+
+	leaseToLocalScript, err := input.LeaseCommitScriptToSelf(
+		delayPub, revPub, csvDelay, leaseExpiry,
+	)
+	require.NoError(t, err)
+	leaseToLocal := p2wshOutput(toLocalAmt, leaseToLocalScript, revPriv)
+
+	toLocalTree, err := input.NewLocalCommitScriptTree(
+		csvDelay, delayPub, revPub,
+	)
+	require.NoError(t, err)
+	taprootToLocal := tapscriptOutput(
+		t, toLocalAmt, toLocalTree.TaprootKey,
+		toLocalTree.RevocationLeaf, revPriv,
+	)
+
+	// The key path of a to-local output revoked via its key commits to
+	// the delayed leaf under the revocation key.
+	delayTree := txscript.AssembleTaprootScriptTree(toLocalTree.SettleLeaf)
+	delayRoot := delayTree.RootNode.TapHash()
+	keySpendPkScript, err := input.PayToTaprootScript(
+		txscript.ComputeTaprootOutputKey(revPub, delayRoot[:]),
+	)
+	require.NoError(t, err)
+	keySpendToLocal := &breachedOutput{
+		txOut: wire.NewTxOut(toLocalAmt, keySpendPkScript),
+		sign: func(tx *wire.MsgTx, hashes *txscript.TxSigHashes,
+			idx int) lnwire.Sig {
+
+			rawSig, err := txscript.RawTxInTaprootSignature(
+				tx, hashes, idx, toLocalAmt, keySpendPkScript,
+				delayRoot[:], txscript.SigHashDefault, revPriv,
+			)
+			require.NoError(t, err)
+
+			sig, err := lnwire.NewSigFromSchnorrRawSignature(rawSig)
+			require.NoError(t, err)
+
+			return sig
+		},
+	}
+
+	// The to-remote outputs of each commitment type.
+	p2wkhToRemote, err := input.CommitScriptUnencumbered(toRemotePub)
+	require.NoError(t, err)
+	legacyToRemote := segwitV0Output(
+		t, toRemoteAmt, p2wkhToRemote, p2wkhToRemote, toRemotePriv,
+	)
+
+	anchorToRemoteScript, err := input.CommitScriptToRemoteConfirmed(
+		toRemotePub,
+	)
+	require.NoError(t, err)
+	anchorToRemote := p2wshOutput(
+		toRemoteAmt, anchorToRemoteScript, toRemotePriv,
+	)
+
+	toRemoteTree, err := input.NewRemoteCommitScriptTree(toRemotePub)
+	require.NoError(t, err)
+	taprootToRemote := tapscriptOutput(
+		t, toRemoteAmt, toRemoteTree.TaprootKey,
+		toRemoteTree.SettleLeaf, toRemotePriv,
+	)
+
+	tests := []struct {
+		name     string
+		blobType blob.Type
+		toLocal  *breachedOutput
+		toRemote *breachedOutput
+	}{
+		{
+			name:     "legacy",
+			blobType: blob.TypeAltruistCommit,
+			toLocal:  toLocal,
+			toRemote: legacyToRemote,
+		},
+		{
+			name:     "legacy to-local only",
+			blobType: blob.TypeAltruistCommit,
+			toLocal:  toLocal,
+		},
+		{
+			name:     "reward",
+			blobType: blob.TypeRewardCommit,
+			toLocal:  toLocal,
+			toRemote: legacyToRemote,
+		},
+		{
+			name:     "anchor",
+			blobType: blob.TypeAltruistAnchorCommit,
+			toLocal:  toLocal,
+			toRemote: anchorToRemote,
+		},
+		{
+			name:     "lease",
+			blobType: leaseType,
+			toLocal:  leaseToLocal,
+		},
+		{
+			name:     "taproot",
+			blobType: blob.TypeAltruistTaprootCommit,
+			toLocal:  taprootToLocal,
+			toRemote: taprootToRemote,
+		},
+		{
+			name: "taproot key spend",
+			blobType: blob.TypeFromFlags(
+				blob.FlagCommitOutputs,
+				blob.FlagTaprootChannel,
+				blob.FlagTaprootKeySpend,
+			),
+			toLocal:  keySpendToLocal,
+			toRemote: taprootToRemote,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kit, err := blob.NewJusticeKitFromScripts(
+				test.blobType, blob.JusticeKitParams{
+					SweepAddress:     makeSweepAddr(),
+					RevocationPubKey: revPub,
+					LocalDelayPubKey: delayPub,
+					CSVDelay:         csvDelay,
+					HasToRemote:      test.toRemote != nil,
+					ToRemotePubKey:   toRemotePub,
+					LeaseExpiry:      leaseExpiry,
+				},
+			)
+			require.NoError(t, err)
+
+			testJusticeKitWitnessesExecute(
+				t, kit, test.toLocal, test.toRemote,
+			)
+		})
+	}
+}
+
+// testJusticeKitWitnessesExecute signs a justice transaction sweeping the
+// given breached outputs, adds the signatures to the kit, and asserts that the
+// witnesses assembled from the decrypted kit pass script execution.
+func testJusticeKitWitnessesExecute(t *testing.T, kit *blob.JusticeKit,
+	toLocal, toRemote *breachedOutput) {
+
+	// Assemble the justice transaction, whose inputs must carry the
+	// sequences required by the kit before they are signed.
+	toLocalSeq, toRemoteSeq := kit.InputSequences()
+	breachTxID := chainhash.Hash{0x01}
+
+	justiceTx := wire.NewMsgTx(2)
+	justiceTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: breachTxID},
+		Sequence:         toLocalSeq,
+	})
+	prevOuts := []*wire.TxOut{toLocal.txOut}
+	totalAmt := toLocal.txOut.Value
+
+	if toRemote != nil {
+		justiceTx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{
+				Hash: breachTxID, Index: 1,
+			},
+			Sequence: toRemoteSeq,
+		})
+		prevOuts = append(prevOuts, toRemote.txOut)
+		totalAmt += toRemote.txOut.Value
+	}
+	justiceTx.AddTxOut(wire.NewTxOut(totalAmt-1_000, kit.SweepAddress))
+
+	fetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for i, txIn := range justiceTx.TxIn {
+		fetcher.AddPrevOut(txIn.PreviousOutPoint, prevOuts[i])
+	}
+	hashes := txscript.NewTxSigHashes(justiceTx, fetcher)
+
+	require.NoError(t, kit.AddToLocalSig(
+		toLocal.sign(justiceTx, hashes, 0),
+	))
+	if toRemote != nil {
+		require.NoError(t, kit.AddToRemoteSig(
+			toRemote.sign(justiceTx, hashes, 1),
+		))
+	}
+	requireExportedWitnesses(t, kit)
+
+	var key blob.BreachKey
+	_, err := rand.Read(key[:])
+	require.NoError(t, err)
+
+	ctxt, err := kit.Encrypt(key)
+	require.NoError(t, err)
+	kit, err = blob.Decrypt(key, ctxt, kit.BlobType)
+	require.NoError(t, err)
+
+	// Attach the witnesses assembled from the decrypted kit.
+	toLocalInfo, err := kit.ToLocalOutputSpendInfo()
+	require.NoError(t, err)
+	justiceTx.TxIn[0].Witness = toLocalInfo.Witness()
+
+	if toRemote != nil {
+		toRemoteInfo, err := kit.ToRemoteOutputSpendInfo()
+		require.NoError(t, err)
+		require.Equal(t, toRemoteSeq, toRemoteInfo.Sequence)
+
+		// The control block of taproot kits should match the one
+		// checked against the breached output.
+		if kit.BlobType.IsTaprootChannel() {
+			ctrlBlock, err := kit.CommitToRemoteControlBlock(
+				toRemote.txOut.PkScript,
+			)
+			require.NoError(t, err)
+			require.Equal(t, ctrlBlock, toRemoteInfo.ControlBlock)
+		} else {
+			require.Nil(t, toRemoteInfo.ControlBlock)
+		}
+		justiceTx.TxIn[1].Witness = toRemoteInfo.Witness()
+	}
+
+	executeWitnesses(t, justiceTx, prevOuts)
+
+	// A witness whose signature doesn't match the transaction should be
+	// rejected, asserting that the signatures are actually verified.
+	justiceTx.TxOut[0].Value--
+	for i := range justiceTx.TxIn {
+		require.Errorf(t, executeInput(t, justiceTx, prevOuts, i),
+			"input %d", i)
+	}
+}
+
+// TestToRemoteOnlyWitnessesExecute asserts that the to-remote witness of a kit
+// with FlagToRemoteOnly satisfies the breached to-remote output of each channel
+// type under the script engine, while the to-local output can't be spent.
+func TestToRemoteOnlyWitnessesExecute(t *testing.T) {
+	const toRemoteAmt = 100_000
+
+	toRemotePriv, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	toRemotePub := toRemotePriv.PubKey()
+
+	p2wkhToRemote, err := input.CommitScriptUnencumbered(toRemotePub)
+	require.NoError(t, err)
+	legacyToRemote := segwitV0Output(
+		t, toRemoteAmt, p2wkhToRemote, p2wkhToRemote, toRemotePriv,
+	)
+
+	anchorToRemoteScript, err := input.CommitScriptToRemoteConfirmed(
+		toRemotePub,
+	)
+	require.NoError(t, err)
+	anchorPkScript, err := input.WitnessScriptHash(anchorToRemoteScript)
+	require.NoError(t, err)
+	anchorToRemote := segwitV0Output(
+		t, toRemoteAmt, anchorPkScript, anchorToRemoteScript,
+		toRemotePriv,
+	)
+
+	toRemoteTree, err := input.NewRemoteCommitScriptTree(toRemotePub)
+	require.NoError(t, err)
+	taprootToRemote := tapscriptOutput(
+		t, toRemoteAmt, toRemoteTree.TaprootKey,
+		toRemoteTree.SettleLeaf, toRemotePriv,
+	)
+
+	tests := []struct {
+		name     string
+		blobType blob.Type
+		toRemote *breachedOutput
+	}{
+		{
+			name:     "legacy",
+			blobType: blob.TypeAltruistToRemote,
+			toRemote: legacyToRemote,
+		},
+		{
+			name: "anchor",
+			blobType: blob.TypeFromFlags(
+				blob.FlagToRemoteOnly, blob.FlagAnchorChannel,
+			),
+			toRemote: anchorToRemote,
+		},
+		{
+			name: "taproot",
+			blobType: blob.TypeFromFlags(
+				blob.FlagToRemoteOnly, blob.FlagTaprootChannel,
+			),
+			toRemote: taprootToRemote,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			kit, err := blob.NewJusticeKitFromScripts(
+				test.blobType, blob.JusticeKitParams{
+					SweepAddress:   makeSweepAddr(),
+					HasToRemote:    true,
+					ToRemotePubKey: toRemotePub,
+				},
+			)
+			require.NoError(t, err)
+
+			_, toRemoteSeq := kit.InputSequences()
+			justiceTx := wire.NewMsgTx(2)
+			justiceTx.AddTxIn(&wire.TxIn{
+				PreviousOutPoint: wire.OutPoint{
+					Hash: chainhash.Hash{0x01}, Index: 1,
+				},
+				Sequence: toRemoteSeq,
+			})
+			justiceTx.AddTxOut(wire.NewTxOut(
+				toRemoteAmt-1_000, kit.SweepAddress,
+			))
+			prevOuts := []*wire.TxOut{test.toRemote.txOut}
+
+			fetcher := txscript.NewMultiPrevOutFetcher(nil)
+			fetcher.AddPrevOut(
+				justiceTx.TxIn[0].PreviousOutPoint,
+				test.toRemote.txOut,
+			)
+			hashes := txscript.NewTxSigHashes(justiceTx, fetcher)
+
+			require.NoError(t, kit.AddToRemoteSig(
+				test.toRemote.sign(justiceTx, hashes, 0),
+			))
+			requireExportedWitnesses(t, kit)
+
+			var key blob.BreachKey
+			_, err = rand.Read(key[:])
+			require.NoError(t, err)
+
+			ctxt, err := kit.Encrypt(key)
+			require.NoError(t, err)
+			kit, err = blob.Decrypt(key, ctxt, kit.BlobType)
+			require.NoError(t, err)
+
+			// Only the to-remote output should be spendable.
+			toLocal, toRemote, _ := kit.SpendableOutputs()
+			require.False(t, toLocal)
+			require.True(t, toRemote)
+
+			_, err = kit.ToLocalOutputSpendInfo()
+			require.ErrorIs(t, err, blob.ErrNoCommitToLocalOutput)
+
+			toRemoteInfo, err := kit.ToRemoteOutputSpendInfo()
+			require.NoError(t, err)
+
+			justiceTx.TxIn[0].Witness = toRemoteInfo.Witness()
+
+			executeWitnesses(t, justiceTx, prevOuts)
+		})
+	}
+}
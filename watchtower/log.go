@@ -3,6 +3,7 @@ package watchtower
 import (
 	"github.com/btcsuite/btclog"
 	"github.com/lightningnetwork/lnd/build"
+	"github.com/lightningnetwork/lnd/watchtower/blob"
 	"github.com/lightningnetwork/lnd/watchtower/lookout"
 	"github.com/lightningnetwork/lnd/watchtower/wtclient"
 	"github.com/lightningnetwork/lnd/watchtower/wtdb"
@@ -30,6 +31,7 @@ func DisableLog() {
 // using btclog.
 func UseLogger(logger btclog.Logger) {
 	log = logger
+	blob.UseLogger(logger)
 	lookout.UseLogger(logger)
 	wtserver.UseLogger(logger)
 	wtclient.UseLogger(logger)
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/btcsuite/btcd/blockchain"
-	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/txsort"
 	"github.com/btcsuite/btcd/txscript"
@@ -47,31 +46,34 @@ type JusticeDescriptor struct {
 // breachedInput contains the required information to construct and spend
 // breached outputs on a commitment transaction.
 type breachedInput struct {
-	txOut    *wire.TxOut
-	outPoint wire.OutPoint
-	witness  [][]byte
-	sequence uint32
+	txOut       *wire.TxOut
+	outPoint    wire.OutPoint
+	witness     [][]byte
+	witnessSize int
+	sequence    uint32
 }
 
 // commitToLocalInput extracts the information required to spend the commit
 // to-local output.
 func (p *JusticeDescriptor) commitToLocalInput() (*breachedInput, error) {
-	// Retrieve the to-local witness script from the justice kit.
-	toLocalScript, err := p.JusticeKit.CommitToLocalWitnessScript()
+	// Retrieve the to-local spend info from the justice kit, which
+	// primarily includes a signature under the revocation pubkey, along
+	// with the witness script and control block for taproot channels.
+	spendInfo, err := p.JusticeKit.ToLocalOutputSpendInfo()
 	if err != nil {
 		return nil, err
 	}
 
-	// Compute the witness script hash, which will be used to locate the
-	// input on the breaching commitment transaction.
-	toLocalWitnessHash, err := input.WitnessScriptHash(toLocalScript)
+	// Derive the pkScript of the to-local output, which will be used to
+	// locate the input on the breaching commitment transaction.
+	toLocalPkScript, err := p.JusticeKit.CommitToLocalPkScript()
 	if err != nil {
 		return nil, err
 	}
 
 	// Locate the to-local output on the breaching commitment transaction.
 	toLocalIndex, toLocalTxOut, err := findTxOutByPkScript(
-		p.BreachedCommitTx, toLocalWitnessHash,
+		p.BreachedCommitTx, toLocalPkScript,
 	)
 	if err != nil {
 		return nil, err
@@ -84,63 +86,54 @@ func (p *JusticeDescriptor) commitToLocalInput() (*breachedInput, error) {
 		Index: toLocalIndex,
 	}
 
-	// Retrieve to-local witness stack, which primarily includes a signature
-	// under the revocation pubkey.
-	witnessStack, err := p.JusticeKit.CommitToLocalRevokeWitnessStack()
-	if err != nil {
-		return nil, err
+	// An older ToLocalPenaltyWitnessSize constant used to underestimate the
+	// size by one byte. The diferrence in weight can cause different output
+	// values on the sweep transaction, so we mimic the original bug to
+	// avoid invalidating signatures by older clients. For anchor channels
+	// we correct this and use the correct witness size, while taproot
+	// channels use the size of the key or script path witness reported by
+	// the justice kit.
+	var witnessSize int
+	switch {
+	case p.JusticeKit.BlobType.IsTaprootChannel():
+		witnessSize = spendInfo.WitnessSize
+
+	case p.JusticeKit.BlobType.IsAnchorChannel():
+		witnessSize = input.ToLocalPenaltyWitnessSize
+
+	default:
+		witnessSize = input.ToLocalPenaltyWitnessSize - 1
 	}
 
 	return &breachedInput{
-		txOut:    toLocalTxOut,
-		outPoint: toLocalOutPoint,
-		witness:  buildWitness(witnessStack, toLocalScript),
+		txOut:       toLocalTxOut,
+		outPoint:    toLocalOutPoint,
+		witness:     spendInfo.Witness(),
+		witnessSize: witnessSize,
 	}, nil
 }
 
 // commitToRemoteInput extracts the information required to spend the commit
 // to-remote output.
 func (p *JusticeDescriptor) commitToRemoteInput() (*breachedInput, error) {
-	// Retrieve the to-remote witness script from the justice kit.
-	toRemoteScript, err := p.JusticeKit.CommitToRemoteWitnessScript()
+	// Retrieve the to-remote witness script and witness stack, which is
+	// just a signature under the to-remote pubkey, from the justice kit,
+	// along with the sequence required to spend the output.
+	spendInfo, err := p.JusticeKit.ToRemoteOutputSpendInfo()
 	if err != nil {
 		return nil, err
 	}
 
-	var (
-		toRemoteScriptHash []byte
-		toRemoteSequence   uint32
-	)
-	if p.JusticeKit.BlobType.IsAnchorChannel() {
-		toRemoteScriptHash, err = input.WitnessScriptHash(
-			toRemoteScript,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		toRemoteSequence = 1
-	} else {
-		// Since the to-remote witness script should just be a regular p2wkh
-		// output, we'll parse it to retrieve the public key.
-		toRemotePubKey, err := btcec.ParsePubKey(toRemoteScript)
-		if err != nil {
-			return nil, err
-		}
-
-		// Compute the witness script hash from the to-remote pubkey, which will
-		// be used to locate the input on the breach commitment transaction.
-		toRemoteScriptHash, err = input.CommitScriptUnencumbered(
-			toRemotePubKey,
-		)
-		if err != nil {
-			return nil, err
-		}
+	// Derive the pkScript of the to-remote output, which will be used to
+	// locate the input on the breaching commitment transaction.
+	toRemotePkScript, err := p.JusticeKit.CommitToRemotePkScript()
+	if err != nil {
+		return nil, err
 	}
 
 	// Locate the to-remote output on the breaching commitment transaction.
 	toRemoteIndex, toRemoteTxOut, err := findTxOutByPkScript(
-		p.BreachedCommitTx, toRemoteScriptHash,
+		p.BreachedCommitTx, toRemotePkScript,
 	)
 	if err != nil {
 		return nil, err
@@ -153,18 +146,12 @@ func (p *JusticeDescriptor) commitToRemoteInput() (*breachedInput, error) {
 		Index: toRemoteIndex,
 	}
 
-	// Retrieve the to-remote witness stack, which is just a signature under
-	// the to-remote pubkey.
-	witnessStack, err := p.JusticeKit.CommitToRemoteWitnessStack()
-	if err != nil {
-		return nil, err
-	}
-
 	return &breachedInput{
-		txOut:    toRemoteTxOut,
-		outPoint: toRemoteOutPoint,
-		witness:  buildWitness(witnessStack, toRemoteScript),
-		sequence: toRemoteSequence,
+		txOut:       toRemoteTxOut,
+		outPoint:    toRemoteOutPoint,
+		witness:     spendInfo.Witness(),
+		witnessSize: spendInfo.WitnessSize,
+		sequence:    spendInfo.Sequence,
 	}, nil
 }
 
@@ -200,9 +187,19 @@ func (p *JusticeDescriptor) assembleJusticeTxn(txWeight int64,
 		return nil, err
 	}
 
-	// Attach the computed txouts to the justice transaction.
+	// Attach the computed txouts to the justice transaction, along with
+	// the zero-value OP_RETURN output if the justice kit carries a data
+	// commitment.
 	justiceTxn.TxOut = outputs
 
+	dataOutput, err := p.JusticeKit.DataCommitmentOutput()
+	if err != nil {
+		return nil, err
+	}
+	if dataOutput != nil {
+		justiceTxn.AddTxOut(dataOutput)
+	}
+
 	// Apply a BIP69 sort to the resulting transaction.
 	txsort.InPlaceSort(justiceTxn)
 
@@ -224,6 +221,10 @@ func (p *JusticeDescriptor) assembleJusticeTxn(txWeight int64,
 		return nil, fmt.Errorf("error creating previous output "+
 			"fetcher: %v", err)
 	}
+
+	// Taproot inputs are validated against the sighash midstate of the
+	// whole transaction, which the engine doesn't compute on its own.
+	hashCache := txscript.NewTxSigHashes(justiceTxn, prevOutFetcher)
 	for _, inp := range inputs {
 		// Lookup the input's new post-sort position.
 		i := inputIndex[inp.outPoint]
@@ -234,7 +235,7 @@ func (p *JusticeDescriptor) assembleJusticeTxn(txWeight int64,
 		vm, err := txscript.NewEngine(
 			inp.txOut.PkScript, justiceTxn, i,
 			txscript.StandardVerifyFlags,
-			nil, nil, inp.txOut.Value, prevOutFetcher,
+			nil, hashCache, inp.txOut.Value, prevOutFetcher,
 		)
 		if err != nil {
 			return nil, err
@@ -278,33 +279,49 @@ func (p *JusticeDescriptor) CreateJusticeTxn() (*wire.MsgTx, error) {
 	}
 
 	// Add our reward address to the weight estimate if the policy's blob
-	// type specifies a reward output.
+	// type specifies a reward output. The reward script is chosen by the
+	// tower for each session, so its contribution depends on its type.
+	// Scripts that aren't p2wsh or p2tr are estimated as p2wkh, which is
+	// what all reward outputs were assumed to be historically.
 	if p.SessionInfo.Policy.BlobType.Has(blob.FlagReward) {
-		weightEstimate.AddP2WKHOutput()
+		rewardPkScript := p.SessionInfo.RewardAddress
+		switch txscript.GetScriptClass(rewardPkScript) {
+		case txscript.WitnessV0ScriptHashTy:
+			weightEstimate.AddP2WSHOutput()
+
+		case txscript.WitnessV1TaprootTy:
+			weightEstimate.AddP2TROutput()
+
+		default:
+			weightEstimate.AddP2WKHOutput()
+		}
 	}
 
-	// Assemble the breached to-local output from the justice descriptor and
-	// add it to our weight estimate.
-	toLocalInput, err := p.commitToLocalInput()
+	// Add the OP_RETURN output committing to the justice kit's data
+	// commitment to the weight estimate, if it carries one.
+	dataOutput, err := p.JusticeKit.DataCommitmentOutput()
 	if err != nil {
 		return nil, err
 	}
-
-	// An older ToLocalPenaltyWitnessSize constant used to underestimate the
-	// size by one byte. The diferrence in weight can cause different output
-	// values on the sweep transaction, so we mimic the original bug to
-	// avoid invalidating signatures by older clients. For anchor channels
-	// we correct this and use the correct witness size.
-	if p.JusticeKit.BlobType.IsAnchorChannel() {
-		weightEstimate.AddWitnessInput(input.ToLocalPenaltyWitnessSize)
-	} else {
-		weightEstimate.AddWitnessInput(input.ToLocalPenaltyWitnessSize - 1)
+	if dataOutput != nil {
+		weightEstimate.AddTxOutput(dataOutput)
 	}
 
-	sweepInputs = append(sweepInputs, toLocalInput)
+	// Assemble the breached to-local output from the justice descriptor and
+	// add it to our weight estimate, unless the justice kit only sweeps
+	// the to-remote output.
+	if !p.JusticeKit.BlobType.Has(blob.FlagToRemoteOnly) {
+		toLocalInput, err := p.commitToLocalInput()
+		if err != nil {
+			return nil, err
+		}
+		sweepInputs = append(sweepInputs, toLocalInput)
+
+		log.Debugf("Found to local witness output=%#v, stack=%v",
+			toLocalInput.txOut, toLocalInput.witness)
 
-	log.Debugf("Found to local witness output=%#v, stack=%v",
-		toLocalInput.txOut, toLocalInput.witness)
+		weightEstimate.AddWitnessInput(toLocalInput.witnessSize)
+	}
 
 	// If the justice kit specifies that we have to sweep the to-remote
 	// output, we'll also try to assemble the output and add it to weight
@@ -319,11 +336,7 @@ func (p *JusticeDescriptor) CreateJusticeTxn() (*wire.MsgTx, error) {
 		log.Debugf("Found to remote witness output=%#v, stack=%v",
 			toRemoteInput.txOut, toRemoteInput.witness)
 
-		if p.JusticeKit.BlobType.IsAnchorChannel() {
-			weightEstimate.AddWitnessInput(input.ToRemoteConfirmedWitnessSize)
-		} else {
-			weightEstimate.AddWitnessInput(input.P2WKHWitnessSize)
-		}
+		weightEstimate.AddWitnessInput(toRemoteInput.witnessSize)
 	}
 
 	// TODO(conner): sweep htlc outputs
@@ -349,15 +362,6 @@ func findTxOutByPkScript(txn *wire.MsgTx,
 	return index, txn.TxOut[index], nil
 }
 
-// buildWitness appends the witness script to a given witness stack.
-func buildWitness(witnessStack [][]byte, witnessScript []byte) [][]byte {
-	witness := make([][]byte, len(witnessStack)+1)
-	lastIdx := copy(witness, witnessStack)
-	witness[lastIdx] = witnessScript
-
-	return witness
-}
-
 // prevOutFetcher returns a txscript.MultiPrevOutFetcher for the given set
 // of inputs.
 func prevOutFetcher(inputs []*breachedInput) (*txscript.MultiPrevOutFetcher,
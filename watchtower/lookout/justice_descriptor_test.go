@@ -1,6 +1,7 @@
 package lookout_test
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
@@ -52,6 +53,13 @@ var (
 	altruistCommitType = blob.FlagCommitOutputs.Type()
 
 	altruistAnchorCommitType = blob.TypeAltruistAnchorCommit
+
+	dataCommitmentType = blob.TypeFromFlags(
+		blob.FlagCommitOutputs, blob.FlagAnchorChannel,
+		blob.FlagDataCommitment,
+	)
+
+	dataCommitment = []byte("proof of burn")
 )
 
 // TestJusticeDescriptor asserts that a JusticeDescriptor is able to produce the
@@ -73,16 +81,152 @@ func TestJusticeDescriptor(t *testing.T) {
 			name:     "altruist anchor commit type",
 			blobType: altruistAnchorCommitType,
 		},
+		{
+			name:     "altruist anchor commit type with data commitment",
+			blobType: dataCommitmentType,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testJusticeDescriptor(
+				t, test.blobType, makeAddrSlice(22),
+				makeAddrSlice(22),
+			)
+		})
+	}
+}
+
+// TestJusticeDescriptorRewardScripts asserts that the tower builds a valid
+// justice transaction for the same blob type regardless of the type of reward
+// script it handed out for the session.
+func TestJusticeDescriptorRewardScripts(t *testing.T) {
+	_, rewardPK := btcec.PrivKeyFromBytes(revPrivBytes)
+
+	p2wkh, err := input.WitnessPubKeyHash(rewardPK.SerializeCompressed())
+	require.NoError(t, err)
+
+	p2tr, err := input.PayToTaprootScript(rewardPK)
+	require.NoError(t, err)
+
+	p2wsh, err := input.WitnessScriptHash(p2wkh)
+	require.NoError(t, err)
+
+	sweepPkScript := makeAddrSlice(22)
+	p2wkhTxn := testJusticeDescriptor(
+		t, rewardCommitType, sweepPkScript, p2wkh,
+	)
+	p2trTxn := testJusticeDescriptor(
+		t, rewardCommitType, sweepPkScript, p2tr,
+	)
+	p2wshTxn := testJusticeDescriptor(
+		t, rewardCommitType, sweepPkScript, p2wsh,
+	)
+
+	// Each justice transaction should pay the tower's reward to the
+	// script of its own session.
+	rewardOutput := func(tx *wire.MsgTx, pkScript []byte) *wire.TxOut {
+		for _, txOut := range tx.TxOut {
+			if bytes.Equal(txOut.PkScript, pkScript) {
+				return txOut
+			}
+		}
+
+		t.Fatalf("reward output %x not found", pkScript)
+		return nil
+	}
+	p2wkhReward := rewardOutput(p2wkhTxn, p2wkh)
+	p2trReward := rewardOutput(p2trTxn, p2tr)
+	p2wshReward := rewardOutput(p2wshTxn, p2wsh)
+
+	// The reward doesn't depend on the weight of the transaction, but the
+	// heavier p2tr and p2wsh outputs must be paid for by the victim's
+	// sweep output. Both are 34 bytes, so they cost the same.
+	require.Equal(t, p2wkhReward.Value, p2trReward.Value)
+	require.Equal(t, p2wkhReward.Value, p2wshReward.Value)
+	require.Less(t, totalOutputValue(p2trTxn), totalOutputValue(p2wkhTxn))
+	require.Equal(t, totalOutputValue(p2trTxn), totalOutputValue(p2wshTxn))
+}
+
+// TestJusticeDescriptorSweepScripts asserts that the tower builds a valid
+// justice transaction paying to the client's sweep script, whether it is a
+// P2WPKH script or a P2WSH script such as a multisig vault.
+func TestJusticeDescriptorSweepScripts(t *testing.T) {
+	_, toLocalPK := btcec.PrivKeyFromBytes(toLocalPrivBytes)
+	_, toRemotePK := btcec.PrivKeyFromBytes(toRemotePrivBytes)
+
+	p2wkh, err := input.WitnessPubKeyHash(toLocalPK.SerializeCompressed())
+	require.NoError(t, err)
+
+	vaultScript, err := input.GenMultiSigScript(
+		toLocalPK.SerializeCompressed(),
+		toRemotePK.SerializeCompressed(),
+	)
+	require.NoError(t, err)
+
+	p2wsh, err := input.WitnessScriptHash(vaultScript)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		blobType      blob.Type
+		sweepPkScript []byte
+	}{
+		{
+			name:          "p2wkh sweep",
+			blobType:      altruistAnchorCommitType,
+			sweepPkScript: p2wkh,
+		},
+		{
+			name:          "p2wsh sweep",
+			blobType:      altruistAnchorCommitType,
+			sweepPkScript: p2wsh,
+		},
+		{
+			name:          "p2wsh sweep with reward",
+			blobType:      rewardCommitType,
+			sweepPkScript: p2wsh,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			testJusticeDescriptor(t, test.blobType)
+			justiceTxn := testJusticeDescriptor(
+				t, test.blobType, test.sweepPkScript,
+				makeAddrSlice(22),
+			)
+
+			err := blockchain.CheckTransactionSanity(
+				btcutil.NewTx(justiceTxn),
+			)
+			require.NoError(t, err)
+
+			// Exactly one output should pay to the sweep script.
+			var numSweeps int
+			for _, txOut := range justiceTxn.TxOut {
+				pkScript := txOut.PkScript
+				if bytes.Equal(pkScript, test.sweepPkScript) {
+					numSweeps++
+				}
+			}
+			require.Equal(t, 1, numSweeps)
 		})
 	}
 }
 
-func testJusticeDescriptor(t *testing.T, blobType blob.Type) {
+// totalOutputValue returns the sum of the values of the outputs of tx.
+func totalOutputValue(tx *wire.MsgTx) int64 {
+	var total int64
+	for _, txOut := range tx.TxOut {
+		total += txOut.Value
+	}
+
+	return total
+}
+
+func testJusticeDescriptor(t *testing.T, blobType blob.Type,
+	sweepPkScript, rewardPkScript []byte) *wire.MsgTx {
+
 	isAnchorChannel := blobType.IsAnchorChannel()
 
 	const (
@@ -204,10 +348,34 @@ func testJusticeDescriptor(t *testing.T, blobType blob.Type) {
 	} else {
 		weightEstimate.AddWitnessInput(input.P2WKHWitnessSize)
 	}
-	weightEstimate.AddP2WKHOutput()
-	if blobType.Has(blob.FlagReward) {
+	if txscript.IsPayToWitnessScriptHash(sweepPkScript) {
+		weightEstimate.AddP2WSHOutput()
+	} else {
 		weightEstimate.AddP2WKHOutput()
 	}
+	if blobType.Has(blob.FlagReward) {
+		switch txscript.GetScriptClass(rewardPkScript) {
+		case txscript.WitnessV1TaprootTy:
+			weightEstimate.AddP2TROutput()
+
+		case txscript.WitnessV0ScriptHashTy:
+			weightEstimate.AddP2WSHOutput()
+
+		default:
+			weightEstimate.AddP2WKHOutput()
+		}
+	}
+
+	// If the blob type carries a data commitment, the justice transaction
+	// also contains a zero-value OP_RETURN output committing to it.
+	var dataOutput *wire.TxOut
+	if blobType.Has(blob.FlagDataCommitment) {
+		pkScript, err := txscript.NullDataScript(dataCommitment)
+		require.Nil(t, err)
+
+		dataOutput = wire.NewTxOut(0, pkScript)
+		weightEstimate.AddTxOutput(dataOutput)
+	}
 	txWeight := weightEstimate.Weight()
 
 	// Create a session info so that simulate agreement of the sweep
@@ -222,19 +390,22 @@ func testJusticeDescriptor(t *testing.T, blobType blob.Type) {
 	}
 	sessionInfo := &wtdb.SessionInfo{
 		Policy:        policy,
-		RewardAddress: makeAddrSlice(22),
+		RewardAddress: rewardPkScript,
 	}
 
 	// Begin to assemble the justice kit, starting with the sweep address,
 	// pubkeys, and csv delay.
 	justiceKit := &blob.JusticeKit{
 		BlobType:     blobType,
-		SweepAddress: makeAddrSlice(22),
+		SweepAddress: sweepPkScript,
 		CSVDelay:     csvDelay,
 	}
 	copy(justiceKit.RevocationPubKey[:], revPK.SerializeCompressed())
 	copy(justiceKit.LocalDelayPubKey[:], toLocalPK.SerializeCompressed())
 	copy(justiceKit.CommitToRemotePubKey[:], toRemotePK.SerializeCompressed())
+	if dataOutput != nil {
+		require.Nil(t, justiceKit.SetDataCommitment(dataCommitment))
+	}
 
 	// Create a transaction spending from the outputs of the breach
 	// transaction created earlier. The inputs are always ordered w/
@@ -267,6 +438,9 @@ func testJusticeDescriptor(t *testing.T, blobType blob.Type) {
 
 	// Attach the txouts and BIP69 sort the resulting transaction.
 	justiceTxn.TxOut = outputs
+	if dataOutput != nil {
+		justiceTxn.AddTxOut(dataOutput)
+	}
 	txsort.InPlaceSort(justiceTxn)
 
 	hashCache := input.NewTxSigHashesV0Only(justiceTxn)
@@ -367,4 +541,335 @@ func testJusticeDescriptor(t *testing.T, blobType blob.Type) {
 
 	// Assert that the watchtower derives the same justice txn.
 	require.Equal(t, justiceTxn, wtJusticeTxn)
+
+	// If the blob carries a data commitment, the justice txn must contain
+	// the OP_RETURN output in addition to the sweep.
+	if dataOutput != nil {
+		require.Len(t, wtJusticeTxn.TxOut, len(outputs)+1)
+		require.Contains(t, wtJusticeTxn.TxOut, dataOutput)
+	}
+
+	return wtJusticeTxn
+}
+
+// TestJusticeDescriptorTaproot asserts that the tower builds a valid justice
+// transaction for taproot channels, spending the to-local output via either
+// its revocation leaf or its key path, and the to-remote output via its leaf.
+func TestJusticeDescriptorTaproot(t *testing.T) {
+	tests := []struct {
+		name     string
+		blobType blob.Type
+	}{
+		{
+			name:     "altruist taproot commit type",
+			blobType: blob.TypeAltruistTaprootCommit,
+		},
+		{
+			name: "altruist taproot key spend type",
+			blobType: blob.TypeFromFlags(
+				blob.FlagCommitOutputs, blob.FlagTaprootChannel,
+				blob.FlagTaprootKeySpend,
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testJusticeDescriptorTaproot(t, test.blobType)
+		})
+	}
+}
+
+func testJusticeDescriptorTaproot(t *testing.T, blobType blob.Type) {
+	const (
+		localAmount  = btcutil.Amount(100000)
+		remoteAmount = btcutil.Amount(200000)
+		totalAmount  = localAmount + remoteAmount
+	)
+
+	revSK, revPK := btcec.PrivKeyFromBytes(revPrivBytes)
+	_, toLocalPK := btcec.PrivKeyFromBytes(toLocalPrivBytes)
+	toRemoteSK, toRemotePK := btcec.PrivKeyFromBytes(toRemotePrivBytes)
+
+	// Derive the taproot outputs of the breaching commitment. A to-local
+	// output revoked via its key path commits to the revocation key,
+	// tweaked with a tree consisting solely of the delayed leaf.
+	toLocalTree, err := input.NewLocalCommitScriptTree(
+		csvDelay, toLocalPK, revPK,
+	)
+	require.NoError(t, err)
+
+	toLocalKey := toLocalTree.TaprootKey
+	delayRoot := txscript.AssembleTaprootScriptTree(
+		toLocalTree.SettleLeaf,
+	).RootNode.TapHash()
+	toLocalWitnessSize := input.TaprootToLocalRevokeWitnessSize
+	if blobType.IsTaprootKeySpend() {
+		toLocalKey = txscript.ComputeTaprootOutputKey(
+			revPK, delayRoot[:],
+		)
+		toLocalWitnessSize = input.TaprootKeyPathWitnessSize
+	}
+	toLocalPkScript, err := input.PayToTaprootScript(toLocalKey)
+	require.NoError(t, err)
+
+	toRemoteTree, err := input.NewRemoteCommitScriptTree(toRemotePK)
+	require.NoError(t, err)
+	toRemotePkScript, err := input.PayToTaprootScript(
+		toRemoteTree.TaprootKey,
+	)
+	require.NoError(t, err)
+
+	breachTxn := &wire.MsgTx{
+		Version: 2,
+		TxOut: []*wire.TxOut{
+			wire.NewTxOut(int64(localAmount), toLocalPkScript),
+			wire.NewTxOut(int64(remoteAmount), toRemotePkScript),
+		},
+	}
+	breachTxID := breachTxn.TxHash()
+
+	// Compute the weight of the justice transaction, and the outputs the
+	// client signs over.
+	var weightEstimate input.TxWeightEstimator
+	weightEstimate.AddWitnessInput(toLocalWitnessSize)
+	weightEstimate.AddWitnessInput(input.TaprootToRemoteWitnessSize)
+	weightEstimate.AddP2WKHOutput()
+
+	policy := wtpolicy.Policy{
+		TxPolicy: wtpolicy.TxPolicy{
+			BlobType:     blobType,
+			SweepFeeRate: 2000,
+		},
+	}
+	sweepPkScript := makeAddrSlice(22)
+	outputs, err := policy.ComputeJusticeTxOuts(
+		totalAmount, int64(weightEstimate.Weight()), sweepPkScript,
+		nil,
+	)
+	require.NoError(t, err)
+
+	justiceTxn := &wire.MsgTx{
+		Version: 2,
+		TxIn: []*wire.TxIn{
+			{
+				PreviousOutPoint: wire.OutPoint{
+					Hash:  breachTxID,
+					Index: 0,
+				},
+			},
+			{
+				PreviousOutPoint: wire.OutPoint{
+					Hash:  breachTxID,
+					Index: 1,
+				},
+				Sequence: 1,
+			},
+		},
+		TxOut: outputs,
+	}
+	txsort.InPlaceSort(justiceTxn)
+
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	for i, txIn := range justiceTxn.TxIn {
+		prevOuts.AddPrevOut(txIn.PreviousOutPoint, breachTxn.TxOut[i])
+	}
+	hashes := txscript.NewTxSigHashes(justiceTxn, prevOuts)
+
+	// Sign the to-local input via the path dictated by the blob type, and
+	// the to-remote input via its leaf.
+	var toLocalSigRaw []byte
+	if blobType.IsTaprootKeySpend() {
+		toLocalSigRaw, err = txscript.RawTxInTaprootSignature(
+			justiceTxn, hashes, 0, int64(localAmount),
+			toLocalPkScript, delayRoot[:], txscript.SigHashDefault,
+			revSK,
+		)
+	} else {
+		toLocalSigRaw, err = txscript.RawTxInTapscriptSignature(
+			justiceTxn, hashes, 0, int64(localAmount),
+			toLocalPkScript, toLocalTree.RevocationLeaf,
+			txscript.SigHashDefault, revSK,
+		)
+	}
+	require.NoError(t, err)
+
+	toRemoteSigRaw, err := txscript.RawTxInTapscriptSignature(
+		justiceTxn, hashes, 1, int64(remoteAmount), toRemotePkScript,
+		toRemoteTree.SettleLeaf, txscript.SigHashDefault, toRemoteSK,
+	)
+	require.NoError(t, err)
+
+	toLocalSig, err := lnwire.NewSigFromSchnorrRawSignature(toLocalSigRaw)
+	require.NoError(t, err)
+	toRemoteSig, err := lnwire.NewSigFromSchnorrRawSignature(
+		toRemoteSigRaw,
+	)
+	require.NoError(t, err)
+
+	justiceKit := &blob.JusticeKit{
+		BlobType:          blobType,
+		SweepAddress:      sweepPkScript,
+		CSVDelay:          csvDelay,
+		CommitToLocalSig:  toLocalSig,
+		CommitToRemoteSig: toRemoteSig,
+	}
+	copy(justiceKit.RevocationPubKey[:], revPK.SerializeCompressed())
+	copy(justiceKit.LocalDelayPubKey[:], toLocalPK.SerializeCompressed())
+	copy(
+		justiceKit.CommitToRemotePubKey[:],
+		toRemotePK.SerializeCompressed(),
+	)
+
+	// Round trip the kit through encryption, such that the signatures are
+	// decoded as done by the tower.
+	var key blob.BreachKey
+	copy(key[:], makeAddrSlice(len(key)))
+	ctxt, err := justiceKit.Encrypt(key)
+	require.NoError(t, err)
+	justiceKit, err = blob.Decrypt(key, ctxt, blobType)
+	require.NoError(t, err)
+
+	justiceDesc := &lookout.JusticeDescriptor{
+		BreachedCommitTx: breachTxn,
+		SessionInfo: &wtdb.SessionInfo{
+			Policy: policy,
+		},
+		JusticeKit: justiceKit,
+	}
+
+	wtJusticeTxn, err := justiceDesc.CreateJusticeTxn()
+	require.NoError(t, err)
+
+	// The tower should have derived the same transaction the client
+	// signed, and each of its witnesses should satisfy the breached
+	// outputs.
+	require.Equal(t, justiceTxn.TxHash(), wtJusticeTxn.TxHash())
+
+	wtHashes := txscript.NewTxSigHashes(wtJusticeTxn, prevOuts)
+	for i, txIn := range wtJusticeTxn.TxIn {
+		prevOut := prevOuts.FetchPrevOutput(txIn.PreviousOutPoint)
+		vm, err := txscript.NewEngine(
+			prevOut.PkScript, wtJusticeTxn, i,
+			txscript.StandardVerifyFlags, nil, wtHashes,
+			prevOut.Value, prevOuts,
+		)
+		require.NoError(t, err)
+		require.NoErrorf(t, vm.Execute(), "input %d", i)
+	}
+}
+
+// TestJusticeDescriptorToRemoteOnly asserts that the tower sweeps only the
+// to-remote output of a breach matched to a session of a blob type with
+// FlagToRemoteOnly, leaving the to-local output untouched.
+func TestJusticeDescriptorToRemoteOnly(t *testing.T) {
+	const (
+		localAmount  = btcutil.Amount(100000)
+		remoteAmount = btcutil.Amount(200000)
+	)
+
+	_, revPK := btcec.PrivKeyFromBytes(revPrivBytes)
+	_, toLocalPK := btcec.PrivKeyFromBytes(toLocalPrivBytes)
+	toRemoteSK, toRemotePK := btcec.PrivKeyFromBytes(toRemotePrivBytes)
+
+	// The breaching commitment carries both outputs, though only the
+	// to-remote output is backed up.
+	toLocalScript, err := input.CommitScriptToSelf(
+		csvDelay, toLocalPK, revPK,
+	)
+	require.NoError(t, err)
+	toLocalPkScript, err := input.WitnessScriptHash(toLocalScript)
+	require.NoError(t, err)
+
+	toRemotePkScript, err := input.CommitScriptUnencumbered(toRemotePK)
+	require.NoError(t, err)
+
+	breachTxn := &wire.MsgTx{
+		Version: 2,
+		TxOut: []*wire.TxOut{
+			wire.NewTxOut(int64(localAmount), toLocalPkScript),
+			wire.NewTxOut(int64(remoteAmount), toRemotePkScript),
+		},
+	}
+
+	// The justice transaction spends the to-remote output alone.
+	var weightEstimate input.TxWeightEstimator
+	weightEstimate.AddWitnessInput(input.P2WKHWitnessSize)
+	weightEstimate.AddP2WKHOutput()
+
+	policy := wtpolicy.Policy{
+		TxPolicy: wtpolicy.TxPolicy{
+			BlobType:     blob.TypeAltruistToRemote,
+			SweepFeeRate: 2000,
+		},
+	}
+	sweepPkScript, err := input.WitnessPubKeyHash(
+		toLocalPK.SerializeCompressed(),
+	)
+	require.NoError(t, err)
+
+	outputs, err := policy.ComputeJusticeTxOuts(
+		remoteAmount, int64(weightEstimate.Weight()), sweepPkScript,
+		nil,
+	)
+	require.NoError(t, err)
+
+	toRemoteOutPoint := wire.OutPoint{Hash: breachTxn.TxHash(), Index: 1}
+	justiceTxn := &wire.MsgTx{
+		Version: 2,
+		TxIn: []*wire.TxIn{
+			{PreviousOutPoint: toRemoteOutPoint},
+		},
+		TxOut: outputs,
+	}
+
+	prevOuts := txscript.NewMultiPrevOutFetcher(nil)
+	prevOuts.AddPrevOut(toRemoteOutPoint, breachTxn.TxOut[1])
+	hashes := txscript.NewTxSigHashes(justiceTxn, prevOuts)
+
+	toRemoteSigRaw, err := txscript.RawTxInWitnessSignature(
+		justiceTxn, hashes, 0, int64(remoteAmount), toRemotePkScript,
+		txscript.SigHashAll, toRemoteSK,
+	)
+	require.NoError(t, err)
+
+	// Trim the sighash flag from the DER signature.
+	toRemoteSig, err := lnwire.NewSigFromECDSARawSignature(
+		toRemoteSigRaw[:len(toRemoteSigRaw)-1],
+	)
+	require.NoError(t, err)
+
+	justiceKit := &blob.JusticeKit{
+		BlobType:          blob.TypeAltruistToRemote,
+		SweepAddress:      sweepPkScript,
+		CommitToRemoteSig: toRemoteSig,
+	}
+	copy(
+		justiceKit.CommitToRemotePubKey[:],
+		toRemotePK.SerializeCompressed(),
+	)
+
+	justiceDesc := &lookout.JusticeDescriptor{
+		BreachedCommitTx: breachTxn,
+		SessionInfo: &wtdb.SessionInfo{
+			Policy: policy,
+		},
+		JusticeKit: justiceKit,
+	}
+
+	wtJusticeTxn, err := justiceDesc.CreateJusticeTxn()
+	require.NoError(t, err)
+
+	// The tower should have derived the same transaction the client
+	// signed, whose only input spends the to-remote output.
+	require.Equal(t, justiceTxn.TxHash(), wtJusticeTxn.TxHash())
+	require.Len(t, wtJusticeTxn.TxIn, 1)
+
+	vm, err := txscript.NewEngine(
+		toRemotePkScript, wtJusticeTxn, 0,
+		txscript.StandardVerifyFlags, nil, hashes,
+		int64(remoteAmount), prevOuts,
+	)
+	require.NoError(t, err)
+	require.NoError(t, vm.Execute())
 }
@@ -205,7 +205,7 @@ func (l *Lookout) processEpoch(epoch *chainntnfs.BlockEpoch,
 		// The decryption key for the state update should be computed as
 		//   key = SHA256(txid).
 		breachTxID := commitTx.TxHash()
-		breachKey := blob.NewBreachKeyFromHash(&breachTxID)
+		breachKey := blob.NewBreachKeyFromHash(&breachTxID, nil)
 
 		// Now, decrypt the blob of justice that we received in the
 		// state update. This will contain all information required to
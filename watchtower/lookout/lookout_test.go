@@ -159,8 +159,8 @@ func TestLookoutBreachMatching(t *testing.T) {
 		CommitToLocalSig: makeTestSig(2),
 	}
 
-	key1 := blob.NewBreachKeyFromHash(&hash1)
-	key2 := blob.NewBreachKeyFromHash(&hash2)
+	key1 := blob.NewBreachKeyFromHash(&hash1, nil)
+	key2 := blob.NewBreachKeyFromHash(&hash2, nil)
 
 	// Encrypt the first justice kit under breach key one.
 	encBlob1, err := blob1.Encrypt(key1)
@@ -74,6 +74,12 @@ func New(cfg *Config) (*Standalone, error) {
 	for _, listenAddr := range cfg.ListenAddrs {
 		listener, err := brontide.NewListener(
 			cfg.NodeKeyECDH, listenAddr.String(),
+			brontide.OnHandshakeError(
+				func(addr net.Addr, err error) {
+					log.Debugf("Unable to complete handshake "+
+						"with %v: %v", addr, err)
+				},
+			),
 		)
 		if err != nil {
 			return nil, err
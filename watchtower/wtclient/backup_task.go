@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	"github.com/btcsuite/btcd/blockchain"
-	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/btcutil/txsort"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -156,6 +155,15 @@ func (t *backupTask) bindSession(session *wtdb.ClientSessionBody,
 		totalAmt += breachInfo.RemoteOutputSignDesc.Output.Value
 	}
 	if breachInfo.LocalOutputSignDesc != nil {
+		// The to-remote key is copied into the justice kit to signal
+		// to the tower that the output should be swept, so we fail
+		// early rather than producing a kit that can't spend it.
+		if breachInfo.KeyRing == nil ||
+			breachInfo.KeyRing.ToRemoteKey == nil {
+
+			return ErrMissingToRemoteKey
+		}
+
 		var witnessType input.WitnessType
 		switch {
 		case chanType.HasAnchors():
@@ -282,24 +290,16 @@ func (t *backupTask) craftSessionPayload(
 	var hint blob.BreachHint
 
 	// First, copy over the sweep pkscript, the pubkeys used to derive the
-	// to-local script, and the remote CSV delay.
-	keyRing := t.breachInfo.KeyRing
-	justiceKit := &blob.JusticeKit{
-		BlobType:         t.blobType,
-		SweepAddress:     t.sweepPkScript,
-		RevocationPubKey: toBlobPubKey(keyRing.RevocationKey),
-		LocalDelayPubKey: toBlobPubKey(keyRing.ToLocalKey),
-		CSVDelay:         t.breachInfo.RemoteDelay,
-	}
-
-	// If this commitment has an output that pays to us, copy the to-remote
-	// pubkey into the justice kit. This serves as the indicator to the
-	// tower that we expect the breaching transaction to have a non-dust
-	// output to spend from.
-	if t.toRemoteInput != nil {
-		justiceKit.CommitToRemotePubKey = toBlobPubKey(
-			keyRing.ToRemoteKey,
-		)
+	// to-local script, and the remote CSV delay. If this commitment has an
+	// output that pays to us, the to-remote pubkey is copied as well. This
+	// serves as the indicator to the tower that we expect the breaching
+	// transaction to have a non-dust output to spend from.
+	justiceKit, err := blob.NewJusticeKit(
+		t.blobType, t.sweepPkScript, t.breachInfo,
+		t.toRemoteInput != nil,
+	)
+	if err != nil {
+		return hint, nil, err
 	}
 
 	// Now, begin construction of the justice transaction. We'll start with
@@ -381,24 +381,27 @@ func (t *backupTask) craftSessionPayload(
 		// field
 		switch inp.WitnessType() {
 		case input.CommitmentRevoke:
-			justiceKit.CommitToLocalSig = signature
+			err = justiceKit.AddToLocalSig(signature)
 
 		case input.CommitSpendNoDelayTweakless:
 			fallthrough
 		case input.CommitmentNoDelay:
 			fallthrough
 		case input.CommitmentToRemoteConfirmed:
-			justiceKit.CommitToRemoteSig = signature
+			err = justiceKit.AddToRemoteSig(signature)
 		default:
 			return hint, nil, fmt.Errorf("invalid witness type: %v",
 				inp.WitnessType())
 		}
+		if err != nil {
+			return hint, nil, err
+		}
 	}
 
 	breachTxID := t.breachInfo.BreachTxHash
 
 	// Compute the breach key as SHA256(txid).
-	hint, key := blob.NewBreachHintAndKeyFromHash(&breachTxID)
+	hint, key := blob.NewBreachHintAndKeyFromHash(&breachTxID, nil)
 
 	// Then, we'll encrypt the computed justice kit using the full breach
 	// transaction id, which will allow the tower to recover the contents
@@ -410,11 +413,3 @@ func (t *backupTask) craftSessionPayload(
 
 	return hint, encBlob, nil
 }
-
-// toBlobPubKey serializes the given pubkey into a blob.PubKey that can be set
-// as a field on a blob.JusticeKit.
-func toBlobPubKey(pubKey *btcec.PublicKey) blob.PubKey {
-	var blobPubKey blob.PubKey
-	copy(blobPubKey[:], pubKey.SerializeCompressed())
-	return blobPubKey
-}
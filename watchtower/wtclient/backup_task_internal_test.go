@@ -578,7 +578,7 @@ func testBackupTask(t *testing.T, test backupTaskTest) {
 
 	// Decrypt the return blob to obtain the JusticeKit containing its
 	// contents.
-	key := blob.NewBreachKeyFromHash(&breachTxID)
+	key := blob.NewBreachKeyFromHash(&breachTxID, nil)
 	jKit, err := blob.Decrypt(key, encBlob, policy.BlobType)
 	require.NoError(t, err, "unable to decrypt blob")
 
@@ -640,3 +640,97 @@ func testBackupTask(t *testing.T, test backupTaskTest) {
 			"should be empty")
 	}
 }
+
+// keyRingTests asserts that backup tasks reject breach retributions whose key
+// ring was mutated into an invalid state, either when binding to a session or
+// when crafting the session payload.
+var keyRingTests = []struct {
+	name          string
+	mutateKeyRing func(*lnwallet.CommitmentKeyRing)
+	bindErr       error
+	payloadErr    error
+}{
+	{
+		name: "missing to-remote key",
+		mutateKeyRing: func(keyRing *lnwallet.CommitmentKeyRing) {
+			keyRing.ToRemoteKey = nil
+		},
+		bindErr: ErrMissingToRemoteKey,
+	},
+	{
+		name: "degenerate keys",
+		mutateKeyRing: func(keyRing *lnwallet.CommitmentKeyRing) {
+			keyRing.ToLocalKey = keyRing.RevocationKey
+		},
+		payloadErr: blob.ErrDegenerateKeys,
+	},
+}
+
+// TestBackupTaskInvalidKeyRing runs the keyRingTests against a revoked state
+// with both a to-local and to-remote output.
+func TestBackupTaskInvalidKeyRing(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range keyRingTests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			testBackupTaskInvalidKeyRing(
+				t, test.mutateKeyRing, test.bindErr,
+				test.payloadErr,
+			)
+		})
+	}
+}
+
+func testBackupTaskInvalidKeyRing(t *testing.T,
+	mutateKeyRing func(*lnwallet.CommitmentKeyRing), bindErr,
+	payloadErr error) {
+
+	test := genTaskTest(
+		"commit no-reward",
+		100,                    // stateNum
+		200000,                 // toLocalAmt
+		100000,                 // toRemoteAmt
+		blobTypeCommitNoReward, // blobType
+		1000,                   // sweepFeeRate
+		nil,                    // rewardScript
+		0,                      // expSweepAmt
+		0,                      // expRewardAmt
+		nil,                    // bindErr
+		channeldb.SingleFunderTweaklessBit,
+	)
+
+	keyRing := *test.breachInfo.KeyRing
+	mutateKeyRing(&keyRing)
+	test.breachInfo.KeyRing = &keyRing
+
+	id := wtdb.BackupID{
+		ChanID:       test.chanID,
+		CommitHeight: test.breachInfo.RevokedStateNum,
+	}
+	task := newBackupTask(id, test.expSweepScript)
+
+	getBreachInfo := func(id lnwire.ChannelID, commitHeight uint64) (
+		*lnwallet.BreachRetribution, channeldb.ChannelType, error) {
+
+		return test.breachInfo, test.chanType, nil
+	}
+
+	err := task.bindSession(test.session, getBreachInfo)
+	require.ErrorIs(t, err, bindErr)
+	if bindErr != nil {
+		// The failed bind should leave the task untouched, so that it
+		// can be retried.
+		require.Nil(t, task.breachInfo)
+		require.Nil(t, task.toRemoteInput)
+		require.Zero(t, task.blobType)
+
+		return
+	}
+
+	_, _, err = task.craftSessionPayload(test.signer)
+	require.ErrorIs(t, err, payloadErr)
+}
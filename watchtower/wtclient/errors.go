@@ -39,4 +39,10 @@ var (
 	// create a new session with a tower with a session key that has already
 	// been used in the past.
 	ErrSessionKeyAlreadyUsed = errors.New("session key already used")
+
+	// ErrMissingToRemoteKey signals that a revoked state has a to-remote
+	// output, but its breach retribution doesn't carry the to-remote key
+	// needed by the tower to spend it.
+	ErrMissingToRemoteKey = errors.New("breach retribution missing " +
+		"to-remote key")
 )
@@ -471,8 +471,16 @@ func (n *sessionNegotiator) tryAddress(sessionKey keychain.SingleKeyECDH,
 
 	switch createSessionReply.Code {
 	case wtwire.CodeOK:
-		// TODO(conner): validate reward address
+		// If the policy pays the tower a reward, ensure the reward
+		// script is one whose weight we know how to estimate, as our
+		// signatures will commit to it.
 		rewardPkScript := createSessionReply.Data
+		if n.cfg.Policy.BlobType.Has(blob.FlagReward) {
+			err := wtpolicy.ValidateRewardPkScript(rewardPkScript)
+			if err != nil {
+				return err
+			}
+		}
 
 		sessionID := wtdb.NewSessionIDFromPubKey(sessionKey.PubKey())
 		dbClientSession := &wtdb.ClientSession{
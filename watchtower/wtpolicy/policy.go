@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/lightningnetwork/lnd/lnwallet"
 	"github.com/lightningnetwork/lnd/lnwallet/chainfee"
@@ -59,6 +60,11 @@ var (
 	// ErrSweepFeeRateTooLow signals that the policy's fee rate is too low
 	// to get into the mempool during low congestion.
 	ErrSweepFeeRateTooLow = errors.New("sweep fee rate too low")
+
+	// ErrNonStandardRewardScript signals that the reward pkscript offered
+	// by a tower is not a p2wkh, p2wsh or p2tr output script.
+	ErrNonStandardRewardScript = errors.New("reward script is not " +
+		"p2wkh, p2wsh or p2tr")
 )
 
 // DefaultPolicy returns a Policy containing the default parameters that can be
@@ -294,3 +300,21 @@ func (p *Policy) ComputeJusticeTxOuts(totalAmt btcutil.Amount, txWeight int64,
 
 	return outputs, nil
 }
+
+// ValidateRewardPkScript checks that the reward pkscript is one of the standard
+// output scripts whose weight can be estimated by both the client and the
+// tower, namely p2wkh, p2wsh or p2tr. Since the reward output is covered by the
+// client's signatures, both parties must agree on its weight when computing
+// the output values of the justice transaction.
+func ValidateRewardPkScript(pkScript []byte) error {
+	switch txscript.GetScriptClass(pkScript) {
+	case txscript.WitnessV0PubKeyHashTy, txscript.WitnessV0ScriptHashTy,
+		txscript.WitnessV1TaprootTy:
+
+		return nil
+
+	default:
+		return fmt.Errorf("%w: %x", ErrNonStandardRewardScript,
+			pkScript)
+	}
+}
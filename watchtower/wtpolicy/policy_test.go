@@ -3,6 +3,8 @@ package wtpolicy_test
 import (
 	"testing"
 
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
 	"github.com/lightningnetwork/lnd/watchtower/blob"
 	"github.com/lightningnetwork/lnd/watchtower/wtpolicy"
 	"github.com/stretchr/testify/require"
@@ -110,3 +112,138 @@ func TestPolicyIsAnchorChannel(t *testing.T) {
 	}
 	require.Equal(t, true, policyAnchor.IsAnchorChannel())
 }
+
+// TestComputeJusticeTxOutsReward asserts that the reward output of a justice
+// transaction is derived from the policy's configured reward base and rate,
+// such that operators can price their service.
+func TestComputeJusticeTxOutsReward(t *testing.T) {
+	const txWeight = 1000
+
+	var (
+		sweepPkScript  = make([]byte, 22)
+		rewardPkScript = make([]byte, 22)
+	)
+	sweepPkScript[0] = 0x01
+	rewardPkScript[0] = 0x02
+
+	tests := []struct {
+		name       string
+		rewardBase uint32
+		rewardRate uint32
+		totalAmt   btcutil.Amount
+	}{
+		{
+			name:       "default rate",
+			rewardRate: wtpolicy.DefaultRewardRate,
+			totalAmt:   100000,
+		},
+		{
+			name:       "base only",
+			rewardBase: 5000,
+			totalAmt:   100000,
+		},
+		{
+			name:       "rate only",
+			rewardRate: 25000,
+			totalAmt:   1000000,
+		},
+		{
+			name:       "base and rate, rounded up",
+			rewardBase: 1234,
+			rewardRate: 333,
+			totalAmt:   987654,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			policy := wtpolicy.Policy{
+				TxPolicy: wtpolicy.TxPolicy{
+					BlobType:     blob.TypeRewardCommit,
+					RewardBase:   test.rewardBase,
+					RewardRate:   test.rewardRate,
+					SweepFeeRate: wtpolicy.DefaultSweepFeeRate,
+				},
+			}
+
+			outputs, err := policy.ComputeJusticeTxOuts(
+				test.totalAmt, txWeight, sweepPkScript,
+				rewardPkScript,
+			)
+			require.NoError(t, err)
+			require.Len(t, outputs, 2)
+
+			// The proportional reward is computed on the total
+			// remaining after the base, rounded up to the nearest
+			// satoshi.
+			base := btcutil.Amount(test.rewardBase)
+			rate := btcutil.Amount(test.rewardRate)
+			expReward := base + ((test.totalAmt-base)*rate+
+				wtpolicy.RewardScale-1)/wtpolicy.RewardScale
+
+			txFee := policy.SweepFeeRate.FeeForWeight(txWeight)
+			expSweep := test.totalAmt - expReward - txFee
+
+			require.Equal(t, sweepPkScript, outputs[0].PkScript)
+			require.EqualValues(t, expSweep, outputs[0].Value)
+			require.Equal(t, rewardPkScript, outputs[1].PkScript)
+			require.EqualValues(t, expReward, outputs[1].Value)
+		})
+	}
+}
+
+// TestValidateRewardPkScript asserts that only p2wkh, p2wsh and p2tr scripts
+// are accepted as reward scripts.
+func TestValidateRewardPkScript(t *testing.T) {
+	witnessScript := func(version byte, size int) []byte {
+		script, err := txscript.NewScriptBuilder().
+			AddOp(version).
+			AddData(make([]byte, size)).
+			Script()
+		require.NoError(t, err)
+
+		return script
+	}
+
+	tests := []struct {
+		name     string
+		pkScript []byte
+		expErr   error
+	}{
+		{
+			name:     "p2wkh",
+			pkScript: witnessScript(txscript.OP_0, 20),
+		},
+		{
+			name:     "p2wsh",
+			pkScript: witnessScript(txscript.OP_0, 32),
+		},
+		{
+			name:     "p2tr",
+			pkScript: witnessScript(txscript.OP_1, 32),
+		},
+		{
+			name:     "unknown witness version",
+			pkScript: witnessScript(txscript.OP_2, 32),
+			expErr:   wtpolicy.ErrNonStandardRewardScript,
+		},
+		{
+			name:   "empty",
+			expErr: wtpolicy.ErrNonStandardRewardScript,
+		},
+		{
+			name:     "garbage",
+			pkScript: []byte{0x01, 0x02, 0x03},
+			expErr:   wtpolicy.ErrNonStandardRewardScript,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			err := wtpolicy.ValidateRewardPkScript(test.pkScript)
+			require.ErrorIs(t, err, test.expErr)
+		})
+	}
+}